@@ -20,6 +20,7 @@ package api
 import (
 	"context"
 	"fmt"
+	"github.com/Peripli/service-manager/api/approvals"
 	"github.com/Peripli/service-manager/api/configuration"
 	"github.com/Peripli/service-manager/api/profile"
 	"github.com/Peripli/service-manager/operations"
@@ -30,6 +31,7 @@ import (
 	"github.com/Peripli/service-manager/pkg/query"
 
 	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/util/slice"
 
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/ws"
@@ -48,23 +50,24 @@ const osbVersion = "2.14"
 
 // Settings type to be loaded from the environment
 type Settings struct {
-	ServiceManagerTenantId     string   `mapstructure:"service_manager_tenant_id" description:"tenant id of the service manager"`
-	TokenIssuerURL             string   `mapstructure:"token_issuer_url" description:"url of the token issuer which to use for validating tokens"`
-	ClientID                   string   `mapstructure:"client_id" description:"id of the client from which the token must be issued"`
-	TokenBasicAuth             bool     `mapstructure:"token_basic_auth" description:"specifies if client credentials to the authorization server should be sent in the header as basic auth (true) or in the body (false)"`
-	ProtectedLabels            []string `mapstructure:"protected_labels" description:"defines labels which cannot be modified/added by REST API requests"`
-	OSBVersion                 string   `mapstructure:"-"`
-	MaxPageSize                int      `mapstructure:"max_page_size" description:"maximum number of items that could be returned in a single page"`
-	DefaultPageSize            int      `mapstructure:"default_page_size" description:"default number of items returned in a single page if not specified in request"`
-	EnableInstanceTransfer     bool     `mapstructure:"enable_instance_transfer" description:"whether service instance transfer is enabled or not"`
-	RateLimit                  string   `mapstructure:"rate_limit" description:"rate limiter configuration defined in format: rate<:path><,rate<:path>,...>"`
-	RateLimitingEnabled        bool     `mapstructure:"rate_limiting_enabled" description:"enable rate limiting"`
-	RateLimitExcludeClients    []string `mapstructure:"rate_limit_exclude_clients" description:"define client users that should be excluded from the rate limiter processing"`
-	RateLimitExcludePaths      []string `mapstructure:"rate_limit_exclude_paths" description:"define paths that should be excluded from the rate limiter processing"`
-	RateLimitUsageLogThreshold int64    `mapstructure:"rate_limiting_usage_log_threshold" description:"defines a threshold for log notification trigger about requests limit usage. Accepts value in range from 0 to 100 (percents)"`
-	DisabledQueryParameters    []string `mapstructure:"disabled_query_parameters" description:"which query parameters are not implemented by service manager and should be extended"`
-	OSBRSAPublicKey            string   `mapstructure:"osb_rsa_public_key"`
-	OSBRSAPrivateKey           string   `mapstructure:"osb_rsa_private_key"`
+	ServiceManagerTenantId     string                     `mapstructure:"service_manager_tenant_id" description:"tenant id of the service manager"`
+	TokenIssuerURL             string                     `mapstructure:"token_issuer_url" description:"url of the token issuer which to use for validating tokens"`
+	ClientID                   string                     `mapstructure:"client_id" description:"id of the client from which the token must be issued"`
+	TokenBasicAuth             bool                       `mapstructure:"token_basic_auth" description:"specifies if client credentials to the authorization server should be sent in the header as basic auth (true) or in the body (false)"`
+	ProtectedLabels            []string                   `mapstructure:"protected_labels" description:"defines labels which cannot be modified/added by REST API requests"`
+	OSBVersion                 string                     `mapstructure:"-"`
+	MaxPageSize                int                        `mapstructure:"max_page_size" description:"maximum number of items that could be returned in a single page"`
+	DefaultPageSize            int                        `mapstructure:"default_page_size" description:"default number of items returned in a single page if not specified in request"`
+	EnableInstanceTransfer     bool                       `mapstructure:"enable_instance_transfer" description:"whether service instance transfer is enabled or not"`
+	RateLimit                  string                     `mapstructure:"rate_limit" description:"rate limiter configuration defined in format: rate<:path><,rate<:path>,...>"`
+	RateLimitingEnabled        bool                       `mapstructure:"rate_limiting_enabled" description:"enable rate limiting"`
+	RateLimitExcludeClients    []string                   `mapstructure:"rate_limit_exclude_clients" description:"define client users that should be excluded from the rate limiter processing"`
+	RateLimitExcludePaths      []string                   `mapstructure:"rate_limit_exclude_paths" description:"define paths that should be excluded from the rate limiter processing"`
+	RateLimitUsageLogThreshold int64                      `mapstructure:"rate_limiting_usage_log_threshold" description:"defines a threshold for log notification trigger about requests limit usage. Accepts value in range from 0 to 100 (percents)"`
+	DisabledQueryParameters    []string                   `mapstructure:"disabled_query_parameters" description:"which query parameters are not implemented by service manager and should be extended"`
+	OSBRSAPublicKey            string                     `mapstructure:"osb_rsa_public_key"`
+	OSBRSAPrivateKey           string                     `mapstructure:"osb_rsa_private_key" sensitive:"true"`
+	RemotePlugins              []osb.RemotePluginSettings `mapstructure:"remote_plugins" description:"external endpoints which intercept OSB requests as plugins"`
 }
 
 // DefaultSettings returns default values for API settings
@@ -83,14 +86,42 @@ func DefaultSettings() *Settings {
 		RateLimitExcludeClients:    []string{},
 		RateLimitUsageLogThreshold: 10,
 		DisabledQueryParameters:    []string{},
+		RemotePlugins:              []osb.RemotePluginSettings{},
 	}
 }
 
+// builtinPluginNames are the names of the OSB plugins of the Service Manager, which remote plugins cannot have
+var builtinPluginNames = []string{
+	osb.CatalogFilterByVisibilityPluginName,
+	osb.OSBStorePluginName,
+	osb.CheckVisibilityPluginName,
+	osb.CheckPlatformIDPluginName,
+	osb.PlatformTerminationPluginName,
+	osb.InstanceSharingPluginName,
+	osb.ContextSignaturePluginName,
+	osb.CheckInstanceOwnerhipPluginName,
+	osb.QuotaPluginName,
+}
+
 // Validate validates the API settings
 func (s *Settings) Validate() error {
 	if (len(s.TokenIssuerURL)) == 0 {
 		return fmt.Errorf("validate Settings: APITokenIssuerURL missing")
 	}
+	remotePluginNames := make(map[string]bool, len(s.RemotePlugins))
+	for i := range s.RemotePlugins {
+		if err := s.RemotePlugins[i].Validate(); err != nil {
+			return err
+		}
+		name := s.RemotePlugins[i].Name
+		if slice.StringsAnyEquals(builtinPluginNames, name) {
+			return fmt.Errorf("validate Settings: remote plugin name %s is the name of a built-in plugin", name)
+		}
+		if remotePluginNames[name] {
+			return fmt.Errorf("validate Settings: duplicate remote plugin name %s", name)
+		}
+		remotePluginNames[name] = true
+	}
 	return validateRateLimiterConfiguration(s.RateLimit)
 }
 
@@ -103,6 +134,7 @@ type Options struct {
 	WaitGroup         *sync.WaitGroup
 	TenantLabelKey    string
 	Agents            *agents.Settings
+	ConfigSettings    interface{}
 }
 
 // New returns the minimum set of REST APIs needed for the Service Manager
@@ -124,7 +156,23 @@ func New(ctx context.Context, e env.Environment, options *Options) (*web.API, er
 			NewController(ctx, options, web.VisibilitiesURL, types.VisibilityType, func() types.Object {
 				return &types.Visibility{}
 			}, false),
-			NewTenantController(options.Repository),
+			NewController(ctx, options, web.QuotasURL, types.QuotaType, func() types.Object {
+				return &types.Quota{}
+			}, false),
+			NewController(ctx, options, web.RolesURL, types.RoleType, func() types.Object {
+				return &types.Role{}
+			}, false),
+			NewController(ctx, options, web.RoleBindingsURL, types.RoleBindingType, func() types.Object {
+				return &types.RoleBinding{}
+			}, false),
+			NewAPITokenController(ctx, options),
+			NewController(ctx, options, web.AdmissionWebhooksURL, types.AdmissionWebhookType, func() types.Object {
+				return &types.AdmissionWebhook{}
+			}, false),
+			NewController(ctx, options, web.LabelPoliciesURL, types.LabelPolicyType, func() types.Object {
+				return &types.LabelPolicy{}
+			}, false),
+			NewTenantController(options.Repository, options.TenantLabelKey),
 			NewServiceInstanceController(ctx, options),
 			NewServiceBindingController(ctx, options),
 			apiNotifications.NewController(ctx, options.Repository, options.WSSettings, options.Notificator),
@@ -132,6 +180,7 @@ func New(ctx context.Context, e env.Environment, options *Options) (*web.API, er
 			NewServiceOfferingController(ctx, options),
 			NewServicePlanController(ctx, options),
 			NewOperationsController(ctx, options),
+			approvals.NewController(operations.NewApprovals(ctx, options.Repository, options.OperationSettings, options.WaitGroup), options.OperationSettings.ApproverScope),
 			NewAgentsController(options.Agents),
 
 			&credentialsController{
@@ -156,20 +205,25 @@ func New(ctx context.Context, e env.Environment, options *Options) (*web.API, er
 			},
 			&configuration.Controller{
 				Environment: e,
+				Settings:    options.ConfigSettings,
 			},
 			&profile.Controller{},
 		},
 		// Default filters - more filters can be registered using the relevant API methods
 		Filters: []web.Filter{
 			&filters.Logging{},
+			&filters.WriteTracking{},
 			&filters.SupportedEncodingsFilter{},
 			&filters.SelectionCriteria{},
 			&filters.ServiceInstanceStripFilter{},
 			&filters.ServiceBindingStripFilter{},
 			filters.NewProtectedLabelsFilter(options.APISettings.ProtectedLabels),
 			&filters.ProtectedSMPlatformFilter{},
+			&filters.RBACAdministrationFilter{},
+			&filters.APITokenCreatorFilter{},
 			&filters.PlatformIDInstanceValidationFilter{},
-			&filters.PlatformAwareVisibilityFilter{},
+			filters.NewPlatformAwareVisibilityFilter(options.Repository),
+			&filters.VisibilityPlatformSelectorFilter{},
 			&filters.PatchOnlyLabelsFilter{},
 			filters.NewPlansFilterByVisibility(options.Repository),
 			filters.NewServicesFilterByVisibility(options.Repository),
@@ -179,6 +233,7 @@ func New(ctx context.Context, e env.Environment, options *Options) (*web.API, er
 			&filters.CheckBrokerCredentialsFilter{},
 			filters.NewServiceInstanceTransferFilter(options.Repository, options.APISettings.EnableInstanceTransfer),
 			filters.NewPlatformTerminationFilter(options.Repository),
+			osb.NewAggregatedBrokerFilter(options.Repository),
 		},
 		Registry: health.NewDefaultRegistry(),
 	}
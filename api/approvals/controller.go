@@ -0,0 +1,148 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package approvals
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/Peripli/service-manager/operations"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// RejectRequest is the body of a request to reject an operation awaiting approval
+type RejectRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Controller lists, approves and rejects operations awaiting approval
+type Controller struct {
+	approvals     *operations.Approvals
+	approverScope string
+}
+
+// NewController returns a new approvals controller. Operations are approved and rejected by users with the approver
+// scope or whose requests are authorized by the security policy.
+func NewController(approvals *operations.Approvals, approverScope string) *Controller {
+	return &Controller{
+		approvals:     approvals,
+		approverScope: approverScope,
+	}
+}
+
+// Routes provides endpoints for listing, approving and rejecting operations awaiting approval
+func (c *Controller) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.ApprovalsURL,
+			},
+			Handler: c.list,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   fmt.Sprintf("%s/{%s}/approve", web.ApprovalsURL, web.PathParamID),
+			},
+			Handler: c.approve,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   fmt.Sprintf("%s/{%s}/reject", web.ApprovalsURL, web.PathParamID),
+			},
+			Handler: c.reject,
+		},
+	}
+}
+
+func (c *Controller) list(r *web.Request) (*web.Response, error) {
+	requests, err := c.approvals.Pending(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	return util.NewJSONResponse(http.StatusOK, struct {
+		Approvals []*operations.ApprovalRequest `json:"approvals"`
+	}{
+		Approvals: requests,
+	})
+}
+
+func (c *Controller) approve(r *web.Request) (*web.Response, error) {
+	if err := c.checkApprover(r); err != nil {
+		return nil, err
+	}
+	request, err := c.approvals.Approve(r.Context(), r.PathParams[web.PathParamID], userName(r))
+	if err != nil {
+		return nil, err
+	}
+	return util.NewLocationResponse(request.OperationID, request.ResourceID, request.ResourceType.String())
+}
+
+func (c *Controller) reject(r *web.Request) (*web.Response, error) {
+	if err := c.checkApprover(r); err != nil {
+		return nil, err
+	}
+	rejectRequest := &RejectRequest{}
+	if len(r.Body) > 0 {
+		if err := util.BytesToObject(r.Body, rejectRequest); err != nil {
+			return nil, err
+		}
+	}
+
+	request, err := c.approvals.Reject(r.Context(), r.PathParams[web.PathParamID], userName(r), rejectRequest.Reason)
+	if err != nil {
+		return nil, err
+	}
+	return util.NewJSONResponse(http.StatusOK, request)
+}
+
+// checkApprover verifies that the user may decide operations awaiting approval. Users whose request was only
+// authenticated need the approver scope, users authorized by a rule of the security policy, for example by a role
+// permitting them to create approvals, are approvers.
+func (c *Controller) checkApprover(r *web.Request) error {
+	user, ok := web.UserFromContext(r.Context())
+	if !ok || user.AccessLevel != web.NoAccess {
+		return nil
+	}
+	if user.Data != nil {
+		var claims json.RawMessage
+		if err := user.Data(&claims); err != nil {
+			return fmt.Errorf("could not unmarshal claims of user %s: %s", user.Name, err)
+		}
+		for _, scope := range gjson.GetBytes(claims, "scope").Array() {
+			if scope.String() == c.approverScope {
+				return nil
+			}
+		}
+	}
+	return security.ForbiddenHTTPError(fmt.Sprintf("user %s is not permitted to approve or reject operations without scope %s", user.Name, c.approverScope))
+}
+
+func userName(r *web.Request) string {
+	user, ok := web.UserFromContext(r.Context())
+	if !ok {
+		return "unknown user"
+	}
+	return user.Name
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package approvals
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Approvals controller", func() {
+	var controller *Controller
+
+	request := func(accessLevel web.AccessLevel, claims string) *web.Request {
+		ctx := web.ContextWithUser(context.Background(), &web.UserContext{
+			Data: func(data interface{}) error {
+				return json.Unmarshal([]byte(claims), data)
+			},
+			AuthenticationType: web.Bearer,
+			Name:               "user",
+			AccessLevel:        accessLevel,
+		})
+		return &web.Request{Request: (&http.Request{Method: http.MethodPost}).WithContext(ctx)}
+	}
+
+	BeforeEach(func() {
+		controller = NewController(nil, "sm.approver")
+	})
+
+	It("rejects authenticated users without the approver scope", func() {
+		for _, handler := range []web.HandlerFunc{controller.approve, controller.reject} {
+			_, err := handler(request(web.NoAccess, `{"scope": ["sm.read"]}`))
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusForbidden))
+		}
+	})
+
+	It("accepts users with the approver scope", func() {
+		Expect(controller.checkApprover(request(web.NoAccess, `{"scope": ["sm.read", "sm.approver"]}`))).To(Succeed())
+	})
+
+	It("accepts users authorized by the security policy", func() {
+		Expect(controller.checkApprover(request(web.TenantAccess, `{}`))).To(Succeed())
+	})
+})
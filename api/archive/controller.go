@@ -0,0 +1,118 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package archive
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// PassphraseHeader is the header holding the passphrase with which the secrets in an archive are encrypted
+const PassphraseHeader = "X-Archive-Passphrase"
+
+// QueryParamTenant restricts an export to the objects of a single tenant
+const QueryParamTenant = "tenant"
+
+// Controller exports the Service Manager state into an archive and imports archives
+type Controller struct {
+	archiver       *storage.Archiver
+	tenantLabelKey string
+}
+
+// NewController returns a new archive controller
+func NewController(archiver *storage.Archiver, tenantLabelKey string) *Controller {
+	return &Controller{
+		archiver:       archiver,
+		tenantLabelKey: tenantLabelKey,
+	}
+}
+
+// Routes provides endpoints for exporting and importing archives
+func (c *Controller) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.ExportURL,
+			},
+			Handler: c.export,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   web.ImportURL,
+			},
+			Handler: c.importArchive,
+		},
+	}
+}
+
+func (c *Controller) export(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	passphrase, err := archivePassphrase(r)
+	if err != nil {
+		return nil, err
+	}
+	tenant := r.URL.Query().Get(QueryParamTenant)
+	log.C(ctx).Infof("Exporting Service Manager state (tenant: %q)", tenant)
+
+	// the archive is streamed, so errors after the first record can only be reported by the missing archive end
+	rw := r.HijackResponseWriter()
+	rw.Header().Set("Content-Type", util.NDJSONContentType)
+	rw.WriteHeader(http.StatusOK)
+	if _, err := c.archiver.Export(ctx, rw, storage.ExportOptions{
+		Passphrase:     passphrase,
+		TenantLabelKey: c.tenantLabelKey,
+		Tenant:         tenant,
+	}); err != nil {
+		log.C(ctx).WithError(err).Error("Export failed, the archive is incomplete")
+	}
+	return &web.Response{}, nil
+}
+
+// importArchive streams the archive from the request body, which is not buffered for the import endpoint
+func (c *Controller) importArchive(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	passphrase, err := archivePassphrase(r)
+	if err != nil {
+		return nil, err
+	}
+
+	log.C(ctx).Info("Importing archive")
+	summary, err := c.archiver.Import(ctx, r.Request.Body, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return util.NewJSONResponse(http.StatusOK, summary)
+}
+
+func archivePassphrase(r *web.Request) (string, error) {
+	passphrase := r.Header.Get(PassphraseHeader)
+	if passphrase == "" {
+		return "", &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: PassphraseHeader + " header should be provided",
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	return passphrase, nil
+}
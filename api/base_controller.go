@@ -202,6 +202,7 @@ func (c *BaseController) CreateObject(r *web.Request) (*web.Response, error) {
 		Context:       c.prepareOperationContextByRequest(r),
 	}
 
+	ctx = operations.ContextWithResource(ctx, result, nil)
 	createdObj, isAsync, err := c.scheduler.ScheduleStorageAction(ctx, operation, action, c.supportsAsync)
 	if err != nil {
 		return nil, err
@@ -216,7 +217,9 @@ func (c *BaseController) CreateObject(r *web.Request) (*web.Response, error) {
 	}
 
 	cleanObject(ctx, createdObj.GetLastOperation())
-	return util.NewJSONResponse(http.StatusCreated, createdObj)
+	return util.NewJSONResponseWithHeaders(http.StatusCreated, createdObj, map[string]string{
+		"ETag": util.ETag(createdObj.GetUpdatedAt()),
+	})
 }
 
 // DeleteObjects handles the deletion of the objects specified in the request
@@ -258,12 +261,28 @@ func (c *BaseController) DeleteSingleObject(r *web.Request) (*web.Response, erro
 	criteria := query.CriteriaForContext(ctx)
 	opCtx := c.prepareOperationContextByRequest(r)
 
+	versioned := len(r.Header.Get("If-Match")) > 0
+	if versioned {
+		object, err := c.repository.Get(ctx, c.objectType, criteria...)
+		if err != nil {
+			return nil, util.HandleStorageError(err, c.objectType.String())
+		}
+		if err := util.IfMatch(r.Request, util.ETag(object.GetUpdatedAt())); err != nil {
+			return nil, err
+		}
+		// the resource is deleted only if it was not modified since it was matched
+		criteria = append(append([]query.Criterion{}, criteria...), storage.ByVersion(object.GetUpdatedAt()))
+	}
+
 	action := func(ctx context.Context, repository storage.Repository) (types.Object, error) {
 		// At this point, the resource will be already deleted if cascade operation requested.
 		if c.supportsCascadeDelete && opCtx.Cascade {
 			return nil, nil
 		}
 		err := repository.Delete(ctx, c.objectType, criteria...)
+		if err == util.ErrNotFoundInStorage && versioned {
+			err = util.ErrConcurrentResourceModification
+		}
 		return nil, util.HandleStorageError(err, c.objectType.String())
 	}
 
@@ -360,6 +379,16 @@ func (c *BaseController) GetSingleObject(r *web.Request) (*web.Response, error)
 		return nil, util.HandleStorageError(err, c.objectType.String())
 	}
 
+	etag := util.ETag(object.GetUpdatedAt())
+	if util.IfNoneMatch(r.Request, etag) {
+		header := http.Header{}
+		header.Set("ETag", etag)
+		return &web.Response{
+			StatusCode: http.StatusNotModified,
+			Header:     header,
+		}, nil
+	}
+
 	cleanObject(ctx, object)
 
 	if err := attachLastOperation(ctx, objectID, object, c.repository); err != nil {
@@ -367,7 +396,9 @@ func (c *BaseController) GetSingleObject(r *web.Request) (*web.Response, error)
 	}
 
 	cleanObject(ctx, object.GetLastOperation())
-	return util.NewJSONResponse(http.StatusOK, object)
+	return util.NewJSONResponseWithHeaders(http.StatusOK, object, map[string]string{
+		"ETag": etag,
+	})
 }
 
 // GetOperation handles the fetching of a single operation with the id specified for the specified resource
@@ -396,6 +427,12 @@ func GetResourceOperation(r *web.Request, repository storage.Repository, objectT
 		return nil, util.HandleStorageError(err, objectType.String())
 	}
 
+	if op := operation.(*types.Operation); op.State == types.IN_PROGRESS {
+		if op.QueuePosition, err = operations.QueuePosition(ctx, repository, op.ID); err != nil {
+			return nil, util.HandleStorageError(err, types.OperationType.String())
+		}
+	}
+
 	return util.NewJSONResponse(http.StatusOK, operation)
 }
 
@@ -491,6 +528,14 @@ func (c *BaseController) PatchObject(r *web.Request) (*web.Response, error) {
 		return nil, util.HandleStorageError(err, c.objectType.String())
 	}
 
+	updateCriteria := criteria
+	if err := util.IfMatch(r.Request, util.ETag(objFromDB.GetUpdatedAt())); err != nil {
+		return nil, err
+	} else if len(r.Header.Get("If-Match")) > 0 {
+		// the object is updated only if it was not modified since it was matched
+		updateCriteria = append(append([]query.Criterion{}, criteria...), storage.ByVersion(objFromDB.GetUpdatedAt()))
+	}
+
 	if r.Body, err = sjson.DeleteBytes(r.Body, "labels"); err != nil {
 		return nil, err
 	}
@@ -510,7 +555,7 @@ func (c *BaseController) PatchObject(r *web.Request) (*web.Response, error) {
 	objFromDB.SetReady(true)
 
 	action := func(ctx context.Context, repository storage.Repository) (types.Object, error) {
-		object, err := repository.Update(ctx, objFromDB, labelChanges, criteria...)
+		object, err := repository.Update(ctx, objFromDB, labelChanges, updateCriteria...)
 		return object, util.HandleStorageError(err, c.objectType.String())
 	}
 
@@ -535,6 +580,7 @@ func (c *BaseController) PatchObject(r *web.Request) (*web.Response, error) {
 		Context:       c.prepareOperationContextByRequest(r),
 	}
 
+	ctx = operations.ContextWithResource(ctx, objFromDB, labelChanges)
 	object, isAsync, err := c.scheduler.ScheduleStorageAction(ctx, operation, action, c.supportsAsync)
 	if err != nil {
 		return nil, err
@@ -550,7 +596,9 @@ func (c *BaseController) PatchObject(r *web.Request) (*web.Response, error) {
 
 	cleanObject(ctx, object.GetLastOperation())
 	cleanObject(ctx, object)
-	return util.NewJSONResponse(http.StatusOK, object)
+	return util.NewJSONResponseWithHeaders(http.StatusOK, object, map[string]string{
+		"ETag": util.ETag(object.GetUpdatedAt()),
+	})
 }
 
 func cleanObject(ctx context.Context, object types.Object) {
@@ -704,6 +752,10 @@ func (c *BaseController) prepareOperationContextByRequest(r *web.Request) *types
 	}
 
 	operationContext.UserInfo = userInfo
+	// the originating identity is provided by the client, the authenticated user identifies the requester
+	if user, ok := web.UserFromContext(r.Context()); ok {
+		operationContext.RequestedBy = user.Name
+	}
 	async := r.URL.Query().Get(web.QueryParamAsync)
 	cascade := r.URL.Query().Get(web.QueryParamCascade)
 
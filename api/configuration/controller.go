@@ -1,7 +1,9 @@
 package configuration
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/Peripli/service-manager/pkg/env"
 
@@ -13,12 +15,57 @@ import (
 // Controller configuration controller
 type Controller struct {
 	Environment env.Environment
+	// Settings is the configuration structure holding the default values of the settings. Values of its fields
+	// tagged with sensitive:"true" are redacted.
+	Settings interface{}
 }
 
 func (c *Controller) getConfiguration(r *web.Request) (*web.Response, error) {
 	log.C(r.Context()).Debug("Obtaining application configuration...")
 
-	return util.NewJSONResponse(http.StatusOK, c.Environment.AllSettings())
+	return util.NewJSONResponse(http.StatusOK, env.RedactedSettings(c.Environment, c.settings()))
+}
+
+func (c *Controller) listSettings(r *web.Request) (*web.Response, error) {
+	log.C(r.Context()).Debug("Obtaining application configuration settings...")
+
+	changedOnly := r.URL.Query().Get("changed") == "true"
+	settings := make([]env.Setting, 0)
+	for _, setting := range env.Settings(c.Environment, c.settings()) {
+		if changedOnly && !setting.Changed {
+			continue
+		}
+		settings = append(settings, setting)
+	}
+
+	return util.NewJSONResponse(http.StatusOK, map[string]interface{}{
+		"num_items": len(settings),
+		"items":     settings,
+	})
+}
+
+func (c *Controller) getSetting(r *web.Request) (*web.Response, error) {
+	key := strings.ToLower(r.PathParams[web.PathParamResourceID])
+	log.C(r.Context()).Debugf("Obtaining application configuration setting %s...", key)
+
+	for _, setting := range env.Settings(c.Environment, c.settings()) {
+		if setting.Key == key {
+			return util.NewJSONResponse(http.StatusOK, setting)
+		}
+	}
+
+	return nil, &util.HTTPError{
+		ErrorType:   "NotFound",
+		Description: fmt.Sprintf("configuration setting %s not found", key),
+		StatusCode:  http.StatusNotFound,
+	}
+}
+
+func (c *Controller) settings() interface{} {
+	if c.Settings == nil {
+		return struct{}{}
+	}
+	return c.Settings
 }
 
 func (c *Controller) getLoggingConfiguration(r *web.Request) (*web.Response, error) {
@@ -69,6 +116,20 @@ func (c *Controller) Routes() []web.Route {
 			},
 			Handler: c.getConfiguration,
 		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.ConfigSettingsURL,
+			},
+			Handler: c.listSettings,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s/{%s}", web.ConfigSettingsURL, web.PathParamResourceID),
+			},
+			Handler: c.getSetting,
+		},
 		{
 			Endpoint: web.Endpoint{
 				Method: http.MethodGet,
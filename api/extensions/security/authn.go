@@ -2,74 +2,167 @@ package security
 
 import (
 	"context"
-	"net/http"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/multitenancy"
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/security/http/authz"
+	"github.com/Peripli/service-manager/pkg/security/policy"
 
 	"github.com/Peripli/service-manager/pkg/security/authenticators"
 
 	"github.com/Peripli/service-manager/config"
 
 	"github.com/Peripli/service-manager/pkg/sm"
-
-	"github.com/Peripli/service-manager/pkg/web"
 )
 
 // Register adds security configuration to the service manager builder
 func Register(ctx context.Context, cfg *config.Settings, smb *sm.ServiceManagerBuilder) error {
-	basicPlatformAuthenticator := &authenticators.Basic{
-		Repository:             smb.Storage,
-		BasicAuthenticatorFunc: authenticators.BasicPlatformAuthenticator,
+	securityPolicy, err := cfg.Security.Load()
+	if err != nil {
+		return err
 	}
 
-	smb.Security().Path(
-		web.ServiceBrokersURL+"/*",
-		web.PlatformsURL+"/*",
-		web.ServiceOfferingsURL+"/*",
-		web.ServicePlansURL+"/*",
-		web.VisibilitiesURL+"/*",
-		web.ServiceInstancesURL+"/*",
-		web.ServiceBindingsURL+"/*",
-		web.NotificationsURL+"/*").
-		Method(http.MethodGet).
-		WithAuthentication(basicPlatformAuthenticator).Required()
-
-	smb.Security().
-		Path(web.BrokerPlatformCredentialsURL + "/**").
-		Method(http.MethodPut).
-		WithAuthentication(basicPlatformAuthenticator).Required()
-
-	basicOSBAuthenticator := &authenticators.Basic{
-		Repository:             smb.Storage,
-		BasicAuthenticatorFunc: authenticators.BasicOSBAuthenticator,
+	issuers := make(map[string]policy.IssuerSettings)
+	for _, issuer := range securityPolicy.Issuers {
+		issuers[issuer.Name] = issuer
 	}
 
-	smb.Security().
-		Path(web.OSBURL+"/**").
-		Method(http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete).
-		WithAuthentication(basicOSBAuthenticator).Required()
+	introspection := make(map[string]policy.IntrospectionSettings)
+	for _, endpoint := range securityPolicy.Introspection {
+		introspection[endpoint.Name] = endpoint
+	}
 
-	bearerAuthenticator, _, err := authenticators.NewOIDCAuthenticator(ctx, &authenticators.OIDCOptions{
-		IssuerURL: cfg.API.TokenIssuerURL,
-		ClientID:  cfg.API.ClientID,
-	})
-	if err != nil {
-		return err
+	authenticatorsByName := make(map[string]httpsec.Authenticator)
+	authenticatorFor := func(name string) (httpsec.Authenticator, error) {
+		if authenticator, found := authenticatorsByName[name]; found {
+			return authenticator, nil
+		}
+		authenticator, err := newAuthenticator(ctx, cfg, smb, issuers, introspection, name)
+		if err != nil {
+			return nil, err
+		}
+		authenticatorsByName[name] = authenticator
+		return authenticator, nil
 	}
 
-	smb.Security().Path(
-		web.ServiceBrokersURL+"/**",
-		web.PlatformsURL+"/**",
-		web.ServiceOfferingsURL+"/**",
-		web.ServicePlansURL+"/**",
-		web.VisibilitiesURL+"/**",
-		web.NotificationsURL+"/**",
-		web.ServiceInstancesURL+"/**",
-		web.ServiceBindingsURL+"/**",
-		web.ConfigURL+"/**",
-		web.ProfileURL+"/**",
-		web.OperationsURL+"/**",
-	).
-		Method(http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete).
-		WithAuthentication(bearerAuthenticator).Required()
+	var rbacAuthorizer httpsec.Authorizer
+	for _, rule := range securityPolicy.Rules {
+		builder := smb.Security().Path(rule.Paths...).Method(rule.Methods...)
+		for _, name := range rule.Authenticators {
+			authenticator, err := authenticatorFor(name)
+			if err != nil {
+				return err
+			}
+			builder.WithAuthentication(authenticator)
+		}
+		if len(rule.Scopes) != 0 {
+			builder.WithScopes(rule.Scopes...)
+		}
+		if len(rule.ClientIDSuffixes) != 0 {
+			builder.WithClientIDSuffixes(rule.ClientIDSuffixes)
+		}
+		if len(rule.ClientID) != 0 {
+			builder.WithClientID(rule.ClientID)
+		}
+		if rule.RBAC {
+			if rbacAuthorizer == nil {
+				rbacAuthorizer = newRBACAuthorizer(cfg, smb)
+			}
+			builder.WithAuthorization(rbacAuthorizer)
+		}
+		if len(rule.AccessLevel) != 0 {
+			builder.SetAccessLevel(rule.Level())
+		}
+		if rule.Optional {
+			builder.Optional()
+		} else {
+			builder.Required()
+		}
+	}
 
 	return nil
 }
+
+func newAuthenticator(ctx context.Context, cfg *config.Settings, smb *sm.ServiceManagerBuilder, issuers map[string]policy.IssuerSettings, introspection map[string]policy.IntrospectionSettings, name string) (httpsec.Authenticator, error) {
+	lockout := authenticators.PlatformLockout{
+		MaxFailedAttempts: cfg.Security.Lockout.MaxFailedAttempts,
+		Duration:          cfg.Security.Lockout.Duration,
+	}
+	switch name {
+	case policy.BasicPlatformAuthenticator:
+		return &authenticators.Basic{
+			Repository:             smb.Storage,
+			BasicAuthenticatorFunc: authenticators.NewBasicPlatformAuthenticator(lockout),
+		}, nil
+	case policy.BasicOSBAuthenticator:
+		return &authenticators.Basic{
+			Repository:             smb.Storage,
+			BasicAuthenticatorFunc: authenticators.NewBasicOSBAuthenticator(lockout),
+		}, nil
+	case policy.APITokenAuthenticator:
+		return &authenticators.APIToken{
+			Repository: smb.Storage,
+		}, nil
+	}
+
+	if endpointName, ok := policy.IntrospectionName(name); ok {
+		return authenticators.NewIntrospectionAuthenticator(ctx, introspectionOptions(introspection[endpointName]))
+	}
+
+	issuerName, ok := policy.IssuerName(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown authenticator %s", name)
+	}
+	if len(issuerName) != 0 {
+		authenticator, _, err := authenticators.NewOIDCAuthenticator(ctx, oidcOptions(issuers[issuerName]))
+		return authenticator, err
+	}
+
+	options := []*authenticators.OIDCOptions{
+		{
+			IssuerURL: cfg.API.TokenIssuerURL,
+			ClientID:  cfg.API.ClientID,
+		},
+	}
+	for _, issuer := range issuers {
+		options = append(options, oidcOptions(issuer))
+	}
+	return authenticators.NewMultiIssuerOIDCAuthenticator(ctx, options...)
+}
+
+func newRBACAuthorizer(cfg *config.Settings, smb *sm.ServiceManagerBuilder) httpsec.Authorizer {
+	tenantClaim := cfg.Security.RBAC.TenantClaim
+	if len(tenantClaim) == 0 {
+		tenantClaim = multitenancy.MappedTenantClaim
+	}
+	return authz.NewRBACAuthorizer(smb.Storage, multitenancy.ExtractTenantFromTokenWrapperFunc(tenantClaim), cfg.Security.RBAC.GroupsClaim)
+}
+
+func oidcOptions(issuer policy.IssuerSettings) *authenticators.OIDCOptions {
+	return &authenticators.OIDCOptions{
+		IssuerURL:           issuer.URL,
+		ClientID:            issuer.ClientID,
+		JWKSRefreshInterval: issuer.JWKSRefreshInterval,
+		ClaimMapping: authenticators.ClaimMapping{
+			UserName: issuer.UserNameClaim,
+			Tenant:   issuer.TenantClaim,
+			Scopes:   issuer.ScopesClaim,
+		},
+	}
+}
+
+func introspectionOptions(endpoint policy.IntrospectionSettings) authenticators.IntrospectionOptions {
+	return authenticators.IntrospectionOptions{
+		URL:          endpoint.URL,
+		ClientID:     endpoint.ClientID,
+		ClientSecret: endpoint.ClientSecret,
+		Timeout:      endpoint.Timeout,
+		CacheSize:    endpoint.CacheSize,
+		ClaimMapping: authenticators.ClaimMapping{
+			UserName: endpoint.UserNameClaim,
+			Tenant:   endpoint.TenantClaim,
+			Scopes:   endpoint.ScopesClaim,
+		},
+	}
+}
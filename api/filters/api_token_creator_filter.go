@@ -0,0 +1,50 @@
+package filters
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const APITokenCreatorFilterName = "APITokenCreatorFilter"
+
+// APITokenCreatorFilter scopes the API tokens which users without global access can see and revoke to the API tokens
+// they created
+type APITokenCreatorFilter struct {
+}
+
+func (f *APITokenCreatorFilter) Name() string {
+	return APITokenCreatorFilterName
+}
+
+func (f *APITokenCreatorFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	ctx := req.Context()
+	user, found := web.UserFromContext(ctx)
+	if !found {
+		log.C(ctx).Debug("No user found in user context. Proceeding without creator criteria...")
+		return next.Handle(req)
+	}
+	if user.AccessLevel == web.GlobalAccess {
+		return next.Handle(req)
+	}
+	ctx, err := query.AddCriteria(ctx, query.ByField(query.EqualsOperator, "created_by", user.Name))
+	if err != nil {
+		return nil, err
+	}
+	req.Request = req.WithContext(ctx)
+
+	return next.Handle(req)
+}
+
+func (f *APITokenCreatorFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.APITokensURL + "/**"),
+				web.Methods(http.MethodGet, http.MethodDelete),
+			},
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package filters_test
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/pkg/web/webfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("API tokens scoping", func() {
+	var handler *webfakes.FakeHandler
+
+	run := func(filter web.Filter, method string, accessLevel web.AccessLevel) []query.Criterion {
+		req, err := http.NewRequest(method, "http://example.com"+web.APITokensURL, nil)
+		Expect(err).ToNot(HaveOccurred())
+		req = req.WithContext(web.ContextWithUser(context.Background(), &web.UserContext{
+			AuthenticationType: web.Bearer,
+			Name:               "jane",
+			AccessLevel:        accessLevel,
+		}))
+		_, err = filter.Run(&web.Request{Request: req}, handler)
+		Expect(err).ToNot(HaveOccurred())
+		return query.CriteriaForContext(handler.HandleArgsForCall(handler.HandleCallCount() - 1).Context())
+	}
+
+	BeforeEach(func() {
+		handler = &webfakes.FakeHandler{}
+	})
+
+	It("scopes listing and revoking API tokens to the tokens created by the user", func() {
+		filter := &filters.APITokenCreatorFilter{}
+		for _, method := range []string{http.MethodGet, http.MethodDelete} {
+			Expect(run(filter, method, web.TenantAccess)).To(ConsistOf(query.ByField(query.EqualsOperator, "created_by", "jane")))
+		}
+	})
+
+	It("does not scope the API tokens of users with global access", func() {
+		Expect(run(&filters.APITokenCreatorFilter{}, http.MethodDelete, web.GlobalAccess)).To(BeEmpty())
+	})
+
+	It("scopes the API tokens to the tenant of the user", func() {
+		multitenancyFilters, err := filters.NewMultitenancyFilters("tenant-label", func(request *web.Request) (string, error) {
+			return "my-tenant", nil
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(run(multitenancyFilters[5], http.MethodDelete, web.TenantAccess)).To(ConsistOf(query.ByField(query.EqualsOperator, "tenant", "my-tenant")))
+	})
+})
@@ -47,8 +47,11 @@ func isBrokerVisible(repository storage.Repository) func(ctx context.Context, br
 			planIds = append(planIds, plansList.ItemAt(i).GetID())
 		}
 
-		cnt, err := repository.Count(ctx, types.VisibilityType, query.ByField(query.InOperator, "service_plan_id", planIds...),
-			query.ByField(query.EqualsOrNilOperator, "platform_id", platformID))
+		criteria, err := visibilitiesForPlatformCriteria(ctx, repository, platformID, query.ByField(query.InOperator, "service_plan_id", planIds...))
+		if err != nil {
+			return false, err
+		}
+		cnt, err := repository.Count(ctx, types.VisibilityType, criteria...)
 		return cnt > 0, err
 	}
 }
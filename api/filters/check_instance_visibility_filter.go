@@ -20,6 +20,7 @@ import (
 	"net/http"
 
 	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/Peripli/service-manager/storage"
@@ -85,6 +86,14 @@ func (f *serviceInstanceVisibilityFilter) Run(req *web.Request, next web.Handler
 		return next.Handle(req)
 	}
 
+	matching, _, err := storage.SelectorVisibilities(ctx, f.repository, visibilityMetadata.PlatformID, query.ByField(query.EqualsOperator, "service_plan_id", planID), storage.ByVisibilityWindow())
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.VisibilityType.String())
+	}
+	if len(matching) > 0 {
+		return next.Handle(req)
+	}
+
 	visibilityError := &util.HTTPError{
 		ErrorType:   "NotFound",
 		Description: "could not find such service plan",
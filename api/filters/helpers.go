@@ -44,7 +44,11 @@ func servicesCriteria(ctx context.Context, repository storage.Repository, planQu
 }
 
 func plansCriteria(ctx context.Context, repository storage.Repository, platformID string) (*query.Criterion, error) {
-	objectList, err := repository.ListNoLabels(ctx, types.VisibilityType, query.ByField(query.EqualsOrNilOperator, "platform_id", platformID))
+	criteria, err := visibilitiesForPlatformCriteria(ctx, repository, platformID)
+	if err != nil {
+		return nil, err
+	}
+	objectList, err := repository.ListNoLabels(ctx, types.VisibilityType, criteria...)
 	if err != nil {
 		return nil, err
 	}
@@ -60,6 +64,17 @@ func plansCriteria(ctx context.Context, repository storage.Repository, platformI
 	return &c, nil
 }
 
+// visibilitiesForPlatformCriteria selects the visibilities within their validity window for the platform, the public ones
+// and the ones whose platform selector matches the platform labels
+func visibilitiesForPlatformCriteria(ctx context.Context, repository storage.Repository, platformID string, criteria ...query.Criterion) ([]query.Criterion, error) {
+	_, unmatchedIDs, err := storage.SelectorVisibilities(ctx, repository, platformID, criteria...)
+	if err != nil {
+		return nil, err
+	}
+	result := append([]query.Criterion{query.ByField(query.EqualsOrNilOperator, "platform_id", platformID), storage.ByVisibilityWindow()}, criteria...)
+	return append(result, storage.ExcludingVisibilities(unmatchedIDs)...), nil
+}
+
 func getSharedProperty(reqBody []byte) *bool {
 	var reqServiceInstance types.ServiceInstance
 	err := util.BytesToObjectNoLabels(reqBody, &reqServiceInstance)
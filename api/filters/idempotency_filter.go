@@ -0,0 +1,230 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// IdempotencyFilterName is the name of the filter which replays create requests with the same Idempotency-Key
+	IdempotencyFilterName = "IdempotencyFilter"
+
+	// IdempotencyKeyHeader is the header with which clients identify retries of the same create request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses which are replayed from a previous request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyFilter stores the result of create requests with an Idempotency-Key header per user,
+// so that retries of the request return the same result instead of creating the resource again
+type IdempotencyFilter struct {
+	repository storage.Repository
+	lifespan   time.Duration
+}
+
+// NewIdempotencyFilter returns a new IdempotencyFilter whose keys expire after lifespan
+func NewIdempotencyFilter(repository storage.Repository, lifespan time.Duration) *IdempotencyFilter {
+	return &IdempotencyFilter{
+		repository: repository,
+		lifespan:   lifespan,
+	}
+}
+
+func (*IdempotencyFilter) Name() string {
+	return IdempotencyFilterName
+}
+
+func (f *IdempotencyFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	key := req.Header.Get(IdempotencyKeyHeader)
+	if len(key) == 0 {
+		return next.Handle(req)
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("%s header must not be longer than %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+
+	// the result is stored even if the client gives up waiting for it
+	ctx := util.StateContext{Context: req.Context()}
+	userName := ""
+	if user, ok := web.UserFromContext(ctx); ok {
+		userName = user.Name
+	}
+	requestHash := hashRequest(req)
+
+	reserved, stored, err := f.reserve(ctx, key, userName, requestHash)
+	if err != nil {
+		return nil, err
+	}
+	if stored != nil {
+		return replay(stored, requestHash)
+	}
+
+	resp, err := next.Handle(req)
+	if err != nil || resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		// failed requests are not stored, so that they can be retried with the same key
+		f.release(ctx, reserved)
+		return resp, err
+	}
+
+	reserved.StatusCode = resp.StatusCode
+	reserved.Location = resp.Header.Get("Location")
+	reserved.Response = resp.Body
+	reserved.UpdatedAt = time.Now().UTC()
+	if _, err := f.repository.Update(ctx, reserved, types.LabelChanges{}); err != nil {
+		log.C(ctx).Errorf("Could not store the result of the request with %s %s: %s", IdempotencyKeyHeader, key, err)
+	}
+	return resp, nil
+}
+
+// reserve stores the key before the request is processed, so that concurrent retries are rejected.
+// If the key is already used, the stored key is returned instead.
+func (f *IdempotencyFilter) reserve(ctx context.Context, key, userName, requestHash string) (*types.IdempotencyKey, *types.IdempotencyKey, error) {
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not generate GUID for idempotency key: %s", err)
+	}
+	currentTime := time.Now().UTC()
+	reserved := &types.IdempotencyKey{
+		Base: types.Base{
+			ID:        UUID.String(),
+			CreatedAt: currentTime,
+			UpdatedAt: currentTime,
+			Ready:     true,
+		},
+		Key:         key,
+		UserName:    userName,
+		RequestHash: requestHash,
+	}
+
+	if _, err := f.repository.Create(ctx, reserved); err == nil {
+		return reserved, nil, nil
+	} else if err != util.ErrAlreadyExistsInStorage {
+		return nil, nil, util.HandleStorageError(err, types.IdempotencyKeyType.String())
+	}
+
+	criteria := []query.Criterion{
+		query.ByField(query.EqualsOperator, "key", key),
+		query.ByField(query.EqualsOperator, "user_name", userName),
+	}
+	object, err := f.repository.Get(ctx, types.IdempotencyKeyType, criteria...)
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return nil, nil, requestInProgressError()
+		}
+		return nil, nil, util.HandleStorageError(err, types.IdempotencyKeyType.String())
+	}
+	stored := object.(*types.IdempotencyKey)
+	if stored.CreatedAt.Add(f.lifespan).After(currentTime) {
+		return nil, stored, nil
+	}
+
+	// the key expired but was not cleaned up yet
+	if err := f.repository.Delete(ctx, types.IdempotencyKeyType, query.ByField(query.EqualsOperator, "id", stored.ID)); err != nil && err != util.ErrNotFoundInStorage {
+		return nil, nil, util.HandleStorageError(err, types.IdempotencyKeyType.String())
+	}
+	if _, err := f.repository.Create(ctx, reserved); err != nil {
+		if err == util.ErrAlreadyExistsInStorage {
+			return nil, nil, requestInProgressError()
+		}
+		return nil, nil, util.HandleStorageError(err, types.IdempotencyKeyType.String())
+	}
+	return reserved, nil, nil
+}
+
+func (f *IdempotencyFilter) release(ctx context.Context, reserved *types.IdempotencyKey) {
+	if err := f.repository.Delete(ctx, types.IdempotencyKeyType, query.ByField(query.EqualsOperator, "id", reserved.ID)); err != nil && err != util.ErrNotFoundInStorage {
+		log.C(ctx).Errorf("Could not release %s %s: %s", IdempotencyKeyHeader, reserved.Key, err)
+	}
+}
+
+func replay(stored *types.IdempotencyKey, requestHash string) (*web.Response, error) {
+	if stored.RequestHash != requestHash {
+		return nil, &util.HTTPError{
+			ErrorType:   "UnprocessableEntity",
+			Description: fmt.Sprintf("%s %s was already used for a different request", IdempotencyKeyHeader, stored.Key),
+			StatusCode:  http.StatusUnprocessableEntity,
+		}
+	}
+	if !stored.Completed() {
+		return nil, requestInProgressError()
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(IdempotentReplayedHeader, "true")
+	if len(stored.Location) > 0 {
+		header.Set("Location", stored.Location)
+	}
+	return &web.Response{
+		StatusCode: stored.StatusCode,
+		Header:     header,
+		Body:       stored.Response,
+	}, nil
+}
+
+func requestInProgressError() error {
+	return &util.HTTPError{
+		ErrorType:   "ConcurrentOperationInProgress",
+		Description: fmt.Sprintf("a request with the same %s is in progress", IdempotencyKeyHeader),
+		StatusCode:  http.StatusConflict,
+	}
+}
+
+func hashRequest(req *web.Request) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + req.URL.RequestURI() + "\n"))
+	hash.Write(req.Body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (*IdempotencyFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(
+					web.ServiceBrokersURL,
+					web.PlatformsURL,
+					web.VisibilitiesURL,
+					web.QuotasURL,
+					web.ServiceInstancesURL,
+					web.ServiceBindingsURL,
+				),
+				web.Methods(http.MethodPost),
+			},
+		},
+	}
+}
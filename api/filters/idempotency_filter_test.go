@@ -0,0 +1,139 @@
+package filters_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/pkg/web/webfakes"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Idempotency filter", func() {
+	var (
+		repository  *storagefakes.FakeStorage
+		handler     *webfakes.FakeHandler
+		filter      *filters.IdempotencyFilter
+		reservedKey *types.IdempotencyKey
+	)
+
+	newRequest := func(key string, body string) *web.Request {
+		u, err := url.Parse(web.ServiceInstancesURL)
+		Expect(err).ToNot(HaveOccurred())
+		req := &web.Request{
+			Request: &http.Request{
+				Method: http.MethodPost,
+				URL:    u,
+				Header: http.Header{},
+			},
+			Body: []byte(body),
+		}
+		req.Request = req.WithContext(context.Background())
+		if len(key) > 0 {
+			req.Header.Set(filters.IdempotencyKeyHeader, key)
+		}
+		return req
+	}
+
+	BeforeEach(func() {
+		repository = &storagefakes.FakeStorage{}
+		handler = &webfakes.FakeHandler{}
+		filter = filters.NewIdempotencyFilter(repository, time.Hour)
+		reservedKey = nil
+		repository.CreateCalls(func(ctx context.Context, object types.Object) (types.Object, error) {
+			reservedKey = object.(*types.IdempotencyKey)
+			return object, nil
+		})
+	})
+
+	When("the request has no Idempotency-Key header", func() {
+		It("passes the request through", func() {
+			handler.HandleReturns(&web.Response{StatusCode: http.StatusCreated}, nil)
+			_, err := filter.Run(newRequest("", `{}`), handler)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.HandleCallCount()).To(Equal(1))
+			Expect(repository.CreateCallCount()).To(Equal(0))
+		})
+	})
+
+	When("the key is new", func() {
+		It("stores the response of a successful request", func() {
+			header := http.Header{}
+			header.Set("Location", "/v1/service_instances/1/operations/2")
+			handler.HandleReturns(&web.Response{StatusCode: http.StatusAccepted, Header: header, Body: []byte(`{}`)}, nil)
+
+			resp, err := filter.Run(newRequest("key", `{"name":"instance"}`), handler)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+			Expect(repository.UpdateCallCount()).To(Equal(1))
+			_, object, _, _ := repository.UpdateArgsForCall(0)
+			stored := object.(*types.IdempotencyKey)
+			Expect(stored.Key).To(Equal("key"))
+			Expect(stored.StatusCode).To(Equal(http.StatusAccepted))
+			Expect(stored.Location).To(Equal("/v1/service_instances/1/operations/2"))
+			Expect(stored.Response).To(Equal([]byte(`{}`)))
+		})
+
+		It("releases the key when the request fails", func() {
+			handler.HandleReturns(nil, &util.HTTPError{StatusCode: http.StatusBadRequest})
+
+			_, err := filter.Run(newRequest("key", `{}`), handler)
+			Expect(err).To(HaveOccurred())
+			Expect(repository.UpdateCallCount()).To(Equal(0))
+			Expect(repository.DeleteCallCount()).To(Equal(1))
+		})
+	})
+
+	When("the key is already used", func() {
+		var stored *types.IdempotencyKey
+
+		BeforeEach(func() {
+			handler.HandleReturns(&web.Response{StatusCode: http.StatusCreated}, nil)
+			_, err := filter.Run(newRequest("key", `{"name":"instance"}`), handler)
+			Expect(err).ToNot(HaveOccurred())
+			stored = &types.IdempotencyKey{
+				Base:        types.Base{ID: "id", CreatedAt: time.Now()},
+				Key:         "key",
+				RequestHash: reservedKey.RequestHash,
+			}
+			repository.CreateReturns(nil, util.ErrAlreadyExistsInStorage)
+			repository.GetReturns(stored, nil)
+			handler = &webfakes.FakeHandler{}
+		})
+
+		It("replays the stored response", func() {
+			stored.StatusCode = http.StatusCreated
+			stored.Response = []byte(`{"id":"1"}`)
+
+			resp, err := filter.Run(newRequest("key", `{"name":"instance"}`), handler)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.HandleCallCount()).To(Equal(0))
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+			Expect(resp.Body).To(Equal([]byte(`{"id":"1"}`)))
+			Expect(resp.Header.Get(filters.IdempotentReplayedHeader)).To(Equal("true"))
+		})
+
+		It("fails with conflict while the first request is in progress", func() {
+			_, err := filter.Run(newRequest("key", `{"name":"instance"}`), handler)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusConflict))
+			Expect(handler.HandleCallCount()).To(Equal(0))
+		})
+
+		It("fails when the key is reused for a different request", func() {
+			stored.StatusCode = http.StatusCreated
+
+			_, err := filter.Run(newRequest("key", `{"name":"other"}`), handler)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusUnprocessableEntity))
+			Expect(handler.HandleCallCount()).To(Equal(0))
+		})
+	})
+})
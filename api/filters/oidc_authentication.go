@@ -57,6 +57,7 @@ func oidcAuthnMatchers() []web.FilterMatcher {
 					web.ConfigURL+"/**",
 					web.ProfileURL+"/**",
 					web.OperationsURL+"/**",
+					web.AdminURL+"/**",
 				),
 			},
 		},
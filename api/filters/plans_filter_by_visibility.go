@@ -30,8 +30,11 @@ type PlanFilterByVisibility struct {
 
 func isPlanVisibile(repository storage.Repository) func(ctx context.Context, planID, platformID string) (bool, error) {
 	return func(ctx context.Context, planID, platformID string) (bool, error) {
-		cnt, err := repository.Count(ctx, types.VisibilityType, query.ByField(query.EqualsOperator, "service_plan_id", planID),
-			query.ByField(query.EqualsOrNilOperator, "platform_id", platformID))
+		criteria, err := visibilitiesForPlatformCriteria(ctx, repository, platformID, query.ByField(query.EqualsOperator, "service_plan_id", planID))
+		if err != nil {
+			return false, err
+		}
+		cnt, err := repository.Count(ctx, types.VisibilityType, criteria...)
 		return cnt > 0, err
 	}
 }
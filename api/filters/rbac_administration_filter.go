@@ -0,0 +1,46 @@
+package filters
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const RBACAdministrationFilterName = "RBACAdministrationFilter"
+
+// RBACAdministrationFilter allows only callers with global access to modify roles and role bindings. Roles are shared
+// by all tenants and role bindings without a tenant grant global access, so a tenant user who may modify them could
+// grant itself global access.
+type RBACAdministrationFilter struct {
+}
+
+func (f *RBACAdministrationFilter) Name() string {
+	return RBACAdministrationFilterName
+}
+
+func (f *RBACAdministrationFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	ctx := req.Context()
+	user, found := web.UserFromContext(ctx)
+	if !found {
+		log.C(ctx).Debug("No user found in user context. Proceeding without access level check...")
+		return next.Handle(req)
+	}
+	if user.AccessLevel != web.GlobalAccess {
+		return nil, security.ForbiddenHTTPError(fmt.Sprintf("user %s is not permitted to modify roles and role bindings without global access", user.Name))
+	}
+	return next.Handle(req)
+}
+
+func (f *RBACAdministrationFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.RolesURL+"/**", web.RoleBindingsURL+"/**"),
+				web.Methods(http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete),
+			},
+		},
+	}
+}
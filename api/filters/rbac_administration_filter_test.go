@@ -0,0 +1,109 @@
+package filters_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/pkg/web/webfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RBAC administration", func() {
+	var handler *webfakes.FakeHandler
+
+	newRequest := func(method, path, body string, accessLevel web.AccessLevel) *web.Request {
+		u, err := url.Parse(path)
+		Expect(err).ToNot(HaveOccurred())
+		ctx := web.ContextWithUser(context.Background(), &web.UserContext{
+			AuthenticationType: web.Bearer,
+			Name:               "tenant-admin",
+			AccessLevel:        accessLevel,
+		})
+		return &web.Request{
+			Request: (&http.Request{Method: method, URL: u, Header: http.Header{}}).WithContext(ctx),
+			Body:    []byte(body),
+		}
+	}
+
+	BeforeEach(func() {
+		handler = &webfakes.FakeHandler{}
+		handler.HandleReturns(&web.Response{StatusCode: http.StatusOK}, nil)
+	})
+
+	It("matches modifications of roles and role bindings only", func() {
+		filter := &filters.RBACAdministrationFilter{}
+		matchers := filter.FilterMatchers()[0].Matchers
+		matches, err := matchers[0].Matches(web.Endpoint{Path: web.RoleBindingsURL + "/binding-id"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(matches).To(BeTrue())
+		matches, err = matchers[1].Matches(web.Endpoint{Method: http.MethodGet})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(matches).To(BeFalse())
+	})
+
+	It("prevents tenant users from granting themselves global access", func() {
+		filter := &filters.RBACAdministrationFilter{}
+		req := newRequest(http.MethodPost, web.RoleBindingsURL,
+			`{"role_id": "admin", "tenant": "", "subjects": [{"kind": "user", "name": "tenant-admin"}]}`, web.TenantAccess)
+		_, err := filter.Run(req, handler)
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusForbidden))
+		Expect(handler.HandleCallCount()).To(Equal(0))
+	})
+
+	It("prevents tenant users from modifying the shared roles", func() {
+		filter := &filters.RBACAdministrationFilter{}
+		req := newRequest(http.MethodPatch, web.RolesURL+"/viewer", `{"permissions": [{"resource_type": "*", "verbs": ["*"]}]}`, web.TenantAccess)
+		_, err := filter.Run(req, handler)
+		Expect(err).To(HaveOccurred())
+		Expect(handler.HandleCallCount()).To(Equal(0))
+	})
+
+	It("allows users with global access to modify roles and role bindings", func() {
+		filter := &filters.RBACAdministrationFilter{}
+		_, err := filter.Run(newRequest(http.MethodDelete, web.RoleBindingsURL+"/binding-id", "", web.GlobalAccess), handler)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handler.HandleCallCount()).To(Equal(1))
+	})
+
+	Describe("role bindings tenant filter", func() {
+		var filter web.Filter
+
+		BeforeEach(func() {
+			multitenancyFilters, err := filters.NewMultitenancyFilters("tenant-label", func(request *web.Request) (string, error) {
+				return "my-tenant", nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			filter = multitenancyFilters[4]
+		})
+
+		It("sets the tenant of created role bindings to the tenant of the user", func() {
+			req := newRequest(http.MethodPost, web.RoleBindingsURL, `{"role_id": "admin", "tenant": ""}`, web.TenantAccess)
+			_, err := filter.Run(req, handler)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gjson.GetBytes(handler.HandleArgsForCall(0).Body, "tenant").String()).To(Equal("my-tenant"))
+		})
+
+		It("scopes the role bindings to the tenant of the user", func() {
+			_, err := filter.Run(newRequest(http.MethodGet, web.RoleBindingsURL, "", web.TenantAccess), handler)
+			Expect(err).ToNot(HaveOccurred())
+			criteria := query.CriteriaForContext(handler.HandleArgsForCall(0).Context())
+			Expect(criteria).To(ConsistOf(query.ByField(query.EqualsOperator, "tenant", "my-tenant")))
+		})
+
+		It("does not scope the role bindings of users with global access", func() {
+			_, err := filter.Run(newRequest(http.MethodGet, web.RoleBindingsURL, "", web.GlobalAccess), handler)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query.CriteriaForContext(handler.HandleArgsForCall(0).Context())).To(BeEmpty())
+		})
+	})
+})
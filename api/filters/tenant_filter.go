@@ -3,7 +3,12 @@ package filters
 import (
 	"errors"
 	"fmt"
+	"net/http"
+
+	"github.com/tidwall/sjson"
+
 	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
 	"github.com/Peripli/service-manager/pkg/web"
 )
 
@@ -16,7 +21,7 @@ func NewMultitenancyFilters(labelKey string, extractTenantFunc func(request *web
 		return nil, errors.New("extractTenantFunc should be provided")
 	}
 
-	return NewLabelingFilters(LabelName, labelKey, []string{web.PlatformsURL, web.ServiceBrokersURL, web.ServiceInstancesURL, web.ServiceBindingsURL}, func(request *web.Request) (string, error) {
+	extractValueFunc := func(request *web.Request) (string, error) {
 		ctx := request.Context()
 
 		userContext, found := web.UserFromContext(ctx)
@@ -30,10 +35,48 @@ func NewMultitenancyFilters(labelKey string, extractTenantFunc func(request *web
 		}
 
 		return extractTenantFunc(request)
-	}), nil
+	}
+
+	multitenancyFilters := NewLabelingFilters(LabelName, labelKey, []string{web.PlatformsURL, web.ServiceBrokersURL, web.ServiceInstancesURL, web.ServiceBindingsURL}, extractValueFunc)
+	// operations awaiting approval are labeled with the tenant of the requester, so tenant users only see and decide their own
+	multitenancyFilters = append(multitenancyFilters, newLabelCriteriaFilter(LabelName+"Approvals", labelKey, []string{web.ApprovalsURL}, extractValueFunc))
+	// operations are labeled with the tenant of the requester, so tenant users only see, cancel and retry their own
+	multitenancyFilters = append(multitenancyFilters, newLabelCriteriaFilter(LabelName+"Operations", labelKey, []string{web.OperationsURL}, extractValueFunc))
+	// role bindings and API tokens are not labeled, tenant users only see and manage those of their tenant
+	multitenancyFilters = append(multitenancyFilters, newTenantFieldFilter(LabelName+"RoleBindings", []string{web.RoleBindingsURL}, extractValueFunc))
+	multitenancyFilters = append(multitenancyFilters, newTenantFieldFilter(LabelName+"APITokens", []string{web.APITokensURL}, extractValueFunc))
+	return multitenancyFilters, nil
 }
 
 //TenantLabelingFilterName returns the name of the filter that is adding the tenant label to tenant-scoped resources
 func TenantLabelingFilterName() string {
 	return fmt.Sprintf("%s%s", LabelName, ResourceLabelingFilterNameSuffix)
 }
+
+// newTenantFieldFilter scopes resources with a tenant field to the tenant of the user and sets the tenant of the
+// resources created by the user to its tenant
+func newTenantFieldFilter(name string, basePaths []string, extractValueFunc func(request *web.Request) (string, error)) *LabelingFilter {
+	return &LabelingFilter{
+		LabelKey:     "tenant",
+		FilterName:   name + LabelCriteriaFilterNameSuffix,
+		BasePaths:    basePaths,
+		Methods:      []string{http.MethodGet, http.MethodPatch, http.MethodDelete, http.MethodPost},
+		ExtractValue: extractValueFunc,
+		LabelingFunc: func(request *web.Request, field, tenant string) error {
+			ctx := request.Context()
+			if request.Method == http.MethodPost {
+				var err error
+				if request.Body, err = sjson.SetBytes(request.Body, field, tenant); err != nil {
+					return fmt.Errorf("could not set %s %s in request body during resource creation: %s", field, tenant, err)
+				}
+				return nil
+			}
+			ctx, err := query.AddCriteria(ctx, query.ByField(query.EqualsOperator, field, tenant))
+			if err != nil {
+				return fmt.Errorf("could not add criteria with field %s and value %s: %s", field, tenant, err)
+			}
+			request.Request = request.WithContext(ctx)
+			return nil
+		},
+	}
+}
@@ -69,6 +69,51 @@ var _ = Describe("TenantFilters", func() {
 				})
 			})
 
+			Describe("Approvals criteria filter", func() {
+				It("should scope the approvals by tenant", func() {
+					approvalsFilter := multitenancyFilters[2]
+					Expect(approvalsFilter.FilterMatchers()).To(HaveLen(1))
+					matches, err := approvalsFilter.FilterMatchers()[0].Matchers[0].Matches(web.Endpoint{Path: web.ApprovalsURL + "/op-id/approve"})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(matches).To(BeTrue())
+
+					newReq, err := http.NewRequest(http.MethodPost, "http://example.com", nil)
+					Expect(err).ShouldNot(HaveOccurred())
+					fakeRequest.Request = newReq.WithContext(web.ContextWithUser(context.Background(), &web.UserContext{
+						AuthenticationType: web.Bearer,
+						Name:               "test",
+						AccessLevel:        web.TenantAccess,
+					}))
+					_, err = approvalsFilter.Run(fakeRequest, fakeHandler)
+					Expect(err).ToNot(HaveOccurred())
+					criteria := query.CriteriaForContext(fakeHandler.HandleArgsForCall(0).Context())
+					Expect(criteria).To(ConsistOf(query.ByLabel(query.EqualsOperator, labelKey, tenant)))
+				})
+			})
+
+			Describe("Operations criteria filter", func() {
+				It("should scope the cancelled and retried operations by tenant", func() {
+					operationsFilter := multitenancyFilters[3]
+					for _, control := range []string{"cancel", "retry"} {
+						matches, err := operationsFilter.FilterMatchers()[0].Matchers[0].Matches(web.Endpoint{Path: web.OperationsURL + "/op-id/" + control})
+						Expect(err).ToNot(HaveOccurred())
+						Expect(matches).To(BeTrue())
+					}
+
+					newReq, err := http.NewRequest(http.MethodPost, "http://example.com", nil)
+					Expect(err).ShouldNot(HaveOccurred())
+					fakeRequest.Request = newReq.WithContext(web.ContextWithUser(context.Background(), &web.UserContext{
+						AuthenticationType: web.Bearer,
+						Name:               "test",
+						AccessLevel:        web.TenantAccess,
+					}))
+					_, err = operationsFilter.Run(fakeRequest, fakeHandler)
+					Expect(err).ToNot(HaveOccurred())
+					criteria := query.CriteriaForContext(fakeHandler.HandleArgsForCall(0).Context())
+					Expect(criteria).To(ConsistOf(query.ByLabel(query.EqualsOperator, labelKey, tenant)))
+				})
+			})
+
 			Describe("Criteria filter", func() {
 				for _, method := range []string{http.MethodGet, http.MethodPatch, http.MethodDelete, http.MethodPost} {
 					When(method+" request is sent with tenant scope", func() {
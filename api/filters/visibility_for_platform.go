@@ -23,18 +23,28 @@ import (
 	"github.com/Peripli/service-manager/pkg/query"
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
 )
 
 const PlatformAwareVisibilityFilterName = "PlatformAwareVisibilityFilter"
 
+// NewPlatformAwareVisibilityFilter returns a filter restricting the visibilities listed by a platform to the ones
+// relevant for it
+func NewPlatformAwareVisibilityFilter(repository storage.Repository) *PlatformAwareVisibilityFilter {
+	return &PlatformAwareVisibilityFilter{
+		repository: repository,
+	}
+}
+
 type PlatformAwareVisibilityFilter struct {
+	repository storage.Repository
 }
 
 func (*PlatformAwareVisibilityFilter) Name() string {
 	return PlatformAwareVisibilityFilterName
 }
 
-func (*PlatformAwareVisibilityFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+func (f *PlatformAwareVisibilityFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
 	ctx := req.Context()
 	user, ok := web.UserFromContext(ctx)
 	if !ok {
@@ -54,9 +64,11 @@ func (*PlatformAwareVisibilityFilter) Run(req *web.Request, next web.Handler) (*
 		return nil, errors.New("user details contain an invalid user")
 	}
 
-	byPlatformID := query.ByField(query.EqualsOrNilOperator, "platform_id", p.ID)
-	var err error
-	if ctx, err = query.AddCriteria(ctx, byPlatformID); err != nil {
+	criteria, err := visibilitiesForPlatformCriteria(ctx, f.repository, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if ctx, err = query.AddCriteria(ctx, criteria...); err != nil {
 		return nil, err
 	}
 	req.Request = req.WithContext(ctx)
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/tidwall/gjson"
+)
+
+const VisibilityPlatformSelectorFilterName = "VisibilityPlatformSelectorFilter"
+
+// VisibilityPlatformSelectorFilter checks that the platform selector of a visibility is a valid label query
+type VisibilityPlatformSelectorFilter struct {
+}
+
+func (*VisibilityPlatformSelectorFilter) Name() string {
+	return VisibilityPlatformSelectorFilterName
+}
+
+func (*VisibilityPlatformSelectorFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	selector := gjson.GetBytes(req.Body, "platform_selector").String()
+	if selector == "" {
+		return next.Handle(req)
+	}
+	if _, err := query.Parse(query.LabelQuery, selector); err != nil {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("invalid platform selector: %s", err),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	return next.Handle(req)
+}
+
+func (*VisibilityPlatformSelectorFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.VisibilitiesURL + "/**"),
+				web.Methods(http.MethodPost, http.MethodPatch),
+			},
+		},
+	}
+}
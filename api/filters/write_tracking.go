@@ -0,0 +1,54 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package filters
+
+import (
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	// WriteTrackingFilterName is the name of the write tracking filter
+	WriteTrackingFilterName = "WriteTrackingFilter"
+)
+
+// WriteTracking is a filter that tracks the storage writes made while processing a request, so that reads which
+// follow a write are served by the primary database rather than by a read replica.
+type WriteTracking struct {
+}
+
+// Name implements the web.Filter interface and returns the identifier of the filter.
+func (*WriteTracking) Name() string {
+	return WriteTrackingFilterName
+}
+
+// Run represents the write tracking middleware function that enables write tracking in the request context.
+func (*WriteTracking) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	req.Request = req.WithContext(storage.ContextWithWriteTracking(req.Context()))
+	return next.Handle(req)
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
+func (*WriteTracking) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path("/**"),
+			},
+		},
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/util/slice"
 	"github.com/Peripli/service-manager/pkg/web"
 	"github.com/gorilla/mux"
 )
@@ -32,6 +33,10 @@ type HTTPHandler struct {
 	requestBodyMaxSize int
 }
 
+// streamedBodyPaths are the paths of the endpoints which read the request body as a stream, e.g. to import archives.
+// Their body is neither buffered nor limited by the maximum body size.
+var streamedBodyPaths = []string{web.ImportURL}
+
 // NewHTTPHandler creates a new HTTPHandler from the provided web.Handler
 func NewHTTPHandler(handler web.Handler, requestBodyMaxSize int) *HTTPHandler {
 	return &HTTPHandler{
@@ -56,7 +61,9 @@ func (h *HTTPHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	}()
 
 	var request *web.Request
-	req.Body = http.MaxBytesReader(res, req.Body, int64(h.requestBodyMaxSize))
+	if !isStreamedBody(req) {
+		req.Body = http.MaxBytesReader(res, req.Body, int64(h.requestBodyMaxSize))
+	}
 	if request, err = convertToWebRequest(req, res); err != nil {
 		return
 	}
@@ -92,7 +99,7 @@ func convertToWebRequest(request *http.Request, rw http.ResponseWriter) (*web.Re
 
 	var body []byte
 	var err error
-	if request.Method == "PUT" || request.Method == "POST" || request.Method == "PATCH" {
+	if (request.Method == "PUT" || request.Method == "POST" || request.Method == "PATCH") && !isStreamedBody(request) {
 		body, err = util.RequestBodyToBytes(request)
 		if err != nil {
 			return nil, isPayloadTooLargeErr(request.Context(), err)
@@ -108,6 +115,10 @@ func convertToWebRequest(request *http.Request, rw http.ResponseWriter) (*web.Re
 	return webReq, nil
 }
 
+func isStreamedBody(request *http.Request) bool {
+	return slice.StringsAnyEquals(streamedBodyPaths, request.URL.Path)
+}
+
 func isPayloadTooLargeErr(ctx context.Context, err error) error {
 	// Go http package uses errors.New() to return the below error, so
 	// we can only check it with string matching
@@ -18,6 +18,7 @@ package api
 
 import (
 	"bytes"
+	"io/ioutil"
 	"net/http/httptest"
 	"strconv"
 
@@ -120,6 +121,23 @@ var _ = Describe("Handler", func() {
 			})
 		})
 
+		Context("when the body of an import is larger than the maximum body size", func() {
+			Specify("the body is streamed to the web handler", func() {
+				fakeHandler.HandleReturns(&web.Response{StatusCode: http.StatusOK}, nil)
+				body := generateJSON(2100000)
+				response := makeRequest(http.MethodPost, "http://example.com"+web.ImportURL, body, map[string]string{
+					"Content-Type": "application/x-ndjson",
+				})
+
+				Expect(response.Code).To(Equal(http.StatusOK))
+				request := fakeHandler.HandleArgsForCall(0)
+				Expect(request.Body).To(BeNil())
+				streamed, err := ioutil.ReadAll(request.Request.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(streamed).To(HaveLen(len(body)))
+			})
+		})
+
 		Context("when call to web handler returns an error", func() {
 			Specify("response contains a proper HTTPError", func() {
 				handlerError := fmt.Errorf("error")
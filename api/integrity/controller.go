@@ -0,0 +1,253 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package integrity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// verifyResourceID is the resource ID of the operations tracking integrity verifications
+const verifyResourceID = "verify"
+
+// verifyResourceType is the resource type of the operations tracking integrity verifications
+const verifyResourceType = types.ObjectType(web.IntegrityURL)
+
+// ResignRequest is the body of a request to recalculate the integrity of objects confirmed to be legitimate
+type ResignRequest struct {
+	ResourceType types.ObjectType `json:"resource_type"`
+	ResourceIDs  []string         `json:"resource_ids"`
+	Reason       string           `json:"reason"`
+}
+
+// Controller verifies the integrity of the integral objects and re-signs objects confirmed to be legitimate
+type Controller struct {
+	wg            *sync.WaitGroup
+	repository    storage.Repository
+	verifier      *storage.IntegrityVerifier
+	actionTimeout time.Duration
+}
+
+// NewController returns a new integrity controller. The repository is used to track the verification operations.
+// Verification operations which are still in progress after the action timeout are considered abandoned, for example
+// after a restart, and are failed when a new verification is requested.
+func NewController(repository storage.Repository, verifier *storage.IntegrityVerifier, actionTimeout time.Duration, wg *sync.WaitGroup) *Controller {
+	return &Controller{
+		wg:            wg,
+		repository:    repository,
+		verifier:      verifier,
+		actionTimeout: actionTimeout,
+	}
+}
+
+// Routes provides endpoints for verifying and re-signing the integrity of objects
+func (c *Controller) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   web.IntegrityVerifyURL,
+			},
+			Handler: c.verify,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s%s/{%s}", web.IntegrityVerifyURL, web.ResourceOperationsURL, web.PathParamID),
+			},
+			Handler: c.getVerifyOperation,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   web.IntegrityResignURL,
+			},
+			Handler: c.resign,
+		},
+	}
+}
+
+func (c *Controller) verify(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+
+	inProgress, err := c.repository.List(ctx, types.OperationType,
+		query.ByField(query.EqualsOperator, "resource_type", string(verifyResourceType)),
+		query.ByField(query.EqualsOperator, "state", string(types.IN_PROGRESS)))
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.OperationType.String())
+	}
+	for i := 0; i < inProgress.Len(); i++ {
+		operation := inProgress.ItemAt(i).(*types.Operation)
+		if time.Since(operation.UpdatedAt) <= c.actionTimeout {
+			log.C(ctx).Infof("Integrity verification with operation id %s is already in progress", operation.ID)
+			return util.NewLocationResponse(operation.ID, verifyResourceID, web.IntegrityURL)
+		}
+		if err := c.failAbandonedVerification(ctx, operation); err != nil {
+			return nil, err
+		}
+	}
+
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate GUID for operation: %s", err)
+	}
+	currentTime := time.Now()
+	operation := &types.Operation{
+		Base: types.Base{
+			ID:        UUID.String(),
+			CreatedAt: currentTime,
+			UpdatedAt: currentTime,
+			Labels:    make(map[string][]string),
+			Ready:     true,
+		},
+		Type:          types.UPDATE,
+		State:         types.IN_PROGRESS,
+		ResourceID:    verifyResourceID,
+		ResourceType:  verifyResourceType,
+		PlatformID:    types.SMPlatform,
+		CorrelationID: log.CorrelationIDFromContext(ctx),
+		Context:       &types.OperationContext{Async: true, UserInfo: userInfo(r)},
+	}
+	if _, err := c.repository.Create(ctx, operation); err != nil {
+		return nil, util.HandleStorageError(err, types.OperationType.String())
+	}
+
+	log.C(ctx).Infof("Starting integrity verification with operation id %s", operation.ID)
+	stateCtx := util.StateContext{Context: ctx}
+	util.StartInWaitGroup(func() {
+		c.runVerification(stateCtx, operation)
+	}, c.wg)
+
+	return util.NewLocationResponse(operation.ID, verifyResourceID, web.IntegrityURL)
+}
+
+func (c *Controller) runVerification(ctx context.Context, operation *types.Operation) {
+	report, err := c.verifier.Verify(ctx)
+	switch {
+	case err != nil:
+		operation.State = types.FAILED
+		operation.Errors = errorsJSON(ctx, &util.HTTPError{
+			ErrorType:   "InternalServerError",
+			Description: fmt.Sprintf("integrity verification failed: %s", err),
+			StatusCode:  http.StatusInternalServerError,
+		})
+	case len(report.Violations) > 0:
+		operation.State = types.FAILED
+		operation.Description = fmt.Sprintf("verified %d objects, found %d with invalid integrity", report.Verified, len(report.Violations))
+		operation.Errors = errorsJSON(ctx, struct {
+			ErrorType   string                       `json:"error"`
+			Description string                       `json:"description"`
+			Violations  []storage.IntegrityViolation `json:"violations"`
+		}{
+			ErrorType:   "IntegrityViolation",
+			Description: operation.Description,
+			Violations:  report.Violations,
+		})
+	default:
+		operation.State = types.SUCCEEDED
+		operation.Description = fmt.Sprintf("verified %d objects, all with valid integrity", report.Verified)
+	}
+
+	operation.UpdatedAt = time.Now()
+	if _, err := c.repository.Update(ctx, operation, types.LabelChanges{}); err != nil {
+		log.C(ctx).WithError(err).Errorf("Could not update state of integrity verification operation with id %s", operation.ID)
+	}
+}
+
+func (c *Controller) failAbandonedVerification(ctx context.Context, operation *types.Operation) error {
+	log.C(ctx).Infof("Failing integrity verification with operation id %s which has not finished within %s", operation.ID, c.actionTimeout)
+	operation.State = types.FAILED
+	operation.Errors = errorsJSON(ctx, &util.HTTPError{
+		ErrorType:   "InternalServerError",
+		Description: fmt.Sprintf("integrity verification did not finish within %s", c.actionTimeout),
+		StatusCode:  http.StatusInternalServerError,
+	})
+	operation.UpdatedAt = time.Now()
+	if _, err := c.repository.Update(ctx, operation, types.LabelChanges{}); err != nil {
+		return util.HandleStorageError(err, types.OperationType.String())
+	}
+	return nil
+}
+
+func (c *Controller) getVerifyOperation(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	operationID := r.PathParams[web.PathParamID]
+	log.C(ctx).Debugf("Getting integrity verification operation with id %s", operationID)
+
+	operation, err := c.repository.Get(ctx, types.OperationType,
+		query.ByField(query.EqualsOperator, "id", operationID),
+		query.ByField(query.EqualsOperator, "resource_type", string(verifyResourceType)))
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.OperationType.String())
+	}
+	operation.(*types.Operation).Sanitize(ctx)
+
+	return util.NewJSONResponse(http.StatusOK, operation)
+}
+
+func (c *Controller) resign(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+
+	request := &ResignRequest{}
+	if err := util.BytesToObject(r.Body, request); err != nil {
+		return nil, err
+	}
+
+	log.C(ctx).Infof("Re-signing integrity of %s with ids %v", request.ResourceType, request.ResourceIDs)
+	if err := c.verifier.Resign(ctx, request.ResourceType, request.ResourceIDs, request.Reason, userInfo(r)); err != nil {
+		return nil, err
+	}
+
+	return util.NewJSONResponse(http.StatusOK, map[string]string{})
+}
+
+func userInfo(r *web.Request) *types.UserInfo {
+	user, ok := web.UserFromContext(r.Context())
+	if !ok {
+		return nil
+	}
+	info, err := json.Marshal(map[string]string{"username": user.Name})
+	if err != nil {
+		log.C(r.Context()).WithError(err).Error("Could not marshal user info")
+		return nil
+	}
+	return &types.UserInfo{
+		Info: string(info),
+	}
+}
+
+func errorsJSON(ctx context.Context, errors interface{}) json.RawMessage {
+	bytes, err := json.Marshal(errors)
+	if err != nil {
+		log.C(ctx).WithError(err).Error("Could not marshal integrity verification errors")
+		return nil
+	}
+	return bytes
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package integrity
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Integrity controller", func() {
+	const actionTimeout = time.Minute
+
+	var (
+		wg             *sync.WaitGroup
+		fakeRepository *storagefakes.FakeStorage
+		controller     *Controller
+		inProgress     *types.Operation
+	)
+
+	BeforeEach(func() {
+		wg = &sync.WaitGroup{}
+		inProgress = &types.Operation{
+			Base:         types.Base{ID: "in-progress", UpdatedAt: time.Now()},
+			State:        types.IN_PROGRESS,
+			ResourceID:   verifyResourceID,
+			ResourceType: verifyResourceType,
+		}
+		fakeRepository = &storagefakes.FakeStorage{}
+		fakeRepository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			return &types.Operations{Operations: []*types.Operation{inProgress}}, nil
+		})
+		fakeRepository.ListNoLabelsReturns(types.NewObjectArray(), nil)
+		fakeRepository.CreateCalls(func(ctx context.Context, obj types.Object) (types.Object, error) {
+			return obj, nil
+		})
+		fakeRepository.UpdateCalls(func(ctx context.Context, obj types.Object, labelChanges types.LabelChanges, criteria ...query.Criterion) (types.Object, error) {
+			return obj, nil
+		})
+		controller = NewController(fakeRepository, &storage.IntegrityVerifier{
+			Repository:         fakeRepository,
+			IntegrityProcessor: storage.DefaultSettings().IntegrityProcessor,
+		}, actionTimeout, wg)
+	})
+
+	verify := func() *web.Response {
+		request := httptest.NewRequest(http.MethodPost, web.IntegrityVerifyURL, nil)
+		response, err := controller.verify(&web.Request{Request: request})
+		Expect(err).ToNot(HaveOccurred())
+		wg.Wait()
+		return response
+	}
+
+	Context("when a verification is in progress", func() {
+		It("returns the operation of the running verification", func() {
+			response := verify()
+			Expect(response.StatusCode).To(Equal(http.StatusAccepted))
+			Expect(response.Header.Get("Location")).To(ContainSubstring(inProgress.ID))
+			Expect(fakeRepository.CreateCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when a verification has not finished within the action timeout", func() {
+		BeforeEach(func() {
+			inProgress.UpdatedAt = time.Now().Add(-2 * actionTimeout)
+		})
+
+		It("fails the abandoned operation and starts a new verification", func() {
+			response := verify()
+			Expect(response.StatusCode).To(Equal(http.StatusAccepted))
+			Expect(response.Header.Get("Location")).ToNot(ContainSubstring(inProgress.ID))
+			Expect(fakeRepository.CreateCallCount()).To(Equal(1))
+
+			_, abandoned, _, _ := fakeRepository.UpdateArgsForCall(0)
+			Expect(abandoned.GetID()).To(Equal(inProgress.ID))
+			Expect(abandoned.(*types.Operation).State).To(Equal(types.FAILED))
+			Expect(string(abandoned.(*types.Operation).Errors)).To(ContainSubstring("did not finish"))
+
+			_, created := fakeRepository.CreateArgsForCall(0)
+			_, finished, _, _ := fakeRepository.UpdateArgsForCall(1)
+			Expect(finished.GetID()).To(Equal(created.GetID()))
+			Expect(finished.(*types.Operation).State).To(Equal(types.SUCCEEDED))
+		})
+	})
+})
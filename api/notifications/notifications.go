@@ -37,6 +37,12 @@ func (c *Controller) handleWS(req *web.Request) (*web.Response, error) {
 	if err != nil {
 		return nil, err
 	}
+	// the platform of the user context does not contain all stored fields (e.g. the password hashes), so the platform
+	// is read again before it is updated
+	platform, err = c.storedPlatform(ctx, platform.ID)
+	if err != nil {
+		return nil, err
+	}
 	version := req.Header.Get(AgentVersionHeader)
 	if platform.Version != version {
 		platform.Version = version
@@ -51,6 +57,7 @@ func (c *Controller) handleWS(req *web.Request) (*web.Response, error) {
 		logger.Debugf("Activating credentials for platform %s", platform.ID)
 		platform.CredentialsActive = true
 		platform.OldCredentials = nil
+		platform.OldPasswordHash = ""
 		_, err = c.repository.Update(ctx, platform, nil)
 		if err != nil {
 			logger.Errorf("Could not activate credentials for platform %s: %v", platform.ID, err)
@@ -197,6 +204,14 @@ func extractPlatformFromContext(userContext *web.UserContext) (*types.Platform,
 	return platform, nil
 }
 
+func (c *Controller) storedPlatform(ctx context.Context, platformID string) (*types.Platform, error) {
+	platform, err := c.repository.Get(ctx, types.PlatformType, query.ByField(query.EqualsOperator, "id", platformID))
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.PlatformType.String())
+	}
+	return platform.(*types.Platform), nil
+}
+
 func newContextWithCorrelationID(baseCtx context.Context, correlationID string) (context.Context, context.CancelFunc) {
 	entry := log.C(baseCtx).WithField(log.FieldCorrelationID, correlationID)
 	newCtx := log.ContextWithLogger(baseCtx, entry)
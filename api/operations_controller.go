@@ -3,14 +3,18 @@ package api
 import (
 	"context"
 	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/operations"
 	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/Peripli/service-manager/pkg/web"
-	"net/http"
 )
 
 // OperationsController implements api.Controller by providing operations API logic
 type OperationsController struct {
 	*BaseController
+	controls *operations.Controls
 }
 
 // NewOperationsController returns a new controller for operations api
@@ -19,6 +23,7 @@ func NewOperationsController(ctx context.Context, options *Options) *OperationsC
 		BaseController: NewController(ctx, options, web.OperationsURL, types.OperationType, func() types.Object {
 			return &types.Operation{}
 		}, false),
+		controls: operations.NewControls(ctx, options.Repository, options.OperationSettings, options.WaitGroup),
 	}
 }
 
@@ -38,5 +43,44 @@ func (c *OperationsController) Routes() []web.Route {
 			},
 			Handler: c.DeleteSingleObject,
 		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   fmt.Sprintf("%s/{%s}/cancel", c.resourceBaseURL, web.PathParamResourceID),
+			},
+			Handler: c.cancel,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   fmt.Sprintf("%s/{%s}/retry", c.resourceBaseURL, web.PathParamResourceID),
+			},
+			Handler: c.retry,
+		},
+	}
+}
+
+func (c *OperationsController) cancel(r *web.Request) (*web.Response, error) {
+	operation, err := c.controls.Cancel(r.Context(), r.PathParams[web.PathParamResourceID], operationUserName(r))
+	if err != nil {
+		return nil, err
+	}
+	cleanObject(r.Context(), operation)
+	return util.NewJSONResponse(http.StatusOK, operation)
+}
+
+func (c *OperationsController) retry(r *web.Request) (*web.Response, error) {
+	operation, err := c.controls.Retry(r.Context(), r.PathParams[web.PathParamResourceID], operationUserName(r))
+	if err != nil {
+		return nil, err
+	}
+	return util.NewLocationResponse(operation.ID, operation.ResourceID, operation.ResourceType.String())
+}
+
+func operationUserName(r *web.Request) string {
+	user, ok := web.UserFromContext(r.Context())
+	if !ok {
+		return "unknown user"
 	}
+	return user.Name
 }
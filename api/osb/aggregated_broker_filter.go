@@ -0,0 +1,354 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	AggregatedBrokerFilterName = "AggregatedBrokerFilter"
+
+	// AggregatedBrokerID is the broker id in the OSB API path of the aggregated broker, which exposes all brokers as one
+	AggregatedBrokerID = "aggregate"
+)
+
+// AggregatedBrokerFilter implements the aggregated broker. Its catalog merges the visible catalogs of all brokers,
+// with the SM ids of the service offerings and plans as service and plan ids and with unique service names. All other requests are routed to the
+// broker which owns the instance, with the ids translated to the catalog ids of the broker, and then processed
+// like requests to the broker itself.
+type AggregatedBrokerFilter struct {
+	repository    storage.Repository
+	catalogFilter *CatalogFilterByVisibilityPlugin
+}
+
+// NewAggregatedBrokerFilter creates a filter which implements the aggregated broker
+func NewAggregatedBrokerFilter(repository storage.Repository) *AggregatedBrokerFilter {
+	return &AggregatedBrokerFilter{
+		repository:    repository,
+		catalogFilter: NewCatalogFilterByVisibilityPlugin(repository),
+	}
+}
+
+func (f *AggregatedBrokerFilter) Name() string {
+	return AggregatedBrokerFilterName
+}
+
+func (f *AggregatedBrokerFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	if req.PathParams[BrokerIDPathParam] != AggregatedBrokerID {
+		return next.Handle(req)
+	}
+	if strings.HasSuffix(req.URL.Path, "/v2/catalog") {
+		return f.catalog(req)
+	}
+	return f.route(req, next)
+}
+
+func (f *AggregatedBrokerFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.OSBURL + "/**"),
+			},
+		},
+	}
+}
+
+// catalog merges the catalogs of all brokers filtered by the visibilities of the platform
+func (f *AggregatedBrokerFilter) catalog(req *web.Request) (*web.Response, error) {
+	ctx := req.Context()
+	brokers, err := f.repository.List(ctx, types.ServiceBrokerType)
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(types.ServiceBrokerType))
+	}
+
+	services := make([]interface{}, 0)
+	serviceNames := make(map[string]bool)
+	for i := 0; i < brokers.Len(); i++ {
+		broker := brokers.ItemAt(i).(*types.ServiceBroker)
+		if len(broker.Catalog) == 0 {
+			log.C(ctx).Infof("Skipping broker %s without catalog in aggregated catalog", broker.ID)
+			continue
+		}
+
+		brokerReq := *req
+		brokerReq.PathParams = copyPathParams(req.PathParams, broker.ID)
+		resp, err := f.catalogFilter.FetchCatalog(&brokerReq, web.HandlerFunc(func(*web.Request) (*web.Response, error) {
+			return util.NewJSONResponse(http.StatusOK, &broker.Catalog)
+		}))
+		if err != nil {
+			return nil, err
+		}
+
+		catalog, err := aggregatedCatalogIDs(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if catalog, err = aggregatedCatalogNames(catalog, serviceNames, broker.Name); err != nil {
+			return nil, err
+		}
+		for _, service := range gjson.GetBytes(catalog, "services").Array() {
+			services = append(services, service.Value())
+		}
+	}
+
+	return util.NewJSONResponse(http.StatusOK, map[string]interface{}{"services": services})
+}
+
+// aggregatedCatalogIDs replaces the ids of the services and plans in the catalog of a broker with their SM ids
+func aggregatedCatalogIDs(catalog []byte) ([]byte, error) {
+	var err error
+	for i, service := range gjson.GetBytes(catalog, "services").Array() {
+		servicePath := fmt.Sprintf("services.%d", i)
+		if catalog, err = sjson.SetBytes(catalog, servicePath+".id", service.Get("metadata.sm_offering_id").String()); err != nil {
+			return nil, err
+		}
+		for j, plan := range service.Get("plans").Array() {
+			planPath := fmt.Sprintf("%s.plans.%d.id", servicePath, j)
+			if catalog, err = sjson.SetBytes(catalog, planPath, plan.Get("metadata.sm_plan_id").String()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return catalog, nil
+}
+
+// aggregatedCatalogNames suffixes the names of services which are already in the merged catalog and of plans which
+// are already in their service with the broker name, because platforms reject catalogs with duplicate names
+func aggregatedCatalogNames(catalog []byte, serviceNames map[string]bool, brokerName string) ([]byte, error) {
+	var err error
+	for i, service := range gjson.GetBytes(catalog, "services").Array() {
+		servicePath := fmt.Sprintf("services.%d", i)
+		serviceName := service.Get("name").String()
+		if name := uniqueName(serviceNames, serviceName, brokerName); name != serviceName {
+			if catalog, err = sjson.SetBytes(catalog, servicePath+".name", name); err != nil {
+				return nil, err
+			}
+		}
+		planNames := make(map[string]bool)
+		for j, plan := range service.Get("plans").Array() {
+			planName := plan.Get("name").String()
+			if name := uniqueName(planNames, planName, brokerName); name != planName {
+				if catalog, err = sjson.SetBytes(catalog, fmt.Sprintf("%s.plans.%d.name", servicePath, j), name); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return catalog, nil
+}
+
+// uniqueName returns the name, or if it is already taken the name suffixed with the broker name and if needed a
+// counter, and marks the returned name as taken
+func uniqueName(names map[string]bool, name, brokerName string) string {
+	unique := name
+	if names[unique] {
+		unique = name + "-" + brokerName
+	}
+	for i := 2; names[unique]; i++ {
+		unique = fmt.Sprintf("%s-%s-%d", name, brokerName, i)
+	}
+	names[unique] = true
+	return unique
+}
+
+// route routes the request to the broker which owns the instance, or for new instances to the broker of the plan
+func (f *AggregatedBrokerFilter) route(req *web.Request, next web.Handler) (*web.Response, error) {
+	ctx := req.Context()
+	plan, err := f.ownerPlan(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	offering, err := f.offering(ctx, plan.ServiceOfferingID)
+	if err != nil {
+		return nil, err
+	}
+	brokerID := offering.BrokerID
+	log.C(ctx).Debugf("Routing aggregated broker request for instance %s to broker %s", req.PathParams[InstanceIDPathParam], brokerID)
+
+	if req.Body, err = f.catalogIDsInBody(ctx, req.Body, brokerID); err != nil {
+		return nil, err
+	}
+	routedURL := *req.URL
+	routedURL.Path = web.OSBURL + "/" + brokerID + strings.TrimPrefix(req.URL.Path, web.OSBURL+"/"+AggregatedBrokerID)
+	routedURL.RawPath = ""
+	routedURL.RawQuery = f.catalogIDsInQuery(ctx, req.URL.Query(), brokerID).Encode()
+	req.Request = req.WithContext(ctx)
+	req.URL = &routedURL
+	req.PathParams = copyPathParams(req.PathParams, brokerID)
+
+	resp, err := next.Handle(req)
+	if err != nil || req.Method != http.MethodGet || !isInstancePath(req) {
+		return resp, err
+	}
+	return f.smIDsInInstanceResponse(ctx, resp, brokerID)
+}
+
+// ownerPlan returns the plan of the stored instance, or for new instances the plan in the request body
+func (f *AggregatedBrokerFilter) ownerPlan(ctx context.Context, req *web.Request) (*types.ServicePlan, error) {
+	instanceID := req.PathParams[InstanceIDPathParam]
+	instance, err := f.repository.Get(ctx, types.ServiceInstanceType, query.ByField(query.EqualsOperator, "id", instanceID))
+	if err == nil {
+		return f.plan(ctx, instance.(*types.ServiceInstance).ServicePlanID)
+	}
+	if err != util.ErrNotFoundInStorage {
+		return nil, util.HandleStorageError(err, string(types.ServiceInstanceType))
+	}
+
+	planID := gjson.GetBytes(req.Body, "plan_id").String()
+	if req.Method != http.MethodPut || !isInstancePath(req) || planID == "" {
+		return nil, &util.HTTPError{
+			ErrorType:   "NotFound",
+			Description: fmt.Sprintf("could not find service instance %s", instanceID),
+			StatusCode:  http.StatusNotFound,
+		}
+	}
+	plan, err := f.plan(ctx, planID)
+	if err != nil {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("unknown plan_id %s", planID),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	return plan, nil
+}
+
+// catalogIDsInBody replaces the SM ids of the service and plan in the request body with the catalog ids of the broker
+func (f *AggregatedBrokerFilter) catalogIDsInBody(ctx context.Context, body []byte, brokerID string) ([]byte, error) {
+	if len(body) == 0 {
+		return body, nil
+	}
+	var err error
+	for _, prefix := range []string{"", "previous_values."} {
+		if serviceID := gjson.GetBytes(body, prefix+"service_id").String(); serviceID != "" {
+			if body, err = sjson.SetBytes(body, prefix+"service_id", f.catalogServiceID(ctx, serviceID, brokerID)); err != nil {
+				return nil, err
+			}
+		}
+		if planID := gjson.GetBytes(body, prefix+"plan_id").String(); planID != "" {
+			if body, err = sjson.SetBytes(body, prefix+"plan_id", f.catalogPlanID(ctx, planID, brokerID)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return body, nil
+}
+
+// catalogIDsInQuery replaces the SM ids of the service and plan in the query with the catalog ids of the broker
+func (f *AggregatedBrokerFilter) catalogIDsInQuery(ctx context.Context, values url.Values, brokerID string) url.Values {
+	if serviceID := values.Get("service_id"); serviceID != "" {
+		values.Set("service_id", f.catalogServiceID(ctx, serviceID, brokerID))
+	}
+	if planID := values.Get("plan_id"); planID != "" {
+		values.Set("plan_id", f.catalogPlanID(ctx, planID, brokerID))
+	}
+	return values
+}
+
+// catalogServiceID returns the catalog id of the service offering with the SM id, or the id itself if it is not the
+// SM id of an offering of the broker
+func (f *AggregatedBrokerFilter) catalogServiceID(ctx context.Context, serviceID, brokerID string) string {
+	offering, err := f.offering(ctx, serviceID)
+	if err != nil || offering.BrokerID != brokerID {
+		return serviceID
+	}
+	return offering.CatalogID
+}
+
+// catalogPlanID returns the catalog id of the plan with the SM id, or the id itself if it is not the SM id of a plan
+// of the broker
+func (f *AggregatedBrokerFilter) catalogPlanID(ctx context.Context, planID, brokerID string) string {
+	plan, err := f.plan(ctx, planID)
+	if err != nil {
+		return planID
+	}
+	offering, err := f.offering(ctx, plan.ServiceOfferingID)
+	if err != nil || offering.BrokerID != brokerID {
+		return planID
+	}
+	return plan.CatalogID
+}
+
+// smIDsInInstanceResponse replaces the catalog ids of the service and plan in a fetched instance with their SM ids
+func (f *AggregatedBrokerFilter) smIDsInInstanceResponse(ctx context.Context, resp *web.Response, brokerID string) (*web.Response, error) {
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	serviceID := gjson.GetBytes(resp.Body, "service_id").String()
+	planID := gjson.GetBytes(resp.Body, "plan_id").String()
+	if serviceID == "" || planID == "" {
+		return resp, nil
+	}
+	plan, err := findServicePlanByCatalogIDs(ctx, f.repository, brokerID, serviceID, planID)
+	if err != nil {
+		log.C(ctx).WithError(err).Warnf("Could not find plan %s of service %s of broker %s", planID, serviceID, brokerID)
+		return resp, nil
+	}
+	if resp.Body, err = sjson.SetBytes(resp.Body, "service_id", plan.ServiceOfferingID); err != nil {
+		return nil, err
+	}
+	if resp.Body, err = sjson.SetBytes(resp.Body, "plan_id", plan.ID); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (f *AggregatedBrokerFilter) plan(ctx context.Context, planID string) (*types.ServicePlan, error) {
+	plan, err := f.repository.Get(ctx, types.ServicePlanType, query.ByField(query.EqualsOperator, "id", planID))
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(types.ServicePlanType))
+	}
+	return plan.(*types.ServicePlan), nil
+}
+
+func (f *AggregatedBrokerFilter) offering(ctx context.Context, offeringID string) (*types.ServiceOffering, error) {
+	offering, err := f.repository.Get(ctx, types.ServiceOfferingType, query.ByField(query.EqualsOperator, "id", offeringID))
+	if err != nil {
+		return nil, util.HandleStorageError(err, string(types.ServiceOfferingType))
+	}
+	return offering.(*types.ServiceOffering), nil
+}
+
+// isInstancePath returns whether the request is to a service instance rather than to its bindings or operations
+func isInstancePath(req *web.Request) bool {
+	_, hasBinding := req.PathParams[BindingIDPathParam]
+	return !hasBinding && !strings.HasSuffix(req.URL.Path, "/last_operation")
+}
+
+func copyPathParams(pathParams map[string]string, brokerID string) map[string]string {
+	result := make(map[string]string, len(pathParams))
+	for key, value := range pathParams {
+		result[key] = value
+	}
+	result[BrokerIDPathParam] = brokerID
+	return result
+}
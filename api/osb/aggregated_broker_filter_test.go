@@ -0,0 +1,243 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/util/slice"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/pkg/web/webfakes"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Aggregated broker filter", func() {
+	var (
+		repository *storagefakes.FakeStorage
+		handler    *webfakes.FakeHandler
+		filter     *osb.AggregatedBrokerFilter
+		objects    map[types.ObjectType][]types.Object
+	)
+
+	fieldValue := func(object types.Object, field string) string {
+		switch o := object.(type) {
+		case *types.ServiceOffering:
+			return map[string]string{"id": o.ID, "broker_id": o.BrokerID, "catalog_id": o.CatalogID}[field]
+		case *types.ServicePlan:
+			return map[string]string{"id": o.ID, "service_offering_id": o.ServiceOfferingID, "catalog_id": o.CatalogID}[field]
+		}
+		return object.GetID()
+	}
+
+	find := func(objectType types.ObjectType, criteria ...query.Criterion) []types.Object {
+		var result []types.Object
+		for _, object := range objects[objectType] {
+			matches := true
+			for _, criterion := range criteria {
+				matches = matches && slice.StringsAnyEquals(criterion.RightOp, fieldValue(object, criterion.LeftOp))
+			}
+			if matches {
+				result = append(result, object)
+			}
+		}
+		return result
+	}
+
+	newRequest := func(method, path, body string, pathParams map[string]string) *web.Request {
+		u, err := url.Parse(path)
+		Expect(err).ToNot(HaveOccurred())
+		ctx := web.ContextWithUser(context.Background(), &web.UserContext{
+			AuthenticationType: web.Basic,
+			Name:               "cf-platform",
+			Data: func(data interface{}) error {
+				return json.Unmarshal([]byte(`{"id": "platform-id", "name": "cf-platform", "type": "cloudfoundry"}`), data)
+			},
+		})
+		req := &web.Request{
+			Request:    (&http.Request{Method: method, URL: u, Header: http.Header{}}).WithContext(ctx),
+			PathParams: pathParams,
+			Body:       []byte(body),
+		}
+		return req
+	}
+
+	BeforeEach(func() {
+		objects = map[types.ObjectType][]types.Object{
+			types.ServiceBrokerType: {
+				&types.ServiceBroker{Base: types.Base{ID: "broker-1"}, Name: "broker-1", Catalog: json.RawMessage(
+					`{"services": [{"id": "service", "name": "mysql", "plans": [{"id": "plan", "name": "small"}]}]}`)},
+				&types.ServiceBroker{Base: types.Base{ID: "broker-2"}, Name: "broker-2", Catalog: json.RawMessage(
+					`{"services": [{"id": "service", "name": "redis", "plans": [{"id": "plan", "name": "small"}]}]}`)},
+				&types.ServiceBroker{Base: types.Base{ID: "broker-3"}},
+			},
+			types.ServiceOfferingType: {
+				&types.ServiceOffering{Base: types.Base{ID: "offering-1"}, Name: "mysql", CatalogID: "service", BrokerID: "broker-1"},
+				&types.ServiceOffering{Base: types.Base{ID: "offering-2"}, Name: "redis", CatalogID: "service", BrokerID: "broker-2"},
+			},
+			types.ServicePlanType: {
+				&types.ServicePlan{Base: types.Base{ID: "plan-1"}, Name: "small", CatalogID: "plan", ServiceOfferingID: "offering-1"},
+				&types.ServicePlan{Base: types.Base{ID: "plan-2"}, Name: "small", CatalogID: "plan", ServiceOfferingID: "offering-2"},
+			},
+			types.ServiceInstanceType: {
+				&types.ServiceInstance{Base: types.Base{ID: "instance-2"}, ServicePlanID: "plan-2"},
+			},
+		}
+
+		repository = &storagefakes.FakeStorage{}
+		repository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			result := find(objectType, criteria...)
+			switch objectType {
+			case types.ServiceBrokerType:
+				list := &types.ServiceBrokers{}
+				for _, object := range result {
+					list.Add(object)
+				}
+				return list, nil
+			case types.ServiceOfferingType:
+				list := &types.ServiceOfferings{}
+				for _, object := range result {
+					list.Add(object)
+				}
+				return list, nil
+			default:
+				list := &types.ServicePlans{}
+				for _, object := range result {
+					list.Add(object)
+				}
+				return list, nil
+			}
+		})
+		repository.GetCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.Object, error) {
+			result := find(objectType, criteria...)
+			if len(result) == 0 {
+				return nil, util.ErrNotFoundInStorage
+			}
+			return result[0], nil
+		})
+		handler = &webfakes.FakeHandler{}
+		handler.HandleReturns(&web.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, nil)
+		filter = osb.NewAggregatedBrokerFilter(repository)
+	})
+
+	It("does not intercept requests to other brokers", func() {
+		req := newRequest(http.MethodGet, "/v1/osb/broker-1/v2/catalog", "", map[string]string{osb.BrokerIDPathParam: "broker-1"})
+		_, err := filter.Run(req, handler)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handler.HandleCallCount()).To(Equal(1))
+		Expect(handler.HandleArgsForCall(0)).To(Equal(req))
+	})
+
+	It("merges the catalogs of all brokers with collision free ids", func() {
+		req := newRequest(http.MethodGet, "/v1/osb/aggregate/v2/catalog", "", map[string]string{osb.BrokerIDPathParam: osb.AggregatedBrokerID})
+		resp, err := filter.Run(req, handler)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handler.HandleCallCount()).To(Equal(0))
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		services := gjson.GetBytes(resp.Body, "services").Array()
+		Expect(services).To(HaveLen(2))
+		Expect(services[0].Get("id").String()).To(Equal("offering-1"))
+		Expect(services[0].Get("plans.0.id").String()).To(Equal("plan-1"))
+		Expect(services[1].Get("id").String()).To(Equal("offering-2"))
+		Expect(services[1].Get("plans.0.id").String()).To(Equal("plan-2"))
+	})
+
+	It("makes the service names and the plan names of each service unique in the merged catalog", func() {
+		objects[types.ServiceBrokerType] = append(objects[types.ServiceBrokerType],
+			&types.ServiceBroker{Base: types.Base{ID: "broker-4"}, Name: "broker-4", Catalog: json.RawMessage(
+				`{"services": [{"id": "service", "name": "mysql", "plans": [{"id": "plan-a", "name": "small"}, {"id": "plan-b", "name": "small"}]},
+					{"id": "other", "name": "mysql-broker-4", "plans": [{"id": "plan", "name": "small"}]}]}`)})
+		objects[types.ServiceOfferingType] = append(objects[types.ServiceOfferingType],
+			&types.ServiceOffering{Base: types.Base{ID: "offering-4"}, Name: "mysql", CatalogID: "service", BrokerID: "broker-4"},
+			&types.ServiceOffering{Base: types.Base{ID: "offering-5"}, Name: "mysql-broker-4", CatalogID: "other", BrokerID: "broker-4"})
+		objects[types.ServicePlanType] = append(objects[types.ServicePlanType],
+			&types.ServicePlan{Base: types.Base{ID: "plan-4a"}, Name: "small", CatalogID: "plan-a", ServiceOfferingID: "offering-4"},
+			&types.ServicePlan{Base: types.Base{ID: "plan-4b"}, Name: "small", CatalogID: "plan-b", ServiceOfferingID: "offering-4"},
+			&types.ServicePlan{Base: types.Base{ID: "plan-5"}, Name: "small", CatalogID: "plan", ServiceOfferingID: "offering-5"})
+		req := newRequest(http.MethodGet, "/v1/osb/aggregate/v2/catalog", "", map[string]string{osb.BrokerIDPathParam: osb.AggregatedBrokerID})
+		resp, err := filter.Run(req, handler)
+		Expect(err).ToNot(HaveOccurred())
+
+		services := gjson.GetBytes(resp.Body, "services").Array()
+		Expect(services).To(HaveLen(4))
+		Expect(services[0].Get("name").String()).To(Equal("mysql"))
+		Expect(services[1].Get("name").String()).To(Equal("redis"))
+		Expect(services[2].Get("name").String()).To(Equal("mysql-broker-4"))
+		Expect(services[2].Get("plans.0.name").String()).To(Equal("small"))
+		Expect(services[2].Get("plans.1.name").String()).To(Equal("small-broker-4"))
+		Expect(services[3].Get("name").String()).To(Equal("mysql-broker-4-broker-4"))
+		Expect(services[3].Get("plans.0.name").String()).To(Equal("small"))
+	})
+
+	It("routes provisioning to the broker of the plan with its catalog ids", func() {
+		pathParams := map[string]string{osb.BrokerIDPathParam: osb.AggregatedBrokerID, osb.InstanceIDPathParam: "new-instance"}
+		req := newRequest(http.MethodPut, "/v1/osb/aggregate/v2/service_instances/new-instance?accepts_incomplete=true",
+			`{"service_id": "offering-1", "plan_id": "plan-1"}`, pathParams)
+		_, err := filter.Run(req, handler)
+		Expect(err).ToNot(HaveOccurred())
+
+		routed := handler.HandleArgsForCall(0)
+		Expect(routed.PathParams[osb.BrokerIDPathParam]).To(Equal("broker-1"))
+		Expect(routed.URL.Path).To(Equal("/v1/osb/broker-1/v2/service_instances/new-instance"))
+		Expect(routed.URL.Query().Get("accepts_incomplete")).To(Equal("true"))
+		Expect(gjson.GetBytes(routed.Body, "service_id").String()).To(Equal("service"))
+		Expect(gjson.GetBytes(routed.Body, "plan_id").String()).To(Equal("plan"))
+	})
+
+	It("routes requests for existing instances to the owning broker", func() {
+		pathParams := map[string]string{osb.BrokerIDPathParam: osb.AggregatedBrokerID, osb.InstanceIDPathParam: "instance-2"}
+		req := newRequest(http.MethodGet, "/v1/osb/aggregate/v2/service_instances/instance-2/last_operation?service_id=offering-2&plan_id=plan-2",
+			"", pathParams)
+		_, err := filter.Run(req, handler)
+		Expect(err).ToNot(HaveOccurred())
+
+		routed := handler.HandleArgsForCall(0)
+		Expect(routed.PathParams[osb.BrokerIDPathParam]).To(Equal("broker-2"))
+		Expect(routed.URL.Path).To(Equal("/v1/osb/broker-2/v2/service_instances/instance-2/last_operation"))
+		Expect(routed.URL.Query().Get("service_id")).To(Equal("service"))
+		Expect(routed.URL.Query().Get("plan_id")).To(Equal("plan"))
+	})
+
+	It("translates the ids of fetched instances to the aggregated catalog ids", func() {
+		handler.HandleReturns(&web.Response{StatusCode: http.StatusOK, Body: []byte(`{"service_id": "service", "plan_id": "plan"}`)}, nil)
+		pathParams := map[string]string{osb.BrokerIDPathParam: osb.AggregatedBrokerID, osb.InstanceIDPathParam: "instance-2"}
+		resp, err := filter.Run(newRequest(http.MethodGet, "/v1/osb/aggregate/v2/service_instances/instance-2", "", pathParams), handler)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(gjson.GetBytes(resp.Body, "service_id").String()).To(Equal("offering-2"))
+		Expect(gjson.GetBytes(resp.Body, "plan_id").String()).To(Equal("plan-2"))
+	})
+
+	It("fails for unknown instances", func() {
+		pathParams := map[string]string{osb.BrokerIDPathParam: osb.AggregatedBrokerID, osb.InstanceIDPathParam: "unknown"}
+		_, err := filter.Run(newRequest(http.MethodDelete, "/v1/osb/aggregate/v2/service_instances/unknown", "", pathParams), handler)
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusNotFound))
+		Expect(handler.HandleCallCount()).To(Equal(0))
+	})
+})
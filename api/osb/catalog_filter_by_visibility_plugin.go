@@ -98,9 +98,15 @@ func getVisiblePlansByBrokerIDAndPlatformID(ctx context.Context, repository stor
 		planIDs = append(planIDs, plansList.ItemAt(i).GetID())
 	}
 
-	visibilitiesList, err := repository.ListNoLabels(ctx, types.VisibilityType,
-		query.ByField(query.EqualsOrNilOperator, "platform_id", platform.ID),
-		query.ByField(query.InOperator, "service_plan_id", planIDs...))
+	byPlanIDs := query.ByField(query.InOperator, "service_plan_id", planIDs...)
+	_, unmatchedIDs, err := storage.SelectorVisibilities(ctx, repository, platform.ID, byPlanIDs)
+	if err != nil {
+		log.C(ctx).Errorf("Could not get %s with platform selector: %v", types.VisibilityType, err)
+		return nil, offeringsMap, plansMap, err
+	}
+	criteria := append([]query.Criterion{query.ByField(query.EqualsOrNilOperator, "platform_id", platform.ID), byPlanIDs, storage.ByVisibilityWindow()},
+		storage.ExcludingVisibilities(unmatchedIDs)...)
+	visibilitiesList, err := repository.ListNoLabels(ctx, types.VisibilityType, criteria...)
 	if err != nil {
 		log.C(ctx).Errorf("Could not get %s: %v", types.VisibilityType, err)
 		return nil, offeringsMap, plansMap, err
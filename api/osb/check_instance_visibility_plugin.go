@@ -130,6 +130,14 @@ func (p *checkVisibilityPlugin) checkVisibility(req *web.Request, next web.Handl
 		return next.Handle(req)
 	}
 
+	matching, _, err := storage.SelectorVisibilities(ctx, p.repository, platform.ID, query.ByField(query.EqualsOperator, "service_plan_id", planID), storage.ByVisibilityWindow())
+	if err != nil {
+		return nil, err
+	}
+	if len(matching) > 0 {
+		return next.Handle(req)
+	}
+
 	log.C(ctx).Errorf("Service plan %v is not visible on platform %v", planID, platform.ID)
 	return nil, errPlanNotAccessible
 }
@@ -0,0 +1,105 @@
+package osb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/tidwall/gjson"
+)
+
+const QuotaPluginName = "QuotaPlugin"
+
+type quotaPlugin struct {
+	repository       storage.Repository
+	tenantIdentifier string
+	checker          *storage.QuotaChecker
+}
+
+// NewQuotaPlugin creates new plugin that rejects provision and bind requests exceeding the quotas of the tenant before they reach the broker
+func NewQuotaPlugin(repository storage.Repository, tenantIdentifier string) *quotaPlugin {
+	return &quotaPlugin{
+		repository:       repository,
+		tenantIdentifier: tenantIdentifier,
+		checker:          &storage.QuotaChecker{TenantKey: tenantIdentifier},
+	}
+}
+
+// Name returns the name of the plugin
+func (p *quotaPlugin) Name() string {
+	return QuotaPluginName
+}
+
+// Provision intercepts provision requests and checks the instance quotas of the tenant in the request context
+func (p *quotaPlugin) Provision(req *web.Request, next web.Handler) (*web.Response, error) {
+	ctx := req.Context()
+	tenant := gjson.GetBytes(req.Body, fmt.Sprintf("context.%s", p.tenantIdentifier)).String()
+	if len(tenant) == 0 {
+		return next.Handle(req)
+	}
+	requestPayload := &provisionRequest{}
+	if err := decodeRequestBody(req, requestPayload); err != nil {
+		return nil, err
+	}
+	exists, err := p.exists(ctx, types.ServiceInstanceType, requestPayload.InstanceID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return next.Handle(req)
+	}
+
+	plan, err := findServicePlanByCatalogIDs(ctx, p.repository, requestPayload.BrokerID, requestPayload.ServiceID, requestPayload.PlanID)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checker.CheckInstance(ctx, p.repository, tenant, plan.GetID()); err != nil {
+		return nil, err
+	}
+	return next.Handle(req)
+}
+
+// Bind intercepts bind requests and checks the binding quotas of the instance owner
+func (p *quotaPlugin) Bind(req *web.Request, next web.Handler) (*web.Response, error) {
+	ctx := req.Context()
+	exists, err := p.exists(ctx, types.ServiceBindingType, req.PathParams[BindingIDPathParam])
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return next.Handle(req)
+	}
+
+	byID := query.ByField(query.EqualsOperator, "id", req.PathParams[InstanceIDPathParam])
+	object, err := p.repository.Get(ctx, types.ServiceInstanceType, byID)
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return next.Handle(req)
+		}
+		return nil, util.HandleStorageError(err, string(types.ServiceInstanceType))
+	}
+	instance := object.(*types.ServiceInstance)
+	tenant := ""
+	if values := instance.Labels[p.tenantIdentifier]; len(values) > 0 {
+		tenant = values[0]
+	}
+	if err := p.checker.CheckBinding(ctx, p.repository, tenant, instance); err != nil {
+		return nil, err
+	}
+	return next.Handle(req)
+}
+
+func (p *quotaPlugin) exists(ctx context.Context, objectType types.ObjectType, id string) (bool, error) {
+	if len(id) == 0 {
+		return false, nil
+	}
+	count, err := p.repository.Count(ctx, objectType, query.ByField(query.EqualsOperator, "id", id))
+	if err != nil {
+		return false, util.HandleStorageError(err, string(objectType))
+	}
+	return count > 0, nil
+}
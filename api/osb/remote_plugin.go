@@ -0,0 +1,401 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/httpclient"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/util/slice"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const (
+	// RemotePluginRequestPhase is the phase in which remote plugins are called with the OSB request before it is sent to the broker
+	RemotePluginRequestPhase = "request"
+	// RemotePluginResponsePhase is the phase in which remote plugins are called with the response of the broker
+	RemotePluginResponsePhase = "response"
+
+	// DefaultRemotePluginTimeout is the timeout of calls to remote plugins without a timeout
+	DefaultRemotePluginTimeout = 5 * time.Second
+)
+
+// remotePluginOperations are the names of the OSB operations which remote plugins can intercept
+var remotePluginOperations = []string{
+	"fetch_catalog", "fetch_service", "provision", "update_service", "deprovision",
+	"fetch_binding", "bind", "unbind", "poll_instance", "poll_binding", "adapt_credentials",
+}
+
+// remotePluginExcludedHeaders are the request headers which are not sent to remote plugins
+var remotePluginExcludedHeaders = []string{"Authorization", "Cookie"}
+
+// RemotePluginSettings configures an external endpoint which intercepts OSB requests as a plugin
+type RemotePluginSettings struct {
+	Name          string        `mapstructure:"name" description:"name of the plugin"`
+	URL           string        `mapstructure:"url" description:"url of the endpoint which is called with the intercepted requests"`
+	Operations    []string      `mapstructure:"operations" description:"OSB operations which the plugin intercepts, all operations if empty"`
+	Timeout       time.Duration `mapstructure:"timeout" description:"timeout of the calls to the plugin"`
+	FailurePolicy string        `mapstructure:"failure_policy" description:"whether requests fail (fail) or continue (ignore) if the plugin cannot be called"`
+	Insecure      bool          `mapstructure:"insecure" description:"whether the plugin can be called over plain http, which sends the intercepted requests and responses including binding credentials unencrypted"`
+}
+
+// Validate validates the remote plugin settings
+func (s *RemotePluginSettings) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("validate Settings: remote plugin name missing")
+	}
+	if strings.Contains(s.Name, ":") {
+		return fmt.Errorf("validate Settings: remote plugin name %s must not contain ':'", s.Name)
+	}
+	pluginURL, err := url.Parse(s.URL)
+	if err != nil || (pluginURL.Scheme != "http" && pluginURL.Scheme != "https") || pluginURL.Host == "" {
+		return fmt.Errorf("validate Settings: url of remote plugin %s must be an https url", s.Name)
+	}
+	if pluginURL.Scheme == "http" && !s.Insecure {
+		return fmt.Errorf("validate Settings: url of remote plugin %s must be an https url unless the plugin is insecure", s.Name)
+	}
+	for _, operation := range s.Operations {
+		if !slice.StringsAnyEquals(remotePluginOperations, operation) {
+			return fmt.Errorf("validate Settings: unknown operation %s of remote plugin %s, must be one of %s",
+				operation, s.Name, strings.Join(remotePluginOperations, ", "))
+		}
+	}
+	if s.Timeout < 0 {
+		return fmt.Errorf("validate Settings: timeout of remote plugin %s must not be negative", s.Name)
+	}
+	if s.FailurePolicy != "" && s.FailurePolicy != types.FailurePolicyFail && s.FailurePolicy != types.FailurePolicyIgnore {
+		return fmt.Errorf("validate Settings: failure policy of remote plugin %s must be %s or %s",
+			s.Name, types.FailurePolicyFail, types.FailurePolicyIgnore)
+	}
+	return nil
+}
+
+// RemotePluginMessage is an HTTP request or response exchanged with a remote plugin
+type RemotePluginMessage struct {
+	StatusCode int             `json:"status_code,omitempty"`
+	Header     http.Header     `json:"headers,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"`
+}
+
+// RemotePluginPlatform is the platform from which an intercepted OSB request is sent
+type RemotePluginPlatform struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// RemotePluginRequest is the body with which remote plugins are called
+type RemotePluginRequest struct {
+	Phase     string                `json:"phase"`
+	Operation string                `json:"operation"`
+	Method    string                `json:"method"`
+	Path      string                `json:"path"`
+	Query     string                `json:"query,omitempty"`
+	Header    http.Header           `json:"headers,omitempty"`
+	Body      json.RawMessage       `json:"body,omitempty"`
+	Platform  *RemotePluginPlatform `json:"platform,omitempty"`
+	Response  *RemotePluginMessage  `json:"response,omitempty"`
+}
+
+// RemotePluginResponse is the response of remote plugins. In the request phase a plugin may modify the request or
+// short-circuit it with a response, and request to be called again with the response of the broker. In the response
+// phase a plugin may replace the response of the broker.
+type RemotePluginResponse struct {
+	Request     *RemotePluginMessage `json:"request,omitempty"`
+	Response    *RemotePluginMessage `json:"response,omitempty"`
+	PostProcess bool                 `json:"post_process,omitempty"`
+}
+
+// RemotePlugin is a plugin which delegates the interception of OSB requests to an external endpoint
+type RemotePlugin struct {
+	settings  *RemotePluginSettings
+	doRequest util.DoRequestFunc
+}
+
+// NewRemotePlugin creates a plugin which calls the configured external endpoint. If a doRequest func is not provided,
+// a client configured with the global http client settings is used.
+func NewRemotePlugin(settings *RemotePluginSettings, doRequest util.DoRequestFunc) (*RemotePlugin, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	if doRequest == nil {
+		transport := &http.Transport{}
+		httpclient.ConfigureTransport(transport)
+		client := &http.Client{
+			Transport: transport,
+			Timeout:   httpclient.GetHttpClientGlobalSettings().Timeout,
+		}
+		doRequest = client.Do
+	}
+	return &RemotePlugin{
+		settings:  settings,
+		doRequest: doRequest,
+	}, nil
+}
+
+// Name returns the name of the plugin
+func (p *RemotePlugin) Name() string {
+	return p.settings.Name
+}
+
+// FetchCatalog intercepts get catalog requests with the remote plugin
+func (p *RemotePlugin) FetchCatalog(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.intercept("fetch_catalog", req, next)
+}
+
+// FetchService intercepts get service instance requests with the remote plugin
+func (p *RemotePlugin) FetchService(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.intercept("fetch_service", req, next)
+}
+
+// Provision intercepts provision requests with the remote plugin
+func (p *RemotePlugin) Provision(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.intercept("provision", req, next)
+}
+
+// UpdateService intercepts update service instance requests with the remote plugin
+func (p *RemotePlugin) UpdateService(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.intercept("update_service", req, next)
+}
+
+// Deprovision intercepts deprovision requests with the remote plugin
+func (p *RemotePlugin) Deprovision(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.intercept("deprovision", req, next)
+}
+
+// FetchBinding intercepts get service binding requests with the remote plugin
+func (p *RemotePlugin) FetchBinding(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.intercept("fetch_binding", req, next)
+}
+
+// Bind intercepts bind requests with the remote plugin
+func (p *RemotePlugin) Bind(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.intercept("bind", req, next)
+}
+
+// Unbind intercepts unbind requests with the remote plugin
+func (p *RemotePlugin) Unbind(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.intercept("unbind", req, next)
+}
+
+// PollInstance intercepts poll instance operation requests with the remote plugin
+func (p *RemotePlugin) PollInstance(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.intercept("poll_instance", req, next)
+}
+
+// PollBinding intercepts poll binding operation requests with the remote plugin
+func (p *RemotePlugin) PollBinding(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.intercept("poll_binding", req, next)
+}
+
+// AdaptCredentials intercepts adapt credentials requests with the remote plugin
+func (p *RemotePlugin) AdaptCredentials(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.intercept("adapt_credentials", req, next)
+}
+
+func (p *RemotePlugin) intercept(operation string, req *web.Request, next web.Handler) (*web.Response, error) {
+	if len(p.settings.Operations) != 0 && !slice.StringsAnyEquals(p.settings.Operations, operation) {
+		return next.Handle(req)
+	}
+
+	ctx := req.Context()
+	pluginRequest := newRemotePluginRequest(req, operation)
+	pluginResponse, err := p.call(ctx, pluginRequest)
+	if err != nil {
+		if err := p.failure(ctx, operation, err); err != nil {
+			return nil, err
+		}
+		return next.Handle(req)
+	}
+
+	if pluginResponse.Response != nil {
+		log.C(ctx).Infof("Remote plugin %s responded to %s request", p.settings.Name, operation)
+		return pluginResponse.Response.toResponse(), nil
+	}
+	if pluginResponse.Request != nil {
+		pluginResponse.Request.applyTo(req)
+	}
+
+	resp, err := next.Handle(req)
+	if err != nil || !pluginResponse.PostProcess {
+		return resp, err
+	}
+
+	pluginRequest.Phase = RemotePluginResponsePhase
+	pluginRequest.Header = remotePluginHeader(req.Header)
+	pluginRequest.Body = remotePluginBody(req.Body)
+	pluginRequest.Response = &RemotePluginMessage{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       remotePluginBody(resp.Body),
+	}
+	if pluginResponse, err = p.call(ctx, pluginRequest); err != nil {
+		if err := p.failure(ctx, operation, err); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+	if pluginResponse.Response != nil {
+		return pluginResponse.Response.toResponse(), nil
+	}
+	return resp, nil
+}
+
+// failure returns the error with which a request fails if the remote plugin cannot be called, or nil if the plugin
+// fails open
+func (p *RemotePlugin) failure(ctx context.Context, operation string, err error) error {
+	if p.settings.FailurePolicy == types.FailurePolicyIgnore {
+		log.C(ctx).WithError(err).Warnf("Remote plugin %s failed, continuing %s request as its failure policy is %s",
+			p.settings.Name, operation, types.FailurePolicyIgnore)
+		return nil
+	}
+	log.C(ctx).WithError(err).Errorf("Remote plugin %s failed", p.settings.Name)
+	return &util.HTTPError{
+		ErrorType:   "RemotePluginError",
+		Description: fmt.Sprintf("remote plugin %s failed to process the request", p.settings.Name),
+		StatusCode:  http.StatusBadGateway,
+	}
+}
+
+func (p *RemotePlugin) call(ctx context.Context, pluginRequest *RemotePluginRequest) (*RemotePluginResponse, error) {
+	body, err := json.Marshal(pluginRequest)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal remote plugin request: %s", err)
+	}
+
+	timeout := p.settings.Timeout
+	if timeout == 0 {
+		timeout = DefaultRemotePluginTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodPost, p.settings.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not call remote plugin: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("remote plugin responded with status %d", resp.StatusCode)
+	}
+
+	pluginResponse := &RemotePluginResponse{}
+	if err := util.BodyToObject(resp.Body, pluginResponse); err != nil {
+		return nil, fmt.Errorf("could not read remote plugin response: %s", err)
+	}
+	if pluginResponse.Response != nil && pluginResponse.Response.StatusCode == 0 {
+		return nil, fmt.Errorf("remote plugin responded without a status code of the response")
+	}
+	return pluginResponse, nil
+}
+
+func newRemotePluginRequest(req *web.Request, operation string) *RemotePluginRequest {
+	pluginRequest := &RemotePluginRequest{
+		Phase:     RemotePluginRequestPhase,
+		Operation: operation,
+		Method:    req.Method,
+		Path:      req.URL.Path,
+		Query:     req.URL.RawQuery,
+		Header:    remotePluginHeader(req.Header),
+		Body:      remotePluginBody(req.Body),
+	}
+	if user, found := web.UserFromContext(req.Context()); found && user.Data != nil {
+		platform := &types.Platform{}
+		if err := user.Data(platform); err == nil && platform.ID != "" {
+			pluginRequest.Platform = &RemotePluginPlatform{
+				ID:   platform.ID,
+				Name: platform.Name,
+				Type: platform.Type,
+			}
+		}
+	}
+	return pluginRequest
+}
+
+// remotePluginHeader returns a copy of the header without the credentials of the caller
+func remotePluginHeader(header http.Header) http.Header {
+	result := make(http.Header, len(header))
+	for key, values := range header {
+		result[key] = values
+	}
+	for _, key := range remotePluginExcludedHeaders {
+		result.Del(key)
+	}
+	return result
+}
+
+// remotePluginBody returns the body as JSON, bodies which are not JSON are sent as JSON strings
+func remotePluginBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return body
+	}
+	encoded, err := json.Marshal(string(body))
+	if err != nil {
+		return nil
+	}
+	return encoded
+}
+
+// applyTo sets the headers and replaces the body of the request with the ones of the message. The headers are set on
+// a clone of the original http.Request.
+func (m *RemotePluginMessage) applyTo(req *web.Request) {
+	if len(m.Header) != 0 {
+		req.Request = req.Request.Clone(req.Context())
+		for key, values := range m.Header {
+			req.Header[http.CanonicalHeaderKey(key)] = values
+		}
+	}
+	if m.Body != nil {
+		req.Body = m.Body
+	}
+}
+
+func (m *RemotePluginMessage) toResponse() *web.Response {
+	header := make(http.Header, len(m.Header)+1)
+	for key, values := range m.Header {
+		header[http.CanonicalHeaderKey(key)] = values
+	}
+	body := []byte(m.Body)
+	if len(body) == 0 {
+		body = []byte("{}")
+	}
+	header.Set("Content-Type", "application/json")
+	return &web.Response{
+		StatusCode: m.StatusCode,
+		Header:     header,
+		Body:       body,
+	}
+}
@@ -0,0 +1,210 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package osb_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Remote plugin", func() {
+	var (
+		settings        *osb.RemotePluginSettings
+		pluginRequests  []*osb.RemotePluginRequest
+		pluginResponses []string
+		pluginErr       error
+		brokerCalled    bool
+		brokerRequest   *web.Request
+		req             *web.Request
+	)
+
+	doRequest := func(request *http.Request) (*http.Response, error) {
+		if pluginErr != nil {
+			return nil, pluginErr
+		}
+		pluginRequest := &osb.RemotePluginRequest{}
+		Expect(util.BodyToObject(request.Body, pluginRequest)).To(Succeed())
+		pluginRequests = append(pluginRequests, pluginRequest)
+		response := pluginResponses[0]
+		pluginResponses = pluginResponses[1:]
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(response)),
+		}, nil
+	}
+
+	broker := web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+		brokerCalled = true
+		brokerRequest = req
+		return &web.Response{
+			StatusCode: http.StatusCreated,
+			Header:     http.Header{},
+			Body:       []byte(`{"dashboard_url": "https://dashboard.example.com"}`),
+		}, nil
+	})
+
+	provision := func() (*web.Response, error) {
+		plugin, err := osb.NewRemotePlugin(settings, doRequest)
+		Expect(err).ToNot(HaveOccurred())
+		return plugin.Provision(req, broker)
+	}
+
+	BeforeEach(func() {
+		settings = &osb.RemotePluginSettings{
+			Name: "remote",
+			URL:  "https://plugin.example.com/intercept",
+		}
+		pluginRequests = nil
+		pluginResponses = nil
+		pluginErr = nil
+		brokerCalled = false
+		brokerRequest = nil
+
+		request, err := http.NewRequest(http.MethodPut, "https://sm.example.com/v1/osb/broker-id/v2/service_instances/instance-id?accepts_incomplete=true", nil)
+		Expect(err).ToNot(HaveOccurred())
+		request.Header.Set("Authorization", "Basic secret")
+		request.Header.Set("X-Broker-API-Version", "2.14")
+		req = &web.Request{
+			Request: request,
+			Body:    []byte(`{"service_id": "service", "plan_id": "plan"}`),
+		}
+	})
+
+	It("sends the request without credentials to the plugin", func() {
+		pluginResponses = []string{`{}`}
+		resp, err := provision()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(brokerCalled).To(BeTrue())
+
+		Expect(pluginRequests).To(HaveLen(1))
+		Expect(pluginRequests[0].Phase).To(Equal(osb.RemotePluginRequestPhase))
+		Expect(pluginRequests[0].Operation).To(Equal("provision"))
+		Expect(pluginRequests[0].Method).To(Equal(http.MethodPut))
+		Expect(pluginRequests[0].Path).To(Equal("/v1/osb/broker-id/v2/service_instances/instance-id"))
+		Expect(pluginRequests[0].Query).To(Equal("accepts_incomplete=true"))
+		Expect(pluginRequests[0].Header.Get("X-Broker-API-Version")).To(Equal("2.14"))
+		Expect(pluginRequests[0].Header.Get("Authorization")).To(BeEmpty())
+		Expect(string(pluginRequests[0].Body)).To(MatchJSON(req.Body))
+		Expect(req.Header.Get("Authorization")).To(Equal("Basic secret"))
+	})
+
+	It("modifies the request", func() {
+		pluginResponses = []string{`{"request": {"headers": {"X-Plugin": ["true"]}, "body": {"service_id": "service", "plan_id": "other-plan"}}}`}
+		_, err := provision()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(brokerRequest.Header.Get("X-Plugin")).To(Equal("true"))
+		Expect(string(brokerRequest.Body)).To(MatchJSON(`{"service_id": "service", "plan_id": "other-plan"}`))
+	})
+
+	It("short-circuits the request with the response of the plugin", func() {
+		pluginResponses = []string{`{"response": {"status_code": 400, "body": {"description": "rejected"}}}`}
+		resp, err := provision()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(brokerCalled).To(BeFalse())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(string(resp.Body)).To(MatchJSON(`{"description": "rejected"}`))
+	})
+
+	It("post-processes the response of the broker", func() {
+		pluginResponses = []string{`{"post_process": true}`, `{"response": {"status_code": 201, "body": {"dashboard_url": "https://other.example.com"}}}`}
+		resp, err := provision()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pluginRequests).To(HaveLen(2))
+		Expect(pluginRequests[1].Phase).To(Equal(osb.RemotePluginResponsePhase))
+		Expect(pluginRequests[1].Response.StatusCode).To(Equal(http.StatusCreated))
+		Expect(string(pluginRequests[1].Response.Body)).To(MatchJSON(`{"dashboard_url": "https://dashboard.example.com"}`))
+		Expect(string(resp.Body)).To(MatchJSON(`{"dashboard_url": "https://other.example.com"}`))
+	})
+
+	It("does not call the plugin for operations it does not intercept", func() {
+		settings.Operations = []string{"bind"}
+		_, err := provision()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pluginRequests).To(BeEmpty())
+		Expect(brokerCalled).To(BeTrue())
+	})
+
+	Context("when the plugin cannot be called", func() {
+		BeforeEach(func() {
+			pluginErr = errors.New("connection refused")
+		})
+
+		It("fails the request by default", func() {
+			_, err := provision()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadGateway))
+			Expect(brokerCalled).To(BeFalse())
+		})
+
+		It("continues the request if the plugin fails open", func() {
+			settings.FailurePolicy = types.FailurePolicyIgnore
+			resp, err := provision()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+			Expect(brokerCalled).To(BeTrue())
+		})
+	})
+
+	It("fails for a response without a status code", func() {
+		pluginResponses = []string{`{"response": {"body": {}}}`}
+		_, err := provision()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("validates its settings", func() {
+		settings.Operations = []string{"purge"}
+		_, err := osb.NewRemotePlugin(settings, doRequest)
+		Expect(err).To(HaveOccurred())
+
+		settings.Operations = nil
+		settings.URL = "ftp://plugin.example.com"
+		_, err = osb.NewRemotePlugin(settings, doRequest)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("requires https unless the plugin is insecure", func() {
+		settings.URL = "http://plugin.example.com/intercept"
+		_, err := osb.NewRemotePlugin(settings, doRequest)
+		Expect(err).To(HaveOccurred())
+
+		settings.Insecure = true
+		_, err = osb.NewRemotePlugin(settings, doRequest)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("sends bodies which are not JSON as strings", func() {
+		req.Body = []byte("not json")
+		pluginResponses = []string{`{}`}
+		_, err := provision()
+		Expect(err).ToNot(HaveOccurred())
+		var body string
+		Expect(json.Unmarshal(pluginRequests[0].Body, &body)).To(Succeed())
+		Expect(body).To(Equal("not json"))
+	})
+})
@@ -3,23 +3,44 @@ package api
 import (
 	"fmt"
 	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/Peripli/service-manager/pkg/web"
 	"github.com/Peripli/service-manager/storage"
 	"net/http"
 )
 
 type TenantController struct {
-	repository storage.Repository
+	repository   storage.Repository
+	quotaChecker *storage.QuotaChecker
 }
 
-func NewTenantController(repository storage.Repository) *TenantController {
-	return &TenantController{repository: repository}
+func NewTenantController(repository storage.Repository, tenantLabelKey string) *TenantController {
+	return &TenantController{
+		repository:   repository,
+		quotaChecker: &storage.QuotaChecker{TenantKey: tenantLabelKey},
+	}
 }
 
 func (c *TenantController) GetOperation(req *web.Request) (resp *web.Response, err error) {
 	return GetResourceOperation(req, c.repository, types.TenantType)
 }
 
+// GetQuotas returns the usage of the quotas of the tenant
+func (c *TenantController) GetQuotas(req *web.Request) (resp *web.Response, err error) {
+	tenant := req.PathParams[web.PathParamResourceID]
+	usages, err := c.quotaChecker.Usage(req.Context(), c.repository, tenant)
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.QuotaType.String())
+	}
+	return util.NewJSONResponse(http.StatusOK, struct {
+		Tenant string                `json:"tenant"`
+		Quotas []*storage.QuotaUsage `json:"quotas"`
+	}{
+		Tenant: tenant,
+		Quotas: usages,
+	})
+}
+
 func (c *TenantController) Routes() []web.Route {
 	return []web.Route{
 		{
@@ -29,5 +50,12 @@ func (c *TenantController) Routes() []web.Route {
 			},
 			Handler: c.GetOperation,
 		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s/{%s}%s", web.TenantURL, web.PathParamResourceID, web.QuotasSubpathURL),
+			},
+			Handler: c.GetQuotas,
+		},
 	}
 }
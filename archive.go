@@ -0,0 +1,125 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Peripli/service-manager/config"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// archivePassphraseEnv is the environment variable holding the passphrase with which the secrets in an archive are encrypted
+const archivePassphraseEnv = "SM_ARCHIVE_PASSPHRASE"
+
+const exportUsage = `  export FILE [TENANT]                   exports the Service Manager state (or the state of a single tenant) into an archive, "-" for stdout
+                                        the secrets are encrypted with the passphrase in ` + archivePassphraseEnv
+
+const importUsage = `  import FILE                           imports an archive, "-" for stdin
+                                        the secrets are decrypted with the passphrase in ` + archivePassphraseEnv
+
+func runExport(ctx context.Context, cfg *config.Settings, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("expected FILE and optional TENANT parameters\n\n%s", exportUsage)
+	}
+	passphrase := os.Getenv(archivePassphraseEnv)
+	if passphrase == "" {
+		return fmt.Errorf("%s should be set", archivePassphraseEnv)
+	}
+	tenant := ""
+	if len(args) == 2 {
+		tenant = args[1]
+	}
+
+	return withArchiver(ctx, cfg, func(archiver *storage.Archiver) error {
+		var writer io.Writer = os.Stdout
+		if args[0] != "-" {
+			file, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+			if err != nil {
+				return err
+			}
+			defer closeFile(ctx, file)
+			writer = file
+		}
+
+		buffered := bufio.NewWriter(writer)
+		summary, err := archiver.Export(ctx, buffered, storage.ExportOptions{
+			Passphrase:     passphrase,
+			TenantLabelKey: cfg.Multitenancy.LabelKey,
+			Tenant:         tenant,
+		})
+		if err != nil {
+			return err
+		}
+		if err := buffered.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exported %v\n", summary.Objects)
+		return nil
+	})
+}
+
+func runImport(ctx context.Context, cfg *config.Settings, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected exactly one FILE parameter\n\n%s", importUsage)
+	}
+	passphrase := os.Getenv(archivePassphraseEnv)
+	if passphrase == "" {
+		return fmt.Errorf("%s should be set", archivePassphraseEnv)
+	}
+
+	return withArchiver(ctx, cfg, func(archiver *storage.Archiver) error {
+		var reader io.Reader = os.Stdin
+		if args[0] != "-" {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeFile(ctx, file)
+			reader = file
+		}
+
+		summary, err := archiver.Import(ctx, bufio.NewReader(reader), passphrase)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Imported %v\n", summary.Objects)
+		return nil
+	})
+}
+
+func withArchiver(ctx context.Context, cfg *config.Settings, f func(archiver *storage.Archiver) error) error {
+	return withEncryptingRepository(ctx, cfg, func(repository storage.TransactionalRepository) error {
+		return f(&storage.Archiver{
+			Repository:         repository,
+			IntegrityProcessor: cfg.Storage.IntegrityProcessor,
+			Encrypter:          &security.AESEncrypter{},
+		})
+	})
+}
+
+func closeFile(ctx context.Context, file *os.File) {
+	if err := file.Close(); err != nil {
+		log.C(ctx).WithError(err).Errorf("Could not close %s", file.Name())
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Peripli/service-manager/config"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/postgres"
+)
+
+// command is an administrative task that runs instead of the Service Manager server
+type command struct {
+	usage string
+	run   func(ctx context.Context, cfg *config.Settings, args []string) error
+}
+
+var commands = map[string]command{
+	"export": {
+		usage: exportUsage,
+		run:   runExport,
+	},
+	"import": {
+		usage: importUsage,
+		run:   runImport,
+	},
+	"integrity": {
+		usage: integrityUsage,
+		run:   runIntegrity,
+	},
+	"migrate": {
+		usage: migrateUsage,
+		run:   runMigrate,
+	},
+}
+
+func runCommand(ctx context.Context, cfg *config.Settings, args []string) error {
+	cmd, found := commands[args[0]]
+	if !found {
+		return fmt.Errorf("unknown command %s\n\n%s", args[0], usage())
+	}
+	if err := cmd.run(ctx, cfg, args[1:]); err != nil {
+		return fmt.Errorf("%s: %s", args[0], err)
+	}
+	return nil
+}
+
+func usage() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	usages := make([]string, 0, len(names))
+	for _, name := range names {
+		usages = append(usages, commands[name].usage)
+	}
+	return "Available commands:\n" + strings.Join(usages, "\n")
+}
+
+// withEncryptingRepository opens the storage and provides a repository which decrypts secrets. Integrity is not
+// validated by the repository, so that commands can process objects with invalid integrity.
+func withEncryptingRepository(ctx context.Context, cfg *config.Settings, f func(repository storage.TransactionalRepository) error) error {
+	if err := cfg.Storage.Validate(); err != nil {
+		return err
+	}
+
+	smStorage := &postgres.Storage{
+		ConnectFunc: func(driver string, url string) (*sql.DB, error) {
+			return sql.Open(driver, url)
+		},
+	}
+	if err := smStorage.Open(cfg.Storage); err != nil {
+		return fmt.Errorf("error opening storage: %s", err)
+	}
+	defer func() {
+		if err := smStorage.Close(); err != nil {
+			log.C(ctx).WithError(err).Error("Could not close storage")
+		}
+	}()
+
+	encryptingRepository, err := storage.EncryptingDecorator(ctx, &security.AESEncrypter{}, smStorage, postgres.EncryptingLocker(smStorage))(smStorage)
+	if err != nil {
+		return fmt.Errorf("error decorating storage with encryption: %s", err)
+	}
+	return f(encryptingRepository)
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/Peripli/service-manager/pkg/env"
 	"github.com/Peripli/service-manager/pkg/health"
 	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/security/policy"
 	"github.com/Peripli/service-manager/pkg/server"
 	"github.com/Peripli/service-manager/pkg/ws"
 	"github.com/Peripli/service-manager/storage"
@@ -48,6 +49,7 @@ type Settings struct {
 	Health       *health.Settings
 	Multitenancy *multitenancy.Settings
 	Agents       *agents.Settings
+	Security     *policy.Settings
 }
 
 // AddPFlags adds the SM config flags to the provided flag set
@@ -69,6 +71,7 @@ func DefaultSettings() *Settings {
 		Health:       health.DefaultSettings(),
 		Multitenancy: multitenancy.DefaultSettings(),
 		Agents:       agents.DefaultSettings(),
+		Security:     policy.DefaultSettings(),
 	}
 }
 
@@ -78,6 +81,9 @@ func New(env env.Environment) (*Settings, error) {
 	if err := env.Unmarshal(config); err != nil {
 		return nil, fmt.Errorf("error loading configuration: %s", err)
 	}
+	if config.Storage != nil {
+		config.Storage.ConfigureIntegrity()
+	}
 	return config, nil
 }
 
@@ -85,7 +91,7 @@ func New(env env.Environment) (*Settings, error) {
 func (c *Settings) Validate() error {
 	validatable := []interface {
 		Validate() error
-	}{c.Server, c.Storage, c.Log, c.Health, c.API, c.Operations, c.WebSocket, c.Multitenancy, c.Agents, c.HTTPClient}
+	}{c.Server, c.Storage, c.Log, c.Health, c.API, c.Operations, c.WebSocket, c.Multitenancy, c.Agents, c.HTTPClient, c.Security}
 
 	for _, item := range validatable {
 		if err := item.Validate(); err != nil {
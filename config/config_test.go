@@ -26,9 +26,11 @@ import (
 	"github.com/Peripli/service-manager/pkg/health"
 
 	"github.com/Peripli/service-manager/api"
+	"github.com/Peripli/service-manager/api/osb"
 	cfg "github.com/Peripli/service-manager/config"
 	"github.com/Peripli/service-manager/pkg/env/envfakes"
 	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/security/policy"
 	"github.com/Peripli/service-manager/pkg/server"
 	"github.com/Peripli/service-manager/storage"
 	. "github.com/onsi/ginkgo"
@@ -213,6 +215,27 @@ var _ = Describe("config", func() {
 			})
 		})
 
+		Context("when remote plugins are configured", func() {
+			remotePlugin := func(name string) osb.RemotePluginSettings {
+				return osb.RemotePluginSettings{Name: name, URL: "https://plugin.example.com"}
+			}
+
+			It("accepts remote plugins with unique names", func() {
+				config.API.RemotePlugins = []osb.RemotePluginSettings{remotePlugin("audit"), remotePlugin("billing")}
+				Expect(config.Validate()).To(Succeed())
+			})
+
+			It("returns an error for duplicate remote plugin names", func() {
+				config.API.RemotePlugins = []osb.RemotePluginSettings{remotePlugin("audit"), remotePlugin("audit")}
+				assertErrorDuringValidate()
+			})
+
+			It("returns an error for remote plugins with the name of a built-in plugin", func() {
+				config.API.RemotePlugins = []osb.RemotePluginSettings{remotePlugin(osb.QuotaPluginName)}
+				assertErrorDuringValidate()
+			})
+		})
+
 		Context("when notification queues size is 0", func() {
 			It("returns an error", func() {
 				config.Storage.Notification.QueuesSize = 0
@@ -270,6 +293,57 @@ var _ = Describe("config", func() {
 			})
 		})
 
+		Context("when operation approval timeout is not smaller than the reconciliation timeout", func() {
+			It("returns an error", func() {
+				config.Operations.ApprovalTimeout = config.Operations.ReconciliationOperationTimeout
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when operation approver scope is empty", func() {
+			It("returns an error", func() {
+				config.Operations.ApproverScope = ""
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when idempotency key lifespan < 0", func() {
+			It("returns an error", func() {
+				config.Operations.IdempotencyKeyLifespan = -time.Second
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when lease warning period < 0", func() {
+			It("returns an error", func() {
+				config.Operations.LeaseWarningPeriod = -time.Second
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when operation queue size < 0", func() {
+			It("returns an error", func() {
+				config.Operations.QueueSize = -1
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when tenant weight < 1", func() {
+			It("returns an error", func() {
+				config.Operations.TenantWeights = []operations.TenantWeightSettings{{Tenant: "tenant", Weight: 0}}
+				assertErrorDuringValidate()
+			})
+		})
+
+		Context("when a security policy rule has an unknown authenticator", func() {
+			It("returns an error", func() {
+				config.Security.Rules = []policy.RuleSettings{
+					{Paths: []string{"/v1/**"}, Methods: []string{"GET"}, Authenticators: []string{"unknown"}},
+				}
+				assertErrorDuringValidate()
+			})
+		})
+
 		Context("when operation rescheduling interval < 0", func() {
 			It("returns an error", func() {
 				config.Operations.ReschedulingInterval = -time.Second
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+
+	"github.com/Peripli/service-manager/config"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const integrityUsage = `  integrity verify                      validates the integrity of all integral objects and reports the invalid ones
+  integrity resign TYPE REASON ID...    recalculates the integrity of objects confirmed to be legitimate (e.g. integrity resign platforms "restored from backup" <id>)
+  integrity upgrade                     signs the valid legacy integrity with storage.integrity_key (requires storage.integrity_legacy)`
+
+func runIntegrity(ctx context.Context, cfg *config.Settings, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing integrity action\n\n%s", integrityUsage)
+	}
+	return withEncryptingRepository(ctx, cfg, func(repository storage.TransactionalRepository) error {
+		verifier := &storage.IntegrityVerifier{
+			Repository:         repository,
+			IntegrityProcessor: cfg.Storage.IntegrityProcessor,
+		}
+		return integrityAction(ctx, verifier, args[0], args[1:])
+	})
+}
+
+func integrityAction(ctx context.Context, verifier *storage.IntegrityVerifier, action string, params []string) error {
+	switch action {
+	case "verify":
+		report, err := verifier.Verify(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "Verified objects: %d\n", report.Verified)
+		fmt.Fprintf(os.Stdout, "Violations:       %d\n", len(report.Violations))
+		for _, violation := range report.Violations {
+			fmt.Fprintf(os.Stdout, "  %s\t%s\n", violation.ResourceType, violation.ResourceID)
+		}
+		if len(report.Violations) > 0 {
+			return fmt.Errorf("found %d objects with invalid integrity", len(report.Violations))
+		}
+		return nil
+	case "resign":
+		if len(params) < 3 {
+			return fmt.Errorf("expected TYPE, REASON and at least one ID parameter")
+		}
+		objectType, err := integralType(params[0])
+		if err != nil {
+			return err
+		}
+		if err := verifier.Resign(ctx, objectType, params[2:], params[1], cliUserInfo()); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "Re-signed integrity of %d %s\n", len(params[2:]), objectType)
+		return nil
+	case "upgrade":
+		upgraded, err := verifier.Upgrade(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "Upgraded integrity of %d objects\n", upgraded)
+		return nil
+	default:
+		return fmt.Errorf("unknown integrity action %s\n\n%s", action, integrityUsage)
+	}
+}
+
+// integralType accepts both the object type (e.g. /v1/platforms) and its short name (e.g. platforms)
+func integralType(name string) (types.ObjectType, error) {
+	for _, objectType := range storage.IntegralTypes {
+		if string(objectType) == name || path.Base(string(objectType)) == name {
+			return objectType, nil
+		}
+	}
+	return "", fmt.Errorf("integrity is not processed for objects of type %s", name)
+}
+
+func cliUserInfo() *types.UserInfo {
+	username := "unknown"
+	if current, err := user.Current(); err == nil {
+		username = current.Username
+	}
+	info, err := json.Marshal(map[string]string{"username": username, "origin": "cli"})
+	if err != nil {
+		return nil
+	}
+	return &types.UserInfo{
+		Info: string(info),
+	}
+}
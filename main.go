@@ -18,6 +18,10 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
 
 	"github.com/Peripli/service-manager/api/extensions/security"
 
@@ -35,7 +39,10 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	env, err := env.DefaultLogger(ctx, config.AddPFlags)
+	var flags *pflag.FlagSet
+	env, err := env.DefaultLogger(ctx, config.AddPFlags, func(set *pflag.FlagSet) {
+		flags = set
+	})
 	if err != nil {
 		panic(err)
 	}
@@ -45,6 +52,14 @@ func main() {
 		panic(err)
 	}
 
+	if args := flags.Args(); len(args) > 0 {
+		if err := runCommand(ctx, cfg, args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	serviceManager, err := sm.New(ctx, cancel, env, cfg)
 	if err != nil {
 		panic(err)
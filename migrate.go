@@ -0,0 +1,140 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Peripli/service-manager/config"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/storage/postgres"
+)
+
+const migrateUsage = `  migrate up              applies all pending schema migrations
+  migrate down N          rolls back the last N applied schema migrations
+  migrate status          shows the current schema version and the available migrations
+  migrate force VERSION   sets the schema version without running migrations and clears the dirty state
+  migrate plan            shows the migrations that "migrate up" would apply`
+
+func runMigrate(ctx context.Context, cfg *config.Settings, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate action\n\n%s", migrateUsage)
+	}
+	if err := cfg.Storage.Validate(); err != nil {
+		return err
+	}
+
+	db, err := postgres.Connect(cfg.Storage, sql.Open)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.C(ctx).WithError(err).Error("Could not close database connection")
+		}
+	}()
+
+	migrator, err := postgres.NewMigrator(db, cfg.Storage.MigrationsURL)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := migrator.Close(); err != nil {
+			log.C(ctx).WithError(err).Error("Could not close migrations source")
+		}
+	}()
+
+	action, params := args[0], args[1:]
+	switch action {
+	case "up":
+		if err := migrator.Up(); err != nil {
+			return err
+		}
+		return printMigrationStatus(migrator)
+	case "down":
+		steps, err := intParam(params, "N")
+		if err != nil {
+			return err
+		}
+		if err := migrator.Down(steps); err != nil {
+			return err
+		}
+		return printMigrationStatus(migrator)
+	case "force":
+		version, err := intParam(params, "VERSION")
+		if err != nil {
+			return err
+		}
+		if err := migrator.Force(version); err != nil {
+			return err
+		}
+		return printMigrationStatus(migrator)
+	case "status":
+		return printMigrationStatus(migrator)
+	case "plan":
+		pending, err := migrator.Plan()
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			fmt.Fprintln(os.Stdout, "Database schema is up to date. No migrations will be applied.")
+			return nil
+		}
+		fmt.Fprintf(os.Stdout, "The following %d migrations will be applied:\n", len(pending))
+		for _, migration := range pending {
+			fmt.Fprintf(os.Stdout, "  %d\t%s\n", migration.Version, migration.Identifier)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate action %s\n\n%s", action, migrateUsage)
+	}
+}
+
+func printMigrationStatus(migrator *postgres.Migrator) error {
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "Current version: %d\n", status.CurrentVersion)
+	fmt.Fprintf(os.Stdout, "Latest version:  %d\n", status.LatestVersion)
+	fmt.Fprintf(os.Stdout, "Dirty:           %t\n", status.Dirty)
+	fmt.Fprintf(os.Stdout, "Pending:         %d\n", len(status.Pending()))
+	for _, migration := range status.Migrations {
+		state := "pending"
+		if migration.Applied {
+			state = "applied"
+		}
+		fmt.Fprintf(os.Stdout, "  %d\t%s\t%s\n", migration.Version, state, migration.Identifier)
+	}
+	return nil
+}
+
+func intParam(params []string, name string) (int, error) {
+	if len(params) != 1 {
+		return 0, fmt.Errorf("expected exactly one %s parameter", name)
+	}
+	value, err := strconv.Atoi(params[0])
+	if err != nil {
+		return 0, fmt.Errorf("%s should be a number: %s", name, err)
+	}
+	return value, nil
+}
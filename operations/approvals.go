@@ -0,0 +1,276 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// ApprovalRequest is an operation which is executed only after it is approved
+type ApprovalRequest struct {
+	OperationID   string           `json:"operation_id"`
+	ResourceID    string           `json:"resource_id"`
+	ResourceType  types.ObjectType `json:"resource_type"`
+	ServicePlanID string           `json:"service_plan_id"`
+	RequestedAt   time.Time        `json:"requested_at"`
+	ExpiresAt     time.Time        `json:"expires_at"`
+}
+
+// Approvals approves and rejects operations awaiting approval. Approved operations are continued by the scheduler,
+// rejected and expired operations fail and their resources are deleted.
+type Approvals struct {
+	repository storage.TransactionalRepository
+	scheduler  *Scheduler
+	timeout    time.Duration
+}
+
+// NewApprovals constructs Approvals
+func NewApprovals(smCtx context.Context, repository storage.TransactionalRepository, settings *Settings, wg *sync.WaitGroup) *Approvals {
+	return &Approvals{
+		repository: repository,
+		scheduler:  NewScheduler(smCtx, repository, settings, settings.DefaultPoolSize, wg),
+		timeout:    settings.ApprovalTimeout,
+	}
+}
+
+// Pending returns the operations awaiting approval whose resources still exist, scoped by the criteria of the context
+func (a *Approvals) Pending(ctx context.Context) ([]*ApprovalRequest, error) {
+	withResource, err := storage.GetSubQueryWithParams(storage.QueryForOperationsWithResource, storage.SubQueryParams{
+		"RESOURCE_TABLE": "service_instances",
+	})
+	if err != nil {
+		return nil, err
+	}
+	criteria := append([]query.Criterion{
+		query.ByField(query.EqualsOperator, "state", string(types.AWAITING_APPROVAL)),
+		query.ByField(query.EqualsOperator, "resource_type", string(types.ServiceInstanceType)),
+		query.ByExists(withResource),
+		query.OrderResultBy("paging_sequence", query.AscOrder),
+	}, query.CriteriaForContext(ctx)...)
+	objectList, err := a.repository.List(ctx, types.OperationType, criteria...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.OperationType.String())
+	}
+
+	requests := make([]*ApprovalRequest, 0, objectList.Len())
+	for i := 0; i < objectList.Len(); i++ {
+		requests = append(requests, a.approvalRequest(objectList.ItemAt(i).(*types.Operation)))
+	}
+	return requests, nil
+}
+
+// Approve continues the operation awaiting approval asynchronously. The requester of the operation cannot approve it,
+// and operations whose requester is unknown cannot be approved at all.
+func (a *Approvals) Approve(ctx context.Context, operationID, approvedBy string) (*ApprovalRequest, error) {
+	var operation *types.Operation
+	var resource types.Object
+	if err := a.repository.InTransaction(ctx, func(ctx context.Context, storage storage.Repository) error {
+		var err error
+		if operation, err = getAwaitingOperation(ctx, storage, operationID); err != nil {
+			return err
+		}
+		requester := operationRequester(operation)
+		if len(requester) == 0 {
+			return &util.HTTPError{
+				ErrorType:   "Forbidden",
+				Description: fmt.Sprintf("operation with id %s cannot be approved because its requester is unknown", operationID),
+				StatusCode:  http.StatusForbidden,
+			}
+		}
+		if requester == approvedBy {
+			return &util.HTTPError{
+				ErrorType:   "Forbidden",
+				Description: fmt.Sprintf("operation with id %s cannot be approved by its requester", operationID),
+				StatusCode:  http.StatusForbidden,
+			}
+		}
+		if err = transitionAwaitingOperation(ctx, storage, operation, types.IN_PROGRESS); err != nil {
+			return err
+		}
+		if resource, err = storage.Get(ctx, operation.ResourceType, query.ByField(query.EqualsOperator, "id", operation.ResourceID)); err != nil {
+			return util.HandleStorageError(err, operation.ResourceType.String())
+		}
+
+		operation.Context.Approved = true
+		operation.Description = fmt.Sprintf("approved by %s", approvedBy)
+		if _, err := storage.Update(ctx, operation, types.LabelChanges{}); err != nil {
+			return util.HandleStorageError(err, types.OperationType.String())
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	request := a.approvalRequest(operation)
+	log.C(ctx).Infof("Operation with id %s for %s with id %s was approved by %s", operation.ID, operation.ResourceType, operation.ResourceID, approvedBy)
+	action := func(ctx context.Context, repository storage.Repository) (types.Object, error) {
+		object, err := repository.Create(ctx, resource)
+		return object, util.HandleStorageError(err, operation.ResourceType.String())
+	}
+	if err := a.scheduler.ScheduleAsyncStorageAction(ctx, operation, action); err != nil {
+		// the operation can be approved again unless it was failed by the scheduler
+		if operation.State == types.IN_PROGRESS {
+			operation.State = types.AWAITING_APPROVAL
+			operation.Context.Approved = false
+			operation.Description = ""
+			if _, updateErr := a.repository.Update(ctx, operation, types.LabelChanges{}); updateErr != nil {
+				log.C(ctx).Errorf("Could not revert approval of operation with id %s: %s", operation.ID, updateErr)
+			}
+		}
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// Reject fails the operation awaiting approval and deletes its resource
+func (a *Approvals) Reject(ctx context.Context, operationID, rejectedBy, reason string) (*ApprovalRequest, error) {
+	description := fmt.Sprintf("rejected by %s", rejectedBy)
+	if len(reason) > 0 {
+		description = fmt.Sprintf("%s: %s", description, reason)
+	}
+	return a.fail(ctx, operationID, &util.HTTPError{
+		ErrorType:   "ApprovalRejected",
+		Description: description,
+		StatusCode:  http.StatusForbidden,
+	})
+}
+
+// FailExpired fails the operations which were not approved within the approval timeout and deletes their resources
+func (a *Approvals) FailExpired(ctx context.Context) {
+	objectList, err := a.repository.List(ctx, types.OperationType,
+		query.ByField(query.EqualsOperator, "state", string(types.AWAITING_APPROVAL)),
+		query.ByField(query.LessThanOperator, "created_at", util.ToRFCNanoFormat(time.Now().Add(-a.timeout))))
+	if err != nil {
+		log.C(ctx).Errorf("Failed to fetch expired approval requests: %s", err)
+		return
+	}
+
+	for i := 0; i < objectList.Len(); i++ {
+		operationID := objectList.ItemAt(i).GetID()
+		if _, err := a.fail(ctx, operationID, &util.HTTPError{
+			ErrorType:   "ApprovalExpired",
+			Description: fmt.Sprintf("operation was not approved within %s", a.timeout),
+			StatusCode:  http.StatusForbidden,
+		}); err != nil {
+			log.C(ctx).Errorf("Failed to fail expired approval request of operation with id %s: %s", operationID, err)
+		}
+	}
+	log.C(ctx).Debugf("Finished failing %d expired approval requests", objectList.Len())
+}
+
+func (a *Approvals) fail(ctx context.Context, operationID string, opErr *util.HTTPError) (*ApprovalRequest, error) {
+	var operation *types.Operation
+	if err := a.repository.InTransaction(ctx, func(ctx context.Context, storage storage.Repository) error {
+		var err error
+		if operation, err = getAwaitingOperation(ctx, storage, operationID); err != nil {
+			return err
+		}
+		if err = transitionAwaitingOperation(ctx, storage, operation, types.FAILED); err != nil {
+			return err
+		}
+
+		operation.Description = opErr.Description
+		if err := updateOperationState(ctx, storage, operation, types.FAILED, opErr); err != nil {
+			return err
+		}
+
+		// the resource was never provisioned, so it is deleted without calling the broker
+		err = storage.Delete(ctx, operation.ResourceType, query.ByField(query.EqualsOperator, "id", operation.ResourceID))
+		if err != nil && err != util.ErrNotFoundInStorage {
+			return util.HandleStorageError(err, operation.ResourceType.String())
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	log.C(ctx).Infof("Operation with id %s for %s with id %s failed: %s", operation.ID, operation.ResourceType, operation.ResourceID, opErr.Description)
+	return a.approvalRequest(operation), nil
+}
+
+func (a *Approvals) approvalRequest(operation *types.Operation) *ApprovalRequest {
+	request := &ApprovalRequest{
+		OperationID:  operation.ID,
+		ResourceID:   operation.ResourceID,
+		ResourceType: operation.ResourceType,
+		RequestedAt:  operation.CreatedAt,
+		ExpiresAt:    operation.CreatedAt.Add(a.timeout),
+	}
+	if operation.Context != nil {
+		request.ServicePlanID = operation.Context.ServicePlanID
+	}
+	return request
+}
+
+// getAwaitingOperation returns the operation awaiting approval, scoped by the criteria of the context
+func getAwaitingOperation(ctx context.Context, repository storage.Repository, operationID string) (*types.Operation, error) {
+	criteria := append([]query.Criterion{query.ByField(query.EqualsOperator, "id", operationID)}, query.CriteriaForContext(ctx)...)
+	object, err := repository.Get(ctx, types.OperationType, criteria...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.OperationType.String())
+	}
+	operation := object.(*types.Operation)
+	if operation.State != types.AWAITING_APPROVAL {
+		return nil, notAwaitingApprovalError(operationID)
+	}
+	if operation.Context == nil {
+		operation.Context = &types.OperationContext{}
+	}
+	return operation, nil
+}
+
+// transitionAwaitingOperation moves the operation out of the awaiting approval state,
+// so that only one of concurrent approvals and rejections succeeds
+func transitionAwaitingOperation(ctx context.Context, repository storage.Repository, operation *types.Operation, newState types.OperationState) error {
+	transitioned, err := transitionOperationState(ctx, repository, operation, newState)
+	if err != nil {
+		return err
+	}
+	if !transitioned {
+		return notAwaitingApprovalError(operation.ID)
+	}
+	return nil
+}
+
+// operationRequester returns the name of the user who requested the operation, if known
+// operationRequester returns the authenticated user who requested the operation. The user info of the operation
+// is not used, because it can be provided by the client as originating identity.
+func operationRequester(operation *types.Operation) string {
+	if operation.Context == nil {
+		return ""
+	}
+	return operation.Context.RequestedBy
+}
+
+func notAwaitingApprovalError(operationID string) error {
+	return &util.HTTPError{
+		ErrorType:   "Conflict",
+		Description: fmt.Sprintf("operation with id %s is not awaiting approval", operationID),
+		StatusCode:  http.StatusConflict,
+	}
+}
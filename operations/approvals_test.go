@@ -0,0 +1,215 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Approvals", func() {
+	const tenantLabelKey = "tenant"
+
+	var (
+		ctx        context.Context
+		cancel     context.CancelFunc
+		wg         *sync.WaitGroup
+		repository *inMemoryRepository
+		approvals  *Approvals
+	)
+
+	newAwaitingOperation := func(id, tenant string, createdAt time.Time) *types.Operation {
+		return &types.Operation{
+			Base: types.Base{
+				ID:        id,
+				CreatedAt: createdAt,
+				UpdatedAt: createdAt,
+				Labels:    types.Labels{tenantLabelKey: {tenant}},
+				Ready:     true,
+			},
+			Type:         types.CREATE,
+			State:        types.AWAITING_APPROVAL,
+			ResourceID:   "instance-" + id,
+			ResourceType: types.ServiceInstanceType,
+			Context: &types.OperationContext{
+				ServicePlanID: "plan",
+				UserInfo:      &types.UserInfo{Info: `{"username": "requester"}`},
+				RequestedBy:   "requester",
+			},
+		}
+	}
+
+	newInstance := func(id string) *types.ServiceInstance {
+		return &types.ServiceInstance{Base: types.Base{ID: id, Ready: false}, ServicePlanID: "plan"}
+	}
+
+	tenantContext := func(tenant string) context.Context {
+		tenantCtx, err := query.AddCriteria(ctx, query.ByLabel(query.EqualsOperator, tenantLabelKey, tenant))
+		Expect(err).ToNot(HaveOccurred())
+		return tenantCtx
+	}
+
+	expectHTTPError := func(err error, statusCode int) {
+		Expect(err).To(HaveOccurred())
+		Expect(err).To(BeAssignableToTypeOf(&util.HTTPError{}))
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(statusCode))
+	}
+
+	operation := func(id string) *types.Operation {
+		object := repository.object(id)
+		Expect(object).ToNot(BeNil())
+		return object.(*types.Operation)
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		wg = &sync.WaitGroup{}
+		now := time.Now()
+		repository = newInMemoryRepository(
+			newAwaitingOperation("op-1", "tenant-1", now),
+			newInstance("instance-op-1"),
+			newAwaitingOperation("op-2", "tenant-2", now),
+			newInstance("instance-op-2"),
+			newAwaitingOperation("op-expired", "tenant-1", now.Add(-48*time.Hour)),
+			newInstance("instance-op-expired"),
+		)
+		settings := DefaultSettings()
+		settings.TenantLabelKey = tenantLabelKey
+		approvals = NewApprovals(ctx, repository, settings, wg)
+	})
+
+	AfterEach(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	Describe("Pending", func() {
+		It("lists only the operations of the tenant in the context", func() {
+			requests, err := approvals.Pending(tenantContext("tenant-2"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].OperationID).To(Equal("op-2"))
+			Expect(requests[0].ServicePlanID).To(Equal("plan"))
+		})
+	})
+
+	Describe("Approve", func() {
+		BeforeEach(func() {
+			// approved instances are already stored, the instance interceptor updates them instead of creating them
+			repository.CreateCalls(func(ctx context.Context, object types.Object) (types.Object, error) {
+				return repository.Update(ctx, object, types.LabelChanges{})
+			})
+		})
+
+		It("continues the operation and provisions the instance", func() {
+			request, err := approvals.Approve(tenantContext("tenant-1"), "op-1", "approver")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(request.OperationID).To(Equal("op-1"))
+			wg.Wait()
+
+			approved := operation("op-1")
+			Expect(approved.State).To(Equal(types.SUCCEEDED))
+			Expect(approved.Context.Approved).To(BeTrue())
+			Expect(approved.Description).To(Equal("approved by approver"))
+			Expect(repository.object("instance-op-1").GetReady()).To(BeTrue())
+		})
+
+		It("does not allow the requester to approve the operation", func() {
+			_, err := approvals.Approve(tenantContext("tenant-1"), "op-1", "requester")
+			expectHTTPError(err, http.StatusForbidden)
+			Expect(operation("op-1").State).To(Equal(types.AWAITING_APPROVAL))
+			Expect(repository.QueryForListCallCount()).To(Equal(0))
+		})
+
+		It("does not approve operations whose requester is unknown", func() {
+			// the user info of the originating identity header has no username and can be chosen by the client
+			spoofed := operation("op-1")
+			spoofed.Context.UserInfo = &types.UserInfo{Info: `{"user_id": "someone-else"}`}
+			spoofed.Context.RequestedBy = ""
+			_, err := repository.Update(ctx, spoofed, types.LabelChanges{})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = approvals.Approve(tenantContext("tenant-1"), "op-1", "approver")
+			expectHTTPError(err, http.StatusForbidden)
+			Expect(operation("op-1").State).To(Equal(types.AWAITING_APPROVAL))
+		})
+
+		It("does not find operations of other tenants", func() {
+			_, err := approvals.Approve(tenantContext("tenant-1"), "op-2", "approver")
+			expectHTTPError(err, http.StatusNotFound)
+			Expect(operation("op-2").State).To(Equal(types.AWAITING_APPROVAL))
+		})
+
+		It("fails with conflict if a concurrent decision changed the state first", func() {
+			rejected := operation("op-1")
+			rejected.State = types.FAILED
+			_, err := repository.Update(ctx, rejected, types.LabelChanges{})
+			Expect(err).ToNot(HaveOccurred())
+			// the operation was read before the concurrent rejection was committed
+			repository.GetReturns(newAwaitingOperation("op-1", "tenant-1", time.Now()), nil)
+
+			_, err = approvals.Approve(tenantContext("tenant-1"), "op-1", "approver")
+			expectHTTPError(err, http.StatusConflict)
+			Expect(repository.QueryForListCallCount()).To(Equal(1))
+			Expect(operation("op-1").State).To(Equal(types.FAILED))
+		})
+	})
+
+	Describe("Reject", func() {
+		It("fails the operation and deletes the instance", func() {
+			request, err := approvals.Reject(tenantContext("tenant-1"), "op-1", "approver", "too expensive")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(request.OperationID).To(Equal("op-1"))
+
+			rejected := operation("op-1")
+			Expect(rejected.State).To(Equal(types.FAILED))
+			Expect(rejected.Description).To(Equal("rejected by approver: too expensive"))
+			Expect(string(rejected.Errors)).To(ContainSubstring("ApprovalRejected"))
+			Expect(repository.object("instance-op-1")).To(BeNil())
+		})
+
+		It("fails with conflict for operations which are not awaiting approval", func() {
+			_, err := approvals.Reject(tenantContext("tenant-1"), "op-1", "approver", "")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = approvals.Reject(tenantContext("tenant-1"), "op-1", "approver", "")
+			expectHTTPError(err, http.StatusConflict)
+		})
+	})
+
+	Describe("FailExpired", func() {
+		It("fails only the operations which were not approved within the approval timeout", func() {
+			approvals.FailExpired(ctx)
+
+			expired := operation("op-expired")
+			Expect(expired.State).To(Equal(types.FAILED))
+			Expect(string(expired.Errors)).To(ContainSubstring("ApprovalExpired"))
+			Expect(repository.object("instance-op-expired")).To(BeNil())
+			Expect(operation("op-1").State).To(Equal(types.AWAITING_APPROVAL))
+			Expect(operation("op-2").State).To(Equal(types.AWAITING_APPROVAL))
+		})
+	})
+})
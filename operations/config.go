@@ -32,12 +32,21 @@ type Settings struct {
 	ActionTimeout                  time.Duration `mapstructure:"action_timeout" description:"timeout for async operations"`
 	ReconciliationOperationTimeout time.Duration `mapstructure:"reconciliation_operation_timeout" description:"the maximum allowed timeout for auto rescheduling of operation actions"`
 	CascadeOrphanMitigationTimeout time.Duration `mapstructure:"cascade_orphan_mitigation_timeout" description:"maximum allowed timeout for orphan mitigation of cascade operations"`
+	ApprovalTimeout                time.Duration `mapstructure:"approval_timeout" description:"after that time is passed since its creation, an operation awaiting approval fails"`
+	ApproverScope                  string        `mapstructure:"approver_scope" description:"scope a user must have to approve or reject operations awaiting approval, unless the request is authorized by the security policy"`
 
 	PollCascadeInterval     time.Duration `mapstructure:"poll_cascade_interval" description:"poll interval for cascade operations"`
 	CleanupInterval         time.Duration `mapstructure:"cleanup_interval" description:"cleanup interval of old operations"`
 	MaintainerRetryInterval time.Duration `mapstructure:"maintainer_retry_interval" description:"maintenance retry interval"`
 	Lifespan                time.Duration `mapstructure:"lifespan" description:"after that time is passed since its creation, the operation can be cleaned up by the maintainer"`
 
+	IdempotencyKeyLifespan time.Duration `mapstructure:"idempotency_key_lifespan" description:"after that time is passed since its creation, an idempotency key expires and is cleaned up by the maintainer"`
+
+	VisibilityWindowInterval time.Duration `mapstructure:"visibility_window_interval" description:"interval for activating and deactivating visibilities whose validity window opened or closed"`
+
+	LeaseInterval      time.Duration `mapstructure:"lease_interval" description:"interval for warning about and deleting service instances whose lease expires"`
+	LeaseWarningPeriod time.Duration `mapstructure:"lease_warning_period" description:"service instances are labeled as expiring this long before their lease expires"`
+
 	ReschedulingInterval time.Duration `mapstructure:"rescheduling_interval" description:"the interval between auto rescheduling of operation actions"`
 	PollingInterval      time.Duration `mapstructure:"polling_interval" description:"the interval between polls for async requests"`
 
@@ -45,6 +54,11 @@ type Settings struct {
 	DefaultCascadePollingPoolSize int            `mapstructure:"default_cascade_polling_pool_size" description:"default worker pool size"`
 	Pools                         []PoolSettings `mapstructure:"pools" description:"defines the different available worker pools"`
 
+	QueueSize              int                    `mapstructure:"queue_size" description:"maximum number of operations waiting for a worker of a pool, 0 disables queuing"`
+	TenantConcurrencyLimit int                    `mapstructure:"tenant_concurrency_limit" description:"maximum number of operations of a tenant executed at the same time by a worker pool, 0 means no limit"`
+	TenantLabelKey         string                 `mapstructure:"tenant_label_key" description:"label of operations identifying their tenant for fair scheduling, defaults to the multitenancy label key"`
+	TenantWeights          []TenantWeightSettings `mapstructure:"tenant_weights" description:"defines the weights of tenants in the round-robin between tenants with queued operations"`
+
 	SMSupportedPlatformType []string `mapstructure:"sm_supported_platform_type" description:"defines the value of the supported platform aliases for the SM platform"`
 }
 
@@ -54,15 +68,24 @@ func DefaultSettings() *Settings {
 		ActionTimeout:                  15 * time.Minute,
 		ReconciliationOperationTimeout: 7 * 24 * time.Hour,
 		CascadeOrphanMitigationTimeout: 6 * time.Hour,
+		ApprovalTimeout:                24 * time.Hour,
+		ApproverScope:                  "sm.approver",
 		CleanupInterval:                1 * time.Hour,
 		MaintainerRetryInterval:        10 * time.Minute,
 		Lifespan:                       7 * 24 * time.Hour,
+		IdempotencyKeyLifespan:         24 * time.Hour,
+		VisibilityWindowInterval:       1 * time.Minute,
+		LeaseInterval:                  5 * time.Minute,
+		LeaseWarningPeriod:             24 * time.Hour,
 		ReschedulingInterval:           10 * time.Second,
 		PollingInterval:                4 * time.Second,
 		PollCascadeInterval:            4 * time.Second,
 		DefaultPoolSize:                20,
 		DefaultCascadePollingPoolSize:  20,
 		Pools:                          []PoolSettings{},
+		QueueSize:                      1000,
+		TenantConcurrencyLimit:         0,
+		TenantWeights:                  []TenantWeightSettings{},
 		SMSupportedPlatformType:        []string{types.SMPlatform},
 	}
 }
@@ -78,6 +101,15 @@ func (s *Settings) Validate() error {
 	if s.ReconciliationOperationTimeout <= minTimePeriod {
 		return fmt.Errorf("validate Settings: ReconciliationOperationTimeout must be larger than %s", minTimePeriod)
 	}
+	if s.ApprovalTimeout <= minTimePeriod || s.ApprovalTimeout >= s.ReconciliationOperationTimeout {
+		return fmt.Errorf("validate Settings: ApprovalTimeout must be larger than %s and smaller than ReconciliationOperationTimeout", minTimePeriod)
+	}
+	if len(s.ApproverScope) == 0 {
+		return fmt.Errorf("validate Settings: ApproverScope must not be empty")
+	}
+	if s.IdempotencyKeyLifespan <= minTimePeriod {
+		return fmt.Errorf("validate Settings: IdempotencyKeyLifespan must be larger than %s", minTimePeriod)
+	}
 	if s.ReschedulingInterval <= minTimePeriod {
 		return fmt.Errorf("validate Settings: ReschedulingInterval must be larger than %s", minTimePeriod)
 	}
@@ -87,6 +119,15 @@ func (s *Settings) Validate() error {
 	if s.MaintainerRetryInterval <= minTimePeriod {
 		return fmt.Errorf("validate Settings: MaintainerRetryInterval must be larger than %s", minTimePeriod)
 	}
+	if s.VisibilityWindowInterval <= minTimePeriod {
+		return fmt.Errorf("validate Settings: VisibilityWindowInterval must be larger than %s", minTimePeriod)
+	}
+	if s.LeaseInterval <= minTimePeriod {
+		return fmt.Errorf("validate Settings: LeaseInterval must be larger than %s", minTimePeriod)
+	}
+	if s.LeaseWarningPeriod < 0 {
+		return fmt.Errorf("validate Settings: LeaseWarningPeriod must not be negative")
+	}
 	if s.DefaultPoolSize <= 0 {
 		return fmt.Errorf("validate Settings: DefaultPoolSize must be larger than 0")
 	}
@@ -98,6 +139,17 @@ func (s *Settings) Validate() error {
 			return err
 		}
 	}
+	if s.QueueSize < 0 {
+		return fmt.Errorf("validate Settings: QueueSize must not be negative")
+	}
+	if s.TenantConcurrencyLimit < 0 {
+		return fmt.Errorf("validate Settings: TenantConcurrencyLimit must not be negative")
+	}
+	for _, weight := range s.TenantWeights {
+		if err := weight.Validate(); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -116,3 +168,18 @@ func (ps *PoolSettings) Validate() error {
 
 	return nil
 }
+
+// TenantWeightSettings defines the share of the workers a tenant gets when several tenants have queued operations
+type TenantWeightSettings struct {
+	Tenant string `mapstructure:"tenant" description:"the tenant"`
+	Weight int    `mapstructure:"weight" description:"number of operations of the tenant started in a round, tenants without weight have weight 1"`
+}
+
+// Validate validates the tenant weight settings
+func (tw *TenantWeightSettings) Validate() error {
+	if tw.Weight <= 0 {
+		return fmt.Errorf("validate Settings: Weight of tenant '%s' must be larger than 0", tw.Tenant)
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// Controls cancels operations in progress and retries failed operations
+type Controls struct {
+	repository storage.TransactionalRepository
+	scheduler  *Scheduler
+}
+
+// NewControls constructs Controls
+func NewControls(smCtx context.Context, repository storage.TransactionalRepository, settings *Settings, wg *sync.WaitGroup) *Controls {
+	return &Controls{
+		repository: repository,
+		scheduler:  NewScheduler(smCtx, repository, settings, settings.DefaultPoolSize, wg),
+	}
+}
+
+// Cancel fails the operation in progress. The job executing the operation stops when it next polls the broker.
+// Orphan mitigation is scheduled for cancelled creations of service instances and bindings.
+func (c *Controls) Cancel(ctx context.Context, operationID, cancelledBy string) (*types.Operation, error) {
+	var operation *types.Operation
+	if err := c.repository.InTransaction(ctx, func(ctx context.Context, storage storage.Repository) error {
+		var err error
+		if operation, err = getControllableOperation(ctx, storage, operationID); err != nil {
+			return err
+		}
+		if operation.State == types.AWAITING_APPROVAL {
+			return operationStateError("operation with id %s is awaiting approval and should be rejected instead", operationID)
+		}
+		if operation.State != types.IN_PROGRESS || operation.InOrphanMitigationState() {
+			return operationStateError("operation with id %s is not in progress", operationID)
+		}
+		transitioned, err := transitionOperationState(ctx, storage, operation, types.FAILED)
+		if err != nil {
+			return err
+		}
+		if !transitioned {
+			return operationStateError("operation with id %s is not in progress", operationID)
+		}
+
+		operation.Context.Cancelled = true
+		operation.Reschedule = false
+		operation.RescheduleTimestamp = time.Time{}
+		operation.Description = fmt.Sprintf("cancelled by %s", cancelledBy)
+		if operation.Type == types.CREATE && isBrokered(operation.ResourceType) {
+			operation.DeletionScheduled = time.Now().UTC()
+		}
+		return updateOperationState(ctx, storage, operation, types.FAILED, &util.HTTPError{
+			ErrorType:   "OperationCancelled",
+			Description: operation.Description,
+			StatusCode:  http.StatusConflict,
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	log.C(ctx).Infof("%s operation with id %s for %s with id %s was cancelled by %s", operation.Type, operation.ID, operation.ResourceType, operation.ResourceID, cancelledBy)
+	return operation, nil
+}
+
+// Retry executes the failed operation again asynchronously, with its original operation context.
+// A failed update is replayed with the changes of its request.
+func (c *Controls) Retry(ctx context.Context, operationID, retriedBy string) (*types.Operation, error) {
+	var operation *types.Operation
+	var failed types.Operation
+	var action storageAction
+	if err := c.repository.InTransaction(ctx, func(ctx context.Context, storage storage.Repository) error {
+		var err error
+		if operation, err = getControllableOperation(ctx, storage, operationID); err != nil {
+			return err
+		}
+		if operation.State != types.FAILED || operation.InOrphanMitigationState() {
+			return operationStateError("operation with id %s has not failed", operationID)
+		}
+		if err := checkLastOperation(ctx, storage, operation); err != nil {
+			return err
+		}
+		if action, err = payloadAction(ctx, storage, operation, operation.Payload); err != nil {
+			if err == util.ErrNotFoundInStorage {
+				return operationStateError("%s with id %s of operation with id %s no longer exists", operation.ResourceType, operation.ResourceID, operationID)
+			}
+			return util.HandleStorageError(err, operation.ResourceType.String())
+		}
+
+		failed = *operation
+		failedContext := *operation.Context
+		failed.Context = &failedContext
+		transitioned, err := transitionOperationState(ctx, storage, operation, types.IN_PROGRESS)
+		if err != nil {
+			return err
+		}
+		if !transitioned {
+			return operationStateError("operation with id %s has not failed", operationID)
+		}
+		operation.Errors = json.RawMessage{}
+		operation.ExternalID = ""
+		operation.Reschedule = false
+		operation.RescheduleTimestamp = time.Time{}
+		operation.Context.Cancelled = false
+		operation.Context.Retried = true
+		operation.Description = fmt.Sprintf("retried by %s", retriedBy)
+		if _, err := storage.Update(ctx, operation, types.LabelChanges{}); err != nil {
+			return util.HandleStorageError(err, types.OperationType.String())
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	log.C(ctx).Infof("%s operation with id %s for %s with id %s was retried by %s", operation.Type, operation.ID, operation.ResourceType, operation.ResourceID, retriedBy)
+	if err := c.scheduler.ScheduleAsyncStorageAction(ctx, operation, action); err != nil {
+		// the operation can be retried again unless it was failed by the scheduler
+		if operation.State == types.IN_PROGRESS {
+			if _, updateErr := c.repository.Update(ctx, &failed, types.LabelChanges{}); updateErr != nil {
+				log.C(ctx).Errorf("Could not revert retry of operation with id %s: %s", operation.ID, updateErr)
+			}
+		}
+		return nil, err
+	}
+
+	return operation, nil
+}
+
+// operationAction returns the storage action which executes the operation for the current state of its resource.
+// Updates stored before their payload was kept are executed with the stored resource.
+func operationAction(ctx context.Context, repository storage.Repository, operation *types.Operation) (storageAction, error) {
+	byID := query.ByField(query.EqualsOperator, "id", operation.ResourceID)
+	switch operation.Type {
+	case types.CREATE:
+		object, err := repository.Get(ctx, operation.ResourceType, byID)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, repository storage.Repository) (types.Object, error) {
+			object, err := repository.Create(ctx, object)
+			return object, util.HandleStorageError(err, operation.ResourceType.String())
+		}, nil
+	case types.UPDATE:
+		object, err := repository.Get(ctx, operation.ResourceType, byID)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, repository storage.Repository) (types.Object, error) {
+			object, err := repository.Update(ctx, object, nil, byID)
+			return object, util.HandleStorageError(err, operation.ResourceType.String())
+		}, nil
+	case types.DELETE:
+		return func(ctx context.Context, repository storage.Repository) (types.Object, error) {
+			err := repository.Delete(ctx, operation.ResourceType, byID)
+			if err != nil {
+				if err == util.ErrNotFoundInStorage {
+					return nil, nil
+				}
+				return nil, util.HandleStorageError(err, operation.ResourceType.String())
+			}
+			return nil, nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("operation type %s is unknown type", operation.Type)
+	}
+}
+
+// getControllableOperation returns an operation of the Service Manager platform which is not part of a cascade,
+// scoped by the criteria of the context
+func getControllableOperation(ctx context.Context, repository storage.Repository, operationID string) (*types.Operation, error) {
+	criteria := append([]query.Criterion{query.ByField(query.EqualsOperator, "id", operationID)}, query.CriteriaForContext(ctx)...)
+	object, err := repository.Get(ctx, types.OperationType, criteria...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.OperationType.String())
+	}
+	operation := object.(*types.Operation)
+	if operation.PlatformID != types.SMPlatform || len(operation.CascadeRootID) > 0 {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("operation with id %s is not executed by Service Manager and cannot be cancelled or retried", operationID),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	if operation.Context == nil {
+		operation.Context = &types.OperationContext{}
+	}
+	return operation, nil
+}
+
+// checkLastOperation verifies that no operation was started for the resource after the operation
+func checkLastOperation(ctx context.Context, repository storage.Repository, operation *types.Operation) error {
+	lastOperations, err := repository.QueryForList(ctx, types.OperationType, storage.QueryForLastOperationsPerResource, map[string]interface{}{
+		"id_list":       []string{operation.ResourceID},
+		"resource_type": string(operation.ResourceType),
+	})
+	if err != nil {
+		return util.HandleStorageError(err, types.OperationType.String())
+	}
+	if lastOperations.Len() > 0 && lastOperations.ItemAt(0).GetID() != operation.ID {
+		return operationStateError("operation with id %s is not the last operation of %s with id %s", operation.ID, operation.ResourceType, operation.ResourceID)
+	}
+	return nil
+}
+
+func isBrokered(resourceType types.ObjectType) bool {
+	return resourceType == types.ServiceInstanceType || resourceType == types.ServiceBindingType
+}
+
+func operationStateError(format string, args ...interface{}) error {
+	return &util.HTTPError{
+		ErrorType:   "Conflict",
+		Description: fmt.Sprintf(format, args...),
+		StatusCode:  http.StatusConflict,
+	}
+}
@@ -0,0 +1,220 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Controls", func() {
+	var (
+		ctx        context.Context
+		cancel     context.CancelFunc
+		wg         *sync.WaitGroup
+		repository *inMemoryRepository
+		controls   *Controls
+	)
+
+	newOperation := func(id string, operationType types.OperationCategory, state types.OperationState, resource types.Object) *types.Operation {
+		now := time.Now()
+		return &types.Operation{
+			Base:         types.Base{ID: id, CreatedAt: now, UpdatedAt: now, Ready: true},
+			Type:         operationType,
+			State:        state,
+			ResourceID:   resource.GetID(),
+			ResourceType: resource.GetType(),
+			PlatformID:   types.SMPlatform,
+			Context:      &types.OperationContext{},
+		}
+	}
+
+	operation := func(id string) *types.Operation {
+		object := repository.object(id)
+		Expect(object).ToNot(BeNil())
+		return object.(*types.Operation)
+	}
+
+	expectConflict := func(err error) {
+		Expect(err).To(HaveOccurred())
+		Expect(err).To(BeAssignableToTypeOf(&util.HTTPError{}))
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusConflict))
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		wg = &sync.WaitGroup{}
+		repository = newInMemoryRepository()
+		controls = NewControls(ctx, repository, DefaultSettings(), wg)
+	})
+
+	AfterEach(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	Describe("Retry", func() {
+		var (
+			platform     *types.Platform
+			labelChanges types.LabelChanges
+		)
+
+		BeforeEach(func() {
+			platform = &types.Platform{Base: types.Base{ID: "platform", Ready: true}, Name: "platform", Type: "kubernetes", Description: "stored"}
+			patched := *platform
+			patched.Description = "patched"
+			labelChanges = types.LabelChanges{{Operation: types.AddLabelOperation, Key: "env", Values: []string{"dev"}}}
+			payload, err := payloadFromContext(ContextWithResource(ctx, &patched, labelChanges))
+			Expect(err).ToNot(HaveOccurred())
+
+			failed := newOperation("update", types.UPDATE, types.FAILED, platform)
+			failed.Payload = payload
+			failed.Errors = []byte(`{"error": "BrokerError"}`)
+			repository.objects[platform.ID] = platform
+			repository.objects[failed.ID] = failed
+		})
+
+		It("replays the changes of the failed update", func() {
+			retried, err := controls.Retry(ctx, "update", "admin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(retried.State).To(Equal(types.IN_PROGRESS))
+			wg.Wait()
+
+			Expect(repository.object("platform").(*types.Platform).Description).To(Equal("patched"))
+			var platformUpdates []types.LabelChanges
+			for i := 0; i < repository.UpdateCallCount(); i++ {
+				_, object, changes, _ := repository.UpdateArgsForCall(i)
+				if object.GetType() == types.PlatformType {
+					platformUpdates = append(platformUpdates, changes)
+				}
+			}
+			Expect(platformUpdates).To(Equal([]types.LabelChanges{labelChanges}))
+
+			succeeded := operation("update")
+			Expect(succeeded.State).To(Equal(types.SUCCEEDED))
+			Expect(succeeded.Context.Retried).To(BeTrue())
+			Expect(succeeded.Payload).To(BeEmpty())
+		})
+
+		It("fails with conflict if a concurrent retry changed the state first", func() {
+			stale := operation("update")
+			inProgress := operation("update")
+			inProgress.State = types.IN_PROGRESS
+			_, err := repository.Update(ctx, inProgress, types.LabelChanges{})
+			Expect(err).ToNot(HaveOccurred())
+			repository.GetReturns(stale, nil)
+
+			_, err = controls.Retry(ctx, "update", "admin")
+			expectConflict(err)
+			Expect(repository.object("platform").(*types.Platform).Description).To(Equal("stored"))
+		})
+	})
+
+	Describe("Cancel", func() {
+		BeforeEach(func() {
+			instance := &types.ServiceInstance{Base: types.Base{ID: "instance", Ready: false}}
+			repository.objects[instance.ID] = instance
+			inProgress := newOperation("create", types.CREATE, types.IN_PROGRESS, instance)
+			repository.objects[inProgress.ID] = inProgress
+		})
+
+		It("fails the operation and schedules orphan mitigation", func() {
+			cancelled, err := controls.Cancel(ctx, "create", "admin")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cancelled.State).To(Equal(types.FAILED))
+
+			stored := operation("create")
+			Expect(stored.State).To(Equal(types.FAILED))
+			Expect(stored.IsCancelled()).To(BeTrue())
+			Expect(stored.InOrphanMitigationState()).To(BeTrue())
+			Expect(stored.Description).To(Equal("cancelled by admin"))
+			Expect(string(stored.Errors)).To(ContainSubstring("OperationCancelled"))
+		})
+
+		It("fails with conflict if a concurrent cancel changed the state first", func() {
+			_, err := controls.Cancel(ctx, "create", "admin")
+			Expect(err).ToNot(HaveOccurred())
+			repository.GetReturns(newOperation("create", types.CREATE, types.IN_PROGRESS, &types.ServiceInstance{Base: types.Base{ID: "instance"}}), nil)
+
+			_, err = controls.Cancel(ctx, "create", "other-admin")
+			expectConflict(err)
+			Expect(operation("create").Description).To(Equal("cancelled by admin"))
+		})
+
+		It("does not cancel operations which are not in progress", func() {
+			stored := operation("create")
+			stored.State = types.SUCCEEDED
+			_, err := repository.Update(ctx, stored, types.LabelChanges{})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = controls.Cancel(ctx, "create", "admin")
+			expectConflict(err)
+			Expect(repository.QueryForListCallCount()).To(Equal(0))
+		})
+	})
+
+	It("does not control operations of other tenants", func() {
+		instance := &types.ServiceInstance{Base: types.Base{ID: "instance"}}
+		inProgress := newOperation("create", types.CREATE, types.IN_PROGRESS, instance)
+		inProgress.Labels = types.Labels{"tenant": {"tenant-1"}}
+		failed := newOperation("update", types.UPDATE, types.FAILED, instance)
+		failed.Labels = types.Labels{"tenant": {"tenant-1"}}
+		repository.objects[instance.ID] = instance
+		repository.objects[inProgress.ID] = inProgress
+		repository.objects[failed.ID] = failed
+
+		otherTenantCtx, err := query.AddCriteria(ctx, query.ByLabel(query.EqualsOperator, "tenant", "tenant-2"))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = controls.Cancel(otherTenantCtx, "create", "intruder")
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusNotFound))
+		_, err = controls.Retry(otherTenantCtx, "update", "intruder")
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusNotFound))
+		Expect(operation("create").State).To(Equal(types.IN_PROGRESS))
+		Expect(operation("update").State).To(Equal(types.FAILED))
+
+		tenantCtx, err := query.AddCriteria(ctx, query.ByLabel(query.EqualsOperator, "tenant", "tenant-1"))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = controls.Cancel(tenantCtx, "create", "owner")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("does not control operations of other platforms", func() {
+		instance := &types.ServiceInstance{Base: types.Base{ID: "instance"}}
+		osbOperation := newOperation("osb", types.CREATE, types.IN_PROGRESS, instance)
+		osbOperation.PlatformID = "cf"
+		repository.objects[osbOperation.ID] = osbOperation
+
+		_, err := controls.Cancel(ctx, "osb", "admin")
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+		_, err = controls.Retry(ctx, "osb", "admin")
+		Expect(err).To(HaveOccurred())
+		Expect(repository.Get(ctx, types.OperationType, query.ByField(query.EqualsOperator, "id", "osb"))).To(
+			WithTransform(func(object types.Object) types.OperationState { return object.(*types.Operation).State }, Equal(types.IN_PROGRESS)))
+	})
+})
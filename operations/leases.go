@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/gofrs/uuid"
+)
+
+// processInstanceLeases labels service instances whose lease expires within the warning period
+// and deletes service instances whose lease expired together with their bindings
+func (om *Maintainer) processInstanceLeases() {
+	now := time.Now().UTC()
+	objectList, err := om.repository.List(om.smCtx, types.ServiceInstanceType,
+		query.ByField(query.EqualsOperator, "platform_id", types.SMPlatform),
+		query.ByField(query.LessThanOperator, "expires_at", util.ToRFCNanoFormat(now.Add(om.settings.LeaseWarningPeriod))))
+	if err != nil {
+		log.C(om.smCtx).Debugf("Failed to fetch service instances whose lease expires: %s", err)
+		return
+	}
+
+	instances := objectList.(*types.ServiceInstances)
+	expired := 0
+	for i := 0; i < instances.Len(); i++ {
+		instance := instances.ItemAt(i).(*types.ServiceInstance)
+		if instance.ExpiresAt.After(now) {
+			om.warnLeaseExpiring(instance)
+			continue
+		}
+		if err := om.deleteExpiredInstance(instance); err != nil {
+			log.C(om.smCtx).Errorf("Failed to delete service instance with id %s whose lease expired: %s", instance.ID, err)
+			continue
+		}
+		expired++
+	}
+	log.C(om.smCtx).Debugf("Finished processing instance leases: %d expiring, %d expired", instances.Len()-expired, expired)
+}
+
+func (om *Maintainer) warnLeaseExpiring(instance *types.ServiceInstance) {
+	expiresAt := instance.ExpiresAt.UTC().Format(time.RFC3339)
+	if values := instance.Labels[types.LeaseExpiringLabelKey]; len(values) == 1 && values[0] == expiresAt {
+		return
+	}
+
+	labelChanges := types.LabelChanges{
+		{Operation: types.RemoveLabelOperation, Key: types.LeaseExpiringLabelKey},
+		{Operation: types.AddLabelOperation, Key: types.LeaseExpiringLabelKey, Values: []string{expiresAt}},
+	}
+	if err := om.repository.InTransaction(om.smCtx, func(ctx context.Context, storage storage.Repository) error {
+		return storage.UpdateLabels(ctx, types.ServiceInstanceType, instance.ID, labelChanges)
+	}); err != nil {
+		log.C(om.smCtx).Errorf("Failed to label service instance with id %s whose lease expires at %s: %s", instance.ID, expiresAt, err)
+		return
+	}
+	log.C(om.smCtx).Infof("Lease of service instance with id %s expires at %s", instance.ID, expiresAt)
+}
+
+// deleteExpiredInstance schedules the cascade deletion of the instance, unless an operation for it is already in progress
+func (om *Maintainer) deleteExpiredInstance(instance *types.ServiceInstance) error {
+	inProgress, err := om.repository.Count(om.smCtx, types.OperationType,
+		query.ByField(query.EqualsOperator, "resource_id", instance.ID),
+		query.ByField(query.InOperator, "state", string(types.IN_PROGRESS), string(types.PENDING), string(types.AWAITING_APPROVAL)))
+	if err != nil {
+		return err
+	}
+	if inProgress > 0 {
+		log.C(om.smCtx).Debugf("Postponing deletion of service instance with id %s whose lease expired until its operations complete", instance.ID)
+		return nil
+	}
+
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("could not generate GUID for operation: %s", err)
+	}
+	labels := types.Labels{}
+	if values := instance.Labels[om.settings.TenantLabelKey]; len(om.settings.TenantLabelKey) > 0 && len(values) > 0 {
+		labels[om.settings.TenantLabelKey] = values
+	}
+	currentTime := time.Now()
+	operation := &types.Operation{
+		Base: types.Base{
+			ID:        UUID.String(),
+			CreatedAt: currentTime,
+			UpdatedAt: currentTime,
+			Labels:    labels,
+			Ready:     true,
+		},
+		Description:   fmt.Sprintf("lease expired at %s", instance.ExpiresAt.UTC().Format(time.RFC3339)),
+		Type:          types.DELETE,
+		State:         types.IN_PROGRESS,
+		ResourceID:    instance.ID,
+		ResourceType:  types.ServiceInstanceType,
+		PlatformID:    types.SMPlatform,
+		CorrelationID: UUID.String(),
+		Context: &types.OperationContext{
+			Async:   true,
+			Cascade: true,
+		},
+		CascadeRootID: UUID.String(),
+	}
+
+	logger := log.C(om.smCtx).WithField(log.FieldCorrelationID, operation.CorrelationID)
+	ctx := log.ContextWithLogger(om.smCtx, logger)
+	// the instance and its bindings are deleted by the cascade operations created for the root operation
+	if _, err := om.scheduler.ScheduleSyncStorageAction(ctx, operation, func(ctx context.Context, repository storage.Repository) (types.Object, error) {
+		return nil, nil
+	}); err != nil {
+		return err
+	}
+	logger.Infof("Scheduled deletion of service instance with id %s whose lease expired at %s", instance.ID, instance.ExpiresAt.UTC().Format(time.RFC3339))
+	return nil
+}
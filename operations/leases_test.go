@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Instance leases", func() {
+	const tenantLabelKey = "tenant"
+
+	var (
+		ctx        context.Context
+		cancel     context.CancelFunc
+		wg         *sync.WaitGroup
+		repository *inMemoryRepository
+		maintainer *Maintainer
+	)
+
+	newInstance := func(id string, expiresIn time.Duration) *types.ServiceInstance {
+		expiresAt := time.Now().UTC().Add(expiresIn)
+		return &types.ServiceInstance{
+			Base:       types.Base{ID: id, Ready: true, Labels: types.Labels{tenantLabelKey: {"tenant-1"}}},
+			PlatformID: types.SMPlatform,
+			ExpiresAt:  &expiresAt,
+		}
+	}
+
+	createdOperations := func() []*types.Operation {
+		var operations []*types.Operation
+		for i := 0; i < repository.CreateCallCount(); i++ {
+			if _, object := repository.CreateArgsForCall(i); object.GetType() == types.OperationType {
+				operations = append(operations, object.(*types.Operation))
+			}
+		}
+		return operations
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		wg = &sync.WaitGroup{}
+		repository = newInMemoryRepository()
+		settings := DefaultSettings()
+		settings.TenantLabelKey = tenantLabelKey
+		maintainer = NewMaintainer(ctx, repository, func(int) storage.Locker { return nil }, settings, wg)
+	})
+
+	AfterEach(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	It("labels instances whose lease expires within the warning period", func() {
+		expiring := newInstance("expiring", time.Hour)
+		repository.objects[expiring.ID] = expiring
+
+		maintainer.processInstanceLeases()
+
+		Expect(repository.UpdateLabelsCallCount()).To(Equal(1))
+		_, objectType, objectID, labelChanges, _ := repository.UpdateLabelsArgsForCall(0)
+		Expect(objectType).To(Equal(types.ServiceInstanceType))
+		Expect(objectID).To(Equal("expiring"))
+		Expect(labelChanges).To(ContainElement(&types.LabelChange{
+			Operation: types.AddLabelOperation,
+			Key:       types.LeaseExpiringLabelKey,
+			Values:    []string{expiring.ExpiresAt.Format(time.RFC3339)},
+		}))
+		Expect(createdOperations()).To(BeEmpty())
+	})
+
+	It("does not label instances again which are labeled with their current expiry", func() {
+		expiring := newInstance("expiring", time.Hour)
+		expiring.Labels[types.LeaseExpiringLabelKey] = []string{expiring.ExpiresAt.Format(time.RFC3339)}
+		repository.objects[expiring.ID] = expiring
+
+		maintainer.processInstanceLeases()
+
+		Expect(repository.UpdateLabelsCallCount()).To(Equal(0))
+	})
+
+	It("schedules the cascade deletion of instances whose lease expired", func() {
+		expired := newInstance("expired", -time.Minute)
+		repository.objects[expired.ID] = expired
+
+		maintainer.processInstanceLeases()
+
+		operations := createdOperations()
+		Expect(operations).To(HaveLen(1))
+		Expect(operations[0].Type).To(Equal(types.DELETE))
+		Expect(operations[0].ResourceID).To(Equal("expired"))
+		Expect(operations[0].CascadeRootID).To(Equal(operations[0].ID))
+		Expect(operations[0].Labels[tenantLabelKey]).To(ConsistOf("tenant-1"))
+		Expect(operations[0].Description).To(HavePrefix("lease expired at"))
+	})
+
+	It("postpones the deletion of expired instances with operations in progress", func() {
+		expired := newInstance("expired", -time.Minute)
+		repository.objects[expired.ID] = expired
+		repository.objects["update"] = &types.Operation{
+			Base:         types.Base{ID: "update"},
+			Type:         types.UPDATE,
+			State:        types.IN_PROGRESS,
+			ResourceID:   expired.ID,
+			ResourceType: types.ServiceInstanceType,
+		}
+
+		maintainer.processInstanceLeases()
+
+		Expect(createdOperations()).To(BeEmpty())
+	})
+
+	It("only processes instances of the Service Manager platform", func() {
+		expired := newInstance("expired", -time.Minute)
+		expired.PlatformID = "cf"
+		repository.objects[expired.ID] = expired
+
+		maintainer.processInstanceLeases()
+
+		Expect(createdOperations()).To(BeEmpty())
+		Expect(repository.UpdateLabelsCallCount()).To(Equal(0))
+	})
+})
@@ -48,6 +48,7 @@ type Maintainer struct {
 	repository              storage.TransactionalRepository
 	scheduler               *Scheduler
 	cascadePollingScheduler *Scheduler
+	approvals               *Approvals
 	settings                *Settings
 	wg                      *sync.WaitGroup
 	functors                []maintainerFunctor
@@ -64,6 +65,11 @@ func NewMaintainer(smCtx context.Context, repository storage.TransactionalReposi
 		settings:                options,
 		wg:                      wg,
 	}
+	maintainer.approvals = &Approvals{
+		repository: repository,
+		scheduler:  maintainer.scheduler,
+		timeout:    options.ApprovalTimeout,
+	}
 
 	maintainer.functors = []maintainerFunctor{
 		{
@@ -116,6 +122,31 @@ func NewMaintainer(smCtx context.Context, repository storage.TransactionalReposi
 			execute:  maintainer.rescheduleOrphanMitigationOperations,
 			interval: options.MaintainerRetryInterval,
 		},
+		{
+			name:     "syncVisibilityWindows",
+			execute:  maintainer.syncVisibilityWindows,
+			interval: options.VisibilityWindowInterval,
+		},
+		{
+			name:     "failExpiredApprovals",
+			execute:  maintainer.failExpiredApprovals,
+			interval: options.MaintainerRetryInterval,
+		},
+		{
+			name:     "cleanupIdempotencyKeys",
+			execute:  maintainer.cleanupIdempotencyKeys,
+			interval: options.CleanupInterval,
+		},
+		{
+			name:     "restoreQueuedOperations",
+			execute:  maintainer.restoreQueuedOperations,
+			interval: options.MaintainerRetryInterval,
+		},
+		{
+			name:     "processInstanceLeases",
+			execute:  maintainer.processInstanceLeases,
+			interval: options.LeaseInterval,
+		},
 	}
 
 	operationLockers := make(map[string]storage.Locker)
@@ -343,6 +374,114 @@ func (om *Maintainer) CleanupResourcelessOperations() {
 	log.C(om.smCtx).Debug("Finished cleaning up resource-less operations")
 }
 
+// syncVisibilityWindows updates the visibilities whose validity window opened or closed since they were last updated.
+// The update marks them as active or inactive and notifies the platforms as if the visibilities were created or deleted.
+func (om *Maintainer) syncVisibilityWindows() {
+	inWindow := storage.GetSubQuery(storage.QueryForVisibilitiesInValidityWindow)
+	opened, err := om.repository.List(om.smCtx, types.VisibilityType, query.ByField(query.EqualsOperator, "inactive", "true"), query.ByExists(inWindow))
+	if err != nil {
+		log.C(om.smCtx).Debugf("Failed to fetch visibilities whose validity window opened: %s", err)
+		return
+	}
+	closed, err := om.repository.List(om.smCtx, types.VisibilityType, query.ByField(query.EqualsOperator, "inactive", "false"),
+		query.ByNotExists(inWindow))
+	if err != nil {
+		log.C(om.smCtx).Debugf("Failed to fetch visibilities whose validity window closed: %s", err)
+		return
+	}
+
+	for _, visibilities := range []types.ObjectList{opened, closed} {
+		for i := 0; i < visibilities.Len(); i++ {
+			visibility := visibilities.ItemAt(i)
+			if _, err := om.repository.Update(om.smCtx, visibility, types.LabelChanges{}); err != nil {
+				log.C(om.smCtx).Errorf("Failed to update visibility with id %s after its validity window changed: %s", visibility.GetID(), err)
+			}
+		}
+	}
+	log.C(om.smCtx).Debugf("Finished syncing visibility windows: %d opened, %d closed", opened.Len(), closed.Len())
+}
+
+// failExpiredApprovals fails operations which were not approved within the approval timeout
+func (om *Maintainer) failExpiredApprovals() {
+	om.approvals.FailExpired(om.smCtx)
+}
+
+// cleanupIdempotencyKeys deletes idempotency keys older than their lifespan
+func (om *Maintainer) cleanupIdempotencyKeys() {
+	expiredBefore := time.Now().Add(-om.settings.IdempotencyKeyLifespan)
+	err := om.repository.Delete(om.smCtx, types.IdempotencyKeyType,
+		query.ByField(query.LessThanOperator, "created_at", util.ToRFCNanoFormat(expiredBefore)))
+	if err != nil && err != util.ErrNotFoundInStorage {
+		log.C(om.smCtx).Debugf("Failed to cleanup expired idempotency keys: %s", err)
+		return
+	}
+	log.C(om.smCtx).Debug("Finished cleaning up expired idempotency keys")
+}
+
+// restoreQueuedOperations queues again operations which are in the queue table but were not executed for the operation's
+// maximum allowed time to execute, e.g. because the node which queued them was restarted
+func (om *Maintainer) restoreQueuedOperations() {
+	currentTime := time.Now()
+	objectList, err := om.repository.List(om.smCtx, types.QueuedOperationType,
+		query.ByField(query.LessThanOperator, "updated_at", util.ToRFCNanoFormat(currentTime.Add(-om.settings.ActionTimeout))))
+	if err != nil {
+		log.C(om.smCtx).Debugf("Failed to fetch queued operations: %s", err)
+		return
+	}
+
+	entries := objectList.(*types.QueuedOperations)
+	for i := 0; i < entries.Len(); i++ {
+		entry := entries.ItemAt(i).(*types.QueuedOperation)
+		byEntryID := query.ByField(query.EqualsOperator, "id", entry.ID)
+
+		object, err := om.repository.Get(om.smCtx, types.OperationType, query.ByField(query.EqualsOperator, "id", entry.OperationID))
+		if err != nil && err != util.ErrNotFoundInStorage {
+			log.C(om.smCtx).Warnf("Failed to fetch queued operation with ID (%s): %s", entry.OperationID, err)
+			continue
+		}
+		if err == util.ErrNotFoundInStorage || object.(*types.Operation).State != types.IN_PROGRESS || object.(*types.Operation).IsCancelled() {
+			if err := om.repository.Delete(om.smCtx, types.QueuedOperationType, byEntryID); err != nil && err != util.ErrNotFoundInStorage {
+				log.C(om.smCtx).Warnf("Failed to delete queued operation with ID (%s): %s", entry.OperationID, err)
+			}
+			continue
+		}
+
+		operation := object.(*types.Operation)
+		logger := log.C(om.smCtx).WithField(log.FieldCorrelationID, operation.CorrelationID)
+		ctx := log.ContextWithLogger(om.smCtx, logger)
+
+		action, err := payloadAction(ctx, om.repository, operation, entry.Payload)
+		if err != nil {
+			logger.Warnf("Failed to restore queued operation with ID (%s): %s", operation.ID, err)
+			if err := updateOperationState(ctx, om.repository, operation, types.FAILED, err); err != nil {
+				logger.Warnf("Failed to update state of queued operation with ID (%s): %s", operation.ID, err)
+				continue
+			}
+			if err := om.repository.Delete(ctx, types.QueuedOperationType, byEntryID); err != nil && err != util.ErrNotFoundInStorage {
+				logger.Warnf("Failed to delete queued operation with ID (%s): %s", operation.ID, err)
+			}
+			continue
+		}
+
+		// claim the entry, so that other nodes do not restore it too
+		lastUpdated := entry.UpdatedAt
+		entry.UpdatedAt = time.Now().UTC()
+		if _, err := om.repository.Update(ctx, entry, types.LabelChanges{}, storage.ByVersion(lastUpdated)); err != nil {
+			logger.Debugf("Failed to claim queued operation with ID (%s): %s", operation.ID, err)
+			continue
+		}
+
+		om.scheduler.queue.push(entry.Tenant, &queuedJob{
+			ctx:       ctx,
+			operation: operation,
+			action:    action,
+			entryID:   entry.ID,
+		}, false)
+		om.scheduler.dispatch()
+		logger.Debugf("Successfully restored queued operation %+v", operation)
+	}
+}
+
 // rescheduleUnfinishedOperations reschedules IN_PROGRESS operations which are reschedulable, not scheduled for deletion and no goroutine is processing at the moment
 func (om *Maintainer) rescheduleUnfinishedOperations() {
 	currentTime := time.Now()
@@ -367,44 +506,10 @@ func (om *Maintainer) rescheduleUnfinishedOperations() {
 		logger := log.C(om.smCtx).WithField(log.FieldCorrelationID, operation.CorrelationID)
 		ctx := log.ContextWithLogger(om.smCtx, logger)
 
-		var action storageAction
-
-		switch operation.Type {
-		case types.CREATE:
-			object, err := om.repository.Get(ctx, operation.ResourceType, query.ByField(query.EqualsOperator, "id", operation.ResourceID))
-			if err != nil {
-				logger.Warnf("Failed to fetch resource with ID (%s) for operation with ID (%s): %s", operation.ResourceID, operation.ID, err)
-				break
-			}
-
-			action = func(ctx context.Context, repository storage.Repository) (types.Object, error) {
-				object, err := repository.Create(ctx, object)
-				return object, util.HandleStorageError(err, operation.ResourceType.String())
-			}
-		case types.UPDATE:
-			byID := query.ByField(query.EqualsOperator, "id", operation.ResourceID)
-			object, err := om.repository.Get(ctx, operation.ResourceType, byID)
-			if err != nil {
-				logger.Warnf("Failed to fetch resource with ID (%s) for operation with ID (%s): %s", operation.ResourceID, operation.ID, err)
-				break
-			}
-			action = func(ctx context.Context, repository storage.Repository) (types.Object, error) {
-				object, err := repository.Update(ctx, object, nil, byID)
-				return object, util.HandleStorageError(err, operation.ResourceType.String())
-			}
-		case types.DELETE:
-			byID := query.ByField(query.EqualsOperator, "id", operation.ResourceID)
-
-			action = func(ctx context.Context, repository storage.Repository) (types.Object, error) {
-				err := repository.Delete(ctx, operation.ResourceType, byID)
-				if err != nil {
-					if err == util.ErrNotFoundInStorage {
-						return nil, nil
-					}
-					return nil, util.HandleStorageError(err, operation.ResourceType.String())
-				}
-				return nil, nil
-			}
+		action, err := operationAction(ctx, om.repository, operation)
+		if err != nil {
+			logger.Warnf("Failed to fetch resource with ID (%s) for operation with ID (%s): %s", operation.ResourceID, operation.ID, err)
+			continue
 		}
 
 		if err := om.scheduler.ScheduleAsyncStorageAction(ctx, operation, action); err != nil {
@@ -574,6 +679,16 @@ func (om *Maintainer) markStuckOperationsFailed() {
 		operation := operations.ItemAt(i).(*types.Operation)
 		logger := log.C(om.smCtx).WithField(log.FieldCorrelationID, operation.CorrelationID)
 
+		// queued operations are not stuck but waiting for a worker
+		queued, err := om.repository.Count(om.smCtx, types.QueuedOperationType, query.ByField(query.EqualsOperator, "operation_id", operation.ID))
+		if err != nil {
+			logger.Warnf("Failed to check if operation with ID (%s) is queued: %s", operation.ID, err)
+			continue
+		}
+		if queued > 0 {
+			continue
+		}
+
 		operation.State = types.FAILED
 
 		if operation.Type == types.CREATE || operation.Type == types.DELETE {
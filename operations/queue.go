@@ -0,0 +1,322 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/gofrs/uuid"
+)
+
+type resourceCtxKey struct{}
+
+// queuedPayload is the resource of a queued operation, stored so that the operation can be restored after a restart
+type queuedPayload struct {
+	Object       json.RawMessage    `json:"object"`
+	LabelChanges types.LabelChanges `json:"label_changes,omitempty"`
+}
+
+type queuedResource struct {
+	object       types.Object
+	labelChanges types.LabelChanges
+}
+
+var queuedResourceBlueprints = map[types.ObjectType]func() types.Object{
+	types.PlatformType:        func() types.Object { return &types.Platform{} },
+	types.ServiceBrokerType:   func() types.Object { return &types.ServiceBroker{} },
+	types.VisibilityType:      func() types.Object { return &types.Visibility{} },
+	types.QuotaType:           func() types.Object { return &types.Quota{} },
+	types.ServiceInstanceType: func() types.Object { return &types.ServiceInstance{} },
+	types.ServiceBindingType:  func() types.Object { return &types.ServiceBinding{} },
+}
+
+// ContextWithResource attaches the resource created or updated by the operation scheduled with the context.
+// If the operation has to wait for a worker, the resource is stored with it, so that it can be restored after a restart.
+func ContextWithResource(ctx context.Context, resource types.Object, labelChanges types.LabelChanges) context.Context {
+	return context.WithValue(ctx, resourceCtxKey{}, &queuedResource{
+		object:       resource,
+		labelChanges: labelChanges,
+	})
+}
+
+func payloadFromContext(ctx context.Context) ([]byte, error) {
+	resource, ok := ctx.Value(resourceCtxKey{}).(*queuedResource)
+	if !ok {
+		return nil, nil
+	}
+	object, err := json.Marshal(resource.object)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&queuedPayload{
+		Object:       object,
+		LabelChanges: resource.labelChanges,
+	})
+}
+
+func newQueuedOperation(ctx context.Context, operation *types.Operation, tenant string) (*types.QueuedOperation, error) {
+	payload, err := payloadFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not store resource of operation with id %s: %s", operation.ID, err)
+	}
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate GUID for queued operation: %s", err)
+	}
+	currentTime := time.Now().UTC()
+	return &types.QueuedOperation{
+		Base: types.Base{
+			ID:        UUID.String(),
+			CreatedAt: currentTime,
+			UpdatedAt: currentTime,
+			Ready:     true,
+		},
+		OperationID:  operation.ID,
+		ResourceType: operation.ResourceType,
+		Tenant:       tenant,
+		Payload:      payload,
+	}, nil
+}
+
+// payloadAction restores the action of a queued or retried operation from its payload, or from the stored resource if there is no payload
+func payloadAction(ctx context.Context, repository storage.Repository, operation *types.Operation, payload []byte) (storageAction, error) {
+	newObject, found := queuedResourceBlueprints[operation.ResourceType]
+	if len(payload) == 0 || operation.Type == types.DELETE || !found {
+		return operationAction(ctx, repository, operation)
+	}
+
+	resource := &queuedPayload{}
+	if err := json.Unmarshal(payload, resource); err != nil {
+		return nil, fmt.Errorf("could not read queued resource of operation with id %s: %s", operation.ID, err)
+	}
+	object := newObject()
+	if err := json.Unmarshal(resource.Object, object); err != nil {
+		return nil, fmt.Errorf("could not read queued resource of operation with id %s: %s", operation.ID, err)
+	}
+
+	if operation.Type == types.CREATE {
+		return func(ctx context.Context, repository storage.Repository) (types.Object, error) {
+			object, err := repository.Create(ctx, object)
+			return object, util.HandleStorageError(err, operation.ResourceType.String())
+		}, nil
+	}
+	return func(ctx context.Context, repository storage.Repository) (types.Object, error) {
+		object, err := repository.Update(ctx, object, resource.LabelChanges, query.ByField(query.EqualsOperator, "id", operation.ResourceID))
+		return object, util.HandleStorageError(err, operation.ResourceType.String())
+	}, nil
+}
+
+// QueuePosition returns the position of the operation among the queued operations of its tenant, or 0 if it is not queued
+func QueuePosition(ctx context.Context, repository storage.Repository, operationID string) (int, error) {
+	object, err := repository.Get(ctx, types.QueuedOperationType, query.ByField(query.EqualsOperator, "operation_id", operationID))
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return 0, nil
+		}
+		return 0, err
+	}
+	queued := object.(*types.QueuedOperation)
+	return repository.Count(ctx, types.QueuedOperationType,
+		query.ByField(query.EqualsOperator, "resource_type", string(queued.ResourceType)),
+		query.ByField(query.EqualsOperator, "tenant", queued.Tenant),
+		query.ByField(query.LessThanOrEqualOperator, "paging_sequence", strconv.FormatInt(queued.PagingSequence, 10)))
+}
+
+// queuedJob is an operation waiting for a worker
+type queuedJob struct {
+	ctx       context.Context
+	operation *types.Operation
+	action    storageAction
+	entryID   string
+}
+
+type tenantQueue struct {
+	jobs    []*queuedJob
+	running int
+	credit  int
+}
+
+// fairQueue hands out the workers of a scheduler to tenants. Tenants with queued jobs are served in weighted
+// round-robin order, and each tenant is limited to a number of workers executing its jobs at the same time.
+type fairQueue struct {
+	mutex    sync.Mutex
+	poolSize int
+	size     int
+	limit    int
+	weights  map[string]int
+
+	running  int
+	queued   int
+	reserved int
+	tenants  map[string]*tenantQueue
+	// order holds the tenants with queued jobs in round-robin order
+	order  []string
+	cursor int
+}
+
+func newFairQueue(poolSize int, settings *Settings) *fairQueue {
+	weights := make(map[string]int)
+	for _, weight := range settings.TenantWeights {
+		weights[weight.Tenant] = weight.Weight
+	}
+	return &fairQueue{
+		poolSize: poolSize,
+		size:     settings.QueueSize,
+		limit:    settings.TenantConcurrencyLimit,
+		weights:  weights,
+		tenants:  make(map[string]*tenantQueue),
+	}
+}
+
+// tryAcquire takes a worker for a job of the tenant if one is free, the tenant has no queued jobs
+// and it is below its concurrency limit
+func (q *fairQueue) tryAcquire(tenant string) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	t := q.tenant(tenant)
+	if q.running >= q.poolSize || len(t.jobs) > 0 || q.isLimited(t) {
+		q.cleanup(tenant)
+		return false
+	}
+	q.running++
+	t.running++
+	return true
+}
+
+// reserve takes a place in the queue, which is used by the next push
+func (q *fairQueue) reserve() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.queued+q.reserved >= q.size {
+		return false
+	}
+	q.reserved++
+	return true
+}
+
+func (q *fairQueue) unreserve() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.reserved--
+}
+
+// push queues the job of the tenant. Jobs restored from storage are queued without a reservation.
+func (q *fairQueue) push(tenant string, job *queuedJob, reserved bool) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if reserved {
+		q.reserved--
+	}
+	t := q.tenant(tenant)
+	if len(t.jobs) == 0 {
+		q.order = append(q.order, tenant)
+	}
+	t.jobs = append(t.jobs, job)
+	q.queued++
+	return len(t.jobs)
+}
+
+// next takes a worker for the next queued job, or returns nil if no worker is free or no tenant is below its limit
+func (q *fairQueue) next() (*queuedJob, string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.running >= q.poolSize {
+		return nil, ""
+	}
+	for i := 0; i < len(q.order); i++ {
+		tenant := q.order[q.cursor]
+		t := q.tenants[tenant]
+		if q.isLimited(t) {
+			t.credit = 0
+			q.cursor = (q.cursor + 1) % len(q.order)
+			continue
+		}
+
+		if t.credit <= 0 {
+			t.credit = q.weight(tenant)
+		}
+		job := t.jobs[0]
+		t.jobs = t.jobs[1:]
+		t.credit--
+		t.running++
+		q.running++
+		q.queued--
+
+		if len(t.jobs) == 0 {
+			t.credit = 0
+			q.order = append(q.order[:q.cursor], q.order[q.cursor+1:]...)
+		} else if t.credit <= 0 {
+			q.cursor++
+		}
+		if q.cursor >= len(q.order) {
+			q.cursor = 0
+		}
+		return job, tenant
+	}
+	return nil, ""
+}
+
+// release returns the worker of a finished job of the tenant
+func (q *fairQueue) release(tenant string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	t := q.tenant(tenant)
+	t.running--
+	q.running--
+	q.cleanup(tenant)
+}
+
+func (q *fairQueue) tenant(tenant string) *tenantQueue {
+	t, found := q.tenants[tenant]
+	if !found {
+		t = &tenantQueue{}
+		q.tenants[tenant] = t
+	}
+	return t
+}
+
+func (q *fairQueue) cleanup(tenant string) {
+	if t := q.tenants[tenant]; t.running <= 0 && len(t.jobs) == 0 {
+		delete(q.tenants, tenant)
+	}
+}
+
+func (q *fairQueue) isLimited(t *tenantQueue) bool {
+	return q.limit > 0 && t.running >= q.limit
+}
+
+func (q *fairQueue) weight(tenant string) int {
+	if weight, found := q.weights[tenant]; found {
+		return weight
+	}
+	return 1
+}
@@ -0,0 +1,320 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fair queue", func() {
+	newQueue := func(poolSize, limit int, weights map[string]int) *fairQueue {
+		settings := DefaultSettings()
+		settings.QueueSize = 10
+		settings.TenantConcurrencyLimit = limit
+		for tenant, weight := range weights {
+			settings.TenantWeights = append(settings.TenantWeights, TenantWeightSettings{Tenant: tenant, Weight: weight})
+		}
+		return newFairQueue(poolSize, settings)
+	}
+
+	push := func(q *fairQueue, tenant string, ids ...string) {
+		for _, id := range ids {
+			Expect(q.reserve()).To(BeTrue())
+			q.push(tenant, &queuedJob{operation: &types.Operation{Base: types.Base{ID: id}}}, true)
+		}
+	}
+
+	next := func(q *fairQueue) string {
+		job, _ := q.next()
+		if job == nil {
+			return ""
+		}
+		return job.operation.ID
+	}
+
+	type fairnessCase struct {
+		weights  map[string]int
+		queued   map[string][]string
+		tenants  []string
+		expected []string
+	}
+
+	DescribeTable("serves the queued jobs of tenants in weighted round-robin order",
+		func(c fairnessCase) {
+			q := newQueue(1, 0, c.weights)
+			for _, tenant := range c.tenants {
+				push(q, tenant, c.queued[tenant]...)
+			}
+
+			var served []string
+			for job, tenant := q.next(); job != nil; job, tenant = q.next() {
+				served = append(served, job.operation.ID)
+				q.release(tenant)
+			}
+			Expect(served).To(Equal(c.expected))
+			Expect(q.running).To(Equal(0))
+			Expect(q.queued).To(Equal(0))
+			Expect(q.tenants).To(BeEmpty())
+		},
+		Entry("alternates between tenants with the default weight", fairnessCase{
+			queued:   map[string][]string{"a": {"a1", "a2", "a3"}, "b": {"b1", "b2"}},
+			tenants:  []string{"a", "b"},
+			expected: []string{"a1", "b1", "a2", "b2", "a3"},
+		}),
+		Entry("serves as many jobs of a tenant in a row as its weight", fairnessCase{
+			weights:  map[string]int{"a": 2},
+			queued:   map[string][]string{"a": {"a1", "a2", "a3"}, "b": {"b1", "b2"}},
+			tenants:  []string{"a", "b"},
+			expected: []string{"a1", "a2", "b1", "a3", "b2"},
+		}),
+		Entry("keeps the order of the jobs of a single tenant", fairnessCase{
+			queued:   map[string][]string{"a": {"a1", "a2", "a3"}},
+			tenants:  []string{"a"},
+			expected: []string{"a1", "a2", "a3"},
+		}),
+	)
+
+	It("does not hand out more workers than the pool size", func() {
+		q := newQueue(2, 0, nil)
+		Expect(q.tryAcquire("a")).To(BeTrue())
+		Expect(q.tryAcquire("b")).To(BeTrue())
+		Expect(q.tryAcquire("c")).To(BeFalse())
+
+		push(q, "c", "c1")
+		Expect(next(q)).To(BeEmpty())
+		q.release("a")
+		Expect(next(q)).To(Equal("c1"))
+	})
+
+	It("queues jobs of a tenant behind its queued jobs even if a worker is free", func() {
+		q := newQueue(1, 0, nil)
+		Expect(q.tryAcquire("a")).To(BeTrue())
+		push(q, "a", "a1")
+		q.release("a")
+		Expect(q.tryAcquire("a")).To(BeFalse())
+		Expect(next(q)).To(Equal("a1"))
+	})
+
+	It("limits the workers executing the jobs of a tenant", func() {
+		q := newQueue(3, 1, nil)
+		Expect(q.tryAcquire("a")).To(BeTrue())
+		Expect(q.tryAcquire("a")).To(BeFalse())
+
+		push(q, "a", "a1")
+		push(q, "b", "b1", "b2")
+		Expect(next(q)).To(Equal("b1"))
+		Expect(next(q)).To(BeEmpty())
+
+		q.release("a")
+		Expect(next(q)).To(Equal("a1"))
+		q.release("b")
+		Expect(next(q)).To(Equal("b2"))
+	})
+
+	It("rejects reservations beyond the queue size and frees them when they are not used", func() {
+		q := newQueue(1, 0, nil)
+		q.size = 2
+		Expect(q.reserve()).To(BeTrue())
+		Expect(q.reserve()).To(BeTrue())
+		Expect(q.reserve()).To(BeFalse())
+
+		q.unreserve()
+		Expect(q.reserve()).To(BeTrue())
+		q.push("a", &queuedJob{operation: &types.Operation{}}, true)
+		Expect(q.reserved).To(Equal(1))
+		Expect(q.queued).To(Equal(1))
+		Expect(q.reserve()).To(BeFalse())
+	})
+})
+
+var _ = Describe("Scheduler queue", func() {
+	var (
+		ctx        context.Context
+		cancel     context.CancelFunc
+		wg         *sync.WaitGroup
+		repository *inMemoryRepository
+		settings   *Settings
+	)
+
+	newPlatformOperation := func(id string, state types.OperationState) (*types.Operation, *types.Platform) {
+		now := time.Now()
+		platform := &types.Platform{Base: types.Base{ID: "platform-" + id, Ready: true}, Name: "platform-" + id, Type: "kubernetes"}
+		return &types.Operation{
+			Base:         types.Base{ID: id, CreatedAt: now, UpdatedAt: now, Ready: true},
+			Type:         types.CREATE,
+			State:        state,
+			ResourceID:   platform.ID,
+			ResourceType: types.PlatformType,
+			PlatformID:   types.SMPlatform,
+			Context:      &types.OperationContext{},
+		}, platform
+	}
+
+	createAction := func(object types.Object) storageAction {
+		return func(ctx context.Context, repository storage.Repository) (types.Object, error) {
+			return repository.Create(ctx, object)
+		}
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		wg = &sync.WaitGroup{}
+		repository = newInMemoryRepository()
+		settings = DefaultSettings()
+		settings.QueueSize = 1
+	})
+
+	AfterEach(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	Describe("ScheduleAsyncStorageAction", func() {
+		var scheduler *Scheduler
+
+		BeforeEach(func() {
+			scheduler = NewScheduler(ctx, repository, settings, 1, wg)
+		})
+
+		It("releases the worker when the operation cannot be scheduled", func() {
+			operation, platform := newPlatformOperation("succeeded", types.SUCCEEDED)
+			Expect(scheduler.ScheduleAsyncStorageAction(ctx, operation, createAction(platform))).To(HaveOccurred())
+			Expect(scheduler.queue.running).To(Equal(0))
+			Expect(scheduler.queue.tenants).To(BeEmpty())
+		})
+
+		It("frees the reservation when a queued operation cannot be scheduled", func() {
+			Expect(scheduler.queue.tryAcquire("")).To(BeTrue())
+			operation, platform := newPlatformOperation("succeeded", types.SUCCEEDED)
+			Expect(scheduler.ScheduleAsyncStorageAction(ctx, operation, createAction(platform))).To(HaveOccurred())
+			Expect(scheduler.queue.reserved).To(Equal(0))
+			Expect(scheduler.queue.queued).To(Equal(0))
+		})
+
+		It("frees the reservation and fails the operation when it cannot be stored in the queue", func() {
+			Expect(scheduler.queue.tryAcquire("")).To(BeTrue())
+			repository.CreateCalls(func(ctx context.Context, object types.Object) (types.Object, error) {
+				if object.GetType() == types.QueuedOperationType {
+					return nil, errors.New("connection refused")
+				}
+				repository.objects[object.GetID()] = copyObject(object)
+				return object, nil
+			})
+			operation, platform := newPlatformOperation("queued", types.IN_PROGRESS)
+			Expect(scheduler.ScheduleAsyncStorageAction(ctx, operation, createAction(platform))).To(HaveOccurred())
+			Expect(scheduler.queue.reserved).To(Equal(0))
+			Expect(repository.object("queued").(*types.Operation).State).To(Equal(types.FAILED))
+		})
+
+		It("queues operations until a worker is free and rejects them when the queue is full", func() {
+			Expect(scheduler.queue.tryAcquire("")).To(BeTrue())
+			queued, queuedPlatform := newPlatformOperation("queued", types.IN_PROGRESS)
+			Expect(scheduler.ScheduleAsyncStorageAction(ctx, queued, createAction(queuedPlatform))).To(Succeed())
+			Expect(repository.find(types.QueuedOperationType)).To(HaveLen(1))
+
+			rejected, rejectedPlatform := newPlatformOperation("rejected", types.IN_PROGRESS)
+			err := scheduler.ScheduleAsyncStorageAction(ctx, rejected, createAction(rejectedPlatform))
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusServiceUnavailable))
+
+			scheduler.releaseWorker("")
+			wg.Wait()
+			Expect(repository.object("queued").(*types.Operation).State).To(Equal(types.SUCCEEDED))
+			Expect(repository.object(queuedPlatform.ID)).ToNot(BeNil())
+			Expect(repository.find(types.QueuedOperationType)).To(BeEmpty())
+			Expect(scheduler.queue.running).To(Equal(0))
+		})
+	})
+
+	Describe("restoreQueuedOperations", func() {
+		var (
+			maintainer *Maintainer
+			entry      *types.QueuedOperation
+			platform   *types.Platform
+		)
+
+		BeforeEach(func() {
+			maintainer = NewMaintainer(ctx, repository, func(int) storage.Locker { return nil }, settings, wg)
+
+			var operation *types.Operation
+			operation, platform = newPlatformOperation("restored", types.IN_PROGRESS)
+			repository.objects[operation.ID] = operation
+
+			var err error
+			entry, err = newQueuedOperation(ContextWithResource(ctx, platform, nil), operation, "tenant")
+			Expect(err).ToNot(HaveOccurred())
+			// the node which queued the operation was restarted before a worker was free
+			entry.UpdatedAt = time.Now().Add(-2 * settings.ActionTimeout)
+			repository.objects[entry.ID] = entry
+		})
+
+		It("executes the operations queued before a restart from their stored payload", func() {
+			maintainer.restoreQueuedOperations()
+			wg.Wait()
+
+			Expect(repository.object("restored").(*types.Operation).State).To(Equal(types.SUCCEEDED))
+			Expect(repository.object(platform.ID).(*types.Platform).Name).To(Equal(platform.Name))
+			Expect(repository.object(entry.ID)).To(BeNil())
+		})
+
+		It("does not execute operations claimed by another node", func() {
+			repository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+				entries := &types.QueuedOperations{}
+				entries.Add(repository.object(entry.ID))
+				// another node claims the entry after it was listed
+				claimed := repository.object(entry.ID)
+				_, err := repository.Update(ctx, claimed, types.LabelChanges{})
+				Expect(err).ToNot(HaveOccurred())
+				return entries, nil
+			})
+
+			maintainer.restoreQueuedOperations()
+			wg.Wait()
+
+			Expect(repository.object("restored").(*types.Operation).State).To(Equal(types.IN_PROGRESS))
+			Expect(repository.object(platform.ID)).To(BeNil())
+			Expect(repository.object(entry.ID)).ToNot(BeNil())
+		})
+
+		It("drops the entries of operations which are no longer in progress", func() {
+			cancelled := repository.object("restored").(*types.Operation)
+			cancelled.State = types.FAILED
+			cancelled.Context.Cancelled = true
+			_, err := repository.Update(ctx, cancelled, types.LabelChanges{})
+			Expect(err).ToNot(HaveOccurred())
+
+			maintainer.restoreQueuedOperations()
+			wg.Wait()
+
+			Expect(repository.object(entry.ID)).To(BeNil())
+			Expect(repository.object(platform.ID)).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,232 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/util/slice"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+)
+
+// inMemoryRepository backs a FakeStorage with a map, so that the scheduler can run whole operations against it
+type inMemoryRepository struct {
+	*storagefakes.FakeStorage
+
+	mutex   sync.Mutex
+	objects map[string]types.Object
+}
+
+func newInMemoryRepository(objects ...types.Object) *inMemoryRepository {
+	r := &inMemoryRepository{
+		FakeStorage: &storagefakes.FakeStorage{},
+		objects:     make(map[string]types.Object),
+	}
+	for _, object := range objects {
+		r.objects[object.GetID()] = object
+	}
+
+	r.InTransactionCalls(func(ctx context.Context, f func(ctx context.Context, storage storage.Repository) error) error {
+		return f(ctx, r)
+	})
+	r.GetCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.Object, error) {
+		objects := r.find(objectType, criteria...)
+		if len(objects) == 0 {
+			return nil, util.ErrNotFoundInStorage
+		}
+		return objects[0], nil
+	})
+	r.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+		objects := r.find(objectType, criteria...)
+		var list types.ObjectList
+		switch objectType {
+		case types.QueuedOperationType:
+			list = &types.QueuedOperations{}
+		case types.ServiceInstanceType:
+			list = &types.ServiceInstances{}
+		default:
+			list = types.NewObjectArray()
+		}
+		for _, object := range objects {
+			list.Add(object)
+		}
+		return list, nil
+	})
+	r.CountCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (int, error) {
+		return len(r.find(objectType, criteria...)), nil
+	})
+	r.CreateCalls(func(ctx context.Context, object types.Object) (types.Object, error) {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		if _, found := r.objects[object.GetID()]; found {
+			return nil, util.ErrAlreadyExistsInStorage
+		}
+		r.objects[object.GetID()] = copyObject(object)
+		return object, nil
+	})
+	r.UpdateCalls(func(ctx context.Context, object types.Object, labelChanges types.LabelChanges, criteria ...query.Criterion) (types.Object, error) {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		stored, found := r.objects[object.GetID()]
+		if !found {
+			return nil, util.ErrNotFoundInStorage
+		}
+		if version, found := storage.VersionFromCriteria(criteria); found && !stored.GetUpdatedAt().Equal(version) {
+			return nil, util.ErrConcurrentResourceModification
+		}
+		object.SetUpdatedAt(time.Now())
+		r.objects[object.GetID()] = copyObject(object)
+		return object, nil
+	})
+	r.DeleteCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) error {
+		objects := r.find(objectType, criteria...)
+		if len(objects) == 0 {
+			return util.ErrNotFoundInStorage
+		}
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		for _, object := range objects {
+			delete(r.objects, object.GetID())
+		}
+		return nil
+	})
+	r.QueryForListCalls(func(ctx context.Context, objectType types.ObjectType, queryName storage.NamedQuery, params map[string]interface{}) (types.ObjectList, error) {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		result := types.NewObjectArray()
+		switch queryName {
+		case storage.QueryForOperationStateTransition:
+			if operation, ok := r.objects[params["id"].(string)].(*types.Operation); ok && string(operation.State) == params["state"] {
+				operation.State = types.OperationState(params["new_state"].(string))
+				result.Add(copyObject(operation))
+			}
+		case storage.QueryForLastOperationsPerResource:
+			var last *types.Operation
+			for _, object := range r.objects {
+				if operation, ok := object.(*types.Operation); ok && operation.ResourceID == params["id_list"].([]string)[0] &&
+					(last == nil || operation.PagingSequence > last.PagingSequence) {
+					last = operation
+				}
+			}
+			if last != nil {
+				result.Add(copyObject(last))
+			}
+		}
+		return result, nil
+	})
+	return r
+}
+
+func (r *inMemoryRepository) object(id string) types.Object {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if object, found := r.objects[id]; found {
+		return copyObject(object)
+	}
+	return nil
+}
+
+func (r *inMemoryRepository) find(objectType types.ObjectType, criteria ...query.Criterion) []types.Object {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var result []types.Object
+	for _, object := range r.objects {
+		if object.GetType() == objectType && matches(object, criteria) {
+			result = append(result, copyObject(object))
+		}
+	}
+	return result
+}
+
+// matches evaluates the equality criteria on the fields used by the operations package, other criteria are ignored
+func matches(object types.Object, criteria []query.Criterion) bool {
+	for _, criterion := range criteria {
+		switch criterion.Type {
+		case query.LabelQuery:
+			values := object.GetLabels()[criterion.LeftOp]
+			if len(values) == 0 || values[0] != criterion.RightOp[0] {
+				return false
+			}
+		case query.FieldQuery:
+			if criterion.Operator == query.LessThanOperator && criterion.LeftOp == "created_at" {
+				createdAt, err := time.Parse(time.RFC3339Nano, criterion.RightOp[0])
+				if err != nil || !object.GetCreatedAt().Before(createdAt) {
+					return false
+				}
+				continue
+			}
+			if criterion.Operator != query.EqualsOperator && criterion.Operator != query.InOperator {
+				continue
+			}
+			fields := map[string]string{"id": object.GetID()}
+			switch o := object.(type) {
+			case *types.Operation:
+				fields["state"] = string(o.State)
+				fields["resource_id"] = o.ResourceID
+				fields["resource_type"] = string(o.ResourceType)
+			case *types.ServiceInstance:
+				fields["platform_id"] = o.PlatformID
+			case *types.Visibility:
+				fields["inactive"] = strconv.FormatBool(o.Inactive)
+			}
+			if value, found := fields[criterion.LeftOp]; found && !slice.StringsAnyEquals(criterion.RightOp, value) {
+				return false
+			}
+		case query.ExistQuery:
+			visibility, ok := object.(*types.Visibility)
+			if !ok || criterion.RightOp[0] != storage.GetSubQuery(storage.QueryForVisibilitiesInValidityWindow) {
+				continue
+			}
+			if visibility.IsActive(time.Now()) != (criterion.Operator == query.ExistsSubquery) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func copyObject(object types.Object) types.Object {
+	switch o := object.(type) {
+	case *types.Operation:
+		operation := *o
+		if o.Context != nil {
+			operationContext := *o.Context
+			operation.Context = &operationContext
+		}
+		return &operation
+	case *types.ServiceInstance:
+		instance := *o
+		return &instance
+	case *types.Platform:
+		platform := *o
+		return &platform
+	case *types.QueuedOperation:
+		entry := *o
+		return &entry
+	case *types.Visibility:
+		visibility := *o
+		return &visibility
+	}
+	return object
+}
@@ -41,7 +41,8 @@ type storageAction func(ctx context.Context, repository storage.Repository) (typ
 type Scheduler struct {
 	smCtx                          context.Context
 	repository                     storage.TransactionalRepository
-	workers                        chan struct{}
+	queue                          *fairQueue
+	tenantLabelKey                 string
 	actionTimeout                  time.Duration
 	reconciliationOperationTimeout time.Duration
 	cascadeOrphanMitigationTimeout time.Duration
@@ -54,7 +55,8 @@ func NewScheduler(smCtx context.Context, repository storage.TransactionalReposit
 	return &Scheduler{
 		smCtx:                          smCtx,
 		repository:                     repository,
-		workers:                        make(chan struct{}, poolSize),
+		queue:                          newFairQueue(poolSize, settings),
+		tenantLabelKey:                 settings.TenantLabelKey,
 		actionTimeout:                  settings.ActionTimeout,
 		reconciliationOperationTimeout: settings.ReconciliationOperationTimeout,
 		cascadeOrphanMitigationTimeout: settings.CascadeOrphanMitigationTimeout,
@@ -87,7 +89,7 @@ func (s *Scheduler) ScheduleStorageAction(ctx context.Context, operation *types.
 			return nil, true, nil
 		}
 
-		return object, false, err
+		return object, lastOperation.State == types.AWAITING_APPROVAL, err
 	}
 
 	if operation.Context.Async {
@@ -109,6 +111,10 @@ func (s *Scheduler) ScheduleStorageAction(ctx context.Context, operation *types.
 
 	log.C(ctx).Debugf("Request will be executed synchronously")
 	object, err = s.ScheduleSyncStorageAction(ctx, operation, action)
+	if err == nil && operation.State == types.AWAITING_APPROVAL {
+		// the operation continues asynchronously after it is approved
+		return nil, true, nil
+	}
 	return object, false, err
 }
 
@@ -137,78 +143,187 @@ func (s *Scheduler) ScheduleSyncStorageAction(ctx context.Context, operation *ty
 	return object, nil
 }
 
-// ScheduleAsyncStorageAction stores the job's Operation entity in DB asynchronously executes the CREATE/UPDATE/DELETE DB transaction in a goroutine
+// ScheduleAsyncStorageAction stores the job's Operation entity in DB asynchronously executes the CREATE/UPDATE/DELETE DB transaction in a goroutine.
+// If no worker is available for the tenant of the operation, the operation is queued until one is.
 func (s *Scheduler) ScheduleAsyncStorageAction(ctx context.Context, operation *types.Operation, action storageAction) error {
-	select {
-	case s.workers <- struct{}{}:
+	tenant := s.tenantOf(ctx, operation)
+	if s.queue.tryAcquire(tenant) {
 		initialLogMessage(ctx, operation, true)
 		if err := s.executeOperationPreconditions(ctx, operation); err != nil {
-			<-s.workers
+			s.releaseWorker(tenant)
 			return err
 		}
 
-		s.wg.Add(1)
-		stateCtx := util.StateContext{Context: ctx}
-		go func(operation *types.Operation) {
-			defer func() {
-				if panicErr := recover(); panicErr != nil {
-					errMessage := fmt.Errorf("job panicked while executing: %s", panicErr)
-					op, opErr := s.refetchOperation(stateCtx, operation)
-					if opErr != nil {
-						errMessage = fmt.Errorf("%s: setting new operation state failed: %s ", errMessage, opErr)
-					}
+		s.execute(ctx, operation, action, tenant, "")
+		return nil
+	}
 
-					if opErr := updateOperationState(stateCtx, s.repository, op, types.FAILED, &util.HTTPError{
-						ErrorType:   "InternalServerError",
-						Description: "job interrupted",
-						StatusCode:  http.StatusInternalServerError,
-					}); opErr != nil {
-						errMessage = fmt.Errorf("%s: setting new operation state failed: %s ", errMessage, opErr)
-					}
-					log.C(stateCtx).Errorf("panic error: %s", errMessage)
-					debug.PrintStack()
-				}
-				<-s.workers
-				s.wg.Done()
-			}()
+	if !s.queue.reserve() {
+		log.C(ctx).Infof("Failed to schedule %s operation with id %s - all workers are busy.", operation.Type, operation.ID)
+		return &util.HTTPError{
+			ErrorType:   "ServiceUnavailable",
+			Description: "Failed to schedule job. Server is busy - try again in a few minutes.",
+			StatusCode:  http.StatusServiceUnavailable,
+		}
+	}
 
-			stateCtxWithOp, err := s.addOperationToContext(stateCtx, operation)
-			if err != nil {
-				log.C(stateCtx).Error(err)
-				return
-			}
+	initialLogMessage(ctx, operation, true)
+	if err := s.executeOperationPreconditions(ctx, operation); err != nil {
+		s.queue.unreserve()
+		return err
+	}
+
+	return s.enqueue(ctx, operation, action, tenant)
+}
 
-			stateCtxWithOpAndTimeout, timeoutCtxCancel := context.WithTimeout(stateCtxWithOp, s.actionTimeout)
-			defer timeoutCtxCancel()
-			go func() {
-				select {
-				case <-s.smCtx.Done():
-					timeoutCtxCancel()
-				case <-stateCtxWithOpAndTimeout.Done():
+// enqueue stores the operation in the queue table, so that it survives restarts, and queues it until a worker is available
+func (s *Scheduler) enqueue(ctx context.Context, operation *types.Operation, action storageAction, tenant string) error {
+	entry, err := newQueuedOperation(ctx, operation, tenant)
+	if err == nil {
+		_, err = s.repository.Create(ctx, entry)
+	}
+	if err != nil {
+		s.queue.unreserve()
+		if err == util.ErrAlreadyExistsInStorage {
+			log.C(ctx).Infof("%s operation with id %s is already queued", operation.Type, operation.ID)
+			return nil
+		}
+
+		err = util.HandleStorageError(err, types.QueuedOperationType.String())
+		if opErr := updateOperationState(ctx, s.repository, operation, types.FAILED, err); opErr != nil {
+			return fmt.Errorf("failed to update error of operation with id %s to %s", operation.ID, err)
+		}
+		return err
+	}
+
+	position := s.queue.push(tenant, &queuedJob{
+		ctx:       util.StateContext{Context: ctx},
+		operation: operation,
+		action:    action,
+		entryID:   entry.ID,
+	}, true)
+	log.C(ctx).Infof("Queued %s operation with id %s at position %d for tenant %q", operation.Type, operation.ID, position, tenant)
+	s.dispatch()
+	return nil
+}
+
+// dispatch starts the queued jobs for which workers are available
+func (s *Scheduler) dispatch() {
+	for s.smCtx.Err() == nil {
+		job, tenant := s.queue.next()
+		if job == nil {
+			return
+		}
+		s.execute(job.ctx, job.operation, job.action, tenant, job.entryID)
+	}
+}
+
+func (s *Scheduler) releaseWorker(tenant string) {
+	s.queue.release(tenant)
+	s.dispatch()
+}
+
+// execute runs the action on a worker acquired for the tenant. Queued jobs are executed only if they are
+// still in the queue table, as they could have been cancelled or restored by another node in the meantime.
+func (s *Scheduler) execute(ctx context.Context, operation *types.Operation, action storageAction, tenant, entryID string) {
+	s.wg.Add(1)
+	stateCtx := util.StateContext{Context: ctx}
+	go func(operation *types.Operation) {
+		defer func() {
+			if panicErr := recover(); panicErr != nil {
+				errMessage := fmt.Errorf("job panicked while executing: %s", panicErr)
+				op, opErr := s.refetchOperation(stateCtx, operation)
+				if opErr != nil {
+					errMessage = fmt.Errorf("%s: setting new operation state failed: %s ", errMessage, opErr)
 				}
 
-			}()
+				if opErr := updateOperationState(stateCtx, s.repository, op, types.FAILED, &util.HTTPError{
+					ErrorType:   "InternalServerError",
+					Description: "job interrupted",
+					StatusCode:  http.StatusInternalServerError,
+				}); opErr != nil {
+					errMessage = fmt.Errorf("%s: setting new operation state failed: %s ", errMessage, opErr)
+				}
+				log.C(stateCtx).Errorf("panic error: %s", errMessage)
+				debug.PrintStack()
+			}
+			s.releaseWorker(tenant)
+			s.wg.Done()
+		}()
 
-			var actionErr error
-			var objectAfterAction types.Object
-			if objectAfterAction, actionErr = action(stateCtxWithOpAndTimeout, s.repository); actionErr != nil {
-				log.C(stateCtx).Errorf("failed to execute action for %s operation with id %s for %s entity with id %s: %s", operation.Type, operation.ID, operation.ResourceType, operation.ResourceID, actionErr)
+		if len(entryID) > 0 {
+			var claimed bool
+			if operation, claimed = s.claimQueuedOperation(stateCtx, operation, entryID); !claimed {
+				return
 			}
+		}
+
+		stateCtxWithOp, err := s.addOperationToContext(stateCtx, operation)
+		if err != nil {
+			log.C(stateCtx).Error(err)
+			return
+		}
 
-			if _, err := s.handleActionResponse(stateCtx, objectAfterAction, actionErr, operation); err != nil {
-				log.C(stateCtx).Error(err)
+		stateCtxWithOpAndTimeout, timeoutCtxCancel := context.WithTimeout(stateCtxWithOp, s.actionTimeout)
+		defer timeoutCtxCancel()
+		go func() {
+			select {
+			case <-s.smCtx.Done():
+				timeoutCtxCancel()
+			case <-stateCtxWithOpAndTimeout.Done():
 			}
-		}(operation)
-	default:
-		log.C(ctx).Infof("Failed to schedule %s operation with id %s - all workers are busy.", operation.Type, operation.ID)
-		return &util.HTTPError{
-			ErrorType:   "ServiceUnavailable",
-			Description: "Failed to schedule job. Server is busy - try again in a few minutes.",
-			StatusCode:  http.StatusServiceUnavailable,
+
+		}()
+
+		var actionErr error
+		var objectAfterAction types.Object
+		if objectAfterAction, actionErr = action(stateCtxWithOpAndTimeout, s.repository); actionErr != nil {
+			log.C(stateCtx).Errorf("failed to execute action for %s operation with id %s for %s entity with id %s: %s", operation.Type, operation.ID, operation.ResourceType, operation.ResourceID, actionErr)
+		}
+
+		if _, err := s.handleActionResponse(stateCtx, objectAfterAction, actionErr, operation); err != nil {
+			log.C(stateCtx).Error(err)
 		}
+	}(operation)
+}
+
+// claimQueuedOperation removes the queued operation from the queue table and returns its current state,
+// if it should still be executed
+func (s *Scheduler) claimQueuedOperation(ctx context.Context, operation *types.Operation, entryID string) (*types.Operation, bool) {
+	if err := s.repository.Delete(ctx, types.QueuedOperationType, query.ByField(query.EqualsOperator, "id", entryID)); err != nil {
+		if err != util.ErrNotFoundInStorage {
+			log.C(ctx).Errorf("Could not dequeue %s operation with id %s: %s", operation.Type, operation.ID, err)
+		}
+		return nil, false
 	}
 
-	return nil
+	object, err := s.repository.Get(ctx, types.OperationType, query.ByField(query.EqualsOperator, "id", operation.ID))
+	if err != nil {
+		log.C(ctx).Errorf("Could not fetch queued %s operation with id %s: %s", operation.Type, operation.ID, err)
+		return nil, false
+	}
+	current := object.(*types.Operation)
+	if current.State != types.IN_PROGRESS || current.IsCancelled() {
+		log.C(ctx).Infof("Skipping queued %s operation with id %s in state %s", operation.Type, operation.ID, current.State)
+		return nil, false
+	}
+	return current, true
+}
+
+// tenantOf returns the tenant of the operation, or an empty string for operations which do not belong to a tenant
+func (s *Scheduler) tenantOf(ctx context.Context, operation *types.Operation) string {
+	if len(s.tenantLabelKey) == 0 {
+		return ""
+	}
+	if values := operation.Labels[s.tenantLabelKey]; len(values) > 0 {
+		return values[0]
+	}
+	for _, criterion := range query.CriteriaForContext(ctx) {
+		if criterion.Type == query.LabelQuery && criterion.LeftOp == s.tenantLabelKey && len(criterion.RightOp) > 0 {
+			return criterion.RightOp[0]
+		}
+	}
+	return ""
 }
 
 func (s *Scheduler) getResourceLastOperation(ctx context.Context, operation *types.Operation, checkForExistingOperation bool) (*types.Operation, bool, bool, error) {
@@ -256,6 +371,9 @@ func (s *Scheduler) checkForConcurrentOperations(ctx context.Context, operation
 
 	isAReschedule := lastOperation.Reschedule && operation.Reschedule
 
+	// an approved or retried operation continues the same operation which was awaiting approval or failed
+	isAContinuation := lastOperation.ID == operation.ID && operation.IsContinuation()
+
 	// depending on the last executed operation on the resource and the currently executing operation we determine if the
 	// currently executing operation should be allowed
 	switch lastOperation.Type {
@@ -267,7 +385,7 @@ func (s *Scheduler) checkForConcurrentOperations(ctx context.Context, operation
 
 			// this means that when the last operation and the new operation which is either reschedulable or has a deletion scheduled
 			// it is up to the client to make sure such operations do not overlap
-			if isLastOpInProgress && !isDeletionScheduled && !isAReschedule {
+			if isLastOpInProgress && !isDeletionScheduled && !isAReschedule && !isAContinuation {
 				return &util.HTTPError{
 					ErrorType:   "ConcurrentOperationInProgress",
 					Description: "Another concurrent operation in progress for this resource",
@@ -307,7 +425,7 @@ func (s *Scheduler) checkForConcurrentOperations(ctx context.Context, operation
 
 			// this means that when the last operation and the new operation which is either reschedulable or has a deletion scheduled
 			// it is up to the client to make sure such operations do not overlap
-			if isLastOpInProgress && !isDeletionScheduled && !isAReschedule {
+			if isLastOpInProgress && !isDeletionScheduled && !isAReschedule && !isAContinuation {
 				return &util.HTTPError{
 					ErrorType:   "ConcurrentOperationInProgress",
 					Description: "Another concurrent operation in progress for this resource",
@@ -346,7 +464,7 @@ func (s *Scheduler) checkForConcurrentOperations(ctx context.Context, operation
 
 			// this means that when the last operation and the new operation which is either reschedulable or has a deletion scheduled
 			// it is up to the client to make sure such operations do not overlap
-			if isLastOpInProgress && !isDeletionScheduled && !isAReschedule {
+			if isLastOpInProgress && !isDeletionScheduled && !isAReschedule && !isAContinuation {
 				return &util.HTTPError{
 					ErrorType:   "ConcurrentOperationInProgress",
 					Description: "Deletion is currently in progress for this resource",
@@ -419,6 +537,25 @@ func fetchAndUpdateResource(ctx context.Context, repository storage.Repository,
 	return err
 }
 
+// transitionOperationState changes the state of the operation with a conditional update, which succeeds only if the
+// stored operation is still in the state of the given operation. It reports whether the state was changed,
+// so that only one of concurrent transitions of an operation succeeds.
+func transitionOperationState(ctx context.Context, repository storage.Repository, operation *types.Operation, newState types.OperationState) (bool, error) {
+	objectList, err := repository.QueryForList(ctx, types.OperationType, storage.QueryForOperationStateTransition, map[string]interface{}{
+		"id":        operation.ID,
+		"state":     string(operation.State),
+		"new_state": string(newState),
+	})
+	if err != nil {
+		return false, util.HandleStorageError(err, types.OperationType.String())
+	}
+	if objectList.Len() == 0 {
+		return false, nil
+	}
+	operation.State = newState
+	return true, nil
+}
+
 func updateOperationState(ctx context.Context, repository storage.Repository, operation *types.Operation, state types.OperationState, opErr error) error {
 	operation.State = state
 
@@ -477,9 +614,18 @@ func (s *Scheduler) handleActionResponse(ctx context.Context, actionObject types
 		return nil, err
 	}
 
+	// an operation cancelled while its action was running fails even if the action succeeded,
+	// unless this is the orphan mitigation of the cancelled operation
+	if actionError == nil && opAfterJob.IsCancelled() && !opBeforeJob.InOrphanMitigationState() {
+		actionError = fmt.Errorf("operation with id %s was cancelled", opAfterJob.ID)
+	}
+
 	// if an action error has occurred we mark the operation as failed and check if deletion has to be scheduled
 	if actionError != nil {
 		return nil, s.handleActionResponseFailure(ctx, actionError, opAfterJob)
+	} else if opAfterJob.State == types.AWAITING_APPROVAL {
+		log.C(ctx).Infof("%s operation with id %s for %s entity with id %s is awaiting approval", opAfterJob.Type, opAfterJob.ID, opAfterJob.ResourceType, opAfterJob.ResourceID)
+		return actionObject, nil
 		// if no error occurred and op is not reschedulable (has finished), mark it as success
 	} else if !opAfterJob.Reschedule {
 		return s.handleActionResponseSuccess(ctx, actionObject, opAfterJob)
@@ -581,6 +727,9 @@ func (s *Scheduler) handleActionResponseSuccess(ctx context.Context, actionObjec
 			}
 			opAfterJob.Errors = json.RawMessage{}
 		}
+		if finalState == types.SUCCEEDED {
+			opAfterJob.Payload = nil
+		}
 
 		// a non reschedulable operation has finished with no errors:
 		// this can either be an actual operation or an orphan mitigation triggered by an actual operation error
@@ -685,9 +834,9 @@ func (s *Scheduler) executeOperationPreconditions(ctx context.Context, operation
 			return err
 		}
 
-		// Block updates of service instances or bindings that were not created successfully
+		// Block updates of service instances or bindings that were not created successfully or are not yet approved
 		if operation.Type == types.UPDATE {
-			if lastOperation.Type == types.CREATE && lastOperation.State == types.FAILED {
+			if lastOperation.Type == types.CREATE && (lastOperation.State == types.FAILED || lastOperation.State == types.AWAITING_APPROVAL) {
 				if operation.ResourceType == types.ServiceBindingType || operation.ResourceType == types.ServiceInstanceType {
 					return &util.HTTPError{
 						ErrorType:   "UpdateOperationIsNotAllowed",
@@ -699,6 +848,15 @@ func (s *Scheduler) executeOperationPreconditions(ctx context.Context, operation
 		}
 	}
 
+	if operation.Type == types.UPDATE && len(operation.Payload) == 0 {
+		// the changes of an update are kept, so that the update can be replayed when it is retried
+		payload, err := payloadFromContext(ctx)
+		if err != nil {
+			return fmt.Errorf("could not store resource of operation with id %s: %s", operation.ID, err)
+		}
+		operation.Payload = payload
+	}
+
 	if err := s.storeOrUpdateOperation(ctx, operation, currentOpExists); err != nil {
 		return err
 	}
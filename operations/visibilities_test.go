@@ -0,0 +1,104 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Visibility windows", func() {
+	var (
+		ctx        context.Context
+		cancel     context.CancelFunc
+		wg         *sync.WaitGroup
+		repository *inMemoryRepository
+		maintainer *Maintainer
+	)
+
+	newVisibility := func(id string, validFrom, validUntil time.Duration, inactive bool) *types.Visibility {
+		from := time.Now().Add(validFrom)
+		until := time.Now().Add(validUntil)
+		return &types.Visibility{
+			Base:          types.Base{ID: id, Ready: true},
+			PlatformID:    "platform-id",
+			ServicePlanID: "plan-id",
+			ValidFrom:     &from,
+			ValidUntil:    &until,
+			Inactive:      inactive,
+		}
+	}
+
+	updatedIDs := func() []string {
+		var ids []string
+		for i := 0; i < repository.UpdateCallCount(); i++ {
+			_, object, _, _ := repository.UpdateArgsForCall(i)
+			ids = append(ids, object.GetID())
+		}
+		return ids
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		wg = &sync.WaitGroup{}
+		repository = newInMemoryRepository(
+			newVisibility("opened", -time.Minute, time.Hour, true),
+			newVisibility("closed", -time.Hour, -time.Minute, false),
+			newVisibility("active", -time.Hour, time.Hour, false),
+			newVisibility("pending", time.Minute, time.Hour, true),
+			newVisibility("expired", -time.Hour, -time.Minute, true),
+		)
+		maintainer = NewMaintainer(ctx, repository, func(int) storage.Locker { return nil }, DefaultSettings(), wg)
+	})
+
+	AfterEach(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	It("updates the inactive visibilities whose window opened and the active visibilities whose window closed", func() {
+		maintainer.syncVisibilityWindows()
+
+		Expect(updatedIDs()).To(ConsistOf("opened", "closed"))
+	})
+
+	It("does not update the visibilities again once they are marked according to their window", func() {
+		// mark the visibilities the way the visibility update interceptor does
+		repository.UpdateCalls(func(ctx context.Context, object types.Object, labelChanges types.LabelChanges, criteria ...query.Criterion) (types.Object, error) {
+			visibility := object.(*types.Visibility)
+			visibility.Inactive = !visibility.IsActive(time.Now())
+			repository.mutex.Lock()
+			defer repository.mutex.Unlock()
+			repository.objects[visibility.ID] = copyObject(visibility)
+			return visibility, nil
+		})
+		maintainer.syncVisibilityWindows()
+		maintainer.syncVisibilityWindows()
+
+		Expect(updatedIDs()).To(ConsistOf("opened", "closed"))
+		Expect(repository.object("opened").(*types.Visibility).Inactive).To(BeFalse())
+		Expect(repository.object("closed").(*types.Visibility).Inactive).To(BeTrue())
+	})
+})
@@ -39,6 +39,8 @@ type File struct {
 	Format   string `description:"extension of the configuration file"`
 }
 
+var envKeyReplacer = strings.NewReplacer(".", "_")
+
 // DefaultConfigFile holds the default SM config file properties
 func DefaultConfigFile() File {
 	return File{
@@ -61,11 +63,16 @@ type Environment interface {
 	Unmarshal(value interface{}) error
 	BindPFlag(key string, flag *pflag.Flag) error
 	AllSettings() map[string]interface{}
+	Source(key string) Source
 }
 
 // ViperEnv represents an implementation of the Environment interface that uses viper
 type ViperEnv struct {
 	*viper.Viper
+
+	flags     *pflag.FlagSet
+	file      *viper.Viper
+	overrides map[string]bool
 }
 
 // EmptyFlagSet creates an empty flag set and adds the default set of flags to it
@@ -95,9 +102,11 @@ func CreatePFlags(set *pflag.FlagSet, value interface{}) {
 // environment should be aware of.
 func New(ctx context.Context, set *pflag.FlagSet, onConfigChangeHandlers ...func(env Environment) func(event fsnotify.Event)) (*ViperEnv, error) {
 	v := &ViperEnv{
-		Viper: viper.New(),
+		Viper:     viper.New(),
+		flags:     set,
+		overrides: make(map[string]bool),
 	}
-	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.SetEnvKeyReplacer(envKeyReplacer)
 	v.AutomaticEnv()
 
 	if err := set.Parse(os.Args[1:]); err != nil {
@@ -121,6 +130,32 @@ func (v *ViperEnv) AllSettings() map[string]interface{} {
 	return v.Viper.AllSettings()
 }
 
+// Set exposes viper's Set. Values set this way override values from all other sources.
+func (v *ViperEnv) Set(key string, value interface{}) {
+	v.overrides[strings.ToLower(key)] = true
+	v.Viper.Set(key, value)
+}
+
+// Source returns the source the value of the key is loaded from. The sources are checked in the order viper resolves them.
+func (v *ViperEnv) Source(key string) Source {
+	key = strings.ToLower(key)
+	if v.overrides[key] {
+		return SourceOverride
+	}
+	if v.flags != nil {
+		if flag := v.flags.Lookup(key); flag != nil && flag.Changed {
+			return SourceFlag
+		}
+	}
+	if value, ok := os.LookupEnv(strings.ToUpper(envKeyReplacer.Replace(key))); ok && value != "" {
+		return SourceEnv
+	}
+	if v.file != nil && v.file.IsSet(key) {
+		return SourceFile
+	}
+	return SourceDefault
+}
+
 // Unmarshal exposes viper's Unmarshal. Prior to unmarshaling it creates the necessary pflag and env var bindings
 // so that pflag / env var values are also used during the unmarshaling.
 func (v *ViperEnv) Unmarshal(value interface{}) error {
@@ -155,6 +190,13 @@ func (v *ViperEnv) setupConfigFile(ctx context.Context, onConfigChangeHandlers .
 		return fmt.Errorf("could not read configuration cfg: %s", err)
 	}
 
+	// the file is also loaded separately so that the keys set in it can be distinguished from keys set by other sources
+	v.file = viper.New()
+	v.file.SetConfigFile(v.Viper.ConfigFileUsed())
+	if err := v.file.ReadInConfig(); err != nil {
+		return fmt.Errorf("could not read configuration cfg: %s", err)
+	}
+
 	v.Viper.WatchConfig()
 
 	dynamicLogHandler := func(env Environment) func(event fsnotify.Event) {
@@ -182,6 +224,9 @@ func (v *ViperEnv) setupConfigFile(ctx context.Context, onConfigChangeHandlers .
 
 	v.Viper.OnConfigChange(func(event fsnotify.Event) {
 		log.C(ctx).Warnf("Configuration file was changed by event %s. Triggering on config changed handlers...", event.String())
+		if err := v.file.ReadInConfig(); err != nil {
+			log.C(ctx).WithError(err).Error("Could not reload configuration file sources")
+		}
 		for _, handler := range onConfigChangeHandlers {
 			handler(v)(event)
 		}
@@ -680,6 +680,140 @@ var _ = Describe("Env", func() {
 		})
 	})
 
+	Describe("Source", func() {
+		BeforeEach(func() {
+			testFlags.AddFlagSet(singlePFlagSet(key, flagDefaultValue, description))
+		})
+
+		AfterEach(func() {
+			cleanUpFile()
+		})
+
+		It("returns the source of the value with the highest priority", func() {
+			verifyEnvCreated()
+			Expect(environment.Source(key)).Should(Equal(env.SourceDefault))
+
+			config.AddPFlags(testFlags)
+			cfgFile = testFile{
+				File: env.DefaultConfigFile(),
+				content: map[string]interface{}{
+					key: fileValue,
+				},
+			}
+			verifyEnvCreated()
+			Expect(environment.Source(key)).Should(Equal(env.SourceFile))
+
+			os.Setenv(strings.ToTitle(key), envValue)
+			Expect(environment.Source(key)).Should(Equal(env.SourceEnv))
+
+			testFlags.Set(key, flagValue)
+			Expect(environment.Source(key)).Should(Equal(env.SourceFlag))
+
+			environment.Set(key, overrideValue)
+			Expect(environment.Source(key)).Should(Equal(env.SourceOverride))
+		})
+	})
+
+	Describe("Settings", func() {
+		type secrets struct {
+			Password string `mapstructure:"password"`
+			Token    string `mapstructure:"token"`
+		}
+
+		type settings struct {
+			Key     string   `mapstructure:"key"`
+			Secret  string   `mapstructure:"secret" sensitive:"true"`
+			Hosts   []string `mapstructure:"hosts"`
+			Secrets secrets  `mapstructure:"secrets" sensitive:"true"`
+		}
+
+		var defaults settings
+
+		BeforeEach(func() {
+			defaults = settings{
+				Key:    flagDefaultValue,
+				Secret: "default-secret",
+				Hosts:  []string{"host1", "host2"},
+				Secrets: secrets{
+					Password: "",
+					Token:    "default-token",
+				},
+			}
+			testFlags.AddFlagSet(generatedPFlagsSet(defaults))
+		})
+
+		AfterEach(func() {
+			cleanUpFile()
+		})
+
+		It("describes the settings with their defaults and sources", func() {
+			verifyEnvCreated()
+			Expect(testFlags.Set(key, flagValue)).ShouldNot(HaveOccurred())
+
+			Expect(env.Settings(environment, defaults)).To(Equal([]env.Setting{
+				{Key: "hosts", Value: []string{"host1", "host2"}, Default: []string{"host1", "host2"}, Source: env.SourceDefault},
+				{Key: key, Value: flagValue, Default: flagDefaultValue, Source: env.SourceFlag, Changed: true},
+				{Key: "secret", Value: env.RedactedValue, Default: env.RedactedValue, Source: env.SourceDefault, Sensitive: true},
+				{Key: "secrets.password", Value: "", Default: "", Source: env.SourceDefault, Sensitive: true},
+				{Key: "secrets.token", Value: env.RedactedValue, Default: env.RedactedValue, Source: env.SourceDefault, Sensitive: true},
+			}))
+		})
+
+		It("marks the settings differing from the defaults as changed", func() {
+			Expect(os.Setenv("SECRETS_TOKEN", "changed-token")).ShouldNot(HaveOccurred())
+			defer os.Unsetenv("SECRETS_TOKEN")
+			verifyEnvCreated()
+
+			for _, setting := range env.Settings(environment, defaults) {
+				Expect(setting.Changed).To(Equal(setting.Key == "secrets.token"), setting.Key)
+				if setting.Key == "secrets.token" {
+					Expect(setting.Source).To(Equal(env.SourceEnv))
+					Expect(setting.Value).To(Equal(env.RedactedValue))
+				}
+			}
+		})
+
+		It("redacts the sensitive settings of all environment settings", func() {
+			verifyEnvCreated()
+
+			settings := env.RedactedSettings(environment, defaults)
+			Expect(settings[key]).To(Equal(flagDefaultValue))
+			Expect(settings["secret"]).To(Equal(env.RedactedValue))
+			Expect(settings["secrets"]).To(Equal(map[string]interface{}{
+				"password": "",
+				"token":    env.RedactedValue,
+			}))
+		})
+
+		It("redacts the sensitive fields of the elements of slices of structures", func() {
+			type endpoint struct {
+				Name         string `mapstructure:"name"`
+				ClientSecret string `mapstructure:"client_secret" sensitive:"true"`
+			}
+			type endpointSettings struct {
+				Endpoints []endpoint `mapstructure:"endpoints"`
+			}
+			verifyEnvCreated()
+			environment.Set("endpoints", []interface{}{
+				map[string]interface{}{"name": "first", "client_secret": "first-secret"},
+				map[interface{}]interface{}{"name": "second", "client_secret": "second-secret"},
+			})
+			expected := []interface{}{
+				map[string]interface{}{"name": "first", "client_secret": env.RedactedValue},
+				map[string]interface{}{"name": "second", "client_secret": env.RedactedValue},
+			}
+
+			settings := env.RedactedSettings(environment, endpointSettings{})
+			Expect(settings["endpoints"]).To(Equal(expected))
+
+			described := env.Settings(environment, endpointSettings{})
+			Expect(described).To(HaveLen(1))
+			Expect(described[0].Value).To(Equal(expected))
+
+			Expect(environment.Get("endpoints").([]interface{})[0]).To(HaveKeyWithValue("client_secret", "first-secret"))
+		})
+	})
+
 	Describe("Unmarshal", func() {
 		var actual Outer
 
@@ -48,6 +48,17 @@ type FakeEnvironment struct {
 		arg1 string
 		arg2 interface{}
 	}
+	SourceStub        func(string) env.Source
+	sourceMutex       sync.RWMutex
+	sourceArgsForCall []struct {
+		arg1 string
+	}
+	sourceReturns struct {
+		result1 env.Source
+	}
+	sourceReturnsOnCall map[int]struct {
+		result1 env.Source
+	}
 	UnmarshalStub        func(interface{}) error
 	unmarshalMutex       sync.RWMutex
 	unmarshalArgsForCall []struct {
@@ -268,6 +279,66 @@ func (fake *FakeEnvironment) SetArgsForCall(i int) (string, interface{}) {
 	return argsForCall.arg1, argsForCall.arg2
 }
 
+func (fake *FakeEnvironment) Source(arg1 string) env.Source {
+	fake.sourceMutex.Lock()
+	ret, specificReturn := fake.sourceReturnsOnCall[len(fake.sourceArgsForCall)]
+	fake.sourceArgsForCall = append(fake.sourceArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("Source", []interface{}{arg1})
+	fake.sourceMutex.Unlock()
+	if fake.SourceStub != nil {
+		return fake.SourceStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.sourceReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeEnvironment) SourceCallCount() int {
+	fake.sourceMutex.RLock()
+	defer fake.sourceMutex.RUnlock()
+	return len(fake.sourceArgsForCall)
+}
+
+func (fake *FakeEnvironment) SourceCalls(stub func(string) env.Source) {
+	fake.sourceMutex.Lock()
+	defer fake.sourceMutex.Unlock()
+	fake.SourceStub = stub
+}
+
+func (fake *FakeEnvironment) SourceArgsForCall(i int) string {
+	fake.sourceMutex.RLock()
+	defer fake.sourceMutex.RUnlock()
+	argsForCall := fake.sourceArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEnvironment) SourceReturns(result1 env.Source) {
+	fake.sourceMutex.Lock()
+	defer fake.sourceMutex.Unlock()
+	fake.SourceStub = nil
+	fake.sourceReturns = struct {
+		result1 env.Source
+	}{result1}
+}
+
+func (fake *FakeEnvironment) SourceReturnsOnCall(i int, result1 env.Source) {
+	fake.sourceMutex.Lock()
+	defer fake.sourceMutex.Unlock()
+	fake.SourceStub = nil
+	if fake.sourceReturnsOnCall == nil {
+		fake.sourceReturnsOnCall = make(map[int]struct {
+			result1 env.Source
+		})
+	}
+	fake.sourceReturnsOnCall[i] = struct {
+		result1 env.Source
+	}{result1}
+}
+
 func (fake *FakeEnvironment) Unmarshal(arg1 interface{}) error {
 	fake.unmarshalMutex.Lock()
 	ret, specificReturn := fake.unmarshalReturnsOnCall[len(fake.unmarshalArgsForCall)]
@@ -339,6 +410,8 @@ func (fake *FakeEnvironment) Invocations() map[string][][]interface{} {
 	defer fake.getMutex.RUnlock()
 	fake.setMutex.RLock()
 	defer fake.setMutex.RUnlock()
+	fake.sourceMutex.RLock()
+	defer fake.sourceMutex.RUnlock()
 	fake.unmarshalMutex.RLock()
 	defer fake.unmarshalMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -46,19 +46,20 @@ func (t *descriptionTree) AddNode(tree *descriptionTree) {
 type configurationParameter struct {
 	Name         string
 	DefaultValue interface{}
+	Sensitive    bool
 }
 
 func buildParametersAndDescriptions(value interface{}) ([]configurationParameter, []string) {
 	tree := &descriptionTree{}
 	var parameters []configurationParameter
-	buildDescriptionTreeWithParameters(value, tree, "", &parameters)
+	buildDescriptionTreeWithParameters(value, tree, "", false, &parameters)
 	return parameters, buildDescriptionsFromTree(tree)
 }
 
 func buildParameters(value interface{}) []configurationParameter {
 	tree := &descriptionTree{}
 	var parameters []configurationParameter
-	buildDescriptionTreeWithParameters(value, tree, "", &parameters)
+	buildDescriptionTreeWithParameters(value, tree, "", false, &parameters)
 	return parameters
 }
 
@@ -88,7 +89,9 @@ func buildDescriptionPaths(root *descriptionTree, path []*descriptionTree) []str
 	return result
 }
 
-func buildDescriptionTreeWithParameters(value interface{}, tree *descriptionTree, buffer string, result *[]configurationParameter) {
+// buildDescriptionTreeWithParameters collects the configuration parameters of the value. Parameters of fields tagged
+// with sensitive:"true", and all parameters nested in such fields, are sensitive.
+func buildDescriptionTreeWithParameters(value interface{}, tree *descriptionTree, buffer string, sensitive bool, result *[]configurationParameter) {
 	v := reflect.ValueOf(value)
 	switch v.Kind() {
 	case reflect.Map:
@@ -97,7 +100,7 @@ func buildDescriptionTreeWithParameters(value interface{}, tree *descriptionTree
 			if isValidField(field) {
 				name := key.String()
 				buffer += name + "."
-				buildDescriptionTreeWithParameters(field, tree, buffer, result)
+				buildDescriptionTreeWithParameters(field, tree, buffer, sensitive, result)
 				buffer = buffer[0:strings.LastIndex(buffer, name)]
 			}
 		}
@@ -108,7 +111,7 @@ func buildDescriptionTreeWithParameters(value interface{}, tree *descriptionTree
 				index = 0
 			}
 			key := strings.ToLower(buffer[0:index])
-			*result = append(*result, configurationParameter{Name: key, DefaultValue: value})
+			*result = append(*result, configurationParameter{Name: key, DefaultValue: value, Sensitive: sensitive})
 			tree.Children = nil
 			return
 		}
@@ -124,8 +127,9 @@ func buildDescriptionTreeWithParameters(value interface{}, tree *descriptionTree
 				if name == "-" {
 					continue
 				}
+				fieldSensitive := sensitive || field.Tag("sensitive") == "true"
 				if name == ",squash" {
-					buildDescriptionTreeWithParameters(field.Value(), tree, buffer, result)
+					buildDescriptionTreeWithParameters(field.Value(), tree, buffer, fieldSensitive, result)
 					continue
 				}
 				description := ""
@@ -135,7 +139,7 @@ func buildDescriptionTreeWithParameters(value interface{}, tree *descriptionTree
 
 				baseTree := newDescriptionTree(description)
 				tree.AddNode(baseTree)
-				buildDescriptionTreeWithParameters(field.Value(), baseTree, buffer+name+".", result)
+				buildDescriptionTreeWithParameters(field.Value(), baseTree, buffer+name+".", fieldSensitive, result)
 			}
 		}
 	}
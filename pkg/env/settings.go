@@ -0,0 +1,234 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package env
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+)
+
+// Source describes where the value of a configuration setting is loaded from
+type Source string
+
+const (
+	// SourceDefault means that the value is the default value of the setting
+	SourceDefault Source = "default"
+	// SourceFile means that the value is loaded from the configuration file
+	SourceFile Source = "file"
+	// SourceEnv means that the value is loaded from an OS environment variable
+	SourceEnv Source = "env"
+	// SourceFlag means that the value is loaded from a command line flag
+	SourceFlag Source = "flag"
+	// SourceOverride means that the value is explicitly set in the environment
+	SourceOverride Source = "override"
+)
+
+// RedactedValue replaces the values of sensitive settings
+const RedactedValue = "<redacted>"
+
+// Setting describes the current state of a configuration setting
+type Setting struct {
+	Key       string      `json:"key"`
+	Value     interface{} `json:"value"`
+	Default   interface{} `json:"default"`
+	Source    Source      `json:"source"`
+	Changed   bool        `json:"changed"`
+	Sensitive bool        `json:"sensitive"`
+}
+
+// Settings describes the configuration settings of the value structure as loaded in the environment. The value
+// structure provides the default values of the settings. Values of fields tagged with sensitive:"true" are redacted.
+func Settings(e Environment, value interface{}) []Setting {
+	parameters := buildParameters(value)
+	result := make([]Setting, 0, len(parameters))
+	for _, parameter := range parameters {
+		current := e.Get(parameter.Name)
+		setting := Setting{
+			Key:       parameter.Name,
+			Value:     current,
+			Default:   parameter.DefaultValue,
+			Source:    e.Source(parameter.Name),
+			Changed:   !equalValues(current, parameter.DefaultValue),
+			Sensitive: parameter.Sensitive,
+		}
+		if setting.Sensitive {
+			setting.Value = redact(setting.Value)
+			setting.Default = redact(setting.Default)
+		} else {
+			setting.Value = redactElements(setting.Value, parameter.DefaultValue)
+			setting.Default = redactElements(setting.Default, parameter.DefaultValue)
+		}
+		result = append(result, setting)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// RedactedSettings returns all settings of the environment with the values of the sensitive settings of the value structure redacted
+func RedactedSettings(e Environment, value interface{}) map[string]interface{} {
+	settings := e.AllSettings()
+	for _, parameter := range buildParameters(value) {
+		if !parameter.Sensitive && elementParameters(parameter.DefaultValue) == nil {
+			continue
+		}
+		path := strings.Split(parameter.Name, ".")
+		parent := settings
+		for _, segment := range path[:len(path)-1] {
+			next, ok := parent[segment].(map[string]interface{})
+			if !ok {
+				parent = nil
+				break
+			}
+			parent = next
+		}
+		if current, found := parent[path[len(path)-1]]; found {
+			if parameter.Sensitive {
+				parent[path[len(path)-1]] = redact(current)
+			} else {
+				parent[path[len(path)-1]] = redactElements(current, parameter.DefaultValue)
+			}
+		}
+	}
+	return settings
+}
+
+// elementParameters returns the parameters of the elements of settings which are slices of structures, or nil if the
+// setting is not such a slice or its elements have no sensitive fields. Such settings are single parameters, so the
+// sensitive fields of their elements are not parameters of their own.
+func elementParameters(defaultValue interface{}) []configurationParameter {
+	t := reflect.TypeOf(defaultValue)
+	if t == nil || t.Kind() != reflect.Slice {
+		return nil
+	}
+	elementType := t.Elem()
+	for elementType.Kind() == reflect.Ptr {
+		elementType = elementType.Elem()
+	}
+	if elementType.Kind() != reflect.Struct {
+		return nil
+	}
+	var parameters []configurationParameter
+	sensitive := false
+	for _, parameter := range buildParameters(reflect.New(elementType).Elem().Interface()) {
+		if parameter.Sensitive || elementParameters(parameter.DefaultValue) != nil {
+			parameters = append(parameters, parameter)
+			sensitive = true
+		}
+	}
+	if !sensitive {
+		return nil
+	}
+	return parameters
+}
+
+// redactElements redacts the sensitive fields of the elements of a setting which is a slice of structures
+func redactElements(value, defaultValue interface{}) interface{} {
+	parameters := elementParameters(defaultValue)
+	elements := reflect.ValueOf(value)
+	if parameters == nil || elements.Kind() != reflect.Slice {
+		return value
+	}
+	redacted := make([]interface{}, 0, elements.Len())
+	for i := 0; i < elements.Len(); i++ {
+		element := elements.Index(i).Interface()
+		for _, parameter := range parameters {
+			element = redactField(element, strings.Split(parameter.Name, "."), parameter)
+		}
+		redacted = append(redacted, element)
+	}
+	return redacted
+}
+
+// redactField returns a copy of the fields with the field at the path redacted. Values which are not maps of fields,
+// such as typed structures, are redacted as a whole.
+func redactField(value interface{}, path []string, parameter configurationParameter) interface{} {
+	fields, err := cast.ToStringMapE(value)
+	if err != nil {
+		return RedactedValue
+	}
+	redacted := make(map[string]interface{}, len(fields))
+	for key, field := range fields {
+		redacted[key] = field
+	}
+	for key, field := range redacted {
+		if !strings.EqualFold(key, path[0]) {
+			continue
+		}
+		switch {
+		case len(path) > 1:
+			redacted[key] = redactField(field, path[1:], parameter)
+		case parameter.Sensitive:
+			redacted[key] = redact(field)
+		default:
+			redacted[key] = redactElements(field, parameter.DefaultValue)
+		}
+	}
+	return redacted
+}
+
+func redact(value interface{}) interface{} {
+	if value == nil || reflect.ValueOf(value).IsZero() {
+		return value
+	}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Slice && v.Len() == 0 {
+		return value
+	}
+	return RedactedValue
+}
+
+// equalValues compares the value loaded in the environment with the default value after converting it to the
+// type of the default value, as values loaded from flags, OS environment variables and files are not typed
+func equalValues(value, defaultValue interface{}) bool {
+	var err error
+	switch defaultValue.(type) {
+	case string:
+		value, err = cast.ToStringE(value)
+	case bool:
+		value, err = cast.ToBoolE(value)
+	case int:
+		value, err = cast.ToIntE(value)
+	case int64:
+		value, err = cast.ToInt64E(value)
+	case float64:
+		value, err = cast.ToFloat64E(value)
+	case time.Duration:
+		value, err = cast.ToDurationE(value)
+	case []string:
+		var values []string
+		if s, ok := value.(string); ok {
+			if len(s) != 0 {
+				values = strings.Split(s, ",")
+			}
+		} else if values, err = cast.ToStringSliceE(value); err != nil {
+			return false
+		}
+		if len(values) == 0 && len(defaultValue.([]string)) == 0 {
+			return true
+		}
+		value = values
+	}
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(value, defaultValue)
+}
@@ -31,7 +31,7 @@ import (
 type Settings struct {
 	Timeout               time.Duration `mapstructure:"timeout" description:"timeout specifies a time limit for the request. The timeout includes connection time, any redirects, and reading the response body"`
 	TLSHandshakeTimeout   time.Duration `mapstructure:"tls_handshake_timeout"`
-	ServerCertificateKey  string        `mapstructure:"server_certificate_key"`
+	ServerCertificateKey  string        `mapstructure:"server_certificate_key" sensitive:"true"`
 	ServerCertificate     string        `mapstructure:"server_certificate"`
 	IdleConnTimeout       time.Duration `mapstructure:"idle_conn_timeout"`
 	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
@@ -9,8 +9,12 @@ import (
 	"github.com/tidwall/gjson"
 )
 
+// MappedTenantClaim is the claim in which authenticators store the tenant of tokens of issuers with a tenant claim mapping
+const MappedTenantClaim = "sm_mapped_tenant"
+
 // ExtractTenantFromTokenWrapperFunc returns function which extracts tenant from JWT token. The specified tenantTokenClaim
-// represents the key in the token that contains the tenant identifier value
+// represents the key in the token that contains the tenant identifier value. The tenant mapped by the authenticator of the
+// token issuer takes precedence.
 func ExtractTenantFromTokenWrapperFunc(tenantTokenClaim string) func(request *web.Request) (string, error) {
 	return func(request *web.Request) (string, error) {
 		if len(tenantTokenClaim) == 0 {
@@ -36,6 +40,11 @@ func ExtractTenantFromTokenWrapperFunc(tenantTokenClaim string) func(request *we
 			return "", fmt.Errorf("could not unmarshal claims from token: %s", err)
 		}
 
+		if mappedTenant := gjson.GetBytes([]byte(userData), MappedTenantClaim).String(); len(mappedTenant) != 0 {
+			logger.Infof("Successfully set tenant ID to mapped tenant %s", mappedTenant)
+			return mappedTenant, nil
+		}
+
 		delimiterClaimValue := gjson.GetBytes([]byte(userData), tenantTokenClaim).String()
 		if len(delimiterClaimValue) == 0 {
 			return "", fmt.Errorf("invalid token: could not find delimiter %s in token claims", tenantTokenClaim)
@@ -120,5 +120,23 @@ var _ = Describe("ExtractTenantFromToken", func() {
 				Expect(extractedTenant).To(Equal(tenant))
 			})
 		})
+
+		When("the tenant is mapped by the authenticator", func() {
+			It("should extract the mapped tenant", func() {
+				fakeRequest.Request = fakeRequest.Request.WithContext(web.ContextWithUser(ctx, &web.UserContext{
+					Data: func(data interface{}) error {
+						return json.Unmarshal([]byte(fmt.Sprintf(`{"%s":"%s","%s":"mappedTenant"}`, tenantTokenClaim, tenant, multitenancy.MappedTenantClaim)), data)
+					},
+					AuthenticationType: web.Bearer,
+					Name:               "test-user",
+					AccessLevel:        web.TenantAccess,
+				}))
+
+				extractorFunc := multitenancy.ExtractTenantFromTokenWrapperFunc(tenantTokenClaim)
+				extractedTenant, err := extractorFunc(fakeRequest)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(extractedTenant).To(Equal("mappedTenant"))
+			})
+		})
 	})
 })
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/util/slice"
+)
+
+// MatchesLabels evaluates label criteria against a set of labels without querying the storage. As in the storage, a
+// criterion matches if the labels contain its key with at least one value satisfying the operator. Criteria of other
+// types never match.
+func MatchesLabels(criteria []Criterion, labels map[string][]string) bool {
+	for _, criterion := range criteria {
+		if criterion.Type != LabelQuery {
+			return false
+		}
+		matches := false
+		for _, value := range labels[criterion.LeftOp] {
+			if matchesValue(criterion, value) {
+				matches = true
+				break
+			}
+		}
+		if !matches {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesValue(criterion Criterion, value string) bool {
+	switch criterion.Operator {
+	case EqualsOperator, EqualsOrNilOperator:
+		return value == criterion.RightOp[0]
+	case NotEqualsOperator:
+		return value != criterion.RightOp[0]
+	case ContainsOperator:
+		return strings.Contains(value, criterion.RightOp[0])
+	case InOperator:
+		return slice.StringsAnyEquals(criterion.RightOp, value)
+	case NotInOperator:
+		return !slice.StringsAnyEquals(criterion.RightOp, value)
+	case GreaterThanOperator:
+		return compareValues(value, criterion.RightOp[0]) > 0
+	case GreaterThanOrEqualOperator:
+		return compareValues(value, criterion.RightOp[0]) >= 0
+	case LessThanOperator:
+		return compareValues(value, criterion.RightOp[0]) < 0
+	case LessThanOrEqualOperator:
+		return compareValues(value, criterion.RightOp[0]) <= 0
+	default:
+		return false
+	}
+}
+
+// compareValues compares the values as numbers if both are numeric and as strings otherwise
+func compareValues(left, right string) int {
+	leftNumber, leftErr := strconv.ParseFloat(left, 64)
+	rightNumber, rightErr := strconv.ParseFloat(right, 64)
+	if leftErr == nil && rightErr == nil {
+		switch {
+		case leftNumber < rightNumber:
+			return -1
+		case leftNumber > rightNumber:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return strings.Compare(left, right)
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	. "github.com/Peripli/service-manager/pkg/query"
+)
+
+var _ = Describe("Label matching", func() {
+	labels := map[string][]string{
+		"region": {"eu"},
+		"tier":   {"2"},
+		"zones":  {"a", "b"},
+	}
+
+	DescribeTable("MatchesLabels",
+		func(expression string, expected bool) {
+			criteria, err := Parse(LabelQuery, expression)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(MatchesLabels(criteria, labels)).To(Equal(expected))
+		},
+		Entry("equals", "region eq 'eu'", true),
+		Entry("equals another value", "region eq 'us'", false),
+		Entry("missing label", "type eq 'kubernetes'", false),
+		Entry("conjunction", "region eq 'eu' and tier eq '2'", true),
+		Entry("conjunction with a missing label", "region eq 'eu' and type eq 'kubernetes'", false),
+		Entry("not equals", "region ne 'us'", true),
+		Entry("in", "region in ('us', 'eu')", true),
+		Entry("not in", "region notin ('us', 'eu')", false),
+		Entry("multiple values", "zones eq 'b'", true),
+		Entry("numeric comparison", "tier gt 10", false),
+		Entry("contains", "region contains 'e'", true),
+	)
+
+	It("does not match field criteria", func() {
+		Expect(MatchesLabels([]Criterion{ByField(EqualsOperator, "region", "eu")}, labels)).To(BeFalse())
+	})
+})
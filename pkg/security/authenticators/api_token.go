@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package authenticators
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/multitenancy"
+	"github.com/Peripli/service-manager/pkg/query"
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// LastUsedRecordInterval is the minimum time between two updates of the last use of an API token
+const LastUsedRecordInterval = time.Minute
+
+// APIToken authenticates bearer tokens which are API tokens issued by the Service Manager
+type APIToken struct {
+	Repository storage.Repository
+}
+
+// Authenticate resolves the API token of the request to the user which created it, restricted to the scopes and
+// the tenant of the token. Bearer tokens which are not API tokens are left to the other authenticators.
+func (a *APIToken) Authenticate(request *web.Request) (*web.UserContext, httpsec.Decision, error) {
+	token, ok := bearerToken(request)
+	if !ok || !strings.HasPrefix(token, types.APITokenPrefix) {
+		return nil, httpsec.Abstain, nil
+	}
+
+	ctx := request.Context()
+	log.C(ctx).Debugf("Attempting to authenticate API token")
+
+	byHash := query.ByField(query.EqualsOperator, "token_hash", types.HashAPIToken(token))
+	obj, err := a.Repository.Get(ctx, types.APITokenType, byHash)
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return nil, httpsec.Deny, fmt.Errorf("provided API token is invalid")
+		}
+		return nil, httpsec.Abstain, fmt.Errorf("could not get API token from storage: %s", err)
+	}
+	apiToken := obj.(*types.APIToken)
+
+	now := time.Now().UTC()
+	if apiToken.Expired(now) {
+		return nil, httpsec.Deny, fmt.Errorf("provided API token expired at %s", apiToken.ExpiresAt)
+	}
+
+	user, err := apiTokenUser(apiToken)
+	if err != nil {
+		return nil, httpsec.Abstain, err
+	}
+
+	if apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) >= LastUsedRecordInterval {
+		apiToken.LastUsedAt = &now
+		if _, err := a.Repository.Update(ctx, apiToken, nil, storage.ByVersion(apiToken.GetUpdatedAt())); err != nil {
+			log.C(ctx).WithError(err).Warnf("Could not record last use of API token %s", apiToken.ID)
+		}
+	}
+
+	return user, httpsec.Allow, nil
+}
+
+func apiTokenUser(apiToken *types.APIToken) (*web.UserContext, error) {
+	name := apiToken.CreatedBy
+	if len(name) == 0 {
+		name = apiToken.Name
+	}
+	scopes := apiToken.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	claims := map[string]interface{}{
+		"sub":          name,
+		"scope":        scopes,
+		"api_token_id": apiToken.ID,
+	}
+	if apiToken.ExpiresAt != nil {
+		claims["exp"] = apiToken.ExpiresAt.Unix()
+	}
+	if len(apiToken.Tenant) != 0 {
+		claims[multitenancy.MappedTenantClaim] = apiToken.Tenant
+	}
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal claims of API token %s: %s", apiToken.ID, err)
+	}
+
+	return &web.UserContext{
+		Data: func(v interface{}) error {
+			return json.Unmarshal(data, v)
+		},
+		AuthenticationType: web.Bearer,
+		Name:               name,
+	}, nil
+}
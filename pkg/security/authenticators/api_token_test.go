@@ -0,0 +1,155 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package authenticators_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/multitenancy"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/security/authenticators"
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("API Token Authenticator", func() {
+	var (
+		request        *http.Request
+		fakeRepository *storagefakes.FakeStorage
+		authenticator  *authenticators.APIToken
+		apiToken       *types.APIToken
+	)
+
+	BeforeEach(func() {
+		expiresAt := time.Now().Add(time.Hour)
+		apiToken = &types.APIToken{
+			Base:      types.Base{ID: "token-id"},
+			Name:      "automation",
+			Scopes:    []string{"sm.read"},
+			Tenant:    "tenant",
+			ExpiresAt: &expiresAt,
+			CreatedBy: "user",
+		}
+		Expect(apiToken.GenerateToken()).To(Succeed())
+
+		fakeRepository = &storagefakes.FakeStorage{}
+		fakeRepository.GetStub = func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.Object, error) {
+			for _, criterion := range criteria {
+				if criterion.LeftOp == "token_hash" && criterion.RightOp[0] == apiToken.TokenHash {
+					return apiToken, nil
+				}
+			}
+			return nil, util.ErrNotFoundInStorage
+		}
+		authenticator = &authenticators.APIToken{Repository: fakeRepository}
+
+		var err error
+		request, err = http.NewRequest(http.MethodGet, "https://example.com/v1/service_instances", nil)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	authenticate := func(token string) (*web.UserContext, httpsec.Decision, error) {
+		request.Header.Set("Authorization", "Bearer "+token)
+		return authenticator.Authenticate(&web.Request{Request: request})
+	}
+
+	It("abstains for requests without bearer tokens", func() {
+		user, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(user).To(BeNil())
+		Expect(decision).To(Equal(httpsec.Abstain))
+	})
+
+	It("abstains for bearer tokens which are not API tokens", func() {
+		user, decision, err := authenticate("eyJhbGciOiJSUzI1NiJ9.e30.signature")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(user).To(BeNil())
+		Expect(decision).To(Equal(httpsec.Abstain))
+		Expect(fakeRepository.GetCallCount()).To(Equal(0))
+	})
+
+	It("denies unknown API tokens", func() {
+		user, decision, err := authenticate(types.APITokenPrefix + "unknown")
+		Expect(err).To(HaveOccurred())
+		Expect(user).To(BeNil())
+		Expect(decision).To(Equal(httpsec.Deny))
+	})
+
+	It("abstains when the API token cannot be read", func() {
+		fakeRepository.GetStub = nil
+		fakeRepository.GetReturns(nil, errors.New("storage error"))
+		_, decision, err := authenticate(apiToken.Token)
+		Expect(err).To(HaveOccurred())
+		Expect(decision).To(Equal(httpsec.Abstain))
+	})
+
+	It("denies expired API tokens", func() {
+		expiredAt := time.Now().Add(-time.Minute)
+		apiToken.ExpiresAt = &expiredAt
+		user, decision, err := authenticate(apiToken.Token)
+		Expect(err).To(HaveOccurred())
+		Expect(user).To(BeNil())
+		Expect(decision).To(Equal(httpsec.Deny))
+	})
+
+	It("resolves valid API tokens to the user with the scopes and tenant of the token", func() {
+		user, decision, err := authenticate(apiToken.Token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decision).To(Equal(httpsec.Allow))
+		Expect(user.Name).To(Equal("user"))
+		Expect(user.AuthenticationType).To(Equal(web.Bearer))
+
+		claims := map[string]interface{}{}
+		Expect(user.Data(&claims)).To(Succeed())
+		Expect(claims["scope"]).To(ConsistOf("sm.read"))
+		Expect(claims[multitenancy.MappedTenantClaim]).To(Equal("tenant"))
+		Expect(claims["api_token_id"]).To(Equal("token-id"))
+		Expect(claims["exp"]).To(BeEquivalentTo(apiToken.ExpiresAt.Unix()))
+	})
+
+	It("records the last use of the API token", func() {
+		_, _, err := authenticate(apiToken.Token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fakeRepository.UpdateCallCount()).To(Equal(1))
+		_, obj, _, _ := fakeRepository.UpdateArgsForCall(0)
+		Expect(obj.(*types.APIToken).LastUsedAt).ToNot(BeNil())
+	})
+
+	It("does not record the last use again within the record interval", func() {
+		lastUsedAt := time.Now().UTC().Add(-authenticators.LastUsedRecordInterval / 2)
+		apiToken.LastUsedAt = &lastUsedAt
+		_, decision, err := authenticate(apiToken.Token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decision).To(Equal(httpsec.Allow))
+		Expect(fakeRepository.UpdateCallCount()).To(Equal(0))
+	})
+
+	It("allows API tokens when the last use cannot be recorded", func() {
+		fakeRepository.UpdateReturns(nil, errors.New("concurrent modification"))
+		_, decision, err := authenticate(apiToken.Token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decision).To(Equal(httpsec.Allow))
+	})
+})
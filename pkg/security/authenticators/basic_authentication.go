@@ -17,8 +17,12 @@
 package authenticators
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
+	"time"
+
 	"github.com/Peripli/service-manager/api/osb"
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/query"
@@ -50,8 +54,27 @@ func (a *Basic) Authenticate(request *web.Request) (*web.UserContext, httpsec.De
 	return a.BasicAuthenticatorFunc(request, a.Repository, username, password)
 }
 
-//BasicPlatformAuthenticator attempts to authenticate basic auth requests with provided platform credentials
+// PlatformLockout defines when the credentials of a platform are temporarily locked after failed authentication attempts
+type PlatformLockout struct {
+	// MaxFailedAttempts is the number of consecutive failed attempts after which the credentials are locked. If 0, the credentials are never locked
+	MaxFailedAttempts int
+	// Duration is the time for which the credentials are locked
+	Duration time.Duration
+}
+
+//BasicPlatformAuthenticator attempts to authenticate basic auth requests with provided platform credentials without locking them after failed attempts
 func BasicPlatformAuthenticator(request *web.Request, repository storage.Repository, username, password string) (*web.UserContext, httpsec.Decision, error) {
+	return authenticatePlatform(request, repository, username, password, PlatformLockout{})
+}
+
+// NewBasicPlatformAuthenticator returns a BasicAuthenticatorFunc which authenticates with platform credentials and locks them after failed attempts
+func NewBasicPlatformAuthenticator(lockout PlatformLockout) BasicAuthenticatorFunc {
+	return func(request *web.Request, repository storage.Repository, username, password string) (*web.UserContext, httpsec.Decision, error) {
+		return authenticatePlatform(request, repository, username, password, lockout)
+	}
+}
+
+func authenticatePlatform(request *web.Request, repository storage.Repository, username, password string, lockout PlatformLockout) (*web.UserContext, httpsec.Decision, error) {
 	ctx := request.Context()
 	log.C(ctx).Debugf("Attempting to authenticate platform credentials")
 
@@ -77,20 +100,100 @@ func BasicPlatformAuthenticator(request *web.Request, repository storage.Reposit
 	}
 
 	platform := platformList.ItemAt(0).(*types.Platform)
-	platformPassword := platform.Credentials.Basic.Password
+	now := time.Now().UTC()
+	if platform.Locked(now) {
+		log.C(ctx).Infof("Credentials of platform %s are locked until %s", platform.ID, platform.LockedUntil)
+		return nil, httpsec.Deny, fmt.Errorf("provided credentials are temporarily locked")
+	}
+
+	platformPassword, passwordHash := platform.Credentials.Basic.Password, platform.PasswordHash
 	if useOldCredentials {
-		platformPassword = platform.OldCredentials.Basic.Password
+		platformPassword, passwordHash = platform.OldCredentials.Basic.Password, platform.OldPasswordHash
 	}
 
-	if platformPassword != password {
+	if !passwordMatches(platformPassword, passwordHash, password) {
+		if lockout.MaxFailedAttempts > 0 {
+			recordFailedAttempt(ctx, repository, platform, lockout, now)
+		}
 		return nil, httpsec.Deny, fmt.Errorf("provided credentials are invalid")
 	}
 
-	return buildResponse(username, platform)
+	changed := platform.FailedLoginAttempts != 0 || platform.LockedUntil != nil
+	platform.FailedLoginAttempts = 0
+	platform.LockedUntil = nil
+	if platform.HasPlainPasswords() {
+		log.C(ctx).Infof("Migrating credentials of platform %s to password hashes", platform.ID)
+		if err := platform.HashCredentials(); err != nil {
+			log.C(ctx).WithError(err).Errorf("Could not hash credentials of platform %s", platform.ID)
+		} else {
+			changed = true
+		}
+	}
+
+	// the user data is built before the update, as storing the platform encrypts its credentials in place
+	user, decision, err := buildResponse(username, platform)
+	if err == nil && changed {
+		updatePlatform(ctx, repository, platform)
+	}
+	return user, decision, err
+}
+
+// passwordMatches compares the password with the password hash, or with the stored password of credentials which are not hashed yet
+func passwordMatches(storedPassword, passwordHash, password string) bool {
+	if len(passwordHash) != 0 {
+		return bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) == nil
+	}
+	return len(storedPassword) != 0 && subtle.ConstantTimeCompare([]byte(storedPassword), []byte(password)) == 1
+}
+
+// recordFailedAttempt counts the failed attempt and locks the credentials once the max failed attempts are reached. The
+// counter is incremented by a single statement in the database, so that concurrent attempts are all counted.
+func recordFailedAttempt(ctx context.Context, repository storage.Repository, platform *types.Platform, lockout PlatformLockout, now time.Time) {
+	// the statement is a write, so it must not be served by a read replica
+	ctx = storage.ContextWithWriteTracking(ctx)
+	storage.RecordWrite(ctx)
+
+	lockedUntil := now.Add(lockout.Duration)
+	result, err := repository.QueryForList(ctx, types.PlatformType, storage.QueryForPlatformFailedLoginAttempt, map[string]interface{}{
+		"id":                  platform.ID,
+		"max_failed_attempts": lockout.MaxFailedAttempts,
+		"locked_until":        lockedUntil,
+	})
+	if err != nil {
+		log.C(ctx).WithError(err).Warnf("Could not record failed authentication attempt for platform %s", platform.ID)
+		return
+	}
+	if result.Len() == 0 {
+		return
+	}
+	// the counter is reset when the credentials get locked
+	if result.ItemAt(0).(*types.Platform).FailedLoginAttempts == 0 {
+		log.C(ctx).Warnf("Locking credentials of platform %s until %s after %d failed authentication attempts", platform.ID, lockedUntil, lockout.MaxFailedAttempts)
+	}
+}
+
+// updatePlatform stores the credentials state of the platform unless the platform was modified in the meantime
+func updatePlatform(ctx context.Context, repository storage.Repository, platform *types.Platform) {
+	ctx = web.ContextWithGeneratePlatformCredentialsFlag(ctx, false)
+	if _, err := repository.Update(ctx, platform, nil, storage.ByVersion(platform.GetUpdatedAt())); err != nil {
+		log.C(ctx).WithError(err).Warnf("Could not update credentials state of platform %s", platform.ID)
+	}
 }
 
 //BasicOSBAuthenticator attempts to authenticate basic auth requests with provided broker platform credentials
 func BasicOSBAuthenticator(request *web.Request, repository storage.Repository, username, password string) (*web.UserContext, httpsec.Decision, error) {
+	return authenticateOSB(request, repository, username, password, PlatformLockout{})
+}
+
+// NewBasicOSBAuthenticator returns a BasicAuthenticatorFunc which authenticates with broker platform credentials and falls back
+// to platform credentials, which are locked after failed attempts
+func NewBasicOSBAuthenticator(lockout PlatformLockout) BasicAuthenticatorFunc {
+	return func(request *web.Request, repository storage.Repository, username, password string) (*web.UserContext, httpsec.Decision, error) {
+		return authenticateOSB(request, repository, username, password, lockout)
+	}
+}
+
+func authenticateOSB(request *web.Request, repository storage.Repository, username, password string, lockout PlatformLockout) (*web.UserContext, httpsec.Decision, error) {
 	ctx := request.Context()
 
 	brokerID, ok := request.PathParams[osb.BrokerIDPathParam]
@@ -119,7 +222,7 @@ func BasicOSBAuthenticator(request *web.Request, repository storage.Repository,
 
 		if credentialsList.Len() != 1 {
 			log.C(ctx).Debugf("Authenticating broker platform credentials failed - will try to fallback to platform credentials authentication")
-			return BasicPlatformAuthenticator(request, repository, username, password)
+			return authenticatePlatform(request, repository, username, password, lockout)
 		}
 
 		useOldCredentials = true
@@ -18,12 +18,15 @@ package authenticators_test
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/Peripli/service-manager/api/osb"
 	"github.com/Peripli/service-manager/pkg/web"
 	"golang.org/x/crypto/bcrypt"
 	"net/http"
+	"time"
 
+	"github.com/Peripli/service-manager/storage"
 	"github.com/Peripli/service-manager/storage/storagefakes"
 
 	"github.com/Peripli/service-manager/pkg/security/authenticators"
@@ -200,6 +203,122 @@ var _ = Describe("Basic Authenticator", func() {
 						Expect(user).To(Not(BeNil()))
 						Expect(decision).To(Equal(httpsec.Allow))
 					})
+
+					It("Should migrate the password to a password hash", func() {
+						user, _, err := authenticator.Authenticate(&web.Request{Request: request})
+						Expect(err).ToNot(HaveOccurred())
+
+						Expect(fakeRepository.UpdateCallCount()).To(Equal(1))
+						_, obj, _, _ := fakeRepository.UpdateArgsForCall(0)
+						platform := obj.(*types.Platform)
+						Expect(platform.Credentials.Basic.Password).To(BeEmpty())
+						Expect(bcrypt.CompareHashAndPassword([]byte(platform.PasswordHash), []byte(password))).To(Succeed())
+
+						userPlatform := &types.Platform{}
+						Expect(user.Data(userPlatform)).To(Succeed())
+						Expect(userPlatform.Credentials.Basic.Password).To(BeEmpty())
+						Expect(userPlatform.PasswordHash).To(BeEmpty())
+					})
+				})
+
+				Context("When passwords are hashed", func() {
+					var platform *types.Platform
+
+					BeforeEach(func() {
+						passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+						Expect(err).ToNot(HaveOccurred())
+						platform = &types.Platform{
+							Base: types.Base{
+								ID: "id1",
+							},
+							Credentials: &types.Credentials{
+								Basic: &types.Basic{
+									Username: "username",
+								},
+							},
+							PasswordHash: string(passwordHash),
+						}
+						fakeRepository.ListReturns(&types.Platforms{Platforms: []*types.Platform{platform}}, nil)
+						authenticator.BasicAuthenticatorFunc = authenticators.NewBasicPlatformAuthenticator(authenticators.PlatformLockout{
+							MaxFailedAttempts: 2,
+							Duration:          time.Minute,
+						})
+					})
+
+					It("Should allow without updating the platform", func() {
+						user, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+						Expect(err).ToNot(HaveOccurred())
+						Expect(user).To(Not(BeNil()))
+						Expect(decision).To(Equal(httpsec.Allow))
+						Expect(fakeRepository.UpdateCallCount()).To(Equal(0))
+					})
+
+					Context("When the password does not match", func() {
+						BeforeEach(func() {
+							request.SetBasicAuth(user, "not-matching-password")
+						})
+
+						It("Should deny and count the failed attempt in the database", func() {
+							fakeRepository.QueryForListReturns(&types.Platforms{Platforms: []*types.Platform{{FailedLoginAttempts: 1}}}, nil)
+
+							_, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+							Expect(err).To(HaveOccurred())
+							Expect(decision).To(Equal(httpsec.Deny))
+
+							Expect(fakeRepository.UpdateCallCount()).To(Equal(0))
+							Expect(fakeRepository.QueryForListCallCount()).To(Equal(1))
+							ctx, objectType, queryName, params := fakeRepository.QueryForListArgsForCall(0)
+							Expect(storage.HasWrites(ctx)).To(BeTrue())
+							Expect(objectType).To(Equal(types.PlatformType))
+							Expect(queryName).To(Equal(storage.QueryForPlatformFailedLoginAttempt))
+							Expect(params["id"]).To(Equal(platform.ID))
+							Expect(params["max_failed_attempts"]).To(Equal(2))
+							Expect(params["locked_until"]).To(BeTemporally("~", time.Now().Add(time.Minute), time.Second))
+						})
+
+						It("Should deny when the failed attempt cannot be recorded", func() {
+							fakeRepository.QueryForListReturns(nil, errors.New("connection refused"))
+
+							user, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+							Expect(err).To(HaveOccurred())
+							Expect(user).To(BeNil())
+							Expect(decision).To(Equal(httpsec.Deny))
+						})
+					})
+
+					Context("When the credentials are locked", func() {
+						BeforeEach(func() {
+							lockedUntil := time.Now().Add(time.Minute)
+							platform.LockedUntil = &lockedUntil
+						})
+
+						It("Should deny even if the password matches", func() {
+							user, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+							Expect(err).To(HaveOccurred())
+							Expect(user).To(BeNil())
+							Expect(decision).To(Equal(httpsec.Deny))
+							Expect(fakeRepository.UpdateCallCount()).To(Equal(0))
+						})
+					})
+
+					Context("When the lock has expired", func() {
+						BeforeEach(func() {
+							lockedUntil := time.Now().Add(-time.Minute)
+							platform.LockedUntil = &lockedUntil
+							platform.FailedLoginAttempts = 1
+						})
+
+						It("Should allow and reset the lockout state", func() {
+							_, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+							Expect(err).ToNot(HaveOccurred())
+							Expect(decision).To(Equal(httpsec.Allow))
+
+							Expect(fakeRepository.UpdateCallCount()).To(Equal(1))
+							_, obj, _, _ := fakeRepository.UpdateArgsForCall(0)
+							Expect(obj.(*types.Platform).FailedLoginAttempts).To(Equal(0))
+							Expect(obj.(*types.Platform).LockedUntil).To(BeNil())
+						})
+					})
 				})
 			})
 
@@ -0,0 +1,312 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticators
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const (
+	// DefaultIntrospectionTimeout is the timeout of introspection requests if none is configured
+	DefaultIntrospectionTimeout = 10 * time.Second
+	// DefaultIntrospectionCacheSize is the maximum number of cached introspection results if none is configured
+	DefaultIntrospectionCacheSize = 10000
+	// DefaultIntrospectionCacheCleanupInterval is the interval in which expired introspection results are evicted if none is configured
+	DefaultIntrospectionCacheCleanupInterval = time.Minute
+)
+
+// IntrospectionOptions is the configuration used to construct a new token introspection authenticator
+type IntrospectionOptions struct {
+	// URL is the token introspection endpoint of the authorization server
+	URL string
+
+	// ClientID is the id of the oauth client with which the introspection endpoint is called
+	ClientID string
+
+	// ClientSecret is the secret of the oauth client with which the introspection endpoint is called
+	ClientSecret string
+
+	// Timeout is the timeout of introspection requests. If zero, DefaultIntrospectionTimeout is used
+	Timeout time.Duration
+
+	// CacheSize is the maximum number of cached introspection results. If zero, DefaultIntrospectionCacheSize is used
+	CacheSize int
+
+	// CacheCleanupInterval is the interval in which expired introspection results are evicted. If zero,
+	// DefaultIntrospectionCacheCleanupInterval is used
+	CacheCleanupInterval time.Duration
+
+	// DoRequestFunc is the function used to call the introspection endpoint. If one is not provided, an http.Client with the timeout is used
+	DoRequestFunc util.DoRequestFunc
+
+	// ClaimMapping defines the claims of the introspection response holding the user name, tenant and scopes
+	ClaimMapping ClaimMapping
+}
+
+// IntrospectionAuthenticator authenticates opaque bearer tokens with an OAuth 2.0 token introspection endpoint (RFC 7662).
+// Active tokens are cached until they expire, up to the configured cache size.
+type IntrospectionAuthenticator struct {
+	options   IntrospectionOptions
+	doRequest util.DoRequestFunc
+
+	mutex sync.Mutex
+	cache map[string]*introspectedToken
+}
+
+type introspectedToken struct {
+	name      string
+	claims    []byte
+	expiresAt time.Time
+}
+
+// introspectionClaims implements httpsec.TokenData for the claims of an introspection response
+type introspectionClaims []byte
+
+func (c introspectionClaims) Claims(v interface{}) error {
+	return json.Unmarshal(c, v)
+}
+
+// NewIntrospectionAuthenticator returns a new token introspection authenticator or an error if one couldn't be configured.
+// Expired introspection results are evicted from the cache until the context is done.
+func NewIntrospectionAuthenticator(ctx context.Context, options IntrospectionOptions) (*IntrospectionAuthenticator, error) {
+	if _, err := url.ParseRequestURI(options.URL); err != nil {
+		return nil, fmt.Errorf("invalid introspection URL %s: %s", options.URL, err)
+	}
+	if options.Timeout == 0 {
+		options.Timeout = DefaultIntrospectionTimeout
+	}
+	if options.CacheSize == 0 {
+		options.CacheSize = DefaultIntrospectionCacheSize
+	}
+	if options.CacheCleanupInterval == 0 {
+		options.CacheCleanupInterval = DefaultIntrospectionCacheCleanupInterval
+	}
+	doRequest := options.DoRequestFunc
+	if doRequest == nil {
+		doRequest = (&http.Client{Timeout: options.Timeout}).Do
+	}
+	authenticator := &IntrospectionAuthenticator{
+		options:   options,
+		doRequest: doRequest,
+		cache:     make(map[string]*introspectedToken),
+	}
+	go authenticator.evictExpired(ctx)
+	return authenticator, nil
+}
+
+// Authenticate introspects the bearer token of the request. Active tokens are allowed with the claims of the introspection
+// response, in which the scope is an array and the client_id is also provided as cid. Inactive tokens are denied.
+func (a *IntrospectionAuthenticator) Authenticate(request *web.Request) (*web.UserContext, httpsec.Decision, error) {
+	token, ok := bearerToken(request)
+	if !ok {
+		return nil, httpsec.Abstain, nil
+	}
+	if token == "" {
+		return nil, httpsec.Deny, nil
+	}
+
+	ctx := request.Context()
+	now := time.Now()
+	key := tokenKey(token)
+	if cached := a.cached(key, now); cached != nil {
+		log.C(ctx).Debugf("Using cached introspection result of bearer token of user %s", cached.name)
+		return introspectedUser(cached), httpsec.Allow, nil
+	}
+
+	raw, err := a.introspect(request, token)
+	if err != nil {
+		return nil, httpsec.Abstain, err
+	}
+
+	var response struct {
+		Active   bool        `json:"active"`
+		Scope    interface{} `json:"scope"`
+		ClientID string      `json:"client_id"`
+		Username string      `json:"username"`
+		Subject  string      `json:"sub"`
+		Expiry   int64       `json:"exp"`
+	}
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return nil, httpsec.Abstain, fmt.Errorf("could not decode introspection response: %s", err)
+	}
+	if !response.Active {
+		return nil, httpsec.Deny, errors.New("bearer token is not active")
+	}
+	var expiresAt time.Time
+	if response.Expiry != 0 {
+		expiresAt = time.Unix(response.Expiry, 0)
+		if !now.Before(expiresAt) {
+			return nil, httpsec.Deny, errors.New("bearer token is expired")
+		}
+	}
+
+	claims := make(map[string]interface{})
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return nil, httpsec.Abstain, fmt.Errorf("could not decode introspection response: %s", err)
+	}
+	claims["scope"] = scopes(response.Scope)
+	if len(response.ClientID) != 0 {
+		claims["cid"] = response.ClientID
+	}
+	normalized, err := json.Marshal(claims)
+	if err != nil {
+		return nil, httpsec.Abstain, err
+	}
+
+	name := response.Username
+	if len(name) == 0 {
+		name = response.Subject
+	}
+	if len(name) == 0 {
+		name = response.ClientID
+	}
+	user := &web.UserContext{
+		Data:               introspectionClaims(normalized).Claims,
+		AuthenticationType: web.Bearer,
+		Name:               name,
+		AccessLevel:        web.NoAccess,
+	}
+	if err := a.options.ClaimMapping.apply(introspectionClaims(normalized), user); err != nil {
+		return nil, httpsec.Deny, err
+	}
+
+	if !expiresAt.IsZero() {
+		var mapped json.RawMessage
+		if err := user.Data(&mapped); err != nil {
+			return nil, httpsec.Abstain, err
+		}
+		a.store(key, &introspectedToken{name: user.Name, claims: mapped, expiresAt: expiresAt})
+	}
+	return user, httpsec.Allow, nil
+}
+
+func (a *IntrospectionAuthenticator) introspect(request *web.Request, token string) ([]byte, error) {
+	form := url.Values{}
+	form.Set("token", token)
+	form.Set("token_type_hint", "access_token")
+
+	introspectionRequest, err := http.NewRequest(http.MethodPost, a.options.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	introspectionRequest.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	introspectionRequest.Header.Set("Accept", "application/json")
+	if len(a.options.ClientID) != 0 {
+		introspectionRequest.SetBasicAuth(url.QueryEscape(a.options.ClientID), url.QueryEscape(a.options.ClientSecret))
+	}
+
+	response, err := a.doRequest(introspectionRequest.WithContext(request.Context()))
+	if err != nil {
+		return nil, fmt.Errorf("could not introspect bearer token: %s", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not introspect bearer token: %s", util.HandleResponseError(response))
+	}
+	return util.BodyToBytes(response.Body)
+}
+
+func (a *IntrospectionAuthenticator) cached(key string, now time.Time) *introspectedToken {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	cached, found := a.cache[key]
+	if !found {
+		return nil
+	}
+	if !now.Before(cached.expiresAt) {
+		delete(a.cache, key)
+		return nil
+	}
+	return cached
+}
+
+// store caches the introspection result. If the cache is full, an arbitrary result is evicted to make room for it.
+func (a *IntrospectionAuthenticator) store(key string, token *introspectedToken) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if _, found := a.cache[key]; !found && len(a.cache) >= a.options.CacheSize {
+		for k := range a.cache {
+			delete(a.cache, k)
+			break
+		}
+	}
+	a.cache[key] = token
+}
+
+func (a *IntrospectionAuthenticator) evictExpired(ctx context.Context) {
+	ticker := time.NewTicker(a.options.CacheCleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			a.mutex.Lock()
+			for key, cached := range a.cache {
+				if !now.Before(cached.expiresAt) {
+					delete(a.cache, key)
+				}
+			}
+			a.mutex.Unlock()
+		}
+	}
+}
+
+func introspectedUser(token *introspectedToken) *web.UserContext {
+	return &web.UserContext{
+		Data:               introspectionClaims(token.claims).Claims,
+		AuthenticationType: web.Bearer,
+		Name:               token.name,
+		AccessLevel:        web.NoAccess,
+	}
+}
+
+// tokenKey returns the key under which the introspection result of a token is cached, so that tokens are not kept in memory
+func tokenKey(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// scopes returns the scopes of a space separated scope string or of a scope array
+func scopes(scope interface{}) []string {
+	result := make([]string, 0)
+	switch value := scope.(type) {
+	case string:
+		result = append(result, strings.Fields(value)...)
+	case []interface{}:
+		for _, s := range value {
+			result = append(result, fmt.Sprint(s))
+		}
+	}
+	return result
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticators
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Introspection cache", func() {
+	var (
+		ctx           context.Context
+		cancel        context.CancelFunc
+		authenticator *IntrospectionAuthenticator
+	)
+
+	cacheLen := func() int {
+		authenticator.mutex.Lock()
+		defer authenticator.mutex.Unlock()
+		return len(authenticator.cache)
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		var err error
+		authenticator, err = NewIntrospectionAuthenticator(ctx, IntrospectionOptions{
+			URL:                  "https://example.com/introspect",
+			CacheSize:            2,
+			CacheCleanupInterval: 10 * time.Millisecond,
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("does not grow beyond the cache size", func() {
+		expiresAt := time.Now().Add(time.Hour)
+		for _, key := range []string{"first", "second", "third"} {
+			authenticator.store(key, &introspectedToken{name: key, expiresAt: expiresAt})
+		}
+		Expect(cacheLen()).To(Equal(2))
+		Expect(authenticator.cached("third", time.Now())).ToNot(BeNil())
+	})
+
+	It("replaces the cached result of a token when the cache is full", func() {
+		expiresAt := time.Now().Add(time.Hour)
+		authenticator.store("first", &introspectedToken{name: "first", expiresAt: expiresAt})
+		authenticator.store("second", &introspectedToken{name: "second", expiresAt: expiresAt})
+		authenticator.store("second", &introspectedToken{name: "renewed", expiresAt: expiresAt})
+		Expect(cacheLen()).To(Equal(2))
+		Expect(authenticator.cached("second", time.Now()).name).To(Equal("renewed"))
+	})
+
+	It("evicts expired results periodically", func() {
+		authenticator.store("expiring", &introspectedToken{expiresAt: time.Now().Add(50 * time.Millisecond)})
+		authenticator.store("valid", &introspectedToken{expiresAt: time.Now().Add(time.Hour)})
+		Eventually(cacheLen).Should(Equal(1))
+		Expect(authenticator.cached("valid", time.Now())).ToNot(BeNil())
+	})
+
+	It("stops evicting when the context is done", func() {
+		cancel()
+		time.Sleep(20 * time.Millisecond)
+		authenticator.store("expired", &introspectedToken{expiresAt: time.Now().Add(-time.Minute)})
+		Consistently(cacheLen, 50*time.Millisecond).Should(Equal(1))
+	})
+})
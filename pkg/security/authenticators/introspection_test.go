@@ -0,0 +1,189 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticators_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/multitenancy"
+	"github.com/Peripli/service-manager/pkg/security/authenticators"
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("Introspection Authenticator", func() {
+	var (
+		server        *ghttp.Server
+		options       authenticators.IntrospectionOptions
+		response      map[string]interface{}
+		responseCode  int
+		authenticator *authenticators.IntrospectionAuthenticator
+		cancel        context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		responseCode = http.StatusOK
+		response = map[string]interface{}{
+			"active":    true,
+			"scope":     "sm.read sm.write",
+			"client_id": "client",
+			"username":  "user",
+			"exp":       time.Now().Add(time.Hour).Unix(),
+			"ext_attr":  map[string]interface{}{"tid": "tenant"},
+		}
+		server.RouteToHandler(http.MethodPost, "/introspect", ghttp.CombineHandlers(
+			ghttp.VerifyBasicAuth("sm", "secret"),
+			ghttp.VerifyContentType("application/x-www-form-urlencoded"),
+			ghttp.VerifyForm(map[string][]string{"token": {"opaque-token"}}),
+			ghttp.RespondWithJSONEncodedPtr(&responseCode, &response),
+		))
+		options = authenticators.IntrospectionOptions{
+			URL:          server.URL() + "/introspect",
+			ClientID:     "sm",
+			ClientSecret: "secret",
+		}
+	})
+
+	JustBeforeEach(func() {
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		var err error
+		authenticator, err = authenticators.NewIntrospectionAuthenticator(ctx, options)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		cancel()
+		server.Close()
+	})
+
+	authenticate := func(authorization string) (*web.UserContext, httpsec.Decision, error) {
+		request, err := http.NewRequest(http.MethodGet, "https://example.com/v1/service_instances", nil)
+		Expect(err).ToNot(HaveOccurred())
+		if len(authorization) != 0 {
+			request.Header.Set("Authorization", authorization)
+		}
+		return authenticator.Authenticate(&web.Request{Request: request})
+	}
+
+	It("fails to be created with an invalid URL", func() {
+		_, err := authenticators.NewIntrospectionAuthenticator(context.Background(), authenticators.IntrospectionOptions{URL: "invalid"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("abstains for requests without bearer tokens", func() {
+		user, decision, err := authenticate("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(user).To(BeNil())
+		Expect(decision).To(Equal(httpsec.Abstain))
+		Expect(server.ReceivedRequests()).To(BeEmpty())
+	})
+
+	It("allows active tokens with the claims of the introspection response", func() {
+		user, decision, err := authenticate("Bearer opaque-token")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decision).To(Equal(httpsec.Allow))
+		Expect(user.Name).To(Equal("user"))
+		Expect(user.AuthenticationType).To(Equal(web.Bearer))
+
+		claims := struct {
+			Scopes   []string `json:"scope"`
+			ClientID string   `json:"client_id"`
+			CID      string   `json:"cid"`
+		}{}
+		Expect(user.Data(&claims)).To(Succeed())
+		Expect(claims.Scopes).To(ConsistOf("sm.read", "sm.write"))
+		Expect(claims.ClientID).To(Equal("client"))
+		Expect(claims.CID).To(Equal("client"))
+	})
+
+	It("denies inactive tokens", func() {
+		response = map[string]interface{}{"active": false}
+		user, decision, err := authenticate("Bearer opaque-token")
+		Expect(err).To(HaveOccurred())
+		Expect(user).To(BeNil())
+		Expect(decision).To(Equal(httpsec.Deny))
+	})
+
+	It("denies expired tokens", func() {
+		response["exp"] = time.Now().Add(-time.Minute).Unix()
+		_, decision, err := authenticate("Bearer opaque-token")
+		Expect(err).To(HaveOccurred())
+		Expect(decision).To(Equal(httpsec.Deny))
+	})
+
+	It("abstains with an error when the introspection fails", func() {
+		responseCode = http.StatusUnauthorized
+		_, decision, err := authenticate("Bearer opaque-token")
+		Expect(err).To(HaveOccurred())
+		Expect(decision).To(Equal(httpsec.Abstain))
+	})
+
+	It("caches active tokens until they expire", func() {
+		for i := 0; i < 3; i++ {
+			user, decision, err := authenticate("Bearer opaque-token")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Allow))
+			Expect(user.Name).To(Equal("user"))
+		}
+		Expect(server.ReceivedRequests()).To(HaveLen(1))
+	})
+
+	It("does not cache tokens without expiry", func() {
+		delete(response, "exp")
+		for i := 0; i < 2; i++ {
+			_, decision, err := authenticate("Bearer opaque-token")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Allow))
+		}
+		Expect(server.ReceivedRequests()).To(HaveLen(2))
+	})
+
+	It("does not cache inactive tokens", func() {
+		response = map[string]interface{}{"active": false}
+		_, _, _ = authenticate("Bearer opaque-token")
+		_, _, _ = authenticate("Bearer opaque-token")
+		Expect(server.ReceivedRequests()).To(HaveLen(2))
+	})
+
+	Context("with a claim mapping", func() {
+		BeforeEach(func() {
+			options.ClaimMapping = authenticators.ClaimMapping{Tenant: "ext_attr.tid"}
+		})
+
+		It("maps the tenant of the token", func() {
+			user, decision, err := authenticate("Bearer opaque-token")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Allow))
+
+			claims := map[string]interface{}{}
+			Expect(user.Data(&claims)).To(Succeed())
+			Expect(claims[multitenancy.MappedTenantClaim]).To(Equal("tenant"))
+
+			user, _, _ = authenticate("Bearer opaque-token")
+			claims = map[string]interface{}{}
+			Expect(user.Data(&claims)).To(Succeed())
+			Expect(claims[multitenancy.MappedTenantClaim]).To(Equal("tenant"))
+		})
+	})
+})
@@ -0,0 +1,191 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authenticators
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	goidc "github.com/coreos/go-oidc"
+	"github.com/tidwall/gjson"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/multitenancy"
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// ClaimMapping defines which token claims hold the user name, tenant and scopes of the user. The claims are
+// specified as paths in the token claims, for example "ext_attr.tenant".
+type ClaimMapping struct {
+	UserName string
+	Tenant   string
+	Scopes   string
+}
+
+// apply sets the mapped claims in the user context. The user name becomes the name of the user, the scopes are
+// stored in the scope claim and the tenant is stored in the multitenancy.MappedTenantClaim claim. A
+// multitenancy.MappedTenantClaim claim of the token itself is always dropped, as it would otherwise take precedence
+// over the tenant claim of the token.
+func (m ClaimMapping) apply(token httpsec.TokenData, user *web.UserContext) error {
+	var raw json.RawMessage
+	if err := token.Claims(&raw); err != nil {
+		return err
+	}
+	claims := make(map[string]interface{})
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return fmt.Errorf("could not unmarshal claims from token: %s", err)
+	}
+	delete(claims, multitenancy.MappedTenantClaim)
+
+	if len(m.UserName) != 0 {
+		user.Name = gjson.GetBytes(raw, m.UserName).String()
+		if len(user.Name) == 0 {
+			return fmt.Errorf("invalid token: could not find user name claim %s in token claims", m.UserName)
+		}
+	}
+	if len(m.Tenant) != 0 {
+		if tenant := gjson.GetBytes(raw, m.Tenant).String(); len(tenant) != 0 {
+			claims[multitenancy.MappedTenantClaim] = tenant
+		}
+	}
+	if len(m.Scopes) != 0 {
+		scopes := make([]string, 0)
+		value := gjson.GetBytes(raw, m.Scopes)
+		if value.IsArray() {
+			for _, scope := range value.Array() {
+				scopes = append(scopes, scope.String())
+			}
+		} else {
+			scopes = strings.Fields(value.String())
+		}
+		claims["scope"] = scopes
+	}
+
+	mapped, err := json.Marshal(claims)
+	if err != nil {
+		return err
+	}
+	user.Data = func(data interface{}) error {
+		return json.Unmarshal(mapped, data)
+	}
+	return nil
+}
+
+// MultiIssuerAuthenticator is an OpenID authenticator which accepts the tokens of several issuers. The issuer
+// verifying a token is selected by the iss claim of the token.
+type MultiIssuerAuthenticator struct {
+	// Authenticators are the authenticators of the issuers by issuer identifier
+	Authenticators map[string]httpsec.Authenticator
+}
+
+// NewMultiIssuerOIDCAuthenticator returns a new OpenID authenticator accepting the tokens of all issuers in the options
+func NewMultiIssuerOIDCAuthenticator(ctx context.Context, options ...*OIDCOptions) (*MultiIssuerAuthenticator, error) {
+	authenticators := make(map[string]httpsec.Authenticator, len(options))
+	for _, issuerOptions := range options {
+		authenticator, issuer, err := NewOIDCAuthenticator(ctx, issuerOptions)
+		if err != nil {
+			return nil, fmt.Errorf("could not configure token issuer %s: %s", issuerOptions.IssuerURL, err)
+		}
+		authenticators[issuer] = authenticator
+	}
+	return &MultiIssuerAuthenticator{
+		Authenticators: authenticators,
+	}, nil
+}
+
+// Authenticate authenticates the request with the authenticator of the issuer of the bearer token. Tokens of unknown
+// issuers are left to other authenticators.
+func (a *MultiIssuerAuthenticator) Authenticate(request *web.Request) (*web.UserContext, httpsec.Decision, error) {
+	token, ok := bearerToken(request)
+	if !ok {
+		return nil, httpsec.Abstain, nil
+	}
+	if token == "" {
+		return nil, httpsec.Deny, nil
+	}
+	issuer, err := tokenIssuer(token)
+	if err != nil {
+		log.C(request.Context()).WithError(err).Debug("Could not determine the issuer of the bearer token")
+		return nil, httpsec.Abstain, nil
+	}
+	authenticator, found := a.Authenticators[issuer]
+	if !found {
+		log.C(request.Context()).Debugf("Bearer token issuer %s is not trusted", issuer)
+		return nil, httpsec.Abstain, nil
+	}
+	return authenticator.Authenticate(request)
+}
+
+// tokenIssuer returns the iss claim of the JWT without verifying the token
+func tokenIssuer(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed jwt, expected 3 parts got %d", len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed jwt payload: %s", err)
+	}
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("malformed jwt claims: %s", err)
+	}
+	return claims.Issuer, nil
+}
+
+func newKeySet(ctx context.Context, jwksURL string, refreshInterval time.Duration) goidc.KeySet {
+	if refreshInterval <= 0 {
+		return goidc.NewRemoteKeySet(ctx, jwksURL)
+	}
+	return &refreshingKeySet{
+		ctx:             ctx,
+		jwksURL:         jwksURL,
+		refreshInterval: refreshInterval,
+	}
+}
+
+// refreshingKeySet is a remote key set whose keys are fetched again once the refresh interval has passed
+type refreshingKeySet struct {
+	ctx             context.Context
+	jwksURL         string
+	refreshInterval time.Duration
+
+	mutex     sync.Mutex
+	keySet    goidc.KeySet
+	fetchedAt time.Time
+}
+
+// VerifySignature implements goidc.KeySet
+func (k *refreshingKeySet) VerifySignature(ctx context.Context, jwt string) ([]byte, error) {
+	k.mutex.Lock()
+	if k.keySet == nil || time.Since(k.fetchedAt) > k.refreshInterval {
+		k.keySet = goidc.NewRemoteKeySet(k.ctx, k.jwksURL)
+		k.fetchedAt = time.Now()
+	}
+	keySet := k.keySet
+	k.mutex.Unlock()
+
+	return keySet.VerifySignature(ctx, jwt)
+}
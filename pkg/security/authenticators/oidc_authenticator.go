@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	httpsec "github.com/Peripli/service-manager/pkg/security/http"
 
@@ -58,6 +59,13 @@ type OIDCOptions struct {
 
 	// ReadConfigurationFunc is the function used to call the token issuer. If one is not provided, http.DefaultClient.Do will be used
 	ReadConfigurationFunc util.DoRequestFunc
+
+	// JWKSRefreshInterval is the maximum time the signing keys of the issuer are cached. If zero, the keys are
+	// fetched again only when a token is signed with an unknown key
+	JWKSRefreshInterval time.Duration
+
+	// ClaimMapping defines the claims holding the user name, tenant and scopes of the tokens of the issuer
+	ClaimMapping ClaimMapping
 }
 
 type oidcVerifier struct {
@@ -71,7 +79,8 @@ func (v *oidcVerifier) Verify(ctx context.Context, idToken string) (httpsec.Toke
 
 // OauthAuthenticator is the OpenID implementation of security.Authenticator
 type OauthAuthenticator struct {
-	Verifier httpsec.TokenVerifier
+	Verifier     httpsec.TokenVerifier
+	ClaimMapping ClaimMapping
 }
 
 // NewOIDCAuthenticator returns a new OpenID authenticator or an error if one couldn't be configured
@@ -93,10 +102,13 @@ func NewOIDCAuthenticator(ctx context.Context, options *OIDCOptions) (httpsec.Au
 		return nil, "", fmt.Errorf("error decoding body of response with status %s: %s", resp.Status, err.Error())
 	}
 
-	keySet := goidc.NewRemoteKeySet(ctx, p.JWKSURL)
-	return &OauthAuthenticator{Verifier: &oidcVerifier{
-		IDTokenVerifier: goidc.NewVerifier(p.Issuer, keySet, newOIDCConfig(options)),
-	}}, p.Issuer, nil
+	keySet := newKeySet(ctx, p.JWKSURL, options.JWKSRefreshInterval)
+	return &OauthAuthenticator{
+		Verifier: &oidcVerifier{
+			IDTokenVerifier: goidc.NewVerifier(p.Issuer, keySet, newOIDCConfig(options)),
+		},
+		ClaimMapping: options.ClaimMapping,
+	}, p.Issuer, nil
 }
 
 func newOIDCConfig(options *OIDCOptions) *goidc.Config {
@@ -108,14 +120,13 @@ func newOIDCConfig(options *OIDCOptions) *goidc.Config {
 
 // Authenticate returns information about the user by obtaining it from the bearer token, or an error if security is unsuccessful
 func (a *OauthAuthenticator) Authenticate(request *web.Request) (*web.UserContext, httpsec.Decision, error) {
-	authorizationHeader := request.Header.Get("Authorization")
-	if authorizationHeader == "" || !strings.HasPrefix(strings.ToLower(authorizationHeader), "bearer ") {
+	token, ok := bearerToken(request)
+	if !ok {
 		return nil, httpsec.Abstain, nil
 	}
 	if a.Verifier == nil {
 		return nil, httpsec.Abstain, errors.New("authenticator is not configured")
 	}
-	token := strings.TrimSpace(authorizationHeader[len("Bearer "):])
 	if token == "" {
 		return nil, httpsec.Deny, nil
 	}
@@ -127,12 +138,25 @@ func (a *OauthAuthenticator) Authenticate(request *web.Request) (*web.UserContex
 	if err := idToken.Claims(claims); err != nil {
 		return nil, httpsec.Deny, err
 	}
-	return &web.UserContext{
+	user := &web.UserContext{
 		Data:               idToken.Claims,
 		AuthenticationType: web.Bearer,
 		Name:               claims.Subject,
 		AccessLevel:        web.NoAccess,
-	}, httpsec.Allow, nil
+	}
+	if err := a.ClaimMapping.apply(idToken, user); err != nil {
+		return nil, httpsec.Deny, err
+	}
+	return user, httpsec.Allow, nil
+}
+
+// bearerToken returns the token of a bearer Authorization header and whether the request has such a header
+func bearerToken(request *web.Request) (string, bool) {
+	authorizationHeader := request.Header.Get("Authorization")
+	if authorizationHeader == "" || !strings.HasPrefix(strings.ToLower(authorizationHeader), "bearer ") {
+		return "", false
+	}
+	return strings.TrimSpace(authorizationHeader[len("Bearer "):]), true
 }
 
 func getOpenIDConfig(ctx context.Context, options *OIDCOptions) (*http.Response, error) {
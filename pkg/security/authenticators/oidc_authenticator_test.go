@@ -19,6 +19,7 @@ package authenticators
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -26,6 +27,7 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/Peripli/service-manager/pkg/multitenancy"
 	httpsec "github.com/Peripli/service-manager/pkg/security/http"
 
 	"github.com/Peripli/service-manager/pkg/security/http/httpfakes"
@@ -399,6 +401,76 @@ var _ = Describe("OIDC Authenticator", func() {
 							Expect(claims.Abc).To(Equal("xyz"))
 						})
 					})
+
+					Context("when claims are mapped", func() {
+						BeforeEach(func() {
+							tokenJSON := `{"sub": "subject", "email": "user@example.com", "ext": {"tid": "tenant-id"}, "roles": "sm.read sm.write"}`
+							token := &httpfakes.FakeTokenData{}
+							token.ClaimsStub = func(v interface{}) error {
+								return json.Unmarshal([]byte(tokenJSON), v)
+							}
+							verifier.VerifyReturns(token, nil)
+						})
+
+						It("should return the mapped user name, tenant and scopes", func() {
+							authenticator = &OauthAuthenticator{
+								Verifier: verifier,
+								ClaimMapping: ClaimMapping{
+									UserName: "email",
+									Tenant:   "ext.tid",
+									Scopes:   "roles",
+								},
+							}
+
+							user, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+							Expect(err).ToNot(HaveOccurred())
+							Expect(decision).To(Equal(httpsec.Allow))
+							Expect(user.Name).To(Equal("user@example.com"))
+
+							claims := map[string]interface{}{}
+							Expect(user.Data(&claims)).To(Succeed())
+							Expect(claims["scope"]).To(ConsistOf("sm.read", "sm.write"))
+							Expect(claims[multitenancy.MappedTenantClaim]).To(Equal("tenant-id"))
+							Expect(claims["sub"]).To(Equal("subject"))
+						})
+
+						It("should drop a mapped tenant claim of the token", func() {
+							tokenJSON := fmt.Sprintf(`{"sub": "subject", "%s": "other-tenant"}`, multitenancy.MappedTenantClaim)
+							token := &httpfakes.FakeTokenData{}
+							token.ClaimsStub = func(v interface{}) error {
+								return json.Unmarshal([]byte(tokenJSON), v)
+							}
+							verifier.VerifyReturns(token, nil)
+
+							for _, mapping := range []ClaimMapping{{}, {Tenant: "ext.tid"}} {
+								authenticator = &OauthAuthenticator{
+									Verifier:     verifier,
+									ClaimMapping: mapping,
+								}
+
+								user, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+								Expect(err).ToNot(HaveOccurred())
+								Expect(decision).To(Equal(httpsec.Allow))
+
+								claims := map[string]interface{}{}
+								Expect(user.Data(&claims)).To(Succeed())
+								Expect(claims).ToNot(HaveKey(multitenancy.MappedTenantClaim))
+								Expect(claims["sub"]).To(Equal("subject"))
+							}
+						})
+
+						It("should deny when the user name claim is missing", func() {
+							authenticator = &OauthAuthenticator{
+								Verifier:     verifier,
+								ClaimMapping: ClaimMapping{UserName: "missing"},
+							}
+
+							user, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+							Expect(err).To(HaveOccurred())
+							Expect(decision).To(Equal(httpsec.Deny))
+							Expect(user).To(BeNil())
+						})
+					})
 				}
 
 				Context("when Bearer starts with uppercase", func() {
@@ -425,4 +497,70 @@ var _ = Describe("OIDC Authenticator", func() {
 			})
 		})
 	})
+
+	Context("MultiIssuerAuthenticator", func() {
+		var (
+			request        *http.Request
+			first, second  *httpfakes.FakeAuthenticator
+			authenticator  *MultiIssuerAuthenticator
+			tokenForIssuer func(issuer string) string
+		)
+
+		BeforeEach(func() {
+			var err error
+			request, err = http.NewRequest(http.MethodGet, "https://example.com", nil)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			first = &httpfakes.FakeAuthenticator{}
+			first.AuthenticateReturns(&web.UserContext{Name: "first"}, httpsec.Allow, nil)
+			second = &httpfakes.FakeAuthenticator{}
+			second.AuthenticateReturns(&web.UserContext{Name: "second"}, httpsec.Allow, nil)
+			authenticator = &MultiIssuerAuthenticator{
+				Authenticators: map[string]httpsec.Authenticator{
+					"https://first.example.com":  first,
+					"https://second.example.com": second,
+				},
+			}
+
+			tokenForIssuer = func(issuer string) string {
+				payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"iss":"%s"}`, issuer)))
+				return "header." + payload + ".signature"
+			}
+		})
+
+		It("should authenticate with the authenticator of the token issuer", func() {
+			request.Header.Set("Authorization", "Bearer "+tokenForIssuer("https://second.example.com"))
+
+			user, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Allow))
+			Expect(user.Name).To(Equal("second"))
+			Expect(first.AuthenticateCallCount()).To(Equal(0))
+		})
+
+		It("should abstain for tokens of unknown issuers", func() {
+			request.Header.Set("Authorization", "Bearer "+tokenForIssuer("https://unknown.example.com"))
+
+			user, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Abstain))
+			Expect(user).To(BeNil())
+		})
+
+		It("should abstain for malformed tokens", func() {
+			request.Header.Set("Authorization", "Bearer token")
+
+			_, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Abstain))
+		})
+
+		It("should abstain without bearer authorization", func() {
+			request.Header.Set("Authorization", "Basic admin:admin")
+
+			_, decision, err := authenticator.Authenticate(&web.Request{Request: request})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Abstain))
+		})
+	})
 })
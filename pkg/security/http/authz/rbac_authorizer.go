@@ -0,0 +1,182 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+type rbacDecisionsKey struct{}
+
+type rbacDecision struct {
+	decision httpsec.Decision
+	level    web.AccessLevel
+	err      error
+}
+
+// NewRBACAuthorizer returns an authorizer which allows requests according to the roles bound to the user and its groups.
+// Role bindings of the tenant returned by tenantFunc grant tenant access and role bindings without a tenant grant global access.
+// The groups of the user are read from groupsClaim. Decisions are cached for the duration of the request.
+func NewRBACAuthorizer(repository storage.Repository, tenantFunc func(*web.Request) (string, error), groupsClaim string) httpsec.Authorizer {
+	return &rbacAuthorizer{
+		repository:  repository,
+		tenantFunc:  tenantFunc,
+		groupsClaim: groupsClaim,
+	}
+}
+
+type rbacAuthorizer struct {
+	repository  storage.Repository
+	tenantFunc  func(*web.Request) (string, error)
+	groupsClaim string
+}
+
+// Authorize allows the request if a role bound to the user or one of its groups permits the verb on the requested resource type
+func (a *rbacAuthorizer) Authorize(request *web.Request) (httpsec.Decision, web.AccessLevel, error) {
+	ctx := request.Context()
+
+	user, ok := web.UserFromContext(ctx)
+	if !ok || user.AuthenticationType != web.Bearer {
+		return httpsec.Abstain, web.NoAccess, nil
+	}
+
+	resourceType, verb, ok := ResourceTypeAndVerb(request)
+	if !ok {
+		return httpsec.Abstain, web.NoAccess, nil
+	}
+
+	decisions, found := ctx.Value(rbacDecisionsKey{}).(map[string]*rbacDecision)
+	if !found {
+		decisions = make(map[string]*rbacDecision)
+		request.Request = request.WithContext(context.WithValue(ctx, rbacDecisionsKey{}, decisions))
+	}
+	key := resourceType + ":" + verb
+	decision, found := decisions[key]
+	if !found {
+		decision = a.decide(request, user, resourceType, verb)
+		decisions[key] = decision
+	}
+	return decision.decision, decision.level, decision.err
+}
+
+func (a *rbacAuthorizer) decide(request *web.Request, user *web.UserContext, resourceType, verb string) *rbacDecision {
+	ctx := request.Context()
+	logger := log.C(ctx)
+
+	groups, err := claimValues(user, a.groupsClaim)
+	if err != nil {
+		return &rbacDecision{decision: httpsec.Deny, level: web.NoAccess, err: fmt.Errorf("could not extract groups from token: %v", err)}
+	}
+
+	tenants := []string{""}
+	if a.tenantFunc != nil {
+		tenant, err := a.tenantFunc(request)
+		if err != nil {
+			logger.WithError(err).Debug("Could not determine the tenant of the user, only global role bindings apply")
+		} else if len(tenant) != 0 {
+			tenants = append(tenants, tenant)
+		}
+	}
+
+	bindings, err := a.repository.List(ctx, types.RoleBindingType, query.ByField(query.InOperator, "tenant", tenants...))
+	if err != nil {
+		return &rbacDecision{decision: httpsec.Deny, level: web.NoAccess, err: fmt.Errorf("could not get role bindings: %v", err)}
+	}
+
+	tenantsByRole := make(map[string][]string)
+	for i := 0; i < bindings.Len(); i++ {
+		binding := bindings.ItemAt(i).(*types.RoleBinding)
+		if binding.Binds(user.Name, groups) {
+			tenantsByRole[binding.RoleID] = append(tenantsByRole[binding.RoleID], binding.Tenant)
+		}
+	}
+	if len(tenantsByRole) == 0 {
+		return &rbacDecision{decision: httpsec.Deny, level: web.NoAccess, err: fmt.Errorf("no roles are bound to user %s", user.Name)}
+	}
+
+	roleIDs := make([]string, 0, len(tenantsByRole))
+	for roleID := range tenantsByRole {
+		roleIDs = append(roleIDs, roleID)
+	}
+	roles, err := a.repository.List(ctx, types.RoleType, query.ByField(query.InOperator, "id", roleIDs...))
+	if err != nil {
+		return &rbacDecision{decision: httpsec.Deny, level: web.NoAccess, err: fmt.Errorf("could not get roles: %v", err)}
+	}
+
+	level := web.NoAccess
+	for i := 0; i < roles.Len(); i++ {
+		role := roles.ItemAt(i).(*types.Role)
+		if !role.Allows(resourceType, verb) {
+			continue
+		}
+		for _, tenant := range tenantsByRole[role.ID] {
+			if len(tenant) == 0 {
+				level = web.GlobalAccess
+			} else if level < web.TenantAccess {
+				level = web.TenantAccess
+			}
+		}
+	}
+	if level == web.NoAccess {
+		return &rbacDecision{decision: httpsec.Deny, level: web.NoAccess, err: fmt.Errorf("user %s is not permitted to %s %s", user.Name, verb, resourceType)}
+	}
+
+	logger.Debugf("User %s is permitted to %s %s with access level %s", user.Name, verb, resourceType, level)
+	return &rbacDecision{decision: httpsec.Allow, level: level}
+}
+
+// ResourceTypeAndVerb returns the resource type and the verb of an API request. The resource type is the name of the
+// resource collection in the path, for example service_instances for /v1/service_instances/{id}.
+func ResourceTypeAndVerb(request *web.Request) (string, string, bool) {
+	segments := strings.Split(strings.Trim(request.URL.Path, "/"), "/")
+	if len(segments) < 2 {
+		return "", "", false
+	}
+	resourceType := segments[1]
+
+	switch request.Method {
+	case http.MethodGet, http.MethodHead:
+		if len(segments) == 2 {
+			return resourceType, types.VerbList, true
+		}
+		return resourceType, types.VerbGet, true
+	case http.MethodPost:
+		return resourceType, types.VerbCreate, true
+	case http.MethodPut, http.MethodPatch:
+		return resourceType, types.VerbUpdate, true
+	case http.MethodDelete:
+		return resourceType, types.VerbDelete, true
+	}
+	return "", "", false
+}
+
+// claimValues returns the values of a claim holding an array or a space separated string
+func claimValues(user *web.UserContext, claim string) ([]string, error) {
+	if len(claim) == 0 {
+		return []string{}, nil
+	}
+	var raw json.RawMessage
+	if err := user.Data(&raw); err != nil {
+		return nil, err
+	}
+	value := gjson.GetBytes(raw, claim)
+	if !value.IsArray() {
+		return strings.Fields(value.String()), nil
+	}
+	values := make([]string, 0)
+	for _, v := range value.Array() {
+		values = append(values, v.String())
+	}
+	return values, nil
+}
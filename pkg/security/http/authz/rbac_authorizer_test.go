@@ -0,0 +1,155 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	httpsec "github.com/Peripli/service-manager/pkg/security/http"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RBAC Authorizer", func() {
+	var (
+		repository *storagefakes.FakeStorage
+		bindings   []*types.RoleBinding
+		tenant     string
+		authorizer httpsec.Authorizer
+	)
+
+	newRequest := func(method, path, claims string) *web.Request {
+		req := httptest.NewRequest(method, path, nil)
+		user := &web.UserContext{
+			Data: func(data interface{}) error {
+				return json.Unmarshal([]byte(claims), data)
+			},
+			AuthenticationType: web.Bearer,
+			Name:               "user",
+		}
+		return &web.Request{Request: req.WithContext(web.ContextWithUser(req.Context(), user))}
+	}
+
+	BeforeEach(func() {
+		tenant = "tenant"
+		bindings = []*types.RoleBinding{}
+		repository = &storagefakes.FakeStorage{}
+		repository.ListStub = func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			switch objectType {
+			case types.RoleBindingType:
+				return &types.RoleBindings{RoleBindings: bindings}, nil
+			case types.RoleType:
+				return &types.Roles{Roles: []*types.Role{
+					{
+						Base:        types.Base{ID: "viewer"},
+						Name:        "viewer",
+						Permissions: []types.Permission{{ResourceType: types.AnyPermission, Verbs: []string{types.VerbGet, types.VerbList}}},
+					},
+					{
+						Base:        types.Base{ID: "admin"},
+						Name:        "admin",
+						Permissions: []types.Permission{{ResourceType: types.AnyPermission, Verbs: []string{types.AnyPermission}}},
+					},
+				}}, nil
+			}
+			return nil, errors.New("unexpected object type")
+		}
+		authorizer = NewRBACAuthorizer(repository, func(*web.Request) (string, error) {
+			return tenant, nil
+		}, "groups")
+	})
+
+	It("abstains for requests without a bearer user", func() {
+		decision, level, err := authorizer.Authorize(&web.Request{Request: httptest.NewRequest(http.MethodGet, "/v1/platforms", nil)})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decision).To(Equal(httpsec.Abstain))
+		Expect(level).To(Equal(web.NoAccess))
+	})
+
+	It("denies users without role bindings", func() {
+		decision, level, err := authorizer.Authorize(newRequest(http.MethodGet, "/v1/platforms", `{}`))
+		Expect(err).To(HaveOccurred())
+		Expect(decision).To(Equal(httpsec.Deny))
+		Expect(level).To(Equal(web.NoAccess))
+	})
+
+	It("looks up the global role bindings and the role bindings of the tenant", func() {
+		_, _, _ = authorizer.Authorize(newRequest(http.MethodGet, "/v1/platforms", `{}`))
+		_, _, criteria := repository.ListArgsForCall(0)
+		Expect(criteria).To(ConsistOf(query.ByField(query.InOperator, "tenant", "", "tenant")))
+	})
+
+	Context("when a role is bound to the user in the tenant", func() {
+		BeforeEach(func() {
+			bindings = append(bindings, &types.RoleBinding{
+				RoleID:   "viewer",
+				Tenant:   "tenant",
+				Subjects: []types.Subject{{Kind: types.UserSubject, Name: "user"}},
+			})
+		})
+
+		It("allows the permitted verbs with tenant access", func() {
+			decision, level, err := authorizer.Authorize(newRequest(http.MethodGet, "/v1/service_instances/id", `{}`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Allow))
+			Expect(level).To(Equal(web.TenantAccess))
+		})
+
+		It("denies other verbs", func() {
+			decision, _, err := authorizer.Authorize(newRequest(http.MethodDelete, "/v1/service_instances/id", `{}`))
+			Expect(err).To(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Deny))
+		})
+
+		It("caches the decision for the request", func() {
+			request := newRequest(http.MethodGet, "/v1/service_instances", `{}`)
+			decision, _, _ := authorizer.Authorize(request)
+			Expect(decision).To(Equal(httpsec.Allow))
+			decision, _, _ = authorizer.Authorize(request)
+			Expect(decision).To(Equal(httpsec.Allow))
+			Expect(repository.ListCallCount()).To(Equal(2))
+		})
+	})
+
+	Context("when a role is bound globally to a group of the user", func() {
+		BeforeEach(func() {
+			bindings = append(bindings, &types.RoleBinding{
+				RoleID:   "admin",
+				Subjects: []types.Subject{{Kind: types.GroupSubject, Name: "operators"}},
+			})
+		})
+
+		It("allows with global access", func() {
+			decision, level, err := authorizer.Authorize(newRequest(http.MethodDelete, "/v1/platforms/id", `{"groups": ["operators"]}`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Allow))
+			Expect(level).To(Equal(web.GlobalAccess))
+		})
+
+		It("denies users outside of the group", func() {
+			decision, _, err := authorizer.Authorize(newRequest(http.MethodDelete, "/v1/platforms/id", `{"groups": ["viewers"]}`))
+			Expect(err).To(HaveOccurred())
+			Expect(decision).To(Equal(httpsec.Deny))
+		})
+	})
+
+	DescribeTable("ResourceTypeAndVerb", func(method, path, resourceType, verb string) {
+		actualType, actualVerb, ok := ResourceTypeAndVerb(&web.Request{Request: httptest.NewRequest(method, path, nil)})
+		Expect(ok).To(BeTrue())
+		Expect(actualType).To(Equal(resourceType))
+		Expect(actualVerb).To(Equal(verb))
+	},
+		Entry("lists collections", http.MethodGet, "/v1/service_instances", "service_instances", types.VerbList),
+		Entry("gets single resources", http.MethodGet, "/v1/service_instances/id", "service_instances", types.VerbGet),
+		Entry("creates resources", http.MethodPost, "/v1/platforms", "platforms", types.VerbCreate),
+		Entry("updates resources", http.MethodPatch, "/v1/platforms/id", "platforms", types.VerbUpdate),
+		Entry("deletes resources", http.MethodDelete, "/v1/role_bindings/id", "role_bindings", types.VerbDelete),
+	)
+})
@@ -18,9 +18,14 @@ package security
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
 	"fmt"
 )
 
+// hmacIntegrityPrefix marks integrities signed with HMAC-SHA256 so that they can be distinguished from plain hashes
+var hmacIntegrityPrefix = []byte("hmac-sha256:")
+
 // IntegralObject interface indicates that an objects needs to be processed with regards to its integrity
 type IntegralObject interface {
 	IntegralData() []byte
@@ -65,3 +70,63 @@ func (h *HashingIntegrityProcessor) ValidateIntegrity(integral IntegralObject) b
 	integrity := integral.GetIntegrity()
 	return bytes.Equal(hashedData, integrity)
 }
+
+// TransitionalIntegrityProcessor is an integrity processor which also accepts integrities calculated in a legacy format
+// until they are re-signed
+type TransitionalIntegrityProcessor interface {
+	IntegrityProcessor
+	IsLegacy(integral IntegralObject) bool
+}
+
+// HMACIntegrityProcessor is an integrity processor that signs the integral data with HMAC-SHA256. The key should be
+// kept outside of the storage, so that write access to the storage is not enough to forge a valid integrity.
+type HMACIntegrityProcessor struct {
+	Key []byte
+	// Legacy validates integrities calculated before signing was enabled. If nil, only signed integrities are valid.
+	Legacy IntegrityProcessor
+}
+
+// CalculateIntegrity calculates the integrity of an integral object by signing it
+func (h *HMACIntegrityProcessor) CalculateIntegrity(integral IntegralObject) ([]byte, error) {
+	if integral == nil {
+		return nil, fmt.Errorf("cannot calculate integrity of nil object")
+	}
+	integralData := integral.IntegralData()
+	if len(integralData) == 0 {
+		return []byte{}, nil
+	}
+	return h.sign(integralData), nil
+}
+
+// ValidateIntegrity validates the signature of an integral object. Unsigned integrities are validated by the legacy
+// integrity processor, if one is configured.
+func (h *HMACIntegrityProcessor) ValidateIntegrity(integral IntegralObject) bool {
+	if integral == nil {
+		return true
+	}
+	integralData := integral.IntegralData()
+	if len(integralData) == 0 {
+		return true
+	}
+	integrity := integral.GetIntegrity()
+	if !bytes.HasPrefix(integrity, hmacIntegrityPrefix) {
+		return h.Legacy != nil && h.Legacy.ValidateIntegrity(integral)
+	}
+	return hmac.Equal(h.sign(integralData), integrity)
+}
+
+// IsLegacy returns true if the integral object has integrity which is not signed
+func (h *HMACIntegrityProcessor) IsLegacy(integral IntegralObject) bool {
+	if integral == nil || len(integral.IntegralData()) == 0 {
+		return false
+	}
+	return !bytes.HasPrefix(integral.GetIntegrity(), hmacIntegrityPrefix)
+}
+
+func (h *HMACIntegrityProcessor) sign(data []byte) []byte {
+	mac := hmac.New(sha256.New, h.Key)
+	mac.Write(data)
+	signature := make([]byte, 0, len(hmacIntegrityPrefix)+sha256.Size)
+	signature = append(signature, hmacIntegrityPrefix...)
+	return mac.Sum(signature)
+}
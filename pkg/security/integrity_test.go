@@ -111,3 +111,69 @@ var _ = Describe("SHA256 processor", func() {
 		})
 	})
 })
+
+var _ = Describe("HMAC processor", func() {
+	var processor *security.HMACIntegrityProcessor
+	var legacyProcessor security.IntegrityProcessor
+	var securedObject *obj
+
+	BeforeEach(func() {
+		legacyProcessor = &security.HashingIntegrityProcessor{HashingFunc: func(data []byte) []byte {
+			bytes := sha256.Sum256(data)
+			return bytes[:]
+		}}
+		processor = &security.HMACIntegrityProcessor{Key: []byte("integrity-key")}
+		securedObject = &obj{
+			integralData: []byte("integral data"),
+		}
+	})
+
+	Context("Calculate", func() {
+		It("returns a signature which depends on the key", func() {
+			integrity, err := processor.CalculateIntegrity(securedObject)
+			Expect(err).ToNot(HaveOccurred())
+			otherKeyIntegrity, err := (&security.HMACIntegrityProcessor{Key: []byte("other-key")}).CalculateIntegrity(securedObject)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(integrity).ToNot(Equal(otherKeyIntegrity))
+		})
+	})
+
+	Context("Validate", func() {
+		It("validates its own signature", func() {
+			integrity, err := processor.CalculateIntegrity(securedObject)
+			Expect(err).ToNot(HaveOccurred())
+			securedObject.SetIntegrity(integrity)
+			Expect(processor.ValidateIntegrity(securedObject)).To(BeTrue())
+			Expect(processor.IsLegacy(securedObject)).To(BeFalse())
+		})
+
+		It("rejects a signature of modified data", func() {
+			integrity, err := processor.CalculateIntegrity(securedObject)
+			Expect(err).ToNot(HaveOccurred())
+			securedObject.SetIntegrity(integrity)
+			securedObject.integralData = []byte("tampered data")
+			Expect(processor.ValidateIntegrity(securedObject)).To(BeFalse())
+		})
+
+		Context("when the integrity is a plain hash", func() {
+			BeforeEach(func() {
+				integrity, err := legacyProcessor.CalculateIntegrity(securedObject)
+				Expect(err).ToNot(HaveOccurred())
+				securedObject.SetIntegrity(integrity)
+			})
+
+			It("is legacy", func() {
+				Expect(processor.IsLegacy(securedObject)).To(BeTrue())
+			})
+
+			It("rejects it when no legacy processor is configured", func() {
+				Expect(processor.ValidateIntegrity(securedObject)).To(BeFalse())
+			})
+
+			It("validates it with the legacy processor", func() {
+				processor.Legacy = legacyProcessor
+				Expect(processor.ValidateIntegrity(securedObject)).To(BeTrue())
+			})
+		})
+	})
+})
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package policy
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// AdminScope is the scope required by the default security policy for the administrative APIs
+const AdminScope = "sm.admin"
+
+// DefaultRules returns the rules of the default security policy of the Service Manager
+func DefaultRules() []RuleSettings {
+	return []RuleSettings{
+		{
+			Paths: []string{
+				web.ServiceBrokersURL + "/*",
+				web.PlatformsURL + "/*",
+				web.ServiceOfferingsURL + "/*",
+				web.ServicePlansURL + "/*",
+				web.VisibilitiesURL + "/*",
+				web.ServiceInstancesURL + "/*",
+				web.ServiceBindingsURL + "/*",
+				web.NotificationsURL + "/*",
+			},
+			Methods:        []string{http.MethodGet},
+			Authenticators: []string{BasicPlatformAuthenticator},
+		},
+		{
+			Paths:          []string{web.BrokerPlatformCredentialsURL + "/**"},
+			Methods:        []string{http.MethodPut},
+			Authenticators: []string{BasicPlatformAuthenticator},
+		},
+		{
+			Paths:          []string{web.OSBURL + "/**"},
+			Methods:        []string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete},
+			Authenticators: []string{BasicOSBAuthenticator},
+		},
+		{
+			Paths:          []string{web.AdminURL + "/**"},
+			Methods:        []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+			Authenticators: []string{APITokenAuthenticator, OIDCAuthenticator},
+			Scopes:         []string{AdminScope},
+		},
+		{
+			Paths: []string{
+				web.ServiceBrokersURL + "/**",
+				web.PlatformsURL + "/**",
+				web.ServiceOfferingsURL + "/**",
+				web.ServicePlansURL + "/**",
+				web.VisibilitiesURL + "/**",
+				web.QuotasURL + "/**",
+				web.RolesURL + "/**",
+				web.RoleBindingsURL + "/**",
+				web.APITokensURL + "/**",
+				web.AdmissionWebhooksURL + "/**",
+				web.TenantURL + "/*" + web.QuotasSubpathURL,
+				web.ApprovalsURL + "/**",
+				web.NotificationsURL + "/**",
+				web.ServiceInstancesURL + "/**",
+				web.ServiceBindingsURL + "/**",
+				web.ConfigURL + "/**",
+				web.ProfileURL + "/**",
+				web.OperationsURL + "/**",
+			},
+			Methods:        []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+			Authenticators: []string{APITokenAuthenticator, OIDCAuthenticator},
+		},
+	}
+}
@@ -0,0 +1,338 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package policy contains the declarative authentication and authorization policy of the Service Manager
+package policy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gobwas/glob"
+	"github.com/spf13/viper"
+
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const (
+	// BasicPlatformAuthenticator authenticates requests with the basic credentials of a platform
+	BasicPlatformAuthenticator = "basic_platform"
+	// BasicOSBAuthenticator authenticates requests with the basic credentials of a broker platform credential
+	BasicOSBAuthenticator = "basic_osb"
+	// APITokenAuthenticator authenticates requests with API tokens issued by the Service Manager
+	APITokenAuthenticator = "api_token"
+	// OIDCAuthenticator authenticates requests with bearer tokens of the issuer configured in the API settings and of all
+	// configured issuers, selected by the iss claim of the token. Tokens of a single configured issuer are authenticated
+	// by OIDCAuthenticator followed by ':' and the name of the issuer.
+	OIDCAuthenticator = "oidc"
+	// IntrospectionAuthenticator authenticates requests with bearer tokens introspected by the introspection endpoint
+	// whose name follows IntrospectionAuthenticator and ':'
+	IntrospectionAuthenticator = "introspection"
+)
+
+var accessLevels = map[string]web.AccessLevel{
+	"no_access":   web.NoAccess,
+	"tenant":      web.TenantAccess,
+	"all_tenants": web.AllTenantAccess,
+	"global":      web.GlobalAccess,
+}
+
+var methods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions}
+
+// Settings type to be loaded from the environment
+type Settings struct {
+	File          string                  `mapstructure:"file" description:"location of a file containing the security policy. If set, the issuers, introspection endpoints and rules of the file replace the configured ones"`
+	Issuers       []IssuerSettings        `mapstructure:"issuers" description:"OIDC token issuers which rules can authenticate with"`
+	Introspection []IntrospectionSettings `mapstructure:"introspection" description:"token introspection endpoints which rules can authenticate opaque tokens with"`
+	Rules         []RuleSettings          `mapstructure:"rules" description:"authentication and authorization rules applied in order. If empty, the default policy is used"`
+	Lockout       *LockoutSettings        `mapstructure:"lockout"`
+	RBAC          *RBACSettings           `mapstructure:"rbac"`
+}
+
+// DefaultSettings returns default values for the security policy settings
+func DefaultSettings() *Settings {
+	return &Settings{
+		File:          "",
+		Issuers:       []IssuerSettings{},
+		Introspection: []IntrospectionSettings{},
+		Rules:         []RuleSettings{},
+		Lockout:       DefaultLockoutSettings(),
+		RBAC:          DefaultRBACSettings(),
+	}
+}
+
+// Validate validates the security policy settings
+func (s *Settings) Validate() error {
+	if err := s.Lockout.Validate(); err != nil {
+		return err
+	}
+	return validate(s.Issuers, s.Introspection, s.Rules)
+}
+
+// Load returns the security policy that should be applied. The policy is read from the policy file if one is configured
+// and falls back to the default rules if no rules are configured.
+func (s *Settings) Load() (*Settings, error) {
+	result := &Settings{
+		Issuers:       s.Issuers,
+		Introspection: s.Introspection,
+		Rules:         s.Rules,
+		Lockout:       s.Lockout,
+		RBAC:          s.RBAC,
+	}
+	if len(s.File) != 0 {
+		v := viper.New()
+		v.SetConfigFile(s.File)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("could not read security policy file %s: %s", s.File, err)
+		}
+		result = &Settings{}
+		if err := v.Unmarshal(result); err != nil {
+			return nil, fmt.Errorf("could not load security policy file %s: %s", s.File, err)
+		}
+		result.Lockout = s.Lockout
+		result.RBAC = s.RBAC
+	}
+	if len(result.Rules) == 0 {
+		result.Rules = DefaultRules()
+	}
+	if err := validate(result.Issuers, result.Introspection, result.Rules); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// LockoutSettings defines when the basic credentials of a platform are temporarily locked
+type LockoutSettings struct {
+	MaxFailedAttempts int           `mapstructure:"max_failed_attempts" description:"number of consecutive failed authentication attempts with the credentials of a platform after which they are locked. If 0, credentials are never locked"`
+	Duration          time.Duration `mapstructure:"duration" description:"time for which the credentials of a platform are locked"`
+}
+
+// DefaultLockoutSettings returns default values for the lockout settings
+func DefaultLockoutSettings() *LockoutSettings {
+	return &LockoutSettings{
+		MaxFailedAttempts: 10,
+		Duration:          5 * time.Minute,
+	}
+}
+
+// Validate validates the lockout settings
+func (ls *LockoutSettings) Validate() error {
+	if ls.MaxFailedAttempts < 0 {
+		return fmt.Errorf("validate Settings: security lockout max failed attempts must not be negative")
+	}
+	if ls.MaxFailedAttempts > 0 && ls.Duration <= 0 {
+		return fmt.Errorf("validate Settings: security lockout duration must be positive")
+	}
+	return nil
+}
+
+// RBACSettings defines how the users of the rules with role-based access control are matched with role bindings
+type RBACSettings struct {
+	TenantClaim string `mapstructure:"tenant_claim" description:"claim holding the tenant of the user whose role bindings apply in addition to the global ones. The tenant claim of the token issuer takes precedence"`
+	GroupsClaim string `mapstructure:"groups_claim" description:"claim holding the groups of the user as an array or a space separated string"`
+}
+
+// DefaultRBACSettings returns default values for the role-based access control settings
+func DefaultRBACSettings() *RBACSettings {
+	return &RBACSettings{
+		TenantClaim: "",
+		GroupsClaim: "groups",
+	}
+}
+
+// IssuerSettings defines an OIDC token issuer
+type IssuerSettings struct {
+	Name                string        `mapstructure:"name" description:"name of the issuer referenced by the rules as oidc:<name>"`
+	URL                 string        `mapstructure:"url" description:"url of the token issuer"`
+	ClientID            string        `mapstructure:"client_id" description:"audience the tokens of the issuer must have. If empty, the audience is not checked"`
+	JWKSRefreshInterval time.Duration `mapstructure:"jwks_refresh_interval" description:"maximum time the signing keys of the issuer are cached. If 0, keys are fetched again only for tokens signed with unknown keys"`
+	UserNameClaim       string        `mapstructure:"user_name_claim" description:"claim holding the user name, defaults to the sub claim"`
+	TenantClaim         string        `mapstructure:"tenant_claim" description:"claim holding the tenant, used instead of the tenant claim of the multitenancy filters"`
+	ScopesClaim         string        `mapstructure:"scopes_claim" description:"claim holding the scopes as an array or a space separated string, defaults to the scope claim"`
+}
+
+// Validate validates the issuer settings
+func (is *IssuerSettings) Validate() error {
+	if len(is.Name) == 0 {
+		return fmt.Errorf("validate Settings: security policy issuer name missing")
+	}
+	if len(is.URL) == 0 {
+		return fmt.Errorf("validate Settings: URL of security policy issuer '%s' missing", is.Name)
+	}
+	if is.JWKSRefreshInterval < 0 {
+		return fmt.Errorf("validate Settings: JWKS refresh interval of security policy issuer '%s' must not be negative", is.Name)
+	}
+	return nil
+}
+
+// IntrospectionSettings defines an OAuth 2.0 token introspection endpoint (RFC 7662) for opaque bearer tokens
+type IntrospectionSettings struct {
+	Name          string        `mapstructure:"name" description:"name of the introspection endpoint referenced by the rules as introspection:<name>"`
+	URL           string        `mapstructure:"url" description:"url of the token introspection endpoint"`
+	ClientID      string        `mapstructure:"client_id" description:"id of the client with which the introspection endpoint is called"`
+	ClientSecret  string        `mapstructure:"client_secret" description:"secret of the client with which the introspection endpoint is called" sensitive:"true"`
+	Timeout       time.Duration `mapstructure:"timeout" description:"timeout of introspection requests, defaults to 10s"`
+	CacheSize     int           `mapstructure:"cache_size" description:"maximum number of cached introspection results, defaults to 10000"`
+	UserNameClaim string        `mapstructure:"user_name_claim" description:"claim holding the user name, defaults to the username claim, the sub claim or the client_id claim"`
+	TenantClaim   string        `mapstructure:"tenant_claim" description:"claim holding the tenant, used instead of the tenant claim of the multitenancy filters"`
+	ScopesClaim   string        `mapstructure:"scopes_claim" description:"claim holding the scopes as an array or a space separated string, defaults to the scope claim"`
+}
+
+// Validate validates the introspection settings
+func (is *IntrospectionSettings) Validate() error {
+	if len(is.Name) == 0 {
+		return fmt.Errorf("validate Settings: security policy introspection endpoint name missing")
+	}
+	if len(is.URL) == 0 {
+		return fmt.Errorf("validate Settings: URL of security policy introspection endpoint '%s' missing", is.Name)
+	}
+	if is.Timeout < 0 {
+		return fmt.Errorf("validate Settings: timeout of security policy introspection endpoint '%s' must not be negative", is.Name)
+	}
+	if is.CacheSize < 0 {
+		return fmt.Errorf("validate Settings: cache size of security policy introspection endpoint '%s' must not be negative", is.Name)
+	}
+	return nil
+}
+
+// RuleSettings defines the authentication and authorization of the requests matching its paths and methods
+type RuleSettings struct {
+	Paths            []string `mapstructure:"paths" description:"path patterns the rule applies to"`
+	Methods          []string `mapstructure:"methods" description:"HTTP methods the rule applies to"`
+	Authenticators   []string `mapstructure:"authenticators" description:"authenticators tried in order: basic_platform, basic_osb, api_token, oidc, oidc:<issuer name> or introspection:<introspection endpoint name>"`
+	Scopes           []string `mapstructure:"scopes" description:"scopes the token must have"`
+	ClientIDSuffixes []string `mapstructure:"client_id_suffixes" description:"suffixes one of which the client id of the token must have"`
+	ClientID         string   `mapstructure:"client_id" description:"client id the token must have"`
+	AccessLevel      string   `mapstructure:"access_level" description:"access level granted by the rule: no_access, tenant, all_tenants or global"`
+	RBAC             bool     `mapstructure:"rbac" description:"whether the requests are authorized by the roles bound to the user"`
+	Optional         bool     `mapstructure:"optional" description:"whether authentication and authorization is optional for the paths and methods of the rule"`
+}
+
+// Validate validates the rule settings against the known issuers and introspection endpoints
+func (rs *RuleSettings) Validate(issuers, introspection map[string]bool) error {
+	if len(rs.Paths) == 0 {
+		return fmt.Errorf("validate Settings: security policy rule paths missing")
+	}
+	for _, path := range rs.Paths {
+		if _, err := glob.Compile(path, '/'); err != nil {
+			return fmt.Errorf("validate Settings: invalid security policy rule path '%s': %s", path, err)
+		}
+	}
+	if len(rs.Methods) == 0 {
+		return fmt.Errorf("validate Settings: methods of security policy rule for %v missing", rs.Paths)
+	}
+	for _, method := range rs.Methods {
+		if !contains(methods, method) {
+			return fmt.Errorf("validate Settings: invalid method '%s' in security policy rule for %v", method, rs.Paths)
+		}
+	}
+	for _, authenticator := range rs.Authenticators {
+		if !isKnownAuthenticator(authenticator, issuers, introspection) {
+			return fmt.Errorf("validate Settings: unknown authenticator '%s' in security policy rule for %v", authenticator, rs.Paths)
+		}
+	}
+	if len(rs.AccessLevel) != 0 {
+		if _, found := accessLevels[rs.AccessLevel]; !found {
+			return fmt.Errorf("validate Settings: invalid access level '%s' in security policy rule for %v", rs.AccessLevel, rs.Paths)
+		}
+	}
+	if len(rs.Authenticators) == 0 && !rs.HasAuthorization() {
+		return fmt.Errorf("validate Settings: security policy rule for %v has neither authenticators nor authorization", rs.Paths)
+	}
+	return nil
+}
+
+// HasAuthorization returns whether the rule authorizes the requests
+func (rs *RuleSettings) HasAuthorization() bool {
+	return len(rs.Scopes) != 0 || len(rs.ClientIDSuffixes) != 0 || len(rs.ClientID) != 0 || len(rs.AccessLevel) != 0 || rs.RBAC
+}
+
+// Level returns the access level granted by the rule
+func (rs *RuleSettings) Level() web.AccessLevel {
+	return accessLevels[rs.AccessLevel]
+}
+
+// IssuerName returns the name of the issuer referenced by an OIDC authenticator, or an empty name for the default issuer
+func IssuerName(authenticator string) (string, bool) {
+	if authenticator == OIDCAuthenticator {
+		return "", true
+	}
+	if strings.HasPrefix(authenticator, OIDCAuthenticator+":") {
+		return strings.TrimPrefix(authenticator, OIDCAuthenticator+":"), true
+	}
+	return "", false
+}
+
+// IntrospectionName returns the name of the introspection endpoint referenced by an introspection authenticator
+func IntrospectionName(authenticator string) (string, bool) {
+	if !strings.HasPrefix(authenticator, IntrospectionAuthenticator+":") {
+		return "", false
+	}
+	name := strings.TrimPrefix(authenticator, IntrospectionAuthenticator+":")
+	return name, len(name) != 0
+}
+
+func validate(issuers []IssuerSettings, introspection []IntrospectionSettings, rules []RuleSettings) error {
+	names := make(map[string]bool)
+	for _, issuer := range issuers {
+		if err := issuer.Validate(); err != nil {
+			return err
+		}
+		if names[issuer.Name] {
+			return fmt.Errorf("validate Settings: duplicate security policy issuer '%s'", issuer.Name)
+		}
+		names[issuer.Name] = true
+	}
+	introspectionNames := make(map[string]bool)
+	for _, endpoint := range introspection {
+		if err := endpoint.Validate(); err != nil {
+			return err
+		}
+		if introspectionNames[endpoint.Name] {
+			return fmt.Errorf("validate Settings: duplicate security policy introspection endpoint '%s'", endpoint.Name)
+		}
+		introspectionNames[endpoint.Name] = true
+	}
+	for _, rule := range rules {
+		if err := rule.Validate(names, introspectionNames); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isKnownAuthenticator(authenticator string, issuers, introspection map[string]bool) bool {
+	switch authenticator {
+	case BasicPlatformAuthenticator, BasicOSBAuthenticator, APITokenAuthenticator, OIDCAuthenticator:
+		return true
+	}
+	if name, ok := IntrospectionName(authenticator); ok {
+		return introspection[name]
+	}
+	name, ok := IssuerName(authenticator)
+	return ok && issuers[name]
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package policy_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/env"
+	"github.com/Peripli/service-manager/pkg/env/envfakes"
+	"github.com/Peripli/service-manager/pkg/security/policy"
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Settings", func() {
+	var settings *policy.Settings
+
+	BeforeEach(func() {
+		settings = policy.DefaultSettings()
+		settings.Issuers = []policy.IssuerSettings{
+			{Name: "partner", URL: "https://partner.example.com", ClientID: "sm"},
+		}
+		settings.Introspection = []policy.IntrospectionSettings{
+			{Name: "opaque", URL: "https://opaque.example.com/introspect", ClientID: "sm", ClientSecret: "secret"},
+		}
+		settings.Rules = []policy.RuleSettings{
+			{
+				Paths:          []string{web.ServiceInstancesURL + "/**"},
+				Methods:        []string{http.MethodGet},
+				Authenticators: []string{policy.OIDCAuthenticator, "oidc:partner", "introspection:opaque"},
+				Scopes:         []string{"sm.read"},
+				AccessLevel:    "tenant",
+			},
+		}
+	})
+
+	Describe("Validate", func() {
+		It("accepts a valid policy", func() {
+			Expect(settings.Validate()).ToNot(HaveOccurred())
+		})
+
+		It("accepts the default policy", func() {
+			settings.Rules = policy.DefaultRules()
+			Expect(settings.Validate()).ToNot(HaveOccurred())
+		})
+
+		DescribeTable("rejects invalid policies",
+			func(modify func()) {
+				modify()
+				Expect(settings.Validate()).To(HaveOccurred())
+			},
+			Entry("issuer without name", func() { settings.Issuers[0].Name = "" }),
+			Entry("issuer without url", func() { settings.Issuers[0].URL = "" }),
+			Entry("issuer with negative jwks refresh interval", func() { settings.Issuers[0].JWKSRefreshInterval = -time.Second }),
+			Entry("duplicate issuer", func() { settings.Issuers = append(settings.Issuers, settings.Issuers[0]) }),
+			Entry("introspection endpoint without name", func() { settings.Introspection[0].Name = "" }),
+			Entry("introspection endpoint without url", func() { settings.Introspection[0].URL = "" }),
+			Entry("introspection endpoint with negative timeout", func() { settings.Introspection[0].Timeout = -time.Second }),
+			Entry("introspection endpoint with negative cache size", func() { settings.Introspection[0].CacheSize = -1 }),
+			Entry("duplicate introspection endpoint", func() { settings.Introspection = append(settings.Introspection, settings.Introspection[0]) }),
+			Entry("rule without paths", func() { settings.Rules[0].Paths = nil }),
+			Entry("rule with invalid path", func() { settings.Rules[0].Paths = []string{"/v1/[a"} }),
+			Entry("rule without methods", func() { settings.Rules[0].Methods = nil }),
+			Entry("rule with invalid method", func() { settings.Rules[0].Methods = []string{"FETCH"} }),
+			Entry("rule with unknown authenticator", func() { settings.Rules[0].Authenticators = []string{"ldap"} }),
+			Entry("rule with unknown issuer", func() { settings.Rules[0].Authenticators = []string{"oidc:unknown"} }),
+			Entry("rule with unknown introspection endpoint", func() { settings.Rules[0].Authenticators = []string{"introspection:unknown"} }),
+			Entry("rule with introspection endpoint without name", func() { settings.Rules[0].Authenticators = []string{"introspection"} }),
+			Entry("rule with invalid access level", func() { settings.Rules[0].AccessLevel = "admin" }),
+			Entry("rule without authentication and authorization", func() {
+				settings.Rules[0] = policy.RuleSettings{Paths: []string{"/**"}, Methods: []string{http.MethodGet}}
+			}),
+		)
+	})
+
+	Describe("DefaultRules", func() {
+		It("requires the admin scope for the administrative APIs", func() {
+			for _, rule := range policy.DefaultRules() {
+				for _, path := range rule.Paths {
+					if path == web.AdminURL+"/**" {
+						Expect(rule.Scopes).To(Equal([]string{policy.AdminScope}))
+						Expect(rule.HasAuthorization()).To(BeTrue())
+					} else {
+						Expect(path).ToNot(HavePrefix(web.AdminURL))
+					}
+				}
+			}
+		})
+	})
+
+	Describe("Load", func() {
+		It("falls back to the default rules", func() {
+			settings.Rules = nil
+			loaded, err := settings.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded.Rules).To(Equal(policy.DefaultRules()))
+			Expect(loaded.Issuers).To(Equal(settings.Issuers))
+		})
+
+		It("returns the configured rules", func() {
+			loaded, err := settings.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded.Rules).To(Equal(settings.Rules))
+			Expect(loaded.Rules[0].Level()).To(Equal(web.TenantAccess))
+		})
+
+		Context("when a policy file is configured", func() {
+			var dir string
+
+			BeforeEach(func() {
+				var err error
+				dir, err = ioutil.TempDir("", "policy")
+				Expect(err).ToNot(HaveOccurred())
+				settings.File = filepath.Join(dir, "policy.yml")
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(dir)
+			})
+
+			It("replaces the configured policy with the policy of the file", func() {
+				content := `
+issuers:
+  - name: internal
+    url: https://internal.example.com
+    client_id: internal-client
+    jwks_refresh_interval: 1h
+    tenant_claim: ext_attr.tid
+rules:
+  - paths: ["/v1/platforms/**"]
+    methods: [GET, POST]
+    authenticators: [oidc:internal]
+    client_id_suffixes: ["-admin"]
+    access_level: global
+`
+				Expect(ioutil.WriteFile(settings.File, []byte(content), 0640)).To(Succeed())
+
+				loaded, err := settings.Load()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(loaded.Issuers).To(Equal([]policy.IssuerSettings{
+					{
+						Name:                "internal",
+						URL:                 "https://internal.example.com",
+						ClientID:            "internal-client",
+						JWKSRefreshInterval: time.Hour,
+						TenantClaim:         "ext_attr.tid",
+					},
+				}))
+				Expect(loaded.Rules).To(Equal([]policy.RuleSettings{
+					{
+						Paths:            []string{"/v1/platforms/**"},
+						Methods:          []string{http.MethodGet, http.MethodPost},
+						Authenticators:   []string{"oidc:internal"},
+						ClientIDSuffixes: []string{"-admin"},
+						AccessLevel:      "global",
+					},
+				}))
+			})
+
+			It("fails when the policy of the file is invalid", func() {
+				content := `
+rules:
+  - paths: ["/v1/platforms/**"]
+    methods: [GET]
+    authenticators: [oidc:missing]
+`
+				Expect(ioutil.WriteFile(settings.File, []byte(content), 0640)).To(Succeed())
+
+				_, err := settings.Load()
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("fails when the file does not exist", func() {
+				settings.File = filepath.Join(dir, "missing.yml")
+				_, err := settings.Load()
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Redaction", func() {
+		It("redacts the client secrets of the introspection endpoints", func() {
+			environment := &envfakes.FakeEnvironment{}
+			environment.AllSettingsReturns(map[string]interface{}{
+				"introspection": []interface{}{
+					map[string]interface{}{"name": "partner", "client_id": "sm", "client_secret": "secret"},
+				},
+			})
+
+			redacted := env.RedactedSettings(environment, settings)
+			Expect(redacted["introspection"]).To(Equal([]interface{}{
+				map[string]interface{}{"name": "partner", "client_id": "sm", "client_secret": env.RedactedValue},
+			}))
+		})
+	})
+})
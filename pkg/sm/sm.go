@@ -47,7 +47,9 @@ import (
 	"github.com/Peripli/service-manager/storage/interceptors"
 
 	"github.com/Peripli/service-manager/api"
+	"github.com/Peripli/service-manager/api/archive"
 	"github.com/Peripli/service-manager/api/healthcheck"
+	"github.com/Peripli/service-manager/api/integrity"
 	"github.com/Peripli/service-manager/config"
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/server"
@@ -77,6 +79,8 @@ type ServiceManagerBuilder struct {
 	securityBuilder      *SecurityBuilder
 	encryptingRepository storage.TransactionalRepository
 	APIOptions           *api.Options
+
+	apiTokenInterceptorProvider *interceptors.GenerateAPITokenInterceptorProvider
 }
 
 // ServiceManager  struct
@@ -136,6 +140,10 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 		return nil, fmt.Errorf("could not create notificator: %v", err)
 	}
 
+	if len(cfg.Operations.TenantLabelKey) == 0 {
+		cfg.Operations.TenantLabelKey = cfg.Multitenancy.LabelKey
+	}
+
 	apiOptions := &api.Options{
 		Repository:        interceptableRepository,
 		APISettings:       cfg.API,
@@ -145,6 +153,7 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 		WaitGroup:         waitGroup,
 		TenantLabelKey:    cfg.Multitenancy.LabelKey,
 		Agents:            cfg.Agents,
+		ConfigSettings:    config.DefaultSettings(),
 	}
 	API, err := api.New(ctx, e, apiOptions)
 	if err != nil {
@@ -157,7 +166,8 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 	securityBuilder, securityFilters := NewSecurityBuilder()
 	API.RegisterFiltersAfter(filters.LoggingFilterName, securityFilters...)
 	API.RegisterFilters(&filters.RegeneratePlatformCredentialsFilter{}, &filters.TechnicalPlatformFilter{Storage: interceptableRepository})
-	API.RegisterFiltersAfter(secFilters.AuthorizationFilterName, &filters.CheckPlatformSuspendedFilter{}, &filters.ForceDeleteValidationFilter{})
+	API.RegisterFiltersAfter(secFilters.AuthorizationFilterName, &filters.CheckPlatformSuspendedFilter{}, &filters.ForceDeleteValidationFilter{},
+		filters.NewIdempotencyFilter(interceptableRepository, cfg.Operations.IdempotencyKeyLifespan))
 
 	storageHealthIndicator, err := storage.NewSQLHealthIndicator(storage.PingFunc(smStorage.PingContext))
 	if err != nil {
@@ -208,16 +218,62 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 		OSBClientProvider:    osbClientProvider,
 		encryptingRepository: encryptingRepository,
 		APIOptions:           apiOptions,
+
+		apiTokenInterceptorProvider: &interceptors.GenerateAPITokenInterceptorProvider{},
 	}
 
+	smb.RegisterControllers(integrity.NewController(interceptableRepository, &storage.IntegrityVerifier{
+		Repository:         encryptingRepository,
+		IntegrityProcessor: cfg.Storage.IntegrityProcessor,
+	}, cfg.Operations.ActionTimeout, waitGroup))
+	smb.RegisterControllers(archive.NewController(&storage.Archiver{
+		Repository:         encryptingRepository,
+		IntegrityProcessor: cfg.Storage.IntegrityProcessor,
+		Encrypter:          &security.AESEncrypter{},
+	}, cfg.Multitenancy.LabelKey))
+
 	smb.RegisterPlugins(osb.NewCatalogFilterByVisibilityPlugin(interceptableRepository))
 	smb.RegisterPluginsBefore(osb.CheckInstanceOwnerhipPluginName, osb.NewStorePlugin(interceptableRepository))
 	smb.RegisterPluginsBefore(osb.OSBStorePluginName, osb.NewCheckVisibilityPlugin(interceptableRepository))
 	smb.RegisterPlugins(osb.NewCheckPlatformIDPlugin(interceptableRepository))
 	smb.RegisterPlugins(osb.NewPlatformTerminationPlugin(interceptableRepository))
 	smb.RegisterPlugins(osb.NewInstanceSharingPlugin(transactionalRepository, cfg.Multitenancy.LabelKey))
+	for i := range cfg.API.RemotePlugins {
+		remotePlugin, err := osb.NewRemotePlugin(&cfg.API.RemotePlugins[i], nil)
+		if err != nil {
+			return nil, err
+		}
+		smb.RegisterPlugins(remotePlugin)
+	}
 	smb.RegisterPlugins(osb.NewCtxSignaturePlugin(&osb.ContextSigner{ContextPrivateKey: cfg.API.OSBRSAPrivateKey}))
 
+	// Register the admission webhooks first so that they are the outermost interceptors and admit the mutations
+	// requested by users before any of the core SM business logic is applied
+	baseAdmissionWebhookInterceptorProvider := &interceptors.BaseAdmissionWebhookInterceptorProvider{
+		Repository: interceptableRepository,
+	}
+	for _, objectType := range []types.ObjectType{types.ServiceBrokerType, types.PlatformType, types.VisibilityType, types.ServiceInstanceType, types.ServiceBindingType} {
+		smb.
+			WithCreateAroundTxInterceptorProvider(objectType, &interceptors.AdmissionWebhookCreateInterceptorProvider{
+				BaseAdmissionWebhookInterceptorProvider: baseAdmissionWebhookInterceptorProvider,
+			}).Register().
+			WithUpdateAroundTxInterceptorProvider(objectType, &interceptors.AdmissionWebhookUpdateInterceptorProvider{
+				BaseAdmissionWebhookInterceptorProvider: baseAdmissionWebhookInterceptorProvider,
+			}).Register().
+			WithDeleteAroundTxInterceptorProvider(objectType, &interceptors.AdmissionWebhookDeleteInterceptorProvider{
+				BaseAdmissionWebhookInterceptorProvider: baseAdmissionWebhookInterceptorProvider,
+				ObjectType:                              objectType,
+			}).Register()
+	}
+
+	// Enforce the label policies in the transactions storing the labels, so that the labels admitted by the webhooks
+	// and the ones of internal updates are checked as well
+	for _, objectType := range types.LabelGovernedTypes {
+		smb.
+			WithCreateOnTxInterceptorProvider(objectType, &interceptors.LabelPolicyCreateInterceptorProvider{}).Register().
+			WithUpdateOnTxInterceptorProvider(objectType, &interceptors.LabelPolicyUpdateInterceptorProvider{}).Register()
+	}
+
 	// Register default interceptors that represent the core SM business logic
 	smb.
 		WithCreateInterceptorProvider(types.ServiceBrokerType, &interceptors.BrokerCreateCatalogInterceptorProvider{
@@ -232,6 +288,8 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 		}).Register().
 		WithCreateAroundTxInterceptorProvider(types.PlatformType, &interceptors.GeneratePlatformCredentialsInterceptorProvider{}).Register().
 		WithUpdateAroundTxInterceptorProvider(types.PlatformType, &interceptors.RegeneratePlatformCredentialsInterceptorProvider{}).Register().
+		WithCreateAroundTxInterceptorProvider(types.APITokenType, smb.apiTokenInterceptorProvider).Register().
+		WithUpdateOnTxInterceptorProvider(types.PlatformType, &interceptors.PlatformVisibilityNotificationsInterceptorProvider{}).Register().
 		WithCreateOnTxInterceptorProvider(types.VisibilityType, &interceptors.VisibilityCreateNotificationsInterceptorProvider{}).Register().
 		WithUpdateOnTxInterceptorProvider(types.VisibilityType, &interceptors.VisibilityUpdateNotificationsInterceptorProvider{}).Register().
 		WithDeleteOnTxInterceptorProvider(types.VisibilityType, &interceptors.VisibilityDeleteNotificationsInterceptorProvider{}).Register().
@@ -265,6 +323,12 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 			TenantIdentifier: cfg.Multitenancy.LabelKey,
 			Repository:       interceptableRepository,
 		}).Register().
+		WithCreateAroundTxInterceptorProvider(types.ServiceInstanceType, &interceptors.InstanceLeaseCreateInterceptorProvider{
+			Repository: interceptableRepository,
+		}).Register().
+		WithUpdateAroundTxInterceptorProvider(types.ServiceInstanceType, &interceptors.InstanceLeaseUpdateInterceptorProvider{
+			Repository: interceptableRepository,
+		}).Register().
 		WithCreateAroundTxInterceptorProvider(types.ServiceBindingType, &interceptors.UniqueBindingNameCreateInterceptorProvider{
 			Repository: interceptableRepository,
 		}).Register().
@@ -562,6 +626,8 @@ func (smb *ServiceManagerBuilder) EnableMultitenancy(labelKey string, extractTen
 	if err != nil {
 		return nil, err
 	}
+	// API tokens created by tenant users are restricted to the tenant resolved for the multitenancy filters
+	smb.apiTokenInterceptorProvider.ExtractTenantFunc = extractTenantFunc
 	smb.RegisterFiltersAfter(filters.ProtectedLabelsFilterName, multitenancyFilters...)
 	smb.RegisterFiltersAfter(fmt.Sprintf("%s%s", filters.LabelName, filters.ResourceLabelingFilterNameSuffix), filters.NewExtractPlanIDByServiceAndPlanNameFilter(smb.Storage, DefaultGetVisibilityMetadataFunc(labelKey)))
 	smb.RegisterFilters(
@@ -570,11 +636,20 @@ func (smb *ServiceManagerBuilder) EnableMultitenancy(labelKey string, extractTen
 	)
 
 	smb.RegisterPlugins(osb.NewCheckInstanceOwnershipPlugin(smb.Storage, labelKey))
+	smb.RegisterPlugins(osb.NewQuotaPlugin(smb.Storage, labelKey))
 
 	smb.WithCreateOnTxInterceptorProvider(types.ServiceInstanceType, interceptors.NewOSBServiceInstanceTenantLabelingInterceptor(labelKey)).
 		AroundTxAfter(interceptors.ServiceInstanceCreateInterceptorProviderName).Register()
 	smb.WithCreateOnTxInterceptorProvider(types.ServiceBindingType, interceptors.NewOSBBindingTenantLabelingInterceptor(labelKey)).
 		AroundTxAfter(interceptors.ServiceBindingCreateInterceptorProviderName).Register()
+	quotaInterceptorProvider := &interceptors.QuotaCreateInterceptorProvider{
+		TenantIdentifier: labelKey,
+		Repository:       smb.Storage,
+	}
+	smb.WithCreateInterceptorProvider(types.ServiceInstanceType, quotaInterceptorProvider).
+		AroundTxBefore(interceptors.ServiceInstanceCreateInterceptorProviderName).Register()
+	smb.WithCreateInterceptorProvider(types.ServiceBindingType, quotaInterceptorProvider).
+		AroundTxBefore(interceptors.ServiceBindingCreateInterceptorProviderName).Register()
 	smb.WithCreateOnTxInterceptorProvider(types.OperationType, &interceptors.OperationsCreateInsterceptorProvider{
 		TenantIdentifier: labelKey,
 	}).Register()
@@ -591,8 +666,8 @@ func (smb *ServiceManagerBuilder) Security() *SecurityBuilder {
 }
 
 func (smb *ServiceManagerBuilder) calculateIntegrity() error {
+	objectTypesWithIntegrity := storage.IntegralTypes
 	return smb.encryptingRepository.InTransaction(smb.ctx, func(ctx context.Context, storage storage.Repository) error {
-		objectTypesWithIntegrity := []types.ObjectType{types.PlatformType, types.ServiceBrokerType, types.ServiceBindingType, types.BrokerPlatformCredentialType}
 		for _, objectType := range objectTypesWithIntegrity {
 			criteria := []query.Criterion{
 				query.ByField(query.EqualsOrNilOperator, "integrity", ""),
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const (
+	// MutatingWebhook is the kind of admission webhooks which may modify the admitted objects
+	MutatingWebhook = "mutating"
+	// ValidatingWebhook is the kind of admission webhooks which only allow or reject the admitted objects
+	ValidatingWebhook = "validating"
+
+	// FailurePolicyFail rejects the mutation if the admission webhook cannot be called
+	FailurePolicyFail = "fail"
+	// FailurePolicyIgnore admits the mutation if the admission webhook cannot be called
+	FailurePolicyIgnore = "ignore"
+
+	// DefaultWebhookTimeoutSeconds is the timeout of admission webhooks without a timeout
+	DefaultWebhookTimeoutSeconds = 10
+	// MaxWebhookTimeoutSeconds is the maximum timeout of admission webhooks
+	MaxWebhookTimeoutSeconds = 30
+)
+
+//go:generate smgen api AdmissionWebhook
+// AdmissionWebhook is an HTTPS endpoint which is called before resources are created, updated or deleted and which can
+// reject the mutation or, if it is a mutating webhook, modify the object with a JSON patch
+type AdmissionWebhook struct {
+	Base
+
+	Name           string   `json:"name"`
+	Kind           string   `json:"kind"`
+	URL            string   `json:"url"`
+	ResourceTypes  []string `json:"resource_types"`
+	Operations     []string `json:"operations"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"`
+	FailurePolicy  string   `json:"failure_policy,omitempty"`
+}
+
+func (e *AdmissionWebhook) Equals(obj Object) bool {
+	if !Equals(e, obj) {
+		return false
+	}
+
+	webhook := obj.(*AdmissionWebhook)
+	if e.Name != webhook.Name ||
+		e.Kind != webhook.Kind ||
+		e.URL != webhook.URL ||
+		e.TimeoutSeconds != webhook.TimeoutSeconds ||
+		e.FailurePolicy != webhook.FailurePolicy ||
+		!reflect.DeepEqual(e.ResourceTypes, webhook.ResourceTypes) ||
+		!reflect.DeepEqual(e.Operations, webhook.Operations) {
+		return false
+	}
+
+	return true
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *AdmissionWebhook) Validate() error {
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	if e.Name == "" {
+		return errors.New("missing admission webhook name")
+	}
+	if e.Kind != MutatingWebhook && e.Kind != ValidatingWebhook {
+		return fmt.Errorf("kind of admission webhook %s must be %s or %s", e.Name, MutatingWebhook, ValidatingWebhook)
+	}
+	webhookURL, err := url.Parse(e.URL)
+	if err != nil || webhookURL.Scheme != "https" || webhookURL.Host == "" {
+		return fmt.Errorf("url of admission webhook %s must be an https url", e.Name)
+	}
+	if len(e.ResourceTypes) == 0 {
+		return fmt.Errorf("missing resource types of admission webhook %s", e.Name)
+	}
+	if len(e.Operations) == 0 {
+		return fmt.Errorf("missing operations of admission webhook %s", e.Name)
+	}
+	for _, operation := range e.Operations {
+		if operation != VerbCreate && operation != VerbUpdate && operation != VerbDelete && operation != AnyPermission {
+			return fmt.Errorf("invalid operation %s of admission webhook %s", operation, e.Name)
+		}
+	}
+	if e.TimeoutSeconds < 0 || e.TimeoutSeconds > MaxWebhookTimeoutSeconds {
+		return fmt.Errorf("timeout of admission webhook %s must be between 0 and %d seconds", e.Name, MaxWebhookTimeoutSeconds)
+	}
+	if e.FailurePolicy != "" && e.FailurePolicy != FailurePolicyFail && e.FailurePolicy != FailurePolicyIgnore {
+		return fmt.Errorf("failure policy of admission webhook %s must be %s or %s", e.Name, FailurePolicyFail, FailurePolicyIgnore)
+	}
+	if err := e.Labels.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Matches returns whether the webhook admits the operation on resources of the type. The resource type is the name of
+// the resource collection in the API path, for example service_instances.
+func (e *AdmissionWebhook) Matches(resourceType, operation string) bool {
+	return containsOrAny(e.ResourceTypes, resourceType) && containsOrAny(e.Operations, operation)
+}
+
+// Timeout returns the timeout of calls to the webhook
+func (e *AdmissionWebhook) Timeout() time.Duration {
+	if e.TimeoutSeconds == 0 {
+		return DefaultWebhookTimeoutSeconds * time.Second
+	}
+	return time.Duration(e.TimeoutSeconds) * time.Second
+}
+
+// FailOpen returns whether mutations are admitted if the webhook cannot be called
+func (e *AdmissionWebhook) FailOpen() bool {
+	return e.FailurePolicy == FailurePolicyIgnore
+}
+
+func containsOrAny(values []string, value string) bool {
+	for _, v := range values {
+		if v == value || v == AnyPermission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const AdmissionWebhookType ObjectType = web.AdmissionWebhooksURL
+
+type AdmissionWebhooks struct {
+	AdmissionWebhooks []*AdmissionWebhook `json:"admission_webhooks"`
+}
+
+func (e *AdmissionWebhooks) Add(object Object) {
+	e.AdmissionWebhooks = append(e.AdmissionWebhooks, object.(*AdmissionWebhook))
+}
+
+func (e *AdmissionWebhooks) ItemAt(index int) Object {
+	return e.AdmissionWebhooks[index]
+}
+
+func (e *AdmissionWebhooks) Len() int {
+	return len(e.AdmissionWebhooks)
+}
+
+func (e *AdmissionWebhook) GetType() ObjectType {
+	return AdmissionWebhookType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *AdmissionWebhook) MarshalJSON() ([]byte, error) {
+	type E AdmissionWebhook
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// APITokenPrefix is the prefix of all API tokens which distinguishes them from other bearer tokens
+const APITokenPrefix = "smt_"
+
+//go:generate smgen api APIToken
+// APIToken is a personal token which authenticates its bearer with the scopes and the tenant of the token.
+// Only the hash of the token is stored, the token itself is returned once when it is created.
+type APIToken struct {
+	Base
+
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes,omitempty"`
+	Tenant     string     `json:"tenant,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	Token      string     `json:"token,omitempty"`
+	TokenHash  string     `json:"-"`
+}
+
+func (e *APIToken) Equals(obj Object) bool {
+	if !Equals(e, obj) {
+		return false
+	}
+
+	token := obj.(*APIToken)
+	if e.Name != token.Name ||
+		e.Tenant != token.Tenant ||
+		e.CreatedBy != token.CreatedBy ||
+		e.TokenHash != token.TokenHash ||
+		!reflect.DeepEqual(e.Scopes, token.Scopes) ||
+		!equalTimes(e.ExpiresAt, token.ExpiresAt) ||
+		!equalTimes(e.LastUsedAt, token.LastUsedAt) {
+		return false
+	}
+
+	return true
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *APIToken) Validate() error {
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	if e.Name == "" {
+		return errors.New("missing API token name")
+	}
+	if e.ExpiresAt == nil {
+		return fmt.Errorf("missing expiry of API token %s", e.Name)
+	}
+	if !e.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("expiry of API token %s must be in the future", e.Name)
+	}
+	if err := e.Labels.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Expired returns whether the token is expired at the given time
+func (e *APIToken) Expired(now time.Time) bool {
+	return e.ExpiresAt == nil || !now.Before(*e.ExpiresAt)
+}
+
+// GenerateToken sets a new random token and its hash
+func (e *APIToken) GenerateToken() error {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	e.Token = APITokenPrefix + base64.RawURLEncoding.EncodeToString(token)
+	e.TokenHash = HashAPIToken(e.Token)
+	return nil
+}
+
+// HashAPIToken returns the hash under which an API token is stored
+func HashAPIToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,65 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const APITokenType ObjectType = web.APITokensURL
+
+type APITokens struct {
+	APITokens []*APIToken `json:"api_tokens"`
+}
+
+func (e *APITokens) Add(object Object) {
+	e.APITokens = append(e.APITokens, object.(*APIToken))
+}
+
+func (e *APITokens) ItemAt(index int) Object {
+	return e.APITokens[index]
+}
+
+func (e *APITokens) Len() int {
+	return len(e.APITokens)
+}
+
+func (e *APIToken) GetType() ObjectType {
+	return APITokenType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *APIToken) MarshalJSON() ([]byte, error) {
+	type E APIToken
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
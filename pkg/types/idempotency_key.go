@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+//go:generate smgen api IdempotencyKey
+// IdempotencyKey is the result of a create request with an Idempotency-Key header.
+// Retries of the request with the same key are answered with the stored result.
+type IdempotencyKey struct {
+	Base
+
+	Key         string `json:"key"`
+	UserName    string `json:"user_name"`
+	RequestHash string `json:"request_hash"`
+
+	// StatusCode is zero while the request is being processed
+	StatusCode int    `json:"status_code,omitempty"`
+	Location   string `json:"location,omitempty"`
+	Response   []byte `json:"-"`
+}
+
+// Completed reports whether the result of the request is stored
+func (e *IdempotencyKey) Completed() bool {
+	return e.StatusCode != 0
+}
+
+func (e *IdempotencyKey) Equals(obj Object) bool {
+	if !Equals(e, obj) {
+		return false
+	}
+
+	key := obj.(*IdempotencyKey)
+	return e.Key == key.Key &&
+		e.UserName == key.UserName &&
+		e.RequestHash == key.RequestHash &&
+		e.StatusCode == key.StatusCode &&
+		e.Location == key.Location
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *IdempotencyKey) Validate() error {
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	if e.Key == "" {
+		return errors.New("missing idempotency key")
+	}
+	if e.RequestHash == "" {
+		return errors.New("missing idempotency key request hash")
+	}
+	return nil
+}
+
+// Encrypt encrypts the stored response, which may contain credentials
+func (e *IdempotencyKey) Encrypt(ctx context.Context, encryptionFunc func(context.Context, []byte) ([]byte, error)) error {
+	return e.transform(ctx, encryptionFunc)
+}
+
+// Decrypt decrypts the stored response
+func (e *IdempotencyKey) Decrypt(ctx context.Context, decryptionFunc func(context.Context, []byte) ([]byte, error)) error {
+	return e.transform(ctx, decryptionFunc)
+}
+
+func (e *IdempotencyKey) transform(ctx context.Context, transformationFunc func(context.Context, []byte) ([]byte, error)) error {
+	if len(e.Response) == 0 {
+		return nil
+	}
+	transformedResponse, err := transformationFunc(ctx, e.Response)
+	if err != nil {
+		return err
+	}
+	e.Response = transformedResponse
+	return nil
+}
@@ -0,0 +1,65 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const IdempotencyKeyType ObjectType = web.IdempotencyKeysURL
+
+type IdempotencyKeys struct {
+	IdempotencyKeys []*IdempotencyKey `json:"idempotency_keys"`
+}
+
+func (e *IdempotencyKeys) Add(object Object) {
+	e.IdempotencyKeys = append(e.IdempotencyKeys, object.(*IdempotencyKey))
+}
+
+func (e *IdempotencyKeys) ItemAt(index int) Object {
+	return e.IdempotencyKeys[index]
+}
+
+func (e *IdempotencyKeys) Len() int {
+	return len(e.IdempotencyKeys)
+}
+
+func (e *IdempotencyKey) GetType() ObjectType {
+	return IdempotencyKeyType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *IdempotencyKey) MarshalJSON() ([]byte, error) {
+	type E IdempotencyKey
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
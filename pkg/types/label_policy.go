@@ -0,0 +1,215 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// LabelGovernedTypes are the types of the resources whose labels are governed by label policies
+var LabelGovernedTypes = []ObjectType{
+	ServiceBrokerType,
+	PlatformType,
+	VisibilityType,
+	ServiceInstanceType,
+	ServiceBindingType,
+}
+
+//go:generate smgen api LabelPolicy
+// LabelPolicy governs the labels of the resources of a type, for example which labels are required and which values
+// they can have
+type LabelPolicy struct {
+	Base
+
+	ResourceType string            `json:"resource_type"`
+	MaxLabels    int               `json:"max_labels,omitempty"`
+	Keys         []*LabelKeyPolicy `json:"keys"`
+}
+
+// LabelKeyPolicy governs the values of a label key
+type LabelKeyPolicy struct {
+	Key string `json:"key"`
+	// Required labels must be set on create and cannot be removed
+	Required bool `json:"required,omitempty"`
+	// AllowedValues are the values the label can have, any value is allowed if empty
+	AllowedValues []string `json:"allowed_values,omitempty"`
+	// Pattern is a regular expression which all values of the label must match
+	Pattern string `json:"pattern,omitempty"`
+	// SingleValued labels can have at most one value
+	SingleValued bool `json:"single_valued,omitempty"`
+	// Immutable labels can only be set on create
+	Immutable bool `json:"immutable,omitempty"`
+}
+
+func (e *LabelPolicy) Equals(obj Object) bool {
+	if !Equals(e, obj) {
+		return false
+	}
+
+	policy := obj.(*LabelPolicy)
+	if e.ResourceType != policy.ResourceType ||
+		e.MaxLabels != policy.MaxLabels ||
+		!reflect.DeepEqual(e.Keys, policy.Keys) {
+		return false
+	}
+
+	return true
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *LabelPolicy) Validate() error {
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	if e.ResourceType == "" {
+		return errors.New("missing label policy resource type")
+	}
+	if !isLabelGovernedResourceType(e.ResourceType) {
+		resourceTypes := make([]string, 0, len(LabelGovernedTypes))
+		for _, objectType := range LabelGovernedTypes {
+			resourceTypes = append(resourceTypes, path.Base(string(objectType)))
+		}
+		return fmt.Errorf("label policy resource type %s is not one of %s", e.ResourceType, strings.Join(resourceTypes, ", "))
+	}
+	if e.MaxLabels < 0 {
+		return fmt.Errorf("max labels of label policy for %s must not be negative", e.ResourceType)
+	}
+	keys := make(map[string]bool, len(e.Keys))
+	for _, keyPolicy := range e.Keys {
+		if keyPolicy == nil || keyPolicy.Key == "" {
+			return fmt.Errorf("missing label key in label policy for %s", e.ResourceType)
+		}
+		if keys[keyPolicy.Key] {
+			return fmt.Errorf("duplicate label key %s in label policy for %s", keyPolicy.Key, e.ResourceType)
+		}
+		keys[keyPolicy.Key] = true
+		if keyPolicy.Pattern != "" {
+			if _, err := regexp.Compile(keyPolicy.Pattern); err != nil {
+				return fmt.Errorf("invalid pattern of label %s in label policy for %s: %s", keyPolicy.Key, e.ResourceType, err)
+			}
+		}
+	}
+	if err := e.Labels.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// CheckLabels verifies that the labels of a created resource comply with the policy
+func (e *LabelPolicy) CheckLabels(labels Labels) error {
+	if e.MaxLabels > 0 && len(labels) > e.MaxLabels {
+		return fmt.Errorf("%s can have at most %d labels", e.ResourceType, e.MaxLabels)
+	}
+	for _, keyPolicy := range e.Keys {
+		values, found := labels[keyPolicy.Key]
+		if !found {
+			if keyPolicy.Required {
+				return fmt.Errorf("label %s is required for %s", keyPolicy.Key, e.ResourceType)
+			}
+			continue
+		}
+		if err := keyPolicy.checkValues(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckLabelChanges verifies that the label changes of an updated resource comply with the policy. The labels are the
+// labels of the resource with the changes applied. Only the changed labels are checked, so that resources which were
+// created before the policy can still be updated.
+func (e *LabelPolicy) CheckLabelChanges(oldLabels, labels Labels, changes LabelChanges) error {
+	if e.MaxLabels > 0 && len(labels) > e.MaxLabels && len(labels) > len(oldLabels) {
+		return fmt.Errorf("%s can have at most %d labels", e.ResourceType, e.MaxLabels)
+	}
+	for _, change := range changes {
+		keyPolicy := e.keyPolicy(change.Key)
+		if keyPolicy == nil {
+			continue
+		}
+		if keyPolicy.Immutable {
+			return fmt.Errorf("label %s of %s cannot be modified", keyPolicy.Key, e.ResourceType)
+		}
+		values, found := labels[keyPolicy.Key]
+		if !found {
+			if keyPolicy.Required {
+				return fmt.Errorf("label %s is required for %s and cannot be removed", keyPolicy.Key, e.ResourceType)
+			}
+			continue
+		}
+		if err := keyPolicy.checkValues(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *LabelPolicy) keyPolicy(key string) *LabelKeyPolicy {
+	for _, keyPolicy := range e.Keys {
+		if keyPolicy.Key == key {
+			return keyPolicy
+		}
+	}
+	return nil
+}
+
+func (k *LabelKeyPolicy) checkValues(values []string) error {
+	if k.SingleValued && len(values) > 1 {
+		return fmt.Errorf("label %s can have only one value", k.Key)
+	}
+	var pattern *regexp.Regexp
+	if k.Pattern != "" {
+		var err error
+		if pattern, err = regexp.Compile("^(?:" + k.Pattern + ")$"); err != nil {
+			return fmt.Errorf("invalid pattern of label %s: %s", k.Key, err)
+		}
+	}
+	for _, value := range values {
+		if len(k.AllowedValues) != 0 && !containsValue(k.AllowedValues, value) {
+			return fmt.Errorf("value %s of label %s is not one of the allowed values %s", value, k.Key, strings.Join(k.AllowedValues, ", "))
+		}
+		if pattern != nil && !pattern.MatchString(value) {
+			return fmt.Errorf("value %s of label %s does not match %s", value, k.Key, k.Pattern)
+		}
+	}
+	return nil
+}
+
+func isLabelGovernedResourceType(resourceType string) bool {
+	for _, objectType := range LabelGovernedTypes {
+		if path.Base(string(objectType)) == resourceType {
+			return true
+		}
+	}
+	return false
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const LabelPolicyType ObjectType = web.LabelPoliciesURL
+
+type LabelPolicies struct {
+	LabelPolicies []*LabelPolicy `json:"label_policies"`
+}
+
+func (e *LabelPolicies) Add(object Object) {
+	e.LabelPolicies = append(e.LabelPolicies, object.(*LabelPolicy))
+}
+
+func (e *LabelPolicies) ItemAt(index int) Object {
+	return e.LabelPolicies[index]
+}
+
+func (e *LabelPolicies) Len() int {
+	return len(e.LabelPolicies)
+}
+
+func (e *LabelPolicy) GetType() ObjectType {
+	return LabelPolicyType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *LabelPolicy) MarshalJSON() ([]byte, error) {
+	type E LabelPolicy
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
@@ -55,6 +55,9 @@ const (
 
 	// FAILED represents the state of an operation after unsuccessful execution
 	FAILED OperationState = "failed"
+
+	// AWAITING_APPROVAL represents the state of an operation which is not executed until it is approved
+	AWAITING_APPROVAL OperationState = "awaiting approval"
 )
 
 type RelatedType struct {
@@ -87,6 +90,13 @@ type Operation struct {
 	RescheduleTimestamp time.Time `json:"reschedule_timestamp,omitempty"`
 	// DeletionScheduled specifies the time when an operation was marked for deletion
 	DeletionScheduled time.Time `json:"deletion_scheduled,omitempty"`
+	// QueuePosition is the position of an operation waiting for a worker among the queued operations of its tenant.
+	// It is not stored and is set only when a single operation is fetched.
+	QueuePosition int `json:"queue_position,omitempty"`
+	// Payload holds the resource and label changes of an update, so that the update can be replayed when it is retried
+	Payload []byte `json:"-"`
+	// EncryptedPayload is the stored form of the payload
+	EncryptedPayload []byte `json:"-"`
 }
 
 type UserInfo struct {
@@ -101,7 +111,11 @@ type OperationContext struct {
 	ServicePlanID     string            `json:"service_plan_id"`
 	ServiceInstanceID string            `json:"service_instance_id"`
 	UserInfo          *UserInfo         `json:"user_info"`
+	RequestedBy       string            `json:"requested_by,omitempty"`
 	Params            map[string]string `json:"params"`
+	Approved          bool              `json:"approved,omitempty"`
+	Cancelled         bool              `json:"cancelled,omitempty"`
+	Retried           bool              `json:"retried,omitempty"`
 }
 
 func (e *Operation) Equals(obj Object) bool {
@@ -165,6 +179,46 @@ func (e *Operation) InOrphanMitigationState() bool {
 	return !e.DeletionScheduled.IsZero()
 }
 
+// IsCancelled reports whether the operation was cancelled while it was in progress
+func (e *Operation) IsCancelled() bool {
+	return e.Context != nil && e.Context.Cancelled
+}
+
+// IsContinuation reports whether the operation continues an operation which was stored before,
+// which is the case for approved and retried operations. The resource of such a CREATE operation is already stored.
+func (e *Operation) IsContinuation() bool {
+	return e.Context != nil && (e.Context.Approved || e.Context.Retried)
+}
+
+// Encrypt encrypts the payload, which may contain credentials. The payload itself is kept, so that the operation
+// can be stored again after it was updated.
+func (e *Operation) Encrypt(ctx context.Context, encryptionFunc func(context.Context, []byte) ([]byte, error)) error {
+	if len(e.Payload) == 0 {
+		e.EncryptedPayload = nil
+		return nil
+	}
+	encryptedPayload, err := encryptionFunc(ctx, e.Payload)
+	if err != nil {
+		return err
+	}
+	e.EncryptedPayload = encryptedPayload
+	return nil
+}
+
+// Decrypt decrypts the stored payload
+func (e *Operation) Decrypt(ctx context.Context, decryptionFunc func(context.Context, []byte) ([]byte, error)) error {
+	if len(e.EncryptedPayload) == 0 {
+		e.Payload = nil
+		return nil
+	}
+	payload, err := decryptionFunc(ctx, e.EncryptedPayload)
+	if err != nil {
+		return err
+	}
+	e.Payload = payload
+	return nil
+}
+
 func (e *Operation) Sanitize(context.Context) {
 	if e != nil {
 		e.Context = nil
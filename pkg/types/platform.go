@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Peripli/service-manager/pkg/web"
+	"golang.org/x/crypto/bcrypt"
 	"reflect"
 	"time"
 
@@ -61,6 +62,14 @@ type Platform struct {
 	Integrity         []byte       `json:"-"`
 	CredentialsActive bool         `json:"credentials_active,omitempty"`
 	Technical         bool         `json:"technical,omitempty"` //technical platforms are only used for managing visibilities, and are excluded in notification and credential management flows
+	// PasswordHash is the bcrypt hash of the password of the credentials. Once it is set, the password is no longer stored.
+	// The hashes are never read from or written to JSON, so that they cannot be set through the API.
+	PasswordHash    string `json:"-"`
+	OldPasswordHash string `json:"-"`
+	// FailedLoginAttempts is the number of consecutive failed authentication attempts with the platform credentials
+	FailedLoginAttempts int `json:"-"`
+	// LockedUntil is the time until which authentication with the platform credentials is locked after too many failed attempts
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
 }
 
 func (e *Platform) Equals(obj Object) bool {
@@ -87,10 +96,44 @@ func (e *Platform) Sanitize(ctx context.Context) {
 		e.Credentials = nil
 	}
 	e.OldCredentials = nil
+	e.PasswordHash = ""
+	e.OldPasswordHash = ""
 	e.CredentialsActive = false
 	e.Technical = false
 }
 
+// Locked returns whether authentication with the platform credentials is locked at the given time
+func (e *Platform) Locked(now time.Time) bool {
+	return e.LockedUntil != nil && now.Before(*e.LockedUntil)
+}
+
+// HasPlainPasswords returns whether the passwords of the current or old platform credentials are not hashed yet
+func (e *Platform) HasPlainPasswords() bool {
+	return (e.Credentials != nil && e.Credentials.Basic != nil && len(e.Credentials.Basic.Password) != 0) ||
+		(e.OldCredentials != nil && e.OldCredentials.Basic != nil && len(e.OldCredentials.Basic.Password) != 0)
+}
+
+// HashCredentials replaces the passwords of the current and old platform credentials with their bcrypt hashes
+func (e *Platform) HashCredentials() error {
+	if e.Credentials != nil && e.Credentials.Basic != nil && len(e.Credentials.Basic.Password) != 0 {
+		hash, err := bcrypt.GenerateFromPassword([]byte(e.Credentials.Basic.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		e.PasswordHash = string(hash)
+		e.Credentials.Basic.Password = ""
+	}
+	if e.OldCredentials != nil && e.OldCredentials.Basic != nil && len(e.OldCredentials.Basic.Password) != 0 {
+		hash, err := bcrypt.GenerateFromPassword([]byte(e.OldCredentials.Basic.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		e.OldPasswordHash = string(hash)
+		e.OldCredentials.Basic.Password = ""
+	}
+	return nil
+}
+
 func (e *Platform) Encrypt(ctx context.Context, encryptionFunc func(context.Context, []byte) ([]byte, error)) error {
 	if !e.Technical {
 		return e.transform(ctx, encryptionFunc)
@@ -108,10 +151,10 @@ func (e *Platform) Decrypt(ctx context.Context, decryptionFunc func(context.Cont
 func (e *Platform) transform(ctx context.Context, transformationFunc func(context.Context, []byte) ([]byte, error)) error {
 	var credentialsExist bool
 	var oldCredentialsExist bool
-	if e.Credentials != nil && e.Credentials.Basic != nil {
+	if e.Credentials != nil && e.Credentials.Basic != nil && len(e.Credentials.Basic.Password) != 0 {
 		credentialsExist = true
 	}
-	if e.OldCredentials != nil && e.OldCredentials.Basic != nil {
+	if e.OldCredentials != nil && e.OldCredentials.Basic != nil && len(e.OldCredentials.Basic.Password) != 0 {
 		oldCredentialsExist = true
 	}
 
@@ -137,16 +180,24 @@ func (e *Platform) IntegralData() []byte {
 	if !e.Technical {
 		oldCredentials := ""
 		if e.OldCredentials != nil && e.OldCredentials.Basic != nil {
-			oldCredentials = fmt.Sprintf(":%s:%s", e.OldCredentials.Basic.Username, e.OldCredentials.Basic.Password)
+			oldCredentials = fmt.Sprintf(":%s:%s", e.OldCredentials.Basic.Username, integralSecret(e.OldCredentials.Basic, e.OldPasswordHash))
 		}
 
 		if e.Credentials != nil {
-			integrity = fmt.Sprintf("%s:%s%s", e.Credentials.Basic.Username, e.Credentials.Basic.Password, oldCredentials)
+			integrity = fmt.Sprintf("%s:%s%s", e.Credentials.Basic.Username, integralSecret(e.Credentials.Basic, e.PasswordHash), oldCredentials)
 		}
 	}
 	return []byte(integrity)
 }
 
+// integralSecret returns the password hash, or the password if the credentials are not hashed yet
+func integralSecret(basic *Basic, passwordHash string) string {
+	if len(passwordHash) != 0 {
+		return passwordHash
+	}
+	return basic.Password
+}
+
 func (e *Platform) SetIntegrity(integrity []byte) {
 	e.Integrity = integrity
 }
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+//go:generate smgen api QueuedOperation
+// QueuedOperation is an operation waiting for a worker of the scheduler. It is deleted when the operation starts.
+type QueuedOperation struct {
+	Base
+
+	OperationID  string     `json:"operation_id"`
+	ResourceType ObjectType `json:"resource_type"`
+	Tenant       string     `json:"tenant"`
+
+	// Payload holds the resource and label changes of the operation, so that it can be restored after a restart
+	Payload []byte `json:"-"`
+}
+
+func (e *QueuedOperation) Equals(obj Object) bool {
+	if !Equals(e, obj) {
+		return false
+	}
+
+	queued := obj.(*QueuedOperation)
+	return e.OperationID == queued.OperationID &&
+		e.ResourceType == queued.ResourceType &&
+		e.Tenant == queued.Tenant
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *QueuedOperation) Validate() error {
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	if e.OperationID == "" {
+		return errors.New("missing queued operation id")
+	}
+	if e.ResourceType == "" {
+		return errors.New("missing queued operation resource type")
+	}
+	return nil
+}
+
+// Encrypt encrypts the payload, which may contain credentials
+func (e *QueuedOperation) Encrypt(ctx context.Context, encryptionFunc func(context.Context, []byte) ([]byte, error)) error {
+	return e.transform(ctx, encryptionFunc)
+}
+
+// Decrypt decrypts the payload
+func (e *QueuedOperation) Decrypt(ctx context.Context, decryptionFunc func(context.Context, []byte) ([]byte, error)) error {
+	return e.transform(ctx, decryptionFunc)
+}
+
+func (e *QueuedOperation) transform(ctx context.Context, transformationFunc func(context.Context, []byte) ([]byte, error)) error {
+	if len(e.Payload) == 0 {
+		return nil
+	}
+	transformedPayload, err := transformationFunc(ctx, e.Payload)
+	if err != nil {
+		return err
+	}
+	e.Payload = transformedPayload
+	return nil
+}
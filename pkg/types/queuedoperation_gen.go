@@ -0,0 +1,65 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const QueuedOperationType ObjectType = web.QueuedOperationsURL
+
+type QueuedOperations struct {
+	QueuedOperations []*QueuedOperation `json:"queued_operations"`
+}
+
+func (e *QueuedOperations) Add(object Object) {
+	e.QueuedOperations = append(e.QueuedOperations, object.(*QueuedOperation))
+}
+
+func (e *QueuedOperations) ItemAt(index int) Object {
+	return e.QueuedOperations[index]
+}
+
+func (e *QueuedOperations) Len() int {
+	return len(e.QueuedOperations)
+}
+
+func (e *QueuedOperation) GetType() ObjectType {
+	return QueuedOperationType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *QueuedOperation) MarshalJSON() ([]byte, error) {
+	type E QueuedOperation
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
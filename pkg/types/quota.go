@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+//go:generate smgen api Quota
+// Quota limits the service instances and bindings of a tenant.
+// A quota without an offering and a plan limits the instances of the tenant in total.
+type Quota struct {
+	Base
+
+	Tenant            string `json:"tenant"`
+	ServiceOfferingID string `json:"service_offering_id,omitempty"`
+	ServicePlanID     string `json:"service_plan_id,omitempty"`
+
+	// MaxInstances limits the number of instances in the scope of the quota, no limit if not set
+	MaxInstances *int `json:"max_instances,omitempty"`
+	// MaxBindingsPerInstance limits the number of bindings of each instance in the scope of the quota, no limit if not set
+	MaxBindingsPerInstance *int `json:"max_bindings_per_instance,omitempty"`
+}
+
+func (e *Quota) Equals(obj Object) bool {
+	if !Equals(e, obj) {
+		return false
+	}
+
+	quota := obj.(*Quota)
+	if e.Tenant != quota.Tenant ||
+		e.ServiceOfferingID != quota.ServiceOfferingID ||
+		e.ServicePlanID != quota.ServicePlanID ||
+		!equalLimits(e.MaxInstances, quota.MaxInstances) ||
+		!equalLimits(e.MaxBindingsPerInstance, quota.MaxBindingsPerInstance) {
+		return false
+	}
+
+	return true
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *Quota) Validate() error {
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	if e.Tenant == "" {
+		return errors.New("missing quota tenant")
+	}
+	if e.ServiceOfferingID != "" && e.ServicePlanID != "" {
+		return errors.New("quota service offering id and service plan id are mutually exclusive")
+	}
+	if e.MaxInstances == nil && e.MaxBindingsPerInstance == nil {
+		return errors.New("quota should have max_instances or max_bindings_per_instance")
+	}
+	if e.MaxInstances != nil && *e.MaxInstances < 0 {
+		return errors.New("quota max_instances cannot be negative")
+	}
+	if e.MaxBindingsPerInstance != nil && *e.MaxBindingsPerInstance < 0 {
+		return errors.New("quota max_bindings_per_instance cannot be negative")
+	}
+	if err := e.Labels.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Scope returns a human readable description of what the quota applies to
+func (e *Quota) Scope() string {
+	switch {
+	case e.ServicePlanID != "":
+		return fmt.Sprintf("service plan %s", e.ServicePlanID)
+	case e.ServiceOfferingID != "":
+		return fmt.Sprintf("service offering %s", e.ServiceOfferingID)
+	default:
+		return "all services"
+	}
+}
+
+func equalLimits(l1, l2 *int) bool {
+	if l1 == nil || l2 == nil {
+		return l1 == l2
+	}
+	return *l1 == *l2
+}
@@ -0,0 +1,65 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const QuotaType ObjectType = web.QuotasURL
+
+type Quotas struct {
+	Quotas []*Quota `json:"quotas"`
+}
+
+func (e *Quotas) Add(object Object) {
+	e.Quotas = append(e.Quotas, object.(*Quota))
+}
+
+func (e *Quotas) ItemAt(index int) Object {
+	return e.Quotas[index]
+}
+
+func (e *Quotas) Len() int {
+	return len(e.Quotas)
+}
+
+func (e *Quota) GetType() ObjectType {
+	return QuotaType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *Quota) MarshalJSON() ([]byte, error) {
+	type E Quota
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
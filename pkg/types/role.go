@@ -0,0 +1,133 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const (
+	// VerbGet reads a single resource
+	VerbGet = "get"
+	// VerbList lists resources
+	VerbList = "list"
+	// VerbCreate creates resources
+	VerbCreate = "create"
+	// VerbUpdate updates resources
+	VerbUpdate = "update"
+	// VerbDelete deletes resources
+	VerbDelete = "delete"
+	// AnyPermission matches any resource type or verb in a permission
+	AnyPermission = "*"
+)
+
+var verbs = []string{VerbGet, VerbList, VerbCreate, VerbUpdate, VerbDelete, AnyPermission}
+
+//go:generate smgen api Role
+// Role is a named set of permissions which is granted to users and groups by role bindings
+type Role struct {
+	Base
+
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// Permission allows the verbs on the resources of a type. The resource type is the name of the resource
+// collection in the API path, for example service_instances.
+type Permission struct {
+	ResourceType string   `json:"resource_type"`
+	Verbs        []string `json:"verbs"`
+}
+
+// Allows returns whether the permission allows the verb on the resource type
+func (p Permission) Allows(resourceType, verb string) bool {
+	if p.ResourceType != AnyPermission && p.ResourceType != resourceType {
+		return false
+	}
+	for _, v := range p.Verbs {
+		if v == AnyPermission || v == verb {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Role) Equals(obj Object) bool {
+	if !Equals(e, obj) {
+		return false
+	}
+
+	role := obj.(*Role)
+	if e.Name != role.Name ||
+		e.Description != role.Description ||
+		!reflect.DeepEqual(e.Permissions, role.Permissions) {
+		return false
+	}
+
+	return true
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *Role) Validate() error {
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	if e.Name == "" {
+		return errors.New("missing role name")
+	}
+	for _, permission := range e.Permissions {
+		if permission.ResourceType == "" {
+			return fmt.Errorf("missing resource type in permission of role %s", e.Name)
+		}
+		if len(permission.Verbs) == 0 {
+			return fmt.Errorf("missing verbs in permission for %s of role %s", permission.ResourceType, e.Name)
+		}
+		for _, verb := range permission.Verbs {
+			if !isVerb(verb) {
+				return fmt.Errorf("invalid verb %s in permission for %s of role %s", verb, permission.ResourceType, e.Name)
+			}
+		}
+	}
+	if err := e.Labels.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Allows returns whether one of the permissions of the role allows the verb on the resource type
+func (e *Role) Allows(resourceType, verb string) bool {
+	for _, permission := range e.Permissions {
+		if permission.Allows(resourceType, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+func isVerb(verb string) bool {
+	for _, v := range verbs {
+		if v == verb {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const (
+	// UserSubject is the kind of subjects identifying a user by its name
+	UserSubject = "user"
+	// GroupSubject is the kind of subjects identifying the users of a group
+	GroupSubject = "group"
+)
+
+//go:generate smgen api RoleBinding
+// RoleBinding grants a role to users and groups within a tenant, or globally if the tenant is not set
+type RoleBinding struct {
+	Base
+
+	RoleID   string    `json:"role_id"`
+	Tenant   string    `json:"tenant,omitempty"`
+	Subjects []Subject `json:"subjects"`
+}
+
+// Subject is a user or a group to which a role is granted
+type Subject struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+func (e *RoleBinding) Equals(obj Object) bool {
+	if !Equals(e, obj) {
+		return false
+	}
+
+	binding := obj.(*RoleBinding)
+	if e.RoleID != binding.RoleID ||
+		e.Tenant != binding.Tenant ||
+		!reflect.DeepEqual(e.Subjects, binding.Subjects) {
+		return false
+	}
+
+	return true
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *RoleBinding) Validate() error {
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	if e.RoleID == "" {
+		return errors.New("missing role binding role id")
+	}
+	if len(e.Subjects) == 0 {
+		return errors.New("missing role binding subjects")
+	}
+	for _, subject := range e.Subjects {
+		if subject.Kind != UserSubject && subject.Kind != GroupSubject {
+			return fmt.Errorf("invalid role binding subject kind %s, expected %s or %s", subject.Kind, UserSubject, GroupSubject)
+		}
+		if subject.Name == "" {
+			return errors.New("missing role binding subject name")
+		}
+	}
+	if err := e.Labels.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Binds returns whether the role binding applies to the user or one of the groups
+func (e *RoleBinding) Binds(user string, groups []string) bool {
+	for _, subject := range e.Subjects {
+		switch subject.Kind {
+		case UserSubject:
+			if subject.Name == user {
+				return true
+			}
+		case GroupSubject:
+			for _, group := range groups {
+				if subject.Name == group {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
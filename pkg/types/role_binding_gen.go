@@ -0,0 +1,65 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const RoleBindingType ObjectType = web.RoleBindingsURL
+
+type RoleBindings struct {
+	RoleBindings []*RoleBinding `json:"role_bindings"`
+}
+
+func (e *RoleBindings) Add(object Object) {
+	e.RoleBindings = append(e.RoleBindings, object.(*RoleBinding))
+}
+
+func (e *RoleBindings) ItemAt(index int) Object {
+	return e.RoleBindings[index]
+}
+
+func (e *RoleBindings) Len() int {
+	return len(e.RoleBindings)
+}
+
+func (e *RoleBinding) GetType() ObjectType {
+	return RoleBindingType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *RoleBinding) MarshalJSON() ([]byte, error) {
+	type E RoleBinding
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
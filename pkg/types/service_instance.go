@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/Peripli/service-manager/pkg/util"
 )
@@ -43,8 +44,15 @@ type ServiceInstance struct {
 	Parameters map[string]interface{} `json:"parameters,omitempty"`
 	Usable     bool                   `json:"usable"`
 	Shared     *bool                  `json:"shared,omitempty"`
+
+	// ExpiresAt is the end of the lease of the instance, after which it is deleted by Service Manager
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
+// LeaseExpiringLabelKey is the label with which Service Manager warns that the lease of an instance expires soon.
+// Its value is the time at which the lease expires.
+const LeaseExpiringLabelKey = "lease_expiring"
+
 type SharedInstance struct{}
 
 type InstanceUpdateValues struct {
@@ -64,6 +72,7 @@ func (e *ServiceInstance) Equals(obj Object) bool {
 		e.DashboardURL != instance.DashboardURL ||
 		e.Ready != instance.Ready ||
 		e.Shared != instance.Shared ||
+		!equalTimes(e.ExpiresAt, instance.ExpiresAt) ||
 		!reflect.DeepEqual(e.UpdateValues, instance.UpdateValues) ||
 		!reflect.DeepEqual(e.Context, instance.Context) ||
 		!reflect.DeepEqual(e.MaintenanceInfo, instance.MaintenanceInfo) {
@@ -17,11 +17,23 @@
 package types
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/Peripli/service-manager/pkg/instance_sharing"
 	"github.com/Peripli/service-manager/pkg/util/slice"
 	"github.com/tidwall/gjson"
 )
 
+// RequiresApprovalLabelKey is the label of plans whose instances are provisioned only after they are approved
+const RequiresApprovalLabelKey = "requires_approval"
+
+// MaxLeaseLabelKey is the label of plans whose instances must expire within this duration, e.g. 720h
+const MaxLeaseLabelKey = "max_lease"
+
+// DefaultLeaseLabelKey is the label of plans whose instances expire after this duration if no expiry is requested
+const DefaultLeaseLabelKey = "default_lease"
+
 // SupportsPlatform determines whether a specific platform type is among the ones that a plan supports
 func (e *ServicePlan) SupportsPlatformType(platformType string) bool {
 	var inputPlatformTypes []string
@@ -80,6 +92,34 @@ func (e *ServicePlan) SupportsInstanceSharing() bool {
 	return isShareable == "true"
 }
 
+// RequiresApproval determines whether instances of the plan have to be approved before they are provisioned
+func (e *ServicePlan) RequiresApproval() bool {
+	values := e.Labels[RequiresApprovalLabelKey]
+	return len(values) > 0 && values[0] == "true"
+}
+
+// MaxLease returns the maximum lease of instances of the plan, or 0 if their lease is not limited
+func (e *ServicePlan) MaxLease() (time.Duration, error) {
+	return e.leaseLabel(MaxLeaseLabelKey)
+}
+
+// DefaultLease returns the lease of instances of the plan created without expiry, or 0 if they do not expire
+func (e *ServicePlan) DefaultLease() (time.Duration, error) {
+	return e.leaseLabel(DefaultLeaseLabelKey)
+}
+
+func (e *ServicePlan) leaseLabel(key string) (time.Duration, error) {
+	values := e.Labels[key]
+	if len(values) == 0 {
+		return 0, nil
+	}
+	lease, err := time.ParseDuration(values[0])
+	if err != nil || lease <= 0 {
+		return 0, fmt.Errorf("label %s of plan with id %s must be a positive duration", key, e.ID)
+	}
+	return lease, nil
+}
+
 func (e *ServicePlan) metadataPropertyAsStringArray(propertyKey string) []string {
 	propertyValue := gjson.GetBytes(e.Metadata, propertyKey)
 	if !propertyValue.IsArray() || len(propertyValue.Array()) == 0 {
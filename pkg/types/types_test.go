@@ -1,6 +1,7 @@
 package types
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -414,6 +415,254 @@ func createPlatform(now time.Time) Object {
 	}
 }
 
+var _ = Describe("Visibility validity window", func() {
+	now := time.Now()
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+
+	It("is active without a window", func() {
+		Expect((&Visibility{}).IsActive(now)).To(BeTrue())
+	})
+
+	It("is active only within the window", func() {
+		visibility := &Visibility{ValidFrom: &now, ValidUntil: &after}
+		Expect(visibility.IsActive(before)).To(BeFalse())
+		Expect(visibility.IsActive(now)).To(BeTrue())
+		Expect(visibility.IsActive(after)).To(BeFalse())
+	})
+
+	It("fails validation when valid_until is not after valid_from", func() {
+		visibility := &Visibility{Base: Base{ID: "id"}, ServicePlanID: "plan", ValidFrom: &now, ValidUntil: &before}
+		Expect(visibility.Validate()).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Service plan approval", func() {
+	It("requires approval only when the plan is labeled", func() {
+		plan := &ServicePlan{}
+		Expect(plan.RequiresApproval()).To(BeFalse())
+
+		plan.Labels = Labels{RequiresApprovalLabelKey: {"false"}}
+		Expect(plan.RequiresApproval()).To(BeFalse())
+
+		plan.Labels = Labels{RequiresApprovalLabelKey: {"true"}}
+		Expect(plan.RequiresApproval()).To(BeTrue())
+	})
+})
+
+var _ = Describe("Service plan leases", func() {
+	It("returns no lease when the plan is not labeled", func() {
+		plan := &ServicePlan{}
+		Expect(plan.MaxLease()).To(BeZero())
+		Expect(plan.DefaultLease()).To(BeZero())
+	})
+
+	It("parses the lease labels of the plan", func() {
+		plan := &ServicePlan{Base: Base{Labels: Labels{MaxLeaseLabelKey: {"720h"}, DefaultLeaseLabelKey: {"168h"}}}}
+		Expect(plan.MaxLease()).To(Equal(720 * time.Hour))
+		Expect(plan.DefaultLease()).To(Equal(168 * time.Hour))
+	})
+
+	It("fails when a lease label is not a positive duration", func() {
+		plan := &ServicePlan{Base: Base{Labels: Labels{MaxLeaseLabelKey: {"30d"}, DefaultLeaseLabelKey: {"-1h"}}}}
+		_, err := plan.MaxLease()
+		Expect(err).To(HaveOccurred())
+		_, err = plan.DefaultLease()
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Operation controls", func() {
+	It("is cancelled only when its context is marked as cancelled", func() {
+		operation := &Operation{}
+		Expect(operation.IsCancelled()).To(BeFalse())
+
+		operation.Context = &OperationContext{Cancelled: true}
+		Expect(operation.IsCancelled()).To(BeTrue())
+	})
+
+	It("is a continuation when it was approved or retried", func() {
+		operation := &Operation{}
+		Expect(operation.IsContinuation()).To(BeFalse())
+
+		operation.Context = &OperationContext{}
+		Expect(operation.IsContinuation()).To(BeFalse())
+
+		operation.Context = &OperationContext{Approved: true}
+		Expect(operation.IsContinuation()).To(BeTrue())
+
+		operation.Context = &OperationContext{Retried: true}
+		Expect(operation.IsContinuation()).To(BeTrue())
+	})
+})
+
+var _ = Describe("Roles", func() {
+	It("allows the verbs of its permissions", func() {
+		role := &Role{Permissions: []Permission{
+			{ResourceType: AnyPermission, Verbs: []string{VerbGet, VerbList}},
+			{ResourceType: "service_instances", Verbs: []string{AnyPermission}},
+		}}
+		Expect(role.Allows("platforms", VerbList)).To(BeTrue())
+		Expect(role.Allows("platforms", VerbDelete)).To(BeFalse())
+		Expect(role.Allows("service_instances", VerbDelete)).To(BeTrue())
+	})
+
+	It("fails validation for unknown verbs", func() {
+		role := &Role{Name: "role", Permissions: []Permission{{ResourceType: "platforms", Verbs: []string{"patch"}}}}
+		Expect(role.Validate()).To(HaveOccurred())
+	})
+
+	It("binds its user and group subjects", func() {
+		binding := &RoleBinding{Subjects: []Subject{{Kind: UserSubject, Name: "user"}, {Kind: GroupSubject, Name: "group"}}}
+		Expect(binding.Binds("user", nil)).To(BeTrue())
+		Expect(binding.Binds("other", []string{"group"})).To(BeTrue())
+		Expect(binding.Binds("group", nil)).To(BeFalse())
+	})
+
+	It("fails validation for unknown subject kinds", func() {
+		binding := &RoleBinding{RoleID: "viewer", Subjects: []Subject{{Kind: "client", Name: "name"}}}
+		Expect(binding.Validate()).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("API Tokens", func() {
+	It("generates a prefixed token and stores its hash", func() {
+		apiToken := &APIToken{}
+		Expect(apiToken.GenerateToken()).To(Succeed())
+		Expect(apiToken.Token).To(HavePrefix(APITokenPrefix))
+		Expect(apiToken.TokenHash).To(Equal(HashAPIToken(apiToken.Token)))
+		Expect(apiToken.TokenHash).ToNot(ContainSubstring(apiToken.Token))
+	})
+
+	It("does not serialize the token hash", func() {
+		apiToken := &APIToken{Name: "token", TokenHash: "hash"}
+		bytes, err := json.Marshal(apiToken)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(bytes)).ToNot(ContainSubstring("hash"))
+	})
+
+	It("fails validation without an expiry in the future", func() {
+		apiToken := &APIToken{Name: "token"}
+		Expect(apiToken.Validate()).To(HaveOccurred())
+		expiresAt := time.Now().Add(-time.Minute)
+		apiToken.ExpiresAt = &expiresAt
+		Expect(apiToken.Validate()).To(HaveOccurred())
+		expiresAt = time.Now().Add(time.Hour)
+		Expect(apiToken.Validate()).To(Succeed())
+	})
+
+	It("is expired from its expiry on", func() {
+		expiresAt := time.Now()
+		apiToken := &APIToken{ExpiresAt: &expiresAt}
+		Expect(apiToken.Expired(expiresAt.Add(-time.Second))).To(BeFalse())
+		Expect(apiToken.Expired(expiresAt)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Admission Webhooks", func() {
+	var webhook *AdmissionWebhook
+
+	BeforeEach(func() {
+		webhook = &AdmissionWebhook{
+			Name:          "webhook",
+			Kind:          MutatingWebhook,
+			URL:           "https://example.com/admit",
+			ResourceTypes: []string{"service_instances"},
+			Operations:    []string{VerbCreate, VerbUpdate},
+		}
+	})
+
+	It("validates a valid webhook", func() {
+		Expect(webhook.Validate()).To(Succeed())
+	})
+
+	It("fails validation for a non-https url", func() {
+		webhook.URL = "http://example.com/admit"
+		Expect(webhook.Validate()).To(HaveOccurred())
+	})
+
+	It("fails validation for an unknown operation", func() {
+		webhook.Operations = []string{"patch"}
+		Expect(webhook.Validate()).To(HaveOccurred())
+	})
+
+	It("fails validation for a timeout above the maximum", func() {
+		webhook.TimeoutSeconds = MaxWebhookTimeoutSeconds + 1
+		Expect(webhook.Validate()).To(HaveOccurred())
+	})
+
+	It("matches the configured resource types and operations", func() {
+		Expect(webhook.Matches("service_instances", VerbCreate)).To(BeTrue())
+		Expect(webhook.Matches("service_instances", VerbDelete)).To(BeFalse())
+		Expect(webhook.Matches("service_bindings", VerbCreate)).To(BeFalse())
+		webhook.ResourceTypes = []string{AnyPermission}
+		Expect(webhook.Matches("service_bindings", VerbCreate)).To(BeTrue())
+	})
+
+	It("defaults the timeout and fails closed", func() {
+		Expect(webhook.Timeout()).To(Equal(DefaultWebhookTimeoutSeconds * time.Second))
+		Expect(webhook.FailOpen()).To(BeFalse())
+		webhook.FailurePolicy = FailurePolicyIgnore
+		Expect(webhook.FailOpen()).To(BeTrue())
+	})
+})
+
+var _ = Describe("Label Policies", func() {
+	It("fails validation for resource types which are not governed by label policies", func() {
+		policy := &LabelPolicy{ResourceType: "service_instance"}
+		Expect(policy.Validate()).To(MatchError(ContainSubstring("service_instances")))
+		policy.ResourceType = "service_plans"
+		Expect(policy.Validate()).To(HaveOccurred())
+		policy.ResourceType = "visibilities"
+		Expect(policy.Validate()).To(Succeed())
+	})
+
+	It("fails validation for duplicate keys", func() {
+		policy := &LabelPolicy{ResourceType: "service_instances", Keys: []*LabelKeyPolicy{{Key: "env"}, {Key: "env"}}}
+		Expect(policy.Validate()).To(HaveOccurred())
+	})
+
+	It("fails validation for invalid patterns", func() {
+		policy := &LabelPolicy{ResourceType: "service_instances", Keys: []*LabelKeyPolicy{{Key: "env", Pattern: "("}}}
+		Expect(policy.Validate()).To(HaveOccurred())
+	})
+
+	It("matches patterns against whole values", func() {
+		policy := &LabelPolicy{ResourceType: "service_instances", Keys: []*LabelKeyPolicy{{Key: "env", Pattern: "dev|prod"}}}
+		Expect(policy.Validate()).To(Succeed())
+		Expect(policy.CheckLabels(Labels{"env": {"prod"}})).To(Succeed())
+		Expect(policy.CheckLabels(Labels{"env": {"production"}})).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Operation payload", func() {
+	reverse := func(ctx context.Context, bytes []byte) ([]byte, error) {
+		reversed := make([]byte, len(bytes))
+		for i, b := range bytes {
+			reversed[len(bytes)-1-i] = b
+		}
+		return reversed, nil
+	}
+
+	It("keeps the payload when it is encrypted, so that the operation can be stored repeatedly", func() {
+		operation := &Operation{Payload: []byte("abc")}
+		Expect(operation.Encrypt(context.Background(), reverse)).To(Succeed())
+		Expect(operation.Encrypt(context.Background(), reverse)).To(Succeed())
+		Expect(operation.EncryptedPayload).To(Equal([]byte("cba")))
+		Expect(operation.Payload).To(Equal([]byte("abc")))
+
+		stored := &Operation{EncryptedPayload: operation.EncryptedPayload}
+		Expect(stored.Decrypt(context.Background(), reverse)).To(Succeed())
+		Expect(stored.Payload).To(Equal([]byte("abc")))
+	})
+
+	It("clears the stored payload when the payload is removed", func() {
+		operation := &Operation{EncryptedPayload: []byte("cba")}
+		Expect(operation.Encrypt(context.Background(), reverse)).To(Succeed())
+		Expect(operation.EncryptedPayload).To(BeEmpty())
+	})
+})
+
 func createBroker(now time.Time) Object {
 	labels := Labels{
 		"label_key": []string{"value"},
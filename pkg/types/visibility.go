@@ -19,6 +19,7 @@ package types
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Peripli/service-manager/pkg/util"
 )
@@ -29,6 +30,13 @@ type Visibility struct {
 	Base
 	PlatformID    string `json:"platform_id"`
 	ServicePlanID string `json:"service_plan_id"`
+	// PlatformSelector is a label query selecting the platforms for which the plan is visible (e.g. region eq 'eu')
+	PlatformSelector string `json:"platform_selector,omitempty"`
+	// ValidFrom and ValidUntil optionally restrict the time window in which the visibility is in effect
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+	// Inactive is set while the visibility is outside of its validity window and the platforms are notified as if it was deleted
+	Inactive bool `json:"-"`
 }
 
 func (e *Visibility) Equals(obj Object) bool {
@@ -38,7 +46,11 @@ func (e *Visibility) Equals(obj Object) bool {
 
 	visibility := obj.(*Visibility)
 	if e.PlatformID != visibility.PlatformID ||
-		e.ServicePlanID != visibility.ServicePlanID {
+		e.ServicePlanID != visibility.ServicePlanID ||
+		e.PlatformSelector != visibility.PlatformSelector ||
+		!equalTimes(e.ValidFrom, visibility.ValidFrom) ||
+		!equalTimes(e.ValidUntil, visibility.ValidUntil) ||
+		e.Inactive != visibility.Inactive {
 		return false
 	}
 
@@ -50,6 +62,12 @@ func (e *Visibility) Validate() error {
 	if e.ServicePlanID == "" {
 		return errors.New("missing visibility service plan id")
 	}
+	if e.PlatformID != "" && e.PlatformSelector != "" {
+		return errors.New("visibility platform id and platform selector are mutually exclusive")
+	}
+	if e.ValidFrom != nil && e.ValidUntil != nil && !e.ValidUntil.After(*e.ValidFrom) {
+		return errors.New("visibility valid_until must be after valid_from")
+	}
 	if util.HasRFC3986ReservedSymbols(e.ID) {
 		return fmt.Errorf("%s contains invalid character(s)", e.ID)
 	}
@@ -58,3 +76,21 @@ func (e *Visibility) Validate() error {
 	}
 	return nil
 }
+
+// IsActive returns true if the time is within the validity window of the visibility
+func (e *Visibility) IsActive(t time.Time) bool {
+	if e.ValidFrom != nil && t.Before(*e.ValidFrom) {
+		return false
+	}
+	if e.ValidUntil != nil && !t.Before(*e.ValidUntil) {
+		return false
+	}
+	return true
+}
+
+func equalTimes(t1, t2 *time.Time) bool {
+	if t1 == nil || t2 == nil {
+		return t1 == t2
+	}
+	return t1.Equal(*t2)
+}
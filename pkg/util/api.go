@@ -36,11 +36,14 @@ import (
 
 const jsonContentType = "application/json"
 
+// NDJSONContentType is the content type of newline delimited JSON documents such as the SM archives
+const NDJSONContentType = "application/x-ndjson"
+
 var (
 	reservedSymbolsRFC3986 = strings.Join([]string{
 		":", "/", "?", "#", "[", "]", "@", "!", "$", "&", "'", "(", ")", "*", "+", ",", ";", "=",
 	}, "")
-	supportedContentTypes = []string{jsonContentType, "application/x-www-form-urlencoded"}
+	supportedContentTypes = []string{jsonContentType, "application/x-www-form-urlencoded", NDJSONContentType}
 )
 
 // InputValidator should be implemented by types that need input validation check. For a reference refer to pkg/types
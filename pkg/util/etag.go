@@ -0,0 +1,72 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const weakETagPrefix = "W/"
+
+// ETag returns a strong entity tag of a resource last updated at updatedAt. The time is rounded to the
+// microsecond precision of the database, so that the tag of a stored object is the same as the tag of the object read back.
+func ETag(updatedAt time.Time) string {
+	return fmt.Sprintf(`"%x"`, updatedAt.Round(time.Microsecond).UnixNano()/int64(time.Microsecond))
+}
+
+// IfMatch checks the If-Match header of the request against the entity tag of the resource and returns
+// 412 Precondition Failed if none of the tags match. Weak tags never match.
+func IfMatch(request *http.Request, etag string) error {
+	header := request.Header.Get("If-Match")
+	if len(header) == 0 || matchesETag(header, etag, false) {
+		return nil
+	}
+	return &HTTPError{
+		ErrorType:   "PreconditionFailed",
+		Description: "the resource was modified, fetch it and retry with its current ETag",
+		StatusCode:  http.StatusPreconditionFailed,
+	}
+}
+
+// IfNoneMatch reports whether the If-None-Match header of the request matches the entity tag of the resource,
+// meaning that the client already has the current representation
+func IfNoneMatch(request *http.Request, etag string) bool {
+	header := request.Header.Get("If-None-Match")
+	return len(header) > 0 && matchesETag(header, etag, true)
+}
+
+func matchesETag(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, weakETagPrefix) {
+			if !weak {
+				continue
+			}
+			tag = strings.TrimPrefix(tag, weakETagPrefix)
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package util_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ETag", func() {
+	updatedAt := time.Date(2026, 10, 18, 12, 0, 0, 123456789, time.UTC)
+	etag := util.ETag(updatedAt)
+
+	request := func(header, value string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(header, value)
+		return r
+	}
+
+	It("is the same for the time stored with microsecond precision", func() {
+		Expect(util.ETag(updatedAt.Round(time.Microsecond))).To(Equal(etag))
+		Expect(util.ETag(updatedAt.Add(time.Microsecond))).ToNot(Equal(etag))
+	})
+
+	Describe("IfMatch", func() {
+		It("passes without a header, with a matching tag or with *", func() {
+			Expect(util.IfMatch(httptest.NewRequest(http.MethodPatch, "/", nil), etag)).To(Succeed())
+			Expect(util.IfMatch(request("If-Match", `"other", `+etag), etag)).To(Succeed())
+			Expect(util.IfMatch(request("If-Match", "*"), etag)).To(Succeed())
+		})
+
+		It("returns precondition failed for other or weak tags", func() {
+			err := util.IfMatch(request("If-Match", `"other"`), etag)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusPreconditionFailed))
+			Expect(util.IfMatch(request("If-Match", "W/"+etag), etag)).ToNot(Succeed())
+		})
+	})
+
+	Describe("IfNoneMatch", func() {
+		It("matches the current tag, also when weak", func() {
+			Expect(util.IfNoneMatch(request("If-None-Match", etag), etag)).To(BeTrue())
+			Expect(util.IfNoneMatch(request("If-None-Match", "W/"+etag), etag)).To(BeTrue())
+			Expect(util.IfNoneMatch(request("If-None-Match", `"other"`), etag)).To(BeFalse())
+			Expect(util.IfNoneMatch(httptest.NewRequest(http.MethodGet, "/", nil), etag)).To(BeFalse())
+		})
+	})
+})
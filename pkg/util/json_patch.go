@@ -0,0 +1,256 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONPatchOperation is an operation of a JSON patch (RFC 6902)
+type JSONPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ApplyJSONPatch applies the operations of a JSON patch (RFC 6902) to a JSON document and returns the patched document
+func ApplyJSONPatch(document []byte, patch []JSONPatchOperation) ([]byte, error) {
+	var doc interface{}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON document: %s", err)
+	}
+
+	for _, operation := range patch {
+		var err error
+		switch operation.Op {
+		case "add", "replace", "test":
+			var value interface{}
+			if err := json.Unmarshal(operation.Value, &value); err != nil {
+				return nil, fmt.Errorf("invalid value of JSON patch operation %s %s: %s", operation.Op, operation.Path, err)
+			}
+			switch operation.Op {
+			case "add":
+				doc, err = patchAdd(doc, operation.Path, value)
+			case "replace":
+				doc, err = patchReplace(doc, operation.Path, value)
+			case "test":
+				err = patchTest(doc, operation.Path, value)
+			}
+		case "remove":
+			doc, _, err = patchRemove(doc, operation.Path)
+		case "move":
+			var value interface{}
+			if doc, value, err = patchRemove(doc, operation.From); err == nil {
+				doc, err = patchAdd(doc, operation.Path, value)
+			}
+		case "copy":
+			var value interface{}
+			if value, err = patchGet(doc, operation.From); err == nil {
+				doc, err = patchAdd(doc, operation.Path, deepCopy(value))
+			}
+		default:
+			err = fmt.Errorf("unsupported operation")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not apply JSON patch operation %s %s: %s", operation.Op, operation.Path, err)
+		}
+	}
+
+	return json.Marshal(doc)
+}
+
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %s", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > length || (!allowEnd && index == length) {
+		return 0, fmt.Errorf("invalid array index %s", token)
+	}
+	return index, nil
+}
+
+// patchContainer applies f to the value containing the last token of the pointer and returns the modified document
+func patchContainer(doc interface{}, pointer string, f func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("the document root cannot be modified")
+	}
+	return patchPath(doc, tokens, f)
+}
+
+func patchPath(value interface{}, tokens []string, f func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return f(value, tokens[0])
+	}
+	switch container := value.(type) {
+	case map[string]interface{}:
+		child, found := container[tokens[0]]
+		if !found {
+			return nil, fmt.Errorf("path segment %s not found", tokens[0])
+		}
+		patched, err := patchPath(child, tokens[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		container[tokens[0]] = patched
+		return container, nil
+	case []interface{}:
+		index, err := arrayIndex(tokens[0], len(container), false)
+		if err != nil {
+			return nil, err
+		}
+		patched, err := patchPath(container[index], tokens[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = patched
+		return container, nil
+	}
+	return nil, fmt.Errorf("path segment %s not found", tokens[0])
+}
+
+func patchAdd(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	return patchContainer(doc, pointer, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			c[token] = value
+			return c, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(c), true)
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, nil)
+			copy(c[index+1:], c[index:])
+			c[index] = value
+			return c, nil
+		}
+		return nil, fmt.Errorf("path segment %s not found", token)
+	})
+}
+
+func patchReplace(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	doc, _, err := patchRemove(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+	return patchAdd(doc, pointer, value)
+}
+
+func patchRemove(doc interface{}, pointer string) (interface{}, interface{}, error) {
+	var removed interface{}
+	doc, err := patchContainer(doc, pointer, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			value, found := c[token]
+			if !found {
+				return nil, fmt.Errorf("path segment %s not found", token)
+			}
+			removed = value
+			delete(c, token)
+			return c, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = c[index]
+			return append(c[:index], c[index+1:]...), nil
+		}
+		return nil, fmt.Errorf("path segment %s not found", token)
+	})
+	return doc, removed, err
+}
+
+func patchGet(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	value := doc
+	for _, token := range tokens {
+		switch c := value.(type) {
+		case map[string]interface{}:
+			child, found := c[token]
+			if !found {
+				return nil, fmt.Errorf("path segment %s not found", token)
+			}
+			value = child
+		case []interface{}:
+			index, err := arrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			value = c[index]
+		default:
+			return nil, fmt.Errorf("path segment %s not found", token)
+		}
+	}
+	return value, nil
+}
+
+func patchTest(doc interface{}, pointer string, expected interface{}) error {
+	value, err := patchGet(doc, pointer)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(value, expected) {
+		return fmt.Errorf("value does not match")
+	}
+	return nil
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			result[key] = deepCopy(child)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, child := range v {
+			result[i] = deepCopy(child)
+		}
+		return result
+	}
+	return value
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package util_test
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JSON Patch", func() {
+	const document = `{"name": "instance", "labels": {"env": ["dev"]}, "a/b": 1, "list": [1, 2, 3]}`
+
+	apply := func(patch string) (string, error) {
+		var operations []util.JSONPatchOperation
+		Expect(json.Unmarshal([]byte(patch), &operations)).To(Succeed())
+		result, err := util.ApplyJSONPatch([]byte(document), operations)
+		return string(result), err
+	}
+
+	DescribeTable("applies operations",
+		func(patch, expected string) {
+			result, err := apply(patch)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(MatchJSON(expected))
+		},
+		Entry("add to an object", `[{"op": "add", "path": "/labels/team", "value": ["a"]}]`,
+			`{"name": "instance", "labels": {"env": ["dev"], "team": ["a"]}, "a/b": 1, "list": [1, 2, 3]}`),
+		Entry("add into an array", `[{"op": "add", "path": "/list/1", "value": 5}]`,
+			`{"name": "instance", "labels": {"env": ["dev"]}, "a/b": 1, "list": [1, 5, 2, 3]}`),
+		Entry("add to the end of an array", `[{"op": "add", "path": "/labels/env/-", "value": "test"}]`,
+			`{"name": "instance", "labels": {"env": ["dev", "test"]}, "a/b": 1, "list": [1, 2, 3]}`),
+		Entry("remove", `[{"op": "remove", "path": "/list/0"}, {"op": "remove", "path": "/a~1b"}]`,
+			`{"name": "instance", "labels": {"env": ["dev"]}, "list": [2, 3]}`),
+		Entry("replace", `[{"op": "replace", "path": "/name", "value": "renamed"}]`,
+			`{"name": "renamed", "labels": {"env": ["dev"]}, "a/b": 1, "list": [1, 2, 3]}`),
+		Entry("move", `[{"op": "move", "from": "/name", "path": "/labels/name"}]`,
+			`{"labels": {"env": ["dev"], "name": "instance"}, "a/b": 1, "list": [1, 2, 3]}`),
+		Entry("copy", `[{"op": "copy", "from": "/labels/env", "path": "/labels/stage"}]`,
+			`{"name": "instance", "labels": {"env": ["dev"], "stage": ["dev"]}, "a/b": 1, "list": [1, 2, 3]}`),
+		Entry("successful test", `[{"op": "test", "path": "/name", "value": "instance"}]`, document),
+	)
+
+	DescribeTable("fails for invalid operations",
+		func(patch string) {
+			_, err := apply(patch)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("unknown operation", `[{"op": "merge", "path": "/name", "value": 1}]`),
+		Entry("missing path", `[{"op": "remove", "path": "/missing"}]`),
+		Entry("replace of a missing path", `[{"op": "replace", "path": "/missing", "value": 1}]`),
+		Entry("add to a missing parent", `[{"op": "add", "path": "/missing/key", "value": 1}]`),
+		Entry("array index out of range", `[{"op": "add", "path": "/list/4", "value": 1}]`),
+		Entry("invalid pointer", `[{"op": "remove", "path": "name"}]`),
+		Entry("root modification", `[{"op": "remove", "path": ""}]`),
+		Entry("failed test", `[{"op": "test", "path": "/name", "value": "other"}]`),
+	)
+})
@@ -42,6 +42,9 @@ const (
 	// LoggingConfigURL is the Logging Configuration API URL path
 	LoggingConfigURL = ConfigURL + "/logging"
 
+	// ConfigSettingsURL is the Configuration Settings API URL path
+	ConfigSettingsURL = ConfigURL + "/settings"
+
 	// ResourceOperationsURL is the URL path fetch operations for a resource
 	ResourceOperationsURL = "/operations"
 
@@ -56,6 +59,55 @@ const (
 	// ProfileURL is the Configuration API base URL path
 	ProfileURL = "/" + apiVersion + "/profile"
 
+	// AdminURL is the base URL path of the administrative APIs
+	AdminURL = "/" + apiVersion + "/admin"
+
+	// IntegrityURL is the Integrity API base URL path
+	IntegrityURL = AdminURL + "/integrity"
+
+	// IntegrityVerifyURL is the URL path to verify the integrity of all integral objects
+	IntegrityVerifyURL = IntegrityURL + "/verify"
+
+	// IntegrityResignURL is the URL path to recalculate the integrity of objects confirmed to be legitimate
+	IntegrityResignURL = IntegrityURL + "/resign"
+
+	// LabelPoliciesURL is the URL path to manage the policies governing the labels of resources
+	LabelPoliciesURL = AdminURL + "/label_policies"
+
+	// ExportURL is the URL path to export the Service Manager state into an archive
+	ExportURL = AdminURL + "/export"
+
+	// ImportURL is the URL path to import an archive into the Service Manager
+	ImportURL = AdminURL + "/import"
+
+	// QuotasURL is the URL path to manage tenant quotas
+	QuotasURL = "/" + apiVersion + "/quotas"
+
 	TenantURL = "/" + apiVersion + "/tenants"
+
+	// QuotasSubpathURL is the URL path of the quota usage of a tenant relative to the tenant
+	QuotasSubpathURL = "/quotas"
+
+	// ApprovalsURL is the URL path to approve and reject operations awaiting approval
+	ApprovalsURL = "/" + apiVersion + "/approvals"
+
+	// IdempotencyKeysURL identifies the stored idempotency keys, they are not exposed through the API
+	IdempotencyKeysURL = "/" + apiVersion + "/idempotency_keys"
+
+	// QueuedOperationsURL identifies the operations waiting for a worker, they are not exposed through the API
+	QueuedOperationsURL = "/" + apiVersion + "/queued_operations"
+
+	// RolesURL is the URL path to manage the roles of the role-based access control
+	RolesURL = "/" + apiVersion + "/roles"
+
+	// RoleBindingsURL is the URL path to manage the bindings of users and groups to roles
+	RoleBindingsURL = "/" + apiVersion + "/role_bindings"
+
+	// APITokensURL is the URL path to manage personal API tokens
+	APITokensURL = "/" + apiVersion + "/api_tokens"
+
+	// AdmissionWebhooksURL is the URL path to manage the admission webhooks called before resources are mutated
+	AdmissionWebhooksURL = "/" + apiVersion + "/admission_webhooks"
+
 	AgentsURL = "/" + apiVersion + "/agents/versions"
 )
@@ -0,0 +1,464 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"golang.org/x/crypto/scrypt"
+)
+
+// ArchiveVersion is the version of the archive format produced by Export
+const ArchiveVersion = 2
+
+// archiveKeyLength is the length of the AES key derived from the archive passphrase
+const archiveKeyLength = 32
+
+// archiveSaltLength is the length of the random salt with which the archive key is derived
+const archiveSaltLength = 32
+
+// maxArchiveKeyCost, maxArchiveKeyBlockSize and maxArchiveKeyParallelization bound the scrypt parameters accepted from
+// an archive header, so that an import cannot exhaust the memory or the CPU
+const (
+	maxArchiveKeyCost            = 1 << 20
+	maxArchiveKeyBlockSize       = 32
+	maxArchiveKeyParallelization = 16
+)
+
+// DefaultArchiveKeyDerivation are the scrypt parameters with which the key of exported archives is derived
+var DefaultArchiveKeyDerivation = ArchiveKeyDerivation{N: 1 << 15, R: 8, P: 1}
+
+// exportPageSize is the number of objects fetched at once during an export
+const exportPageSize = 500
+
+// ArchiveTypes are the object types contained in an archive in the order in which they are exported and imported
+var ArchiveTypes = []types.ObjectType{
+	types.PlatformType,
+	types.ServiceBrokerType,
+	types.ServiceOfferingType,
+	types.ServicePlanType,
+	types.VisibilityType,
+	types.QuotaType,
+	types.BrokerPlatformCredentialType,
+	types.ServiceInstanceType,
+	types.ServiceBindingType,
+}
+
+var archiveBlueprints = map[types.ObjectType]func() types.Object{
+	types.PlatformType:                 func() types.Object { return &types.Platform{} },
+	types.ServiceBrokerType:            func() types.Object { return &types.ServiceBroker{} },
+	types.ServiceOfferingType:          func() types.Object { return &types.ServiceOffering{} },
+	types.ServicePlanType:              func() types.Object { return &types.ServicePlan{} },
+	types.VisibilityType:               func() types.Object { return &types.Visibility{} },
+	types.QuotaType:                    func() types.Object { return &types.Quota{} },
+	types.BrokerPlatformCredentialType: func() types.Object { return &types.BrokerPlatformCredential{} },
+	types.ServiceInstanceType:          func() types.Object { return &types.ServiceInstance{} },
+	types.ServiceBindingType:           func() types.Object { return &types.ServiceBinding{} },
+}
+
+// ArchiveHeader is the first line of an archive
+type ArchiveHeader struct {
+	Version       int                   `json:"version"`
+	CreatedAt     time.Time             `json:"created_at"`
+	Tenant        string                `json:"tenant,omitempty"`
+	KeyDerivation *ArchiveKeyDerivation `json:"key_derivation"`
+}
+
+// ArchiveKeyDerivation holds the salt and the scrypt parameters with which the archive key is derived from the passphrase
+type ArchiveKeyDerivation struct {
+	Salt []byte `json:"salt"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+}
+
+// Key derives the key used to encrypt the secrets of an archive from a passphrase
+func (d *ArchiveKeyDerivation) Key(passphrase string) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), d.Salt, d.N, d.R, d.P, archiveKeyLength)
+}
+
+func (d *ArchiveKeyDerivation) validate() error {
+	if len(d.Salt) < archiveSaltLength/2 {
+		return fmt.Errorf("salt should be at least %d bytes long", archiveSaltLength/2)
+	}
+	if d.N <= 1 || d.N&(d.N-1) != 0 || d.N > maxArchiveKeyCost {
+		return fmt.Errorf("n should be a power of 2 between 2 and %d", maxArchiveKeyCost)
+	}
+	if d.R <= 0 || d.R > maxArchiveKeyBlockSize {
+		return fmt.Errorf("r should be between 1 and %d", maxArchiveKeyBlockSize)
+	}
+	if d.P <= 0 || d.P > maxArchiveKeyParallelization {
+		return fmt.Errorf("p should be between 1 and %d", maxArchiveKeyParallelization)
+	}
+	return nil
+}
+
+// ArchiveRecord is a line of an archive holding a single object. Secrets in the object are encrypted with the archive key.
+type ArchiveRecord struct {
+	Type      types.ObjectType `json:"type"`
+	Object    json.RawMessage  `json:"object"`
+	Integrity []byte           `json:"integrity,omitempty"`
+	// Catalog holds the catalog of service brokers, as it is not part of their JSON representation
+	Catalog json.RawMessage `json:"catalog,omitempty"`
+	// Inactive holds whether a visibility is outside of its validity window, as it is not part of its JSON representation
+	Inactive bool `json:"inactive,omitempty"`
+	// PasswordHash and OldPasswordHash hold the password hashes of platforms, as they are not part of their JSON representation
+	PasswordHash    string `json:"password_hash,omitempty"`
+	OldPasswordHash string `json:"old_password_hash,omitempty"`
+}
+
+// archiveTrailer is the last line of an archive. It allows detecting truncated archives.
+type archiveTrailer struct {
+	End *ArchiveSummary `json:"end"`
+}
+
+// ArchiveSummary contains the number of exported or imported objects per type
+type ArchiveSummary struct {
+	Objects map[types.ObjectType]int `json:"objects"`
+}
+
+// ExportOptions specify the passphrase and the scope of an export
+type ExportOptions struct {
+	// Passphrase is used to derive the key with which the secrets in the archive are encrypted
+	Passphrase string
+	// TenantLabelKey and Tenant restrict the export to the objects of a single tenant
+	TenantLabelKey string
+	Tenant         string
+}
+
+// Archiver exports the Service Manager state into a versioned JSON lines archive and imports it back preserving IDs,
+// labels and paging order. The repository has to decrypt the secrets but should not validate integrity itself
+// (e.g. the encrypting repository).
+type Archiver struct {
+	Repository         TransactionalRepository
+	IntegrityProcessor security.IntegrityProcessor
+	Encrypter          security.Encrypter
+}
+
+// Export writes all objects in scope to the writer. Objects with invalid integrity abort the export.
+func (a *Archiver) Export(ctx context.Context, writer io.Writer, options ExportOptions) (*ArchiveSummary, error) {
+	if options.Passphrase == "" {
+		return nil, archiveError("an archive passphrase should be provided")
+	}
+	if options.Tenant != "" && options.TenantLabelKey == "" {
+		return nil, archiveError("tenant label key should be provided when exporting a tenant")
+	}
+
+	keyDerivation := DefaultArchiveKeyDerivation
+	keyDerivation.Salt = make([]byte, archiveSaltLength)
+	if _, err := rand.Read(keyDerivation.Salt); err != nil {
+		return nil, fmt.Errorf("could not generate archive salt: %s", err)
+	}
+	key, err := keyDerivation.Key(options.Passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("could not derive archive key: %s", err)
+	}
+
+	encoder := json.NewEncoder(writer)
+	if err := encoder.Encode(&ArchiveHeader{
+		Version:       ArchiveVersion,
+		CreatedAt:     time.Now().UTC(),
+		Tenant:        options.Tenant,
+		KeyDerivation: &keyDerivation,
+	}); err != nil {
+		return nil, err
+	}
+
+	summary := &ArchiveSummary{Objects: make(map[types.ObjectType]int)}
+	exportedIDs := make(map[types.ObjectType][]string)
+	for _, objectType := range ArchiveTypes {
+		criteria, inScope := exportCriteria(objectType, options, exportedIDs)
+		if !inScope {
+			continue
+		}
+		var pagingSequence int64
+		for {
+			pageCriteria := append(append([]query.Criterion{}, criteria...),
+				query.LimitResultBy(exportPageSize),
+				query.OrderResultBy("paging_sequence", query.AscOrder),
+				query.ByField(query.GreaterThanOperator, "paging_sequence", strconv.FormatInt(pagingSequence, 10)))
+			objects, err := a.Repository.List(ctx, objectType, pageCriteria...)
+			if err != nil {
+				return nil, fmt.Errorf("could not list %s: %s", objectType, err)
+			}
+			for i := 0; i < objects.Len(); i++ {
+				obj := objects.ItemAt(i)
+				pagingSequence = obj.GetPagingSequence()
+				if objectType == types.PlatformType && obj.GetID() == types.SMPlatform {
+					continue
+				}
+				record, err := a.record(ctx, obj, key)
+				if err != nil {
+					return nil, err
+				}
+				if err := encoder.Encode(record); err != nil {
+					return nil, err
+				}
+				exportedIDs[objectType] = append(exportedIDs[objectType], obj.GetID())
+				summary.Objects[objectType]++
+			}
+			if objects.Len() < exportPageSize {
+				break
+			}
+		}
+	}
+
+	if err := encoder.Encode(&archiveTrailer{End: summary}); err != nil {
+		return nil, err
+	}
+
+	log.C(ctx).Infof("Exported %v", summary.Objects)
+	return summary, nil
+}
+
+// Import creates the objects of the archive in a single transaction. The import fails if an object already exists, its
+// integrity does not match the one recorded in the archive or its labels violate the label policy of its type.
+func (a *Archiver) Import(ctx context.Context, reader io.Reader, passphrase string) (*ArchiveSummary, error) {
+	if passphrase == "" {
+		return nil, archiveError("an archive passphrase should be provided")
+	}
+
+	decoder := json.NewDecoder(reader)
+	header := &ArchiveHeader{}
+	if err := decoder.Decode(header); err != nil {
+		return nil, archiveError(fmt.Sprintf("could not read archive header: %s", err))
+	}
+	if header.Version != ArchiveVersion {
+		return nil, archiveError(fmt.Sprintf("unsupported archive version %d, expected %d", header.Version, ArchiveVersion))
+	}
+	if header.KeyDerivation == nil {
+		return nil, archiveError("archive header does not contain the key derivation")
+	}
+	if err := header.KeyDerivation.validate(); err != nil {
+		return nil, archiveError(fmt.Sprintf("invalid key derivation in archive header: %s", err))
+	}
+	key, err := header.KeyDerivation.Key(passphrase)
+	if err != nil {
+		return nil, archiveError(fmt.Sprintf("could not derive archive key: %s", err))
+	}
+
+	summary := &ArchiveSummary{Objects: make(map[types.ObjectType]int)}
+	var trailer *archiveTrailer
+	err = a.Repository.InTransaction(ctx, func(ctx context.Context, storage Repository) error {
+		for decoder.More() {
+			var line json.RawMessage
+			if err := decoder.Decode(&line); err != nil {
+				return archiveError(fmt.Sprintf("could not read archive record: %s", err))
+			}
+			if trailer != nil {
+				return archiveError("archive contains records after its end")
+			}
+			record := &ArchiveRecord{}
+			if err := json.Unmarshal(line, record); err != nil {
+				return archiveError(fmt.Sprintf("could not read archive record: %s", err))
+			}
+			if record.Type == "" {
+				trailer = &archiveTrailer{}
+				if err := json.Unmarshal(line, trailer); err != nil || trailer.End == nil {
+					return archiveError("archive record without type")
+				}
+				continue
+			}
+
+			obj, err := a.object(ctx, record, key)
+			if err != nil {
+				return err
+			}
+			if err := CheckLabelPolicy(ctx, storage, obj); err != nil {
+				return err
+			}
+			if _, err := storage.Create(ctx, obj); err != nil {
+				if err == util.ErrAlreadyExistsInStorage {
+					return &util.HTTPError{
+						ErrorType:   "Conflict",
+						Description: fmt.Sprintf("%s with id %s already exists", record.Type, obj.GetID()),
+						StatusCode:  http.StatusConflict,
+					}
+				}
+				return fmt.Errorf("could not import %s with id %s: %s", record.Type, obj.GetID(), err)
+			}
+			summary.Objects[record.Type]++
+		}
+
+		if trailer == nil {
+			return archiveError("archive is incomplete")
+		}
+		if !reflect.DeepEqual(trailer.End.Objects, summary.Objects) {
+			return archiveError(fmt.Sprintf("archive should contain %v but contains %v", trailer.End.Objects, summary.Objects))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.C(ctx).Infof("Imported %v from archive created at %s", summary.Objects, header.CreatedAt)
+	return summary, nil
+}
+
+func (a *Archiver) record(ctx context.Context, obj types.Object, key []byte) (*ArchiveRecord, error) {
+	record := &ArchiveRecord{
+		Type: obj.GetType(),
+	}
+	if integralObject, isIntegral := obj.(security.IntegralObject); isIntegral {
+		if !a.IntegrityProcessor.ValidateIntegrity(integralObject) {
+			return nil, fmt.Errorf("invalid integrity for %s with ID %s", obj.GetType(), obj.GetID())
+		}
+		record.Integrity = integralObject.GetIntegrity()
+	}
+	if broker, isBroker := obj.(*types.ServiceBroker); isBroker {
+		record.Catalog = broker.Catalog
+	}
+	if visibility, isVisibility := obj.(*types.Visibility); isVisibility {
+		record.Inactive = visibility.Inactive
+	}
+	if platform, isPlatform := obj.(*types.Platform); isPlatform {
+		record.PasswordHash = platform.PasswordHash
+		record.OldPasswordHash = platform.OldPasswordHash
+	}
+	if securedObject, isSecured := obj.(types.Secured); isSecured {
+		if err := securedObject.Encrypt(ctx, func(ctx context.Context, plaintext []byte) ([]byte, error) {
+			return a.encryptSecret(ctx, plaintext, key)
+		}); err != nil {
+			return nil, fmt.Errorf("could not encrypt secrets of %s with ID %s: %s", obj.GetType(), obj.GetID(), err)
+		}
+	}
+
+	bytes, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	record.Object = bytes
+	return record, nil
+}
+
+func (a *Archiver) object(ctx context.Context, record *ArchiveRecord, key []byte) (types.Object, error) {
+	blueprint, found := archiveBlueprints[record.Type]
+	if !found {
+		return nil, archiveError(fmt.Sprintf("unsupported object type %s in archive", record.Type))
+	}
+	obj := blueprint()
+	if err := json.Unmarshal(record.Object, obj); err != nil {
+		return nil, archiveError(fmt.Sprintf("could not read %s from archive: %s", record.Type, err))
+	}
+	if obj.GetID() == "" {
+		return nil, archiveError(fmt.Sprintf("missing id of %s in archive", record.Type))
+	}
+
+	if platform, isPlatform := obj.(*types.Platform); isPlatform {
+		platform.PasswordHash = record.PasswordHash
+		platform.OldPasswordHash = record.OldPasswordHash
+	}
+	if securedObject, isSecured := obj.(types.Secured); isSecured {
+		if err := securedObject.Decrypt(ctx, func(ctx context.Context, ciphertext []byte) ([]byte, error) {
+			return a.decryptSecret(ctx, ciphertext, key)
+		}); err != nil {
+			return nil, archiveError(fmt.Sprintf("could not decrypt secrets of %s with ID %s, the archive key may be wrong: %s", record.Type, obj.GetID(), err))
+		}
+	}
+	if integralObject, isIntegral := obj.(security.IntegralObject); isIntegral {
+		integralObject.SetIntegrity(record.Integrity)
+		if !a.IntegrityProcessor.ValidateIntegrity(integralObject) {
+			return nil, archiveError(fmt.Sprintf("invalid integrity for %s with ID %s", record.Type, obj.GetID()))
+		}
+	}
+	if broker, isBroker := obj.(*types.ServiceBroker); isBroker {
+		broker.Catalog = record.Catalog
+	}
+	if visibility, isVisibility := obj.(*types.Visibility); isVisibility {
+		visibility.Inactive = record.Inactive
+	}
+	return obj, nil
+}
+
+// encryptSecret encrypts a secret and encodes it as a JSON string so that the object remains valid JSON
+func (a *Archiver) encryptSecret(ctx context.Context, plaintext, key []byte) ([]byte, error) {
+	ciphertext, err := a.Encrypter.Encrypt(ctx, plaintext, key)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(base64.StdEncoding.EncodeToString(ciphertext))
+}
+
+func (a *Archiver) decryptSecret(ctx context.Context, encoded, key []byte) ([]byte, error) {
+	var encodedCiphertext string
+	if err := json.Unmarshal(encoded, &encodedCiphertext); err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encodedCiphertext)
+	if err != nil {
+		return nil, err
+	}
+	return a.Encrypter.Decrypt(ctx, ciphertext, key)
+}
+
+// exportCriteria returns the criteria selecting the objects of the specified type which are in scope of the export.
+// When exporting a tenant, objects which have a tenant label are selected by it and the rest are selected by their
+// relation to the already exported objects.
+func exportCriteria(objectType types.ObjectType, options ExportOptions, exportedIDs map[types.ObjectType][]string) ([]query.Criterion, bool) {
+	if options.Tenant == "" {
+		return []query.Criterion{}, true
+	}
+
+	var criteria []query.Criterion
+	switch objectType {
+	case types.ServiceOfferingType:
+		criteria = inCriteria("broker_id", exportedIDs[types.ServiceBrokerType])
+	case types.ServicePlanType:
+		criteria = inCriteria("service_offering_id", exportedIDs[types.ServiceOfferingType])
+	case types.QuotaType:
+		criteria = []query.Criterion{query.ByField(query.EqualsOperator, "tenant", options.Tenant)}
+	case types.BrokerPlatformCredentialType:
+		criteria = append(inCriteria("broker_id", exportedIDs[types.ServiceBrokerType]),
+			inCriteria("platform_id", exportedIDs[types.PlatformType])...)
+		if len(criteria) < 2 {
+			return nil, false
+		}
+	default:
+		return []query.Criterion{query.ByLabel(query.EqualsOperator, options.TenantLabelKey, options.Tenant)}, true
+	}
+	return criteria, len(criteria) > 0
+}
+
+func inCriteria(field string, ids []string) []query.Criterion {
+	if len(ids) == 0 {
+		return []query.Criterion{}
+	}
+	return []query.Criterion{query.ByField(query.InOperator, field, ids...)}
+}
+
+func archiveError(description string) error {
+	return &util.HTTPError{
+		ErrorType:   "BadRequest",
+		Description: description,
+		StatusCode:  http.StatusBadRequest,
+	}
+}
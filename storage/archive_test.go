@@ -0,0 +1,302 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Archiver", func() {
+	var (
+		ctx                context.Context
+		fakeRepository     *storagefakes.FakeStorage
+		integrityProcessor security.IntegrityProcessor
+		archiver           *storage.Archiver
+		passphrase         string
+		archive            *bytes.Buffer
+		listCriteria       map[types.ObjectType][]query.Criterion
+	)
+
+	newPlatform := func() *types.Platform {
+		platform := &types.Platform{
+			Base: types.Base{ID: "platform-id", Labels: types.Labels{"tenant": {"t1"}}},
+			Type: "kubernetes",
+			Name: "platform",
+			Credentials: &types.Credentials{
+				Basic: &types.Basic{Username: "user", Password: "platform-secret"},
+			},
+			OldCredentials: &types.Credentials{
+				Basic: &types.Basic{Username: "old-user"},
+			},
+			OldPasswordHash: "old-password-hash",
+		}
+		integrity, _ := integrityProcessor.CalculateIntegrity(platform)
+		platform.SetIntegrity(integrity)
+		return platform
+	}
+
+	newBroker := func() *types.ServiceBroker {
+		broker := &types.ServiceBroker{
+			Base:      types.Base{ID: "broker-id"},
+			Name:      "broker",
+			BrokerURL: "http://example.com",
+			Credentials: &types.Credentials{
+				Basic: &types.Basic{Username: "admin", Password: "broker-secret"},
+			},
+			Catalog: json.RawMessage(`{"services":[]}`),
+		}
+		integrity, _ := integrityProcessor.CalculateIntegrity(broker)
+		broker.SetIntegrity(integrity)
+		return broker
+	}
+
+	newBinding := func() *types.ServiceBinding {
+		binding := &types.ServiceBinding{
+			Base:              types.Base{ID: "binding-id"},
+			Name:              "binding",
+			ServiceInstanceID: "instance-id",
+			Credentials:       json.RawMessage(`{"password":"binding-secret"}`),
+		}
+		integrity, _ := integrityProcessor.CalculateIntegrity(binding)
+		binding.SetIntegrity(integrity)
+		return binding
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		passphrase = "passphrase"
+		archive = &bytes.Buffer{}
+		integrityProcessor = storage.DefaultSettings().IntegrityProcessor
+		listCriteria = make(map[types.ObjectType][]query.Criterion)
+
+		fakeRepository = &storagefakes.FakeStorage{}
+		fakeRepository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			listCriteria[objectType] = criteria
+			switch objectType {
+			case types.PlatformType:
+				return types.NewObjectArray(newPlatform(), &types.Platform{Base: types.Base{ID: types.SMPlatform}}), nil
+			case types.ServiceBrokerType:
+				return types.NewObjectArray(newBroker()), nil
+			case types.VisibilityType:
+				return types.NewObjectArray(&types.Visibility{
+					Base:          types.Base{ID: "visibility-id"},
+					ServicePlanID: "plan-id",
+					Inactive:      true,
+				}), nil
+			case types.ServiceBindingType:
+				return types.NewObjectArray(newBinding()), nil
+			}
+			return types.NewObjectArray(), nil
+		})
+		fakeRepository.InTransactionCalls(func(ctx context.Context, f func(ctx context.Context, storage storage.Repository) error) error {
+			return f(ctx, fakeRepository)
+		})
+
+		archiver = &storage.Archiver{
+			Repository:         fakeRepository,
+			IntegrityProcessor: integrityProcessor,
+			Encrypter:          &security.AESEncrypter{},
+		}
+	})
+
+	createdObjects := func() []types.Object {
+		objects := make([]types.Object, 0)
+		for i := 0; i < fakeRepository.CreateCallCount(); i++ {
+			_, obj := fakeRepository.CreateArgsForCall(i)
+			objects = append(objects, obj)
+		}
+		return objects
+	}
+
+	Describe("Export", func() {
+		It("writes a versioned archive without the SM platform", func() {
+			summary, err := archiver.Export(ctx, archive, storage.ExportOptions{Passphrase: passphrase})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(summary.Objects).To(Equal(map[types.ObjectType]int{
+				types.PlatformType:       1,
+				types.ServiceBrokerType:  1,
+				types.VisibilityType:     1,
+				types.ServiceBindingType: 1,
+			}))
+
+			lines := strings.Split(strings.TrimSpace(archive.String()), "\n")
+			Expect(lines).To(HaveLen(6))
+			Expect(lines[0]).To(ContainSubstring(`"version":2`))
+			Expect(archive.String()).ToNot(ContainSubstring(types.SMPlatform))
+		})
+
+		It("derives the archive key with a random salt recorded in the header", func() {
+			_, err := archiver.Export(ctx, archive, storage.ExportOptions{Passphrase: passphrase})
+			Expect(err).ToNot(HaveOccurred())
+			other := &bytes.Buffer{}
+			_, err = archiver.Export(ctx, other, storage.ExportOptions{Passphrase: passphrase})
+			Expect(err).ToNot(HaveOccurred())
+
+			header, otherHeader := &storage.ArchiveHeader{}, &storage.ArchiveHeader{}
+			Expect(json.NewDecoder(archive).Decode(header)).To(Succeed())
+			Expect(json.NewDecoder(other).Decode(otherHeader)).To(Succeed())
+			Expect(header.KeyDerivation.Salt).To(HaveLen(32))
+			Expect(header.KeyDerivation.N).To(Equal(storage.DefaultArchiveKeyDerivation.N))
+			Expect(header.KeyDerivation.Salt).ToNot(Equal(otherHeader.KeyDerivation.Salt))
+		})
+
+		It("does not write secrets in plain text", func() {
+			_, err := archiver.Export(ctx, archive, storage.ExportOptions{Passphrase: passphrase})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(archive.String()).ToNot(ContainSubstring("secret"))
+		})
+
+		It("orders the objects by paging sequence", func() {
+			_, err := archiver.Export(ctx, archive, storage.ExportOptions{Passphrase: passphrase})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(listCriteria[types.PlatformType]).To(ContainElement(query.OrderResultBy("paging_sequence", query.AscOrder)))
+		})
+
+		Context("when a tenant is specified", func() {
+			It("selects the labeled objects by tenant and the related ones by the exported objects", func() {
+				_, err := archiver.Export(ctx, archive, storage.ExportOptions{Passphrase: passphrase, TenantLabelKey: "tenant", Tenant: "t1"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(listCriteria[types.PlatformType]).To(ContainElement(query.ByLabel(query.EqualsOperator, "tenant", "t1")))
+				Expect(listCriteria[types.ServiceOfferingType]).To(ContainElement(query.ByField(query.InOperator, "broker_id", "broker-id")))
+				Expect(listCriteria).ToNot(HaveKey(types.ServicePlanType))
+			})
+		})
+
+		Context("when an object has invalid integrity", func() {
+			It("returns an error", func() {
+				fakeRepository.ListReturns(types.NewObjectArray(&types.ServiceBinding{
+					Base:        types.Base{ID: "tampered"},
+					Credentials: json.RawMessage(`{}`),
+				}), nil)
+				_, err := archiver.Export(ctx, archive, storage.ExportOptions{Passphrase: passphrase})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Import", func() {
+		BeforeEach(func() {
+			_, err := archiver.Export(ctx, archive, storage.ExportOptions{Passphrase: passphrase})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("creates the objects with their IDs, secrets, catalog and state in archive order", func() {
+			summary, err := archiver.Import(ctx, archive, passphrase)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(summary.Objects).To(HaveLen(4))
+
+			objects := createdObjects()
+			Expect(objects).To(HaveLen(4))
+
+			platform := objects[0].(*types.Platform)
+			Expect(platform.ID).To(Equal("platform-id"))
+			Expect(platform.Labels).To(Equal(types.Labels{"tenant": {"t1"}}))
+			Expect(platform.Credentials.Basic.Password).To(Equal("platform-secret"))
+			Expect(platform.OldPasswordHash).To(Equal("old-password-hash"))
+			Expect(integrityProcessor.ValidateIntegrity(platform)).To(BeTrue())
+
+			broker := objects[1].(*types.ServiceBroker)
+			Expect(broker.Credentials.Basic.Password).To(Equal("broker-secret"))
+			Expect(string(broker.Catalog)).To(Equal(`{"services":[]}`))
+
+			visibility := objects[2].(*types.Visibility)
+			Expect(visibility.ID).To(Equal("visibility-id"))
+			Expect(visibility.Inactive).To(BeTrue())
+
+			binding := objects[3].(*types.ServiceBinding)
+			Expect(string(binding.Credentials)).To(Equal(`{"password":"binding-secret"}`))
+		})
+
+		Context("when the passphrase is wrong", func() {
+			It("returns an error", func() {
+				_, err := archiver.Import(ctx, archive, "wrong")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the archive is truncated", func() {
+			It("returns an error", func() {
+				lines := strings.Split(strings.TrimSpace(archive.String()), "\n")
+				truncated := strings.Join(lines[:len(lines)-1], "\n")
+				_, err := archiver.Import(ctx, strings.NewReader(truncated), passphrase)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when the integrity in the archive does not match", func() {
+			It("returns an error", func() {
+				lines := strings.Split(strings.TrimSpace(archive.String()), "\n")
+				record := &storage.ArchiveRecord{}
+				Expect(json.Unmarshal([]byte(lines[1]), record)).To(Succeed())
+				record.Integrity = []byte("tampered")
+				tampered, err := json.Marshal(record)
+				Expect(err).ToNot(HaveOccurred())
+				lines[1] = string(tampered)
+
+				_, err = archiver.Import(ctx, strings.NewReader(strings.Join(lines, "\n")), passphrase)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid integrity"))
+			})
+		})
+
+		Context("when an object already exists", func() {
+			It("returns conflict", func() {
+				fakeRepository.CreateReturns(nil, util.ErrAlreadyExistsInStorage)
+				_, err := archiver.Import(ctx, archive, passphrase)
+				Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusConflict))
+			})
+		})
+
+		Context("when the archive version is not supported", func() {
+			It("returns an error", func() {
+				_, err := archiver.Import(ctx, strings.NewReader(`{"version":1}`), passphrase)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("unsupported archive version"))
+			})
+		})
+
+		Context("when the key derivation in the archive header is invalid", func() {
+			It("returns an error without deriving the key", func() {
+				lines := strings.Split(strings.TrimSpace(archive.String()), "\n")
+				header := &storage.ArchiveHeader{}
+				Expect(json.Unmarshal([]byte(lines[0]), header)).To(Succeed())
+				header.KeyDerivation.N = 1 << 30
+				tampered, err := json.Marshal(header)
+				Expect(err).ToNot(HaveOccurred())
+				lines[0] = string(tampered)
+
+				_, err = archiver.Import(ctx, strings.NewReader(strings.Join(lines, "\n")), passphrase)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid key derivation"))
+			})
+		})
+	})
+})
@@ -201,12 +201,12 @@ func (er *encryptingRepository) CountLabelValues(ctx context.Context, objectType
 	return er.repository.CountLabelValues(ctx, objectType, criteria...)
 }
 
-func (er *encryptingRepository) Update(ctx context.Context, obj types.Object, labelChanges types.LabelChanges, _ ...query.Criterion) (types.Object, error) {
+func (er *encryptingRepository) Update(ctx context.Context, obj types.Object, labelChanges types.LabelChanges, criteria ...query.Criterion) (types.Object, error) {
 	if err := er.encrypt(ctx, obj); err != nil {
 		return nil, err
 	}
 
-	updatedObj, err := er.repository.Update(ctx, obj, labelChanges)
+	updatedObj, err := er.repository.Update(ctx, obj, labelChanges, criteria...)
 	if err != nil {
 		return nil, err
 	}
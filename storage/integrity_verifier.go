@@ -0,0 +1,220 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// maxResignReasonLength leaves room for the description prefix within the operation description limit
+const maxResignReasonLength = 200
+
+// IntegralTypes are the object types whose integrity is calculated and validated
+var IntegralTypes = []types.ObjectType{
+	types.PlatformType,
+	types.ServiceBrokerType,
+	types.ServiceBindingType,
+	types.BrokerPlatformCredentialType,
+}
+
+// IntegrityViolation identifies an object whose stored integrity does not match its data
+type IntegrityViolation struct {
+	ResourceType types.ObjectType `json:"resource_type"`
+	ResourceID   string           `json:"resource_id"`
+}
+
+// IntegrityReport is the result of an integrity verification
+type IntegrityReport struct {
+	// Verified is the number of objects which were verified
+	Verified int `json:"verified"`
+	// Violations contains the objects whose integrity is invalid
+	Violations []IntegrityViolation `json:"violations"`
+}
+
+// IntegrityVerifier validates the integrity of all integral objects and re-signs objects confirmed to be legitimate.
+// The repository should not validate integrity itself (e.g. the encrypting repository), as otherwise listing fails
+// on the first violation.
+type IntegrityVerifier struct {
+	Repository         TransactionalRepository
+	IntegrityProcessor security.IntegrityProcessor
+}
+
+// Verify validates the integrity of all integral objects and reports the ones whose integrity is invalid
+func (iv *IntegrityVerifier) Verify(ctx context.Context) (*IntegrityReport, error) {
+	report := &IntegrityReport{
+		Violations: make([]IntegrityViolation, 0),
+	}
+	for _, objectType := range IntegralTypes {
+		objects, err := iv.Repository.ListNoLabels(ctx, objectType)
+		if err != nil {
+			return nil, fmt.Errorf("could not list %s: %s", objectType, err)
+		}
+		for i := 0; i < objects.Len(); i++ {
+			obj := objects.ItemAt(i)
+			report.Verified++
+			if !iv.IntegrityProcessor.ValidateIntegrity(obj.(security.IntegralObject)) {
+				log.C(ctx).Warnf("Invalid integrity for %s with ID %s", objectType, obj.GetID())
+				report.Violations = append(report.Violations, IntegrityViolation{
+					ResourceType: objectType,
+					ResourceID:   obj.GetID(),
+				})
+			}
+		}
+	}
+	log.C(ctx).Infof("Verified integrity of %d objects, found %d violations", report.Verified, len(report.Violations))
+	return report, nil
+}
+
+// Resign recalculates the integrity of the specified objects. For each object an operation recording the reason and
+// the user who requested it is stored as an audit trail.
+func (iv *IntegrityVerifier) Resign(ctx context.Context, objectType types.ObjectType, ids []string, reason string, userInfo *types.UserInfo) error {
+	if err := validateResign(objectType, ids, reason); err != nil {
+		return &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: err.Error(),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+
+	return iv.Repository.InTransaction(ctx, func(ctx context.Context, storage Repository) error {
+		for _, id := range ids {
+			obj, err := storage.Get(ctx, objectType, query.ByField(query.EqualsOperator, "id", id))
+			if err != nil {
+				return util.HandleStorageError(err, objectType.String())
+			}
+			integralObject := obj.(security.IntegralObject)
+			integrity, err := iv.IntegrityProcessor.CalculateIntegrity(integralObject)
+			if err != nil {
+				return err
+			}
+			integralObject.SetIntegrity(integrity)
+			if _, err := storage.Update(ctx, obj, types.LabelChanges{}); err != nil {
+				return fmt.Errorf("could not update integrity of %s with ID %s: %s", objectType, id, err)
+			}
+
+			UUID, err := uuid.NewV4()
+			if err != nil {
+				return fmt.Errorf("could not generate GUID for operation: %s", err)
+			}
+			currentTime := time.Now()
+			if _, err := storage.Create(ctx, &types.Operation{
+				Base: types.Base{
+					ID:        UUID.String(),
+					CreatedAt: currentTime,
+					UpdatedAt: currentTime,
+					Labels:    make(map[string][]string),
+					Ready:     true,
+				},
+				Description:   "integrity re-signed: " + reason,
+				Type:          types.UPDATE,
+				State:         types.SUCCEEDED,
+				ResourceID:    id,
+				ResourceType:  objectType,
+				PlatformID:    types.SMPlatform,
+				CorrelationID: log.CorrelationIDFromContext(ctx),
+				Context:       &types.OperationContext{UserInfo: userInfo},
+			}); err != nil {
+				return fmt.Errorf("could not store audit operation for %s with ID %s: %s", objectType, id, err)
+			}
+			log.C(ctx).Infof("Integrity of %s with ID %s re-signed by %+v with reason: %s", objectType, id, userInfo, reason)
+		}
+		return nil
+	})
+}
+
+// Upgrade re-signs the objects whose integrity is valid but was calculated in a legacy format, e.g. before an integrity
+// key was set. Objects with invalid integrity are left unchanged, so that they are still reported by Verify.
+// It rewrites all integral objects in a single transaction, so it is run once by an operator through the integrity
+// upgrade command rather than by every node at startup.
+func (iv *IntegrityVerifier) Upgrade(ctx context.Context) (int, error) {
+	processor, ok := iv.IntegrityProcessor.(security.TransitionalIntegrityProcessor)
+	if !ok {
+		return 0, fmt.Errorf("integrity processor does not support upgrading legacy integrity")
+	}
+
+	upgraded := 0
+	err := iv.Repository.InTransaction(ctx, func(ctx context.Context, storage Repository) error {
+		for _, objectType := range IntegralTypes {
+			objects, err := storage.List(ctx, objectType)
+			if err != nil {
+				return fmt.Errorf("could not list %s: %s", objectType, err)
+			}
+			for i := 0; i < objects.Len(); i++ {
+				obj := objects.ItemAt(i)
+				integralObject := obj.(security.IntegralObject)
+				if !processor.IsLegacy(integralObject) {
+					continue
+				}
+				if !processor.ValidateIntegrity(integralObject) {
+					log.C(ctx).Warnf("Invalid integrity for %s with ID %s, skipping upgrade", objectType, obj.GetID())
+					continue
+				}
+				integrity, err := processor.CalculateIntegrity(integralObject)
+				if err != nil {
+					return err
+				}
+				integralObject.SetIntegrity(integrity)
+				if _, err := storage.Update(ctx, obj, types.LabelChanges{}); err != nil {
+					return fmt.Errorf("could not update integrity of %s with ID %s: %s", objectType, obj.GetID(), err)
+				}
+				upgraded++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	log.C(ctx).Infof("Upgraded integrity of %d objects", upgraded)
+	return upgraded, nil
+}
+
+func validateResign(objectType types.ObjectType, ids []string, reason string) error {
+	if !isIntegralType(objectType) {
+		return fmt.Errorf("integrity is not processed for objects of type %s", objectType)
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("at least one object ID should be provided")
+	}
+	if reason == "" {
+		return fmt.Errorf("a reason for re-signing should be provided")
+	}
+	if len(reason) > maxResignReasonLength {
+		return fmt.Errorf("reason should not be longer than %d characters", maxResignReasonLength)
+	}
+	return nil
+}
+
+func isIntegralType(objectType types.ObjectType) bool {
+	for _, integralType := range IntegralTypes {
+		if integralType == objectType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/security/securityfakes"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Integrity Verifier", func() {
+	var (
+		ctx                    context.Context
+		fakeRepository         *storagefakes.FakeStorage
+		fakeIntegrityProcessor *securityfakes.FakeIntegrityProcessor
+		verifier               *storage.IntegrityVerifier
+		broker                 *types.ServiceBroker
+		tamperedBroker         *types.ServiceBroker
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		broker = &types.ServiceBroker{
+			Base:        types.Base{ID: "broker-id"},
+			Credentials: &types.Credentials{Integrity: []byte("valid")},
+		}
+		tamperedBroker = &types.ServiceBroker{
+			Base:        types.Base{ID: "tampered-broker-id"},
+			Credentials: &types.Credentials{Integrity: []byte("invalid")},
+		}
+
+		fakeRepository = &storagefakes.FakeStorage{}
+		fakeRepository.ListNoLabelsCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			if objectType == types.ServiceBrokerType {
+				return &types.ServiceBrokers{ServiceBrokers: []*types.ServiceBroker{broker, tamperedBroker}}, nil
+			}
+			return types.NewObjectArray(), nil
+		})
+		fakeRepository.InTransactionCalls(func(ctx context.Context, f func(ctx context.Context, storage storage.Repository) error) error {
+			return f(ctx, fakeRepository)
+		})
+
+		fakeIntegrityProcessor = &securityfakes.FakeIntegrityProcessor{}
+		fakeIntegrityProcessor.ValidateIntegrityCalls(func(integral security.IntegralObject) bool {
+			return string(integral.GetIntegrity()) == "valid"
+		})
+		fakeIntegrityProcessor.CalculateIntegrityReturns([]byte("valid"), nil)
+
+		verifier = &storage.IntegrityVerifier{
+			Repository:         fakeRepository,
+			IntegrityProcessor: fakeIntegrityProcessor,
+		}
+	})
+
+	Describe("Verify", func() {
+		It("lists all integral types", func() {
+			_, err := verifier.Verify(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeRepository.ListNoLabelsCallCount()).To(Equal(len(storage.IntegralTypes)))
+		})
+
+		It("reports the objects with invalid integrity", func() {
+			report, err := verifier.Verify(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Verified).To(Equal(2))
+			Expect(report.Violations).To(ConsistOf(storage.IntegrityViolation{
+				ResourceType: types.ServiceBrokerType,
+				ResourceID:   tamperedBroker.ID,
+			}))
+		})
+
+		Context("when listing fails", func() {
+			It("returns an error", func() {
+				fakeRepository.ListNoLabelsReturns(nil, fmt.Errorf("error"))
+				_, err := verifier.Verify(ctx)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Resign", func() {
+		BeforeEach(func() {
+			fakeRepository.GetReturns(tamperedBroker, nil)
+		})
+
+		It("recalculates the integrity and stores an audit operation", func() {
+			userInfo := &types.UserInfo{Info: `{"username": "admin"}`}
+			err := verifier.Resign(ctx, types.ServiceBrokerType, []string{tamperedBroker.ID}, "restored from backup", userInfo)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRepository.UpdateCallCount()).To(Equal(1))
+			_, updated, _, _ := fakeRepository.UpdateArgsForCall(0)
+			Expect(updated.(*types.ServiceBroker).GetIntegrity()).To(Equal([]byte("valid")))
+
+			Expect(fakeRepository.CreateCallCount()).To(Equal(1))
+			_, created := fakeRepository.CreateArgsForCall(0)
+			operation := created.(*types.Operation)
+			Expect(operation.ResourceID).To(Equal(tamperedBroker.ID))
+			Expect(operation.ResourceType).To(Equal(types.ServiceBrokerType))
+			Expect(operation.State).To(Equal(types.SUCCEEDED))
+			Expect(operation.Description).To(ContainSubstring("restored from backup"))
+			Expect(operation.Context.UserInfo).To(Equal(userInfo))
+		})
+
+		DescribeTable("rejects invalid requests",
+			func(objectType types.ObjectType, ids []string, reason string) {
+				err := verifier.Resign(ctx, objectType, ids, reason, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(fakeRepository.UpdateCallCount()).To(Equal(0))
+			},
+			Entry("non-integral type", types.VisibilityType, []string{"id"}, "reason"),
+			Entry("no ids", types.ServiceBrokerType, []string{}, "reason"),
+			Entry("no reason", types.ServiceBrokerType, []string{"id"}, ""),
+		)
+
+		Context("when the object does not exist", func() {
+			It("returns not found", func() {
+				fakeRepository.GetReturns(nil, util.ErrNotFoundInStorage)
+				err := verifier.Resign(ctx, types.ServiceBrokerType, []string{"missing"}, "reason", nil)
+				Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("Upgrade", func() {
+		var (
+			legacyProcessor security.IntegrityProcessor
+			processor       *security.HMACIntegrityProcessor
+			brokers         []*types.ServiceBroker
+		)
+
+		newBroker := func(id string, integrityProcessor security.IntegrityProcessor) *types.ServiceBroker {
+			broker := &types.ServiceBroker{
+				Base: types.Base{ID: id},
+				Credentials: &types.Credentials{
+					Basic: &types.Basic{Username: "admin", Password: id},
+				},
+			}
+			integrity, err := integrityProcessor.CalculateIntegrity(broker)
+			Expect(err).ToNot(HaveOccurred())
+			broker.SetIntegrity(integrity)
+			return broker
+		}
+
+		BeforeEach(func() {
+			legacyProcessor = storage.DefaultSettings().IntegrityProcessor
+			processor = &security.HMACIntegrityProcessor{
+				Key:    []byte("integrity-key"),
+				Legacy: legacyProcessor,
+			}
+			tampered := newBroker("tampered", legacyProcessor)
+			tampered.Credentials.Basic.Password = "changed"
+			brokers = []*types.ServiceBroker{
+				newBroker("legacy", legacyProcessor),
+				newBroker("signed", processor),
+				tampered,
+			}
+			fakeRepository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+				if objectType == types.ServiceBrokerType {
+					return &types.ServiceBrokers{ServiceBrokers: brokers}, nil
+				}
+				return types.NewObjectArray(), nil
+			})
+			verifier.IntegrityProcessor = processor
+		})
+
+		It("signs only the objects with valid legacy integrity", func() {
+			upgraded, err := verifier.Upgrade(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(upgraded).To(Equal(1))
+			Expect(fakeRepository.UpdateCallCount()).To(Equal(1))
+			_, obj, _, _ := fakeRepository.UpdateArgsForCall(0)
+			Expect(obj.GetID()).To(Equal("legacy"))
+			Expect(processor.IsLegacy(obj.(security.IntegralObject))).To(BeFalse())
+			Expect(processor.ValidateIntegrity(obj.(security.IntegralObject))).To(BeTrue())
+		})
+
+		Context("when the integrity processor has no legacy format", func() {
+			It("returns an error", func() {
+				verifier.IntegrityProcessor = legacyProcessor
+				_, err := verifier.Upgrade(ctx)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
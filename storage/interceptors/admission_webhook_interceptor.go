@@ -0,0 +1,421 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/util/slice"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	AdmissionWebhookCreateInterceptorName = "AdmissionWebhookCreateInterceptor"
+	AdmissionWebhookUpdateInterceptorName = "AdmissionWebhookUpdateInterceptor"
+	AdmissionWebhookDeleteInterceptorName = "AdmissionWebhookDeleteInterceptor"
+)
+
+// AdmissionRequest is the body with which admission webhooks are called
+type AdmissionRequest struct {
+	UID          string             `json:"uid"`
+	Operation    string             `json:"operation"`
+	ResourceType string             `json:"resource_type"`
+	Object       types.Object       `json:"object"`
+	OldObject    types.Object       `json:"old_object,omitempty"`
+	LabelChanges types.LabelChanges `json:"label_changes,omitempty"`
+	User         string             `json:"user,omitempty"`
+}
+
+// AdmissionResponse is the response of admission webhooks. Mutating webhooks may modify the object of
+// create and update operations with a JSON patch.
+type AdmissionResponse struct {
+	Allowed bool                      `json:"allowed"`
+	Message string                    `json:"message,omitempty"`
+	Patch   []util.JSONPatchOperation `json:"patch,omitempty"`
+}
+
+// BaseAdmissionWebhookInterceptorProvider provides the configuration shared by the admission webhook interceptors
+type BaseAdmissionWebhookInterceptorProvider struct {
+	Repository storage.Repository
+	// DoRequestFunc is used to call the webhooks. If one is not provided, http.DefaultClient.Do is used
+	DoRequestFunc util.DoRequestFunc
+}
+
+func (p *BaseAdmissionWebhookInterceptorProvider) provide(objectType types.ObjectType) *admissionWebhookInterceptor {
+	doRequest := p.DoRequestFunc
+	if doRequest == nil {
+		doRequest = http.DefaultClient.Do
+	}
+	return &admissionWebhookInterceptor{
+		repository: p.Repository,
+		doRequest:  doRequest,
+		objectType: objectType,
+	}
+}
+
+// AdmissionWebhookCreateInterceptorProvider provides an interceptor which admits created objects with the admission webhooks
+type AdmissionWebhookCreateInterceptorProvider struct {
+	*BaseAdmissionWebhookInterceptorProvider
+}
+
+func (p *AdmissionWebhookCreateInterceptorProvider) Provide() storage.CreateAroundTxInterceptor {
+	return p.provide("")
+}
+
+func (p *AdmissionWebhookCreateInterceptorProvider) Name() string {
+	return AdmissionWebhookCreateInterceptorName
+}
+
+// AdmissionWebhookUpdateInterceptorProvider provides an interceptor which admits updated objects with the admission webhooks
+type AdmissionWebhookUpdateInterceptorProvider struct {
+	*BaseAdmissionWebhookInterceptorProvider
+}
+
+func (p *AdmissionWebhookUpdateInterceptorProvider) Provide() storage.UpdateAroundTxInterceptor {
+	return p.provide("")
+}
+
+func (p *AdmissionWebhookUpdateInterceptorProvider) Name() string {
+	return AdmissionWebhookUpdateInterceptorName
+}
+
+// AdmissionWebhookDeleteInterceptorProvider provides an interceptor which admits the deletion of objects of the object type
+// with the admission webhooks
+type AdmissionWebhookDeleteInterceptorProvider struct {
+	*BaseAdmissionWebhookInterceptorProvider
+	ObjectType types.ObjectType
+}
+
+func (p *AdmissionWebhookDeleteInterceptorProvider) Provide() storage.DeleteAroundTxInterceptor {
+	return p.provide(p.ObjectType)
+}
+
+func (p *AdmissionWebhookDeleteInterceptorProvider) Name() string {
+	return AdmissionWebhookDeleteInterceptorName
+}
+
+type admissionWebhookInterceptor struct {
+	repository storage.Repository
+	doRequest  util.DoRequestFunc
+	objectType types.ObjectType
+}
+
+// AroundTxCreate admits the created object with the matching admission webhooks
+func (i *admissionWebhookInterceptor) AroundTxCreate(h storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
+	return func(ctx context.Context, obj types.Object) (types.Object, error) {
+		webhooks, err := i.webhooks(ctx, obj.GetType(), types.VerbCreate)
+		if err != nil {
+			return nil, err
+		}
+		if len(webhooks) != 0 {
+			if obj, err = i.admit(ctx, webhooks, &AdmissionRequest{Operation: types.VerbCreate, Object: obj}); err != nil {
+				return nil, err
+			}
+		}
+		return h(ctx, obj)
+	}
+}
+
+// AroundTxUpdate admits the updated object and its label changes with the matching admission webhooks. As only the
+// label changes of an update are stored, label patches of mutating webhooks are turned into label changes which are
+// applied after the label changes of the request.
+func (i *admissionWebhookInterceptor) AroundTxUpdate(h storage.InterceptUpdateAroundTxFunc) storage.InterceptUpdateAroundTxFunc {
+	return func(ctx context.Context, obj types.Object, labelChanges ...*types.LabelChange) (types.Object, error) {
+		webhooks, err := i.webhooks(ctx, obj.GetType(), types.VerbUpdate)
+		if err != nil {
+			return nil, err
+		}
+		if len(webhooks) != 0 {
+			byID := query.ByField(query.EqualsOperator, "id", obj.GetID())
+			oldObj, err := i.repository.Get(ctx, obj.GetType(), byID)
+			if err != nil {
+				return nil, util.HandleStorageError(err, string(obj.GetType()))
+			}
+			request := &AdmissionRequest{
+				Operation:    types.VerbUpdate,
+				Object:       obj,
+				OldObject:    oldObj,
+				LabelChanges: labelChanges,
+			}
+			labels := obj.GetLabels()
+			if obj, err = i.admit(ctx, webhooks, request); err != nil {
+				return nil, err
+			}
+			labelChanges = append(labelChanges, patchedLabelChanges(labels, obj.GetLabels())...)
+		}
+		return h(ctx, obj, labelChanges...)
+	}
+}
+
+// AroundTxDelete admits the deletion of each of the deleted objects with the matching admission webhooks
+func (i *admissionWebhookInterceptor) AroundTxDelete(h storage.InterceptDeleteAroundTxFunc) storage.InterceptDeleteAroundTxFunc {
+	return func(ctx context.Context, deletionCriteria ...query.Criterion) error {
+		webhooks, err := i.webhooks(ctx, i.objectType, types.VerbDelete)
+		if err != nil {
+			return err
+		}
+		if len(webhooks) != 0 {
+			objects, err := i.repository.List(ctx, i.objectType, deletionCriteria...)
+			if err != nil {
+				return util.HandleStorageError(err, string(i.objectType))
+			}
+			for j := 0; j < objects.Len(); j++ {
+				if _, err := i.admit(ctx, webhooks, &AdmissionRequest{Operation: types.VerbDelete, Object: objects.ItemAt(j)}); err != nil {
+					return err
+				}
+			}
+		}
+		return h(ctx, deletionCriteria...)
+	}
+}
+
+// webhooks returns the admission webhooks matching the operation on objects of the type, the mutating webhooks first.
+// Only mutations on behalf of users are admitted, internal mutations of the Service Manager are not.
+func (i *admissionWebhookInterceptor) webhooks(ctx context.Context, objectType types.ObjectType, operation string) ([]*types.AdmissionWebhook, error) {
+	if _, found := web.UserFromContext(ctx); !found {
+		return nil, nil
+	}
+
+	objectList, err := i.repository.List(ctx, types.AdmissionWebhookType)
+	if err != nil {
+		return nil, fmt.Errorf("could not get admission webhooks: %s", err)
+	}
+	resourceType := path.Base(string(objectType))
+	webhooks := make([]*types.AdmissionWebhook, 0)
+	for j := 0; j < objectList.Len(); j++ {
+		webhook := objectList.ItemAt(j).(*types.AdmissionWebhook)
+		if webhook.Matches(resourceType, operation) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	sort.SliceStable(webhooks, func(a, b int) bool {
+		if webhooks[a].Kind != webhooks[b].Kind {
+			return webhooks[a].Kind == types.MutatingWebhook
+		}
+		return webhooks[a].Name < webhooks[b].Name
+	})
+	return webhooks, nil
+}
+
+// admit calls the webhooks in order and returns the object with the patches of the mutating webhooks applied
+func (i *admissionWebhookInterceptor) admit(ctx context.Context, webhooks []*types.AdmissionWebhook, request *AdmissionRequest) (types.Object, error) {
+	logger := log.C(ctx)
+	obj := request.Object
+	request.ResourceType = path.Base(string(obj.GetType()))
+	if user, found := web.UserFromContext(ctx); found {
+		request.User = user.Name
+	}
+
+	for _, webhook := range webhooks {
+		uid, err := uuid.NewV4()
+		if err != nil {
+			return nil, fmt.Errorf("could not generate admission request uid: %s", err)
+		}
+		request.UID = uid.String()
+		request.Object = obj
+
+		response, err := i.call(ctx, webhook, request)
+		if err == nil && !response.Allowed {
+			logger.Infof("Admission webhook %s rejected %s of %s %s: %s",
+				webhook.Name, request.Operation, request.ResourceType, obj.GetID(), response.Message)
+			return nil, &util.HTTPError{
+				ErrorType:   "BadRequest",
+				Description: fmt.Sprintf("admission webhook %s rejected the request: %s", webhook.Name, response.Message),
+				StatusCode:  http.StatusBadRequest,
+			}
+		}
+		if err == nil && len(response.Patch) != 0 {
+			obj, err = i.patch(webhook, request, response.Patch)
+		}
+		if err != nil {
+			if webhook.FailOpen() {
+				logger.WithError(err).Warnf("Admission webhook %s failed, admitting %s of %s %s as its failure policy is %s",
+					webhook.Name, request.Operation, request.ResourceType, request.Object.GetID(), types.FailurePolicyIgnore)
+				obj = request.Object
+				continue
+			}
+			logger.WithError(err).Errorf("Admission webhook %s failed", webhook.Name)
+			return nil, &util.HTTPError{
+				ErrorType:   "AdmissionWebhookError",
+				Description: fmt.Sprintf("admission webhook %s failed: %s", webhook.Name, err),
+				StatusCode:  http.StatusBadGateway,
+			}
+		}
+	}
+
+	return obj, nil
+}
+
+// call sends the admission request to the webhook and returns its response
+func (i *admissionWebhookInterceptor) call(ctx context.Context, webhook *types.AdmissionWebhook, request *AdmissionRequest) (*AdmissionResponse, error) {
+	sanitized := *request
+	var err error
+	if sanitized.Object, err = sanitizedCopy(ctx, request.Object); err != nil {
+		return nil, err
+	}
+	if request.OldObject != nil {
+		if sanitized.OldObject, err = sanitizedCopy(ctx, request.OldObject); err != nil {
+			return nil, err
+		}
+	}
+	body, err := json.Marshal(sanitized)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal admission request: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhook.Timeout())
+	defer cancel()
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := i.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not call admission webhook: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("admission webhook responded with status %d", resp.StatusCode)
+	}
+
+	response := &AdmissionResponse{}
+	if err := util.BodyToObject(resp.Body, response); err != nil {
+		return nil, fmt.Errorf("could not read admission webhook response: %s", err)
+	}
+	return response, nil
+}
+
+// patch returns a copy of the admitted object with the patch of a mutating webhook applied
+func (i *admissionWebhookInterceptor) patch(webhook *types.AdmissionWebhook, request *AdmissionRequest, patch []util.JSONPatchOperation) (types.Object, error) {
+	if webhook.Kind != types.MutatingWebhook {
+		return nil, fmt.Errorf("%s webhook responded with a patch", webhook.Kind)
+	}
+	if request.Operation == types.VerbDelete {
+		return nil, fmt.Errorf("webhook responded with a patch for a %s operation", types.VerbDelete)
+	}
+
+	obj := request.Object
+	document, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	if document, err = util.ApplyJSONPatch(document, patch); err != nil {
+		return nil, fmt.Errorf("could not apply patch: %s", err)
+	}
+
+	patched := reflect.New(reflect.TypeOf(obj).Elem())
+	if err := json.Unmarshal(document, patched.Interface()); err != nil {
+		return nil, fmt.Errorf("could not apply patch: %s", err)
+	}
+	copyUnmarshaledFields(reflect.ValueOf(obj).Elem(), patched.Elem())
+
+	result := patched.Interface().(types.Object)
+	if result.GetID() != obj.GetID() {
+		return nil, fmt.Errorf("patch modifies the id of %s %s", request.ResourceType, obj.GetID())
+	}
+	if err := result.Validate(); err != nil {
+		return nil, fmt.Errorf("patched %s is invalid: %s", request.ResourceType, err)
+	}
+	return result, nil
+}
+
+// patchedLabelChanges returns the label changes which turn the labels of the object into its patched labels
+func patchedLabelChanges(labels, patchedLabels types.Labels) types.LabelChanges {
+	keys := make([]string, 0, len(labels)+len(patchedLabels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	for key := range patchedLabels {
+		if _, found := labels[key]; !found {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make(types.LabelChanges, 0)
+	for _, key := range keys {
+		if removed := missingValues(labels[key], patchedLabels[key]); len(removed) != 0 {
+			changes = append(changes, &types.LabelChange{Operation: types.RemoveLabelValuesOperation, Key: key, Values: removed})
+		}
+		if added := missingValues(patchedLabels[key], labels[key]); len(added) != 0 {
+			changes = append(changes, &types.LabelChange{Operation: types.AddLabelValuesOperation, Key: key, Values: added})
+		}
+	}
+	return changes
+}
+
+// missingValues returns the values which are not in the other values
+func missingValues(values, otherValues []string) []string {
+	var missing []string
+	for _, value := range values {
+		if !slice.StringsAnyEquals(otherValues, value) {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
+
+// sanitizedCopy returns a copy of the object without its sensitive data
+func sanitizedCopy(ctx context.Context, obj types.Object) (types.Object, error) {
+	strip, ok := obj.(types.Strip)
+	if !ok {
+		return obj, nil
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	cp := reflect.New(reflect.TypeOf(obj).Elem()).Interface()
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, err
+	}
+	strip = cp.(types.Strip)
+	strip.Sanitize(ctx)
+	return strip.(types.Object), nil
+}
+
+// copyUnmarshaledFields copies the fields which are not unmarshaled from JSON, such as credentials, from the source
+// to the destination struct
+func copyUnmarshaledFields(source, destination reflect.Value) {
+	for j := 0; j < source.NumField(); j++ {
+		field := source.Type().Field(j)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			copyUnmarshaledFields(source.Field(j), destination.Field(j))
+			continue
+		}
+		if field.PkgPath == "" && field.Tag.Get("json") == "-" {
+			destination.Field(j).Set(source.Field(j))
+		}
+	}
+}
@@ -0,0 +1,325 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package interceptors_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/interceptors"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Admission webhook interceptor", func() {
+	type admissionRequest struct {
+		UID          string             `json:"uid"`
+		Operation    string             `json:"operation"`
+		ResourceType string             `json:"resource_type"`
+		Object       *types.Platform    `json:"object"`
+		OldObject    *types.Platform    `json:"old_object"`
+		LabelChanges types.LabelChanges `json:"label_changes"`
+		User         string             `json:"user"`
+	}
+
+	var (
+		ctx        context.Context
+		repository *storagefakes.FakeStorage
+		provider   *interceptors.BaseAdmissionWebhookInterceptorProvider
+		webhooks   []*types.AdmissionWebhook
+		servers    map[string]*httptest.Server
+		mutex      sync.Mutex
+		received   map[string][]admissionRequest
+		platform   *types.Platform
+		stored     types.Object
+		changes    []*types.LabelChange
+	)
+
+	// webhook starts a webhook server responding with the response function and registers the webhook
+	webhook := func(name, kind, failurePolicy string, respond func(request admissionRequest) (int, interceptors.AdmissionResponse)) *types.AdmissionWebhook {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var request admissionRequest
+			Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+			mutex.Lock()
+			received[name] = append(received[name], request)
+			mutex.Unlock()
+
+			status, response := respond(request)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			Expect(json.NewEncoder(w).Encode(response)).To(Succeed())
+		}))
+		servers[name] = server
+		wh := &types.AdmissionWebhook{
+			Base:           types.Base{ID: name},
+			Name:           name,
+			Kind:           kind,
+			URL:            server.URL,
+			ResourceTypes:  []string{"platforms"},
+			Operations:     []string{types.AnyPermission},
+			TimeoutSeconds: 1,
+			FailurePolicy:  failurePolicy,
+		}
+		webhooks = append(webhooks, wh)
+		return wh
+	}
+
+	allow := func(admissionRequest) (int, interceptors.AdmissionResponse) {
+		return http.StatusOK, interceptors.AdmissionResponse{Allowed: true}
+	}
+
+	patch := func(operations ...util.JSONPatchOperation) func(admissionRequest) (int, interceptors.AdmissionResponse) {
+		return func(admissionRequest) (int, interceptors.AdmissionResponse) {
+			return http.StatusOK, interceptors.AdmissionResponse{Allowed: true, Patch: operations}
+		}
+	}
+
+	replace := func(path string, value interface{}) util.JSONPatchOperation {
+		raw, err := json.Marshal(value)
+		Expect(err).ToNot(HaveOccurred())
+		return util.JSONPatchOperation{Op: "replace", Path: path, Value: raw}
+	}
+
+	requests := func(name string) []admissionRequest {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return received[name]
+	}
+
+	create := func() error {
+		stored = nil
+		interceptor := (&interceptors.AdmissionWebhookCreateInterceptorProvider{BaseAdmissionWebhookInterceptorProvider: provider}).Provide()
+		_, err := interceptor.AroundTxCreate(func(ctx context.Context, obj types.Object) (types.Object, error) {
+			stored = obj
+			return obj, nil
+		})(ctx, platform)
+		return err
+	}
+
+	update := func(labelChanges ...*types.LabelChange) error {
+		stored, changes = nil, nil
+		interceptor := (&interceptors.AdmissionWebhookUpdateInterceptorProvider{BaseAdmissionWebhookInterceptorProvider: provider}).Provide()
+		_, err := interceptor.AroundTxUpdate(func(ctx context.Context, obj types.Object, labelChanges ...*types.LabelChange) (types.Object, error) {
+			stored, changes = obj, labelChanges
+			return obj, nil
+		})(ctx, platform, labelChanges...)
+		return err
+	}
+
+	expectStatus := func(err error, status int) {
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(status))
+		Expect(stored).To(BeNil())
+	}
+
+	BeforeEach(func() {
+		ctx = web.ContextWithUser(context.Background(), &web.UserContext{Name: "admin", AuthenticationType: web.Basic})
+		webhooks = nil
+		servers = make(map[string]*httptest.Server)
+		received = make(map[string][]admissionRequest)
+		platform = &types.Platform{
+			Base:        types.Base{ID: "platform-id", Labels: types.Labels{"team": {"payments"}}},
+			Type:        "kubernetes",
+			Name:        "platform",
+			Description: "description",
+		}
+		repository = &storagefakes.FakeStorage{}
+		repository.ListStub = func(_ context.Context, objectType types.ObjectType, _ ...query.Criterion) (types.ObjectList, error) {
+			if objectType == types.AdmissionWebhookType {
+				return &types.AdmissionWebhooks{AdmissionWebhooks: webhooks}, nil
+			}
+			return &types.Platforms{Platforms: []*types.Platform{platform}}, nil
+		}
+		provider = &interceptors.BaseAdmissionWebhookInterceptorProvider{Repository: repository}
+	})
+
+	AfterEach(func() {
+		for _, server := range servers {
+			server.Close()
+		}
+	})
+
+	It("does not call webhooks for mutations which are not requested by users", func() {
+		webhook("validator", types.ValidatingWebhook, "", allow)
+		ctx = context.Background()
+
+		Expect(create()).To(Succeed())
+		Expect(stored).To(Equal(platform))
+		Expect(requests("validator")).To(BeEmpty())
+	})
+
+	It("admits the created object with the webhooks", func() {
+		webhook("validator", types.ValidatingWebhook, "", allow)
+
+		Expect(create()).To(Succeed())
+		Expect(stored).To(Equal(platform))
+		Expect(requests("validator")).To(HaveLen(1))
+		request := requests("validator")[0]
+		Expect(request.UID).ToNot(BeEmpty())
+		Expect(request.Operation).To(Equal(types.VerbCreate))
+		Expect(request.ResourceType).To(Equal("platforms"))
+		Expect(request.User).To(Equal("admin"))
+		Expect(request.Object.ID).To(Equal(platform.ID))
+	})
+
+	It("rejects mutations which are not allowed by a webhook", func() {
+		webhook("validator", types.ValidatingWebhook, "", func(admissionRequest) (int, interceptors.AdmissionResponse) {
+			return http.StatusOK, interceptors.AdmissionResponse{Allowed: false, Message: "platforms are frozen"}
+		})
+
+		err := create()
+		expectStatus(err, http.StatusBadRequest)
+		Expect(err.Error()).To(ContainSubstring("platforms are frozen"))
+	})
+
+	It("applies the patches of mutating webhooks before calling the validating webhooks", func() {
+		webhook("validator", types.ValidatingWebhook, "", allow)
+		webhook("mutator", types.MutatingWebhook, "", patch(replace("/description", "patched")))
+
+		Expect(create()).To(Succeed())
+		Expect(stored.(*types.Platform).Description).To(Equal("patched"))
+		Expect(requests("validator")[0].Object.Description).To(Equal("patched"))
+		Expect(platform.Description).To(Equal("description"))
+	})
+
+	DescribeTable("fails closed",
+		func(respond func(admissionRequest) (int, interceptors.AdmissionResponse), kind string) {
+			webhook("webhook", kind, types.FailurePolicyFail, respond)
+			expectStatus(create(), http.StatusBadGateway)
+		},
+		Entry("when a patch modifies the id", patch(replace("/id", "other-id")), types.MutatingWebhook),
+		Entry("when a patch makes the object invalid", patch(replace("/name", "")), types.MutatingWebhook),
+		Entry("when a validating webhook responds with a patch", patch(replace("/description", "patched")), types.ValidatingWebhook),
+		Entry("when the webhook responds with an error status", func(admissionRequest) (int, interceptors.AdmissionResponse) {
+			return http.StatusInternalServerError, interceptors.AdmissionResponse{}
+		}, types.ValidatingWebhook),
+		Entry("when the webhook times out", func(admissionRequest) (int, interceptors.AdmissionResponse) {
+			time.Sleep(1500 * time.Millisecond)
+			return http.StatusOK, interceptors.AdmissionResponse{Allowed: true}
+		}, types.ValidatingWebhook),
+	)
+
+	It("admits the unpatched object when a failing webhook fails open", func() {
+		webhook("mutator", types.MutatingWebhook, types.FailurePolicyIgnore, patch(replace("/id", "other-id")))
+		webhook("validator", types.ValidatingWebhook, types.FailurePolicyIgnore, func(admissionRequest) (int, interceptors.AdmissionResponse) {
+			time.Sleep(1500 * time.Millisecond)
+			return http.StatusOK, interceptors.AdmissionResponse{Allowed: false}
+		})
+
+		Expect(create()).To(Succeed())
+		Expect(stored).To(Equal(platform))
+	})
+
+	It("does not admit rejections of webhooks which fail open", func() {
+		webhook("validator", types.ValidatingWebhook, types.FailurePolicyIgnore, func(admissionRequest) (int, interceptors.AdmissionResponse) {
+			return http.StatusOK, interceptors.AdmissionResponse{Allowed: false}
+		})
+
+		expectStatus(create(), http.StatusBadRequest)
+	})
+
+	Describe("update", func() {
+		var oldPlatform *types.Platform
+
+		BeforeEach(func() {
+			oldPlatform = &types.Platform{Base: types.Base{ID: platform.ID}, Type: "kubernetes", Name: "old-name"}
+			repository.GetReturns(oldPlatform, nil)
+		})
+
+		It("sends the old object and the label changes", func() {
+			webhook("validator", types.ValidatingWebhook, "", allow)
+			labelChange := &types.LabelChange{Operation: types.AddLabelValuesOperation, Key: "team", Values: []string{"payments"}}
+
+			Expect(update(labelChange)).To(Succeed())
+			request := requests("validator")[0]
+			Expect(request.Operation).To(Equal(types.VerbUpdate))
+			Expect(request.OldObject.Name).To(Equal("old-name"))
+			Expect(request.LabelChanges).To(Equal(types.LabelChanges{labelChange}))
+			Expect(changes).To(Equal([]*types.LabelChange{labelChange}))
+		})
+
+		It("turns label patches into label changes", func() {
+			webhook("mutator", types.MutatingWebhook, "", patch(replace("/labels", types.Labels{
+				"team":  {"billing"},
+				"owner": {"alice", "bob"},
+			})))
+			labelChange := &types.LabelChange{Operation: types.AddLabelValuesOperation, Key: "team", Values: []string{"payments"}}
+
+			Expect(update(labelChange)).To(Succeed())
+			Expect(changes).To(Equal([]*types.LabelChange{
+				labelChange,
+				{Operation: types.AddLabelValuesOperation, Key: "owner", Values: []string{"alice", "bob"}},
+				{Operation: types.RemoveLabelValuesOperation, Key: "team", Values: []string{"payments"}},
+				{Operation: types.AddLabelValuesOperation, Key: "team", Values: []string{"billing"}},
+			}))
+		})
+
+		It("does not add label changes for patches which keep the labels", func() {
+			webhook("mutator", types.MutatingWebhook, "", patch(replace("/description", "patched")))
+
+			Expect(update()).To(Succeed())
+			Expect(stored.(*types.Platform).Description).To(Equal("patched"))
+			Expect(changes).To(BeEmpty())
+		})
+	})
+
+	Describe("delete", func() {
+		deleteObjects := func() (bool, error) {
+			deleted := false
+			interceptor := (&interceptors.AdmissionWebhookDeleteInterceptorProvider{
+				BaseAdmissionWebhookInterceptorProvider: provider,
+				ObjectType:                              types.PlatformType,
+			}).Provide()
+			err := interceptor.AroundTxDelete(func(ctx context.Context, deletionCriteria ...query.Criterion) error {
+				deleted = true
+				return nil
+			})(ctx, query.ByField(query.EqualsOperator, "id", platform.ID))
+			return deleted, err
+		}
+
+		It("admits the deletion of each deleted object", func() {
+			webhook("validator", types.ValidatingWebhook, "", allow)
+
+			deleted, err := deleteObjects()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deleted).To(BeTrue())
+			Expect(requests("validator")).To(HaveLen(1))
+			Expect(requests("validator")[0].Operation).To(Equal(types.VerbDelete))
+			Expect(requests("validator")[0].Object.ID).To(Equal(platform.ID))
+		})
+
+		It("rejects patches of deletions", func() {
+			webhook("mutator", types.MutatingWebhook, "", patch(replace("/description", "patched")))
+
+			deleted, err := deleteObjects()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadGateway))
+			Expect(deleted).To(BeFalse())
+		})
+	})
+})
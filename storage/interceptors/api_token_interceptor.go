@@ -0,0 +1,154 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/multitenancy"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/util/slice"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const generateAPITokenInterceptorName = "GenerateAPITokenInterceptor"
+
+// GenerateAPITokenInterceptorProvider provides an interceptor which generates the token of created API tokens
+type GenerateAPITokenInterceptorProvider struct {
+	// ExtractTenantFunc resolves the tenant of the user creating the API token. It should be the function used by the
+	// multitenancy filters. If not set, the tenant is taken from the multitenancy.MappedTenantClaim claim.
+	ExtractTenantFunc func(request *web.Request) (string, error)
+}
+
+func (c *GenerateAPITokenInterceptorProvider) Provide() storage.CreateAroundTxInterceptor {
+	extractTenantFunc := c.ExtractTenantFunc
+	if extractTenantFunc == nil {
+		extractTenantFunc = multitenancy.ExtractTenantFromTokenWrapperFunc(multitenancy.MappedTenantClaim)
+	}
+	return &generateAPITokenInterceptor{
+		extractTenantFunc: extractTenantFunc,
+	}
+}
+
+func (c *GenerateAPITokenInterceptorProvider) Name() string {
+	return generateAPITokenInterceptorName
+}
+
+type generateAPITokenInterceptor struct {
+	extractTenantFunc func(request *web.Request) (string, error)
+}
+
+// AroundTxCreate generates the token of the API token, stores only its hash and returns the token once.
+// API tokens created by bearer users are restricted to the scopes of the user and, unless the user has global
+// access, to the tenant of the user. API tokens created with an API token expire at the latest with it.
+func (c *generateAPITokenInterceptor) AroundTxCreate(h storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
+	return func(ctx context.Context, obj types.Object) (types.Object, error) {
+		apiToken, ok := obj.(*types.APIToken)
+		if !ok {
+			return nil, errors.New("created object is not an API token")
+		}
+
+		apiToken.CreatedBy = ""
+		apiToken.LastUsedAt = nil
+		if user, found := web.UserFromContext(ctx); found {
+			apiToken.CreatedBy = user.Name
+			if user.AuthenticationType == web.Bearer {
+				if err := c.restrictToCreator(ctx, user, apiToken); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if err := apiToken.GenerateToken(); err != nil {
+			log.C(ctx).Error("could not generate API token")
+			return nil, err
+		}
+		token := apiToken.Token
+		apiToken.Token = ""
+
+		createdObj, err := h(ctx, apiToken)
+		if err != nil {
+			return nil, err
+		}
+		createdObj.(*types.APIToken).Token = token
+		return createdObj, nil
+	}
+}
+
+func (c *generateAPITokenInterceptor) restrictToCreator(ctx context.Context, user *web.UserContext, apiToken *types.APIToken) error {
+	var raw json.RawMessage
+	if err := user.Data(&raw); err != nil {
+		return fmt.Errorf("could not unmarshal claims from token: %s", err)
+	}
+	scopes := make([]string, 0)
+	for _, scope := range gjson.GetBytes(raw, "scope").Array() {
+		scopes = append(scopes, scope.String())
+	}
+
+	if len(scopes) == 0 {
+		return forbidden("user %s has no scopes which can be granted to API token %s", user.Name, apiToken.Name)
+	}
+	if len(apiToken.Scopes) == 0 {
+		apiToken.Scopes = scopes
+	}
+	for _, scope := range apiToken.Scopes {
+		if !slice.StringsAnyEquals(scopes, scope) {
+			return forbidden("scope %s of API token %s is not granted to user %s", scope, apiToken.Name, user.Name)
+		}
+	}
+
+	// API tokens created with an API token must not outlive it
+	if gjson.GetBytes(raw, "api_token_id").Exists() {
+		expiresAt := gjson.GetBytes(raw, "exp")
+		if !expiresAt.Exists() || apiToken.ExpiresAt == nil || apiToken.ExpiresAt.Unix() > expiresAt.Int() {
+			return forbidden("API token %s must not expire after the API token of user %s", apiToken.Name, user.Name)
+		}
+	}
+
+	if user.AccessLevel == web.GlobalAccess {
+		return nil
+	}
+	tenant, err := c.extractTenantFunc(&web.Request{Request: (&http.Request{}).WithContext(ctx)})
+	if err != nil || len(tenant) == 0 {
+		log.C(ctx).WithError(err).Infof("Could not determine the tenant of user %s", user.Name)
+		return forbidden("could not determine the tenant of user %s", user.Name)
+	}
+	if len(apiToken.Tenant) == 0 {
+		apiToken.Tenant = tenant
+	}
+	if apiToken.Tenant != tenant {
+		return forbidden("tenant %s of API token %s is not the tenant of user %s", apiToken.Tenant, apiToken.Name, user.Name)
+	}
+	return nil
+}
+
+func forbidden(format string, args ...interface{}) error {
+	return &util.HTTPError{
+		ErrorType:   "Forbidden",
+		Description: fmt.Sprintf(format, args...),
+		StatusCode:  http.StatusForbidden,
+	}
+}
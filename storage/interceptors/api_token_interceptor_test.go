@@ -0,0 +1,146 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package interceptors_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/multitenancy"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/interceptors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Generate API token interceptor", func() {
+	var (
+		provider *interceptors.GenerateAPITokenInterceptorProvider
+		apiToken *types.APIToken
+		created  *types.APIToken
+	)
+
+	userContext := func(accessLevel web.AccessLevel, claims string) context.Context {
+		return web.ContextWithUser(context.Background(), &web.UserContext{
+			Data: func(data interface{}) error {
+				return json.Unmarshal([]byte(claims), data)
+			},
+			AuthenticationType: web.Bearer,
+			Name:               "user",
+			AccessLevel:        accessLevel,
+		})
+	}
+
+	create := func(ctx context.Context) error {
+		created = nil
+		_, err := provider.Provide().AroundTxCreate(func(ctx context.Context, obj types.Object) (types.Object, error) {
+			created = obj.(*types.APIToken)
+			return obj, nil
+		})(ctx, apiToken)
+		return err
+	}
+
+	expectForbidden := func(err error) {
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusForbidden))
+		Expect(created).To(BeNil())
+	}
+
+	BeforeEach(func() {
+		provider = &interceptors.GenerateAPITokenInterceptorProvider{
+			ExtractTenantFunc: multitenancy.ExtractTenantFromTokenWrapperFunc("zid"),
+		}
+		apiToken = &types.APIToken{Name: "token"}
+	})
+
+	It("restricts the token to the scopes and the tenant of the creator", func() {
+		err := create(userContext(web.TenantAccess, `{"scope":["sm.read","sm.write"],"zid":"tenant-1"}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(created.Scopes).To(ConsistOf("sm.read", "sm.write"))
+		Expect(created.Tenant).To(Equal("tenant-1"))
+		Expect(created.CreatedBy).To(Equal("user"))
+		Expect(created.Token).ToNot(BeEmpty())
+	})
+
+	It("allows a subset of the scopes of the creator", func() {
+		apiToken.Scopes = []string{"sm.read"}
+		err := create(userContext(web.TenantAccess, `{"scope":["sm.read","sm.write"],"zid":"tenant-1"}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(created.Scopes).To(ConsistOf("sm.read"))
+	})
+
+	It("rejects scopes the creator does not have", func() {
+		apiToken.Scopes = []string{"sm.admin"}
+		expectForbidden(create(userContext(web.TenantAccess, `{"scope":["sm.read"],"zid":"tenant-1"}`)))
+	})
+
+	It("rejects creators without scopes", func() {
+		apiToken.Scopes = []string{"sm.read"}
+		expectForbidden(create(userContext(web.TenantAccess, `{"zid":"tenant-1"}`)))
+	})
+
+	It("rejects a tenant other than the tenant of the creator's tenant claim", func() {
+		apiToken.Tenant = "tenant-2"
+		expectForbidden(create(userContext(web.TenantAccess, `{"scope":["sm.read"],"zid":"tenant-1"}`)))
+	})
+
+	It("rejects tokens of creators whose tenant cannot be determined", func() {
+		apiToken.Tenant = "tenant-2"
+		expectForbidden(create(userContext(web.TenantAccess, `{"scope":["sm.read"]}`)))
+	})
+
+	It("resolves the tenant from the mapped tenant claim if no extractor is configured", func() {
+		provider.ExtractTenantFunc = nil
+		err := create(userContext(web.TenantAccess, `{"scope":["sm.read"],"zid":"tenant-2","sm_mapped_tenant":"tenant-1"}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(created.Tenant).To(Equal("tenant-1"))
+	})
+
+	It("allows creators with global access to create tokens for any tenant", func() {
+		apiToken.Tenant = "tenant-2"
+		err := create(userContext(web.GlobalAccess, `{"scope":["sm.admin"]}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(created.Tenant).To(Equal("tenant-2"))
+	})
+
+	It("rejects tokens created with an API token which expire after it", func() {
+		parentExpiry := time.Now().Add(time.Hour)
+		claims := fmt.Sprintf(`{"scope":["sm.read"],"zid":"tenant-1","api_token_id":"parent","exp":%d}`, parentExpiry.Unix())
+
+		expiresAt := parentExpiry.Add(time.Minute)
+		apiToken.ExpiresAt = &expiresAt
+		expectForbidden(create(userContext(web.TenantAccess, claims)))
+
+		expiresAt = parentExpiry.Add(-time.Minute)
+		apiToken.ExpiresAt = &expiresAt
+		Expect(create(userContext(web.TenantAccess, claims))).To(Succeed())
+	})
+
+	It("does not restrict tokens created with basic credentials", func() {
+		apiToken.Tenant = "tenant-2"
+		ctx := web.ContextWithUser(context.Background(), &web.UserContext{AuthenticationType: web.Basic, Name: "admin"})
+		Expect(create(ctx)).To(Succeed())
+		Expect(created.Tenant).To(Equal("tenant-2"))
+		Expect(created.CreatedBy).To(Equal("admin"))
+	})
+})
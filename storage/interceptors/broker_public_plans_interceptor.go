@@ -186,6 +186,10 @@ func resyncPublicPlanVisibilities(ctx context.Context, txStorage storage.Reposit
 
 	for i := 0; i < planVisibilities.Len(); i++ {
 		visibility := planVisibilities.ItemAt(i).(*types.Visibility)
+		if visibility.PlatformSelector != "" {
+			// visibilities with a platform selector are managed explicitly and are not public
+			continue
+		}
 		byVisibilityID := query.ByField(query.EqualsOperator, "id", visibility.ID)
 
 		shouldDeleteVisibility := true
@@ -219,6 +223,9 @@ func resyncPublicPlanVisibilities(ctx context.Context, txStorage storage.Reposit
 func resyncPlanVisibilitiesWithSupportedPlatforms(ctx context.Context, txStorage storage.Repository, planVisibilities types.ObjectList, isPlanPublic bool, planID string, broker *types.ServiceBroker, supportedPlatforms map[string]*types.Platform, tenantKey string, labelLessVisibilitiesByID map[string]bool) error {
 	for i := 0; i < planVisibilities.Len(); i++ {
 		visibility := planVisibilities.ItemAt(i).(*types.Visibility)
+		if visibility.PlatformSelector != "" {
+			continue
+		}
 
 		shouldDeleteVisibility := true
 
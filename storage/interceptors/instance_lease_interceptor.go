@@ -0,0 +1,174 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/operations/opcontext"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	InstanceLeaseCreateInterceptorName = "InstanceLeaseCreateInterceptor"
+	InstanceLeaseUpdateInterceptorName = "InstanceLeaseUpdateInterceptor"
+)
+
+// InstanceLeaseCreateInterceptorProvider provides an interceptor that applies the lease policy of the plan to created instances
+type InstanceLeaseCreateInterceptorProvider struct {
+	Repository storage.TransactionalRepository
+}
+
+func (c *InstanceLeaseCreateInterceptorProvider) Name() string {
+	return InstanceLeaseCreateInterceptorName
+}
+
+func (c *InstanceLeaseCreateInterceptorProvider) Provide() storage.CreateAroundTxInterceptor {
+	return &instanceLeaseInterceptor{
+		repository: c.Repository,
+	}
+}
+
+// InstanceLeaseUpdateInterceptorProvider provides an interceptor that applies the lease policy of the plan to renewed instances
+type InstanceLeaseUpdateInterceptorProvider struct {
+	Repository storage.TransactionalRepository
+}
+
+func (c *InstanceLeaseUpdateInterceptorProvider) Name() string {
+	return InstanceLeaseUpdateInterceptorName
+}
+
+func (c *InstanceLeaseUpdateInterceptorProvider) Provide() storage.UpdateAroundTxInterceptor {
+	return &instanceLeaseInterceptor{
+		repository: c.Repository,
+	}
+}
+
+type instanceLeaseInterceptor struct {
+	repository storage.TransactionalRepository
+}
+
+func (c *instanceLeaseInterceptor) AroundTxCreate(h storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
+	return func(ctx context.Context, obj types.Object) (types.Object, error) {
+		instance := obj.(*types.ServiceInstance)
+		if instance.PlatformID == types.SMPlatform && !isRepeatedExecution(ctx) {
+			if err := c.applyLeasePolicy(ctx, instance, true); err != nil {
+				return nil, err
+			}
+		}
+		return h(ctx, obj)
+	}
+}
+
+func (c *instanceLeaseInterceptor) AroundTxUpdate(h storage.InterceptUpdateAroundTxFunc) storage.InterceptUpdateAroundTxFunc {
+	return func(ctx context.Context, newObj types.Object, labelChanges ...*types.LabelChange) (types.Object, error) {
+		newInstance := newObj.(*types.ServiceInstance)
+		if newInstance.PlatformID != types.SMPlatform || isRepeatedExecution(ctx) {
+			return h(ctx, newObj, labelChanges...)
+		}
+
+		oldObj, err := c.repository.Get(ctx, types.ServiceInstanceType, query.ByField(query.EqualsOperator, "id", newObj.GetID()))
+		if err != nil {
+			return nil, util.HandleStorageError(err, types.ServiceInstanceType.String())
+		}
+		oldInstance := oldObj.(*types.ServiceInstance)
+		if sameExpiry(oldInstance.ExpiresAt, newInstance.ExpiresAt) && oldInstance.ServicePlanID == newInstance.ServicePlanID {
+			return h(ctx, newObj, labelChanges...)
+		}
+
+		if err := c.applyLeasePolicy(ctx, newInstance, false); err != nil {
+			return nil, err
+		}
+		if _, warned := oldInstance.Labels[types.LeaseExpiringLabelKey]; warned && !sameExpiry(oldInstance.ExpiresAt, newInstance.ExpiresAt) {
+			// the lease was renewed, so the warning no longer applies
+			labelChanges = append(labelChanges, &types.LabelChange{
+				Operation: types.RemoveLabelOperation,
+				Key:       types.LeaseExpiringLabelKey,
+			})
+		}
+		return h(ctx, newObj, labelChanges...)
+	}
+}
+
+// applyLeasePolicy sets the default lease of the plan on created instances without expiry
+// and verifies that the expiry of the instance is within the maximum lease of the plan
+func (c *instanceLeaseInterceptor) applyLeasePolicy(ctx context.Context, instance *types.ServiceInstance, created bool) error {
+	planObject, err := c.repository.Get(ctx, types.ServicePlanType, query.ByField(query.EqualsOperator, "id", instance.ServicePlanID))
+	if err != nil {
+		return util.HandleStorageError(err, types.ServicePlanType.String())
+	}
+	plan := planObject.(*types.ServicePlan)
+	maxLease, err := plan.MaxLease()
+	if err != nil {
+		return err
+	}
+	defaultLease, err := plan.DefaultLease()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if instance.ExpiresAt == nil {
+		if !created && maxLease > 0 {
+			return leaseError("instances of plan with id %s must have an expiry", plan.ID)
+		}
+		lease := defaultLease
+		if lease == 0 || (maxLease > 0 && lease > maxLease) {
+			lease = maxLease
+		}
+		if lease > 0 {
+			expiresAt := now.Add(lease)
+			instance.ExpiresAt = &expiresAt
+		}
+		return nil
+	}
+
+	if !instance.ExpiresAt.After(now) {
+		return leaseError("expires_at must be in the future")
+	}
+	if maxLease > 0 && instance.ExpiresAt.After(now.Add(maxLease)) {
+		return leaseError("instances of plan with id %s can be leased for at most %s", plan.ID, maxLease)
+	}
+	return nil
+}
+
+// isRepeatedExecution determines whether the lease was already applied when the operation was first executed
+func isRepeatedExecution(ctx context.Context) bool {
+	operation, found := opcontext.Get(ctx)
+	return found && (operation.Reschedule || operation.IsContinuation())
+}
+
+func sameExpiry(t1, t2 *time.Time) bool {
+	if t1 == nil || t2 == nil {
+		return t1 == t2
+	}
+	return t1.Equal(*t2)
+}
+
+func leaseError(format string, args ...interface{}) error {
+	return &util.HTTPError{
+		ErrorType:   "BadRequest",
+		Description: fmt.Sprintf(format, args...),
+		StatusCode:  http.StatusBadRequest,
+	}
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package interceptors_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Peripli/service-manager/operations/opcontext"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage/interceptors"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Instance lease interceptor", func() {
+	var (
+		repository *storagefakes.FakeStorage
+		plan       *types.ServicePlan
+		stored     *types.ServiceInstance
+		created    types.Object
+		updated    types.Object
+		changes    []*types.LabelChange
+	)
+
+	expiresIn := func(duration time.Duration) *time.Time {
+		expiresAt := time.Now().UTC().Add(duration)
+		return &expiresAt
+	}
+
+	expectBadRequest := func(err error) {
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+	}
+
+	create := func(ctx context.Context, instance *types.ServiceInstance) error {
+		provider := &interceptors.InstanceLeaseCreateInterceptorProvider{Repository: repository}
+		_, err := provider.Provide().AroundTxCreate(func(ctx context.Context, obj types.Object) (types.Object, error) {
+			created = obj
+			return obj, nil
+		})(ctx, instance)
+		return err
+	}
+
+	update := func(ctx context.Context, instance *types.ServiceInstance) error {
+		provider := &interceptors.InstanceLeaseUpdateInterceptorProvider{Repository: repository}
+		_, err := provider.Provide().AroundTxUpdate(func(ctx context.Context, obj types.Object, labelChanges ...*types.LabelChange) (types.Object, error) {
+			updated = obj
+			changes = labelChanges
+			return obj, nil
+		})(ctx, instance)
+		return err
+	}
+
+	BeforeEach(func() {
+		created, updated, changes = nil, nil, nil
+		plan = &types.ServicePlan{Base: types.Base{ID: "plan", Labels: types.Labels{
+			types.MaxLeaseLabelKey:     {"720h"},
+			types.DefaultLeaseLabelKey: {"168h"},
+		}}}
+		stored = &types.ServiceInstance{
+			Base:          types.Base{ID: "instance", Labels: types.Labels{}},
+			PlatformID:    types.SMPlatform,
+			ServicePlanID: "plan",
+			ExpiresAt:     expiresIn(time.Hour),
+		}
+		repository = &storagefakes.FakeStorage{}
+		repository.GetCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.Object, error) {
+			if objectType == types.ServicePlanType {
+				return plan, nil
+			}
+			instance := *stored
+			return &instance, nil
+		})
+	})
+
+	Describe("create", func() {
+		It("sets the default lease of the plan on instances without expiry", func() {
+			Expect(create(context.Background(), &types.ServiceInstance{PlatformID: types.SMPlatform, ServicePlanID: "plan"})).To(Succeed())
+			expiresAt := created.(*types.ServiceInstance).ExpiresAt
+			Expect(expiresAt).ToNot(BeNil())
+			Expect(*expiresAt).To(BeTemporally("~", time.Now().Add(168*time.Hour), time.Minute))
+		})
+
+		It("limits the default lease to the maximum lease", func() {
+			plan.Labels[types.DefaultLeaseLabelKey] = []string{"1000h"}
+			Expect(create(context.Background(), &types.ServiceInstance{PlatformID: types.SMPlatform, ServicePlanID: "plan"})).To(Succeed())
+			Expect(*created.(*types.ServiceInstance).ExpiresAt).To(BeTemporally("~", time.Now().Add(720*time.Hour), time.Minute))
+		})
+
+		It("rejects expiries in the past and beyond the maximum lease", func() {
+			expectBadRequest(create(context.Background(), &types.ServiceInstance{PlatformID: types.SMPlatform, ServicePlanID: "plan", ExpiresAt: expiresIn(-time.Hour)}))
+			expectBadRequest(create(context.Background(), &types.ServiceInstance{PlatformID: types.SMPlatform, ServicePlanID: "plan", ExpiresAt: expiresIn(1000 * time.Hour)}))
+			Expect(created).To(BeNil())
+		})
+
+		It("does not apply the lease policy to instances of other platforms", func() {
+			Expect(create(context.Background(), &types.ServiceInstance{PlatformID: "cf", ServicePlanID: "plan"})).To(Succeed())
+			Expect(created.(*types.ServiceInstance).ExpiresAt).To(BeNil())
+			Expect(repository.GetCallCount()).To(Equal(0))
+		})
+
+		It("does not apply the lease policy again when the operation is rescheduled", func() {
+			ctx, err := opcontext.Set(context.Background(), &types.Operation{Base: types.Base{ID: "operation"}, Type: types.CREATE, State: types.IN_PROGRESS, ResourceID: "instance", ResourceType: types.ServiceInstanceType, Reschedule: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(create(ctx, &types.ServiceInstance{PlatformID: types.SMPlatform, ServicePlanID: "plan", ExpiresAt: expiresIn(-time.Hour)})).To(Succeed())
+		})
+	})
+
+	Describe("update", func() {
+		It("renews the lease and removes the expiry warning", func() {
+			stored.Labels[types.LeaseExpiringLabelKey] = []string{stored.ExpiresAt.Format(time.RFC3339)}
+			renewed := *stored
+			renewed.ExpiresAt = expiresIn(240 * time.Hour)
+
+			Expect(update(context.Background(), &renewed)).To(Succeed())
+			Expect(updated).To(Equal(&renewed))
+			Expect(changes).To(ConsistOf(&types.LabelChange{Operation: types.RemoveLabelOperation, Key: types.LeaseExpiringLabelKey}))
+		})
+
+		It("rejects renewals beyond the maximum lease", func() {
+			renewed := *stored
+			renewed.ExpiresAt = expiresIn(1000 * time.Hour)
+			expectBadRequest(update(context.Background(), &renewed))
+			Expect(updated).To(BeNil())
+		})
+
+		It("rejects removing the expiry of instances of plans with a maximum lease", func() {
+			renewed := *stored
+			renewed.ExpiresAt = nil
+			expectBadRequest(update(context.Background(), &renewed))
+		})
+
+		It("does not check the lease of updates which do not change the expiry or plan", func() {
+			plan.Labels[types.MaxLeaseLabelKey] = []string{"1m"}
+			unchanged := *stored
+			unchanged.Name = "renamed"
+			Expect(update(context.Background(), &unchanged)).To(Succeed())
+			Expect(changes).To(BeEmpty())
+		})
+	})
+})
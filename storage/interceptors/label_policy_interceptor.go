@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	LabelPolicyCreateInterceptorName = "LabelPolicyCreateInterceptor"
+	LabelPolicyUpdateInterceptorName = "LabelPolicyUpdateInterceptor"
+)
+
+// LabelPolicyCreateInterceptorProvider provides an interceptor that enforces the label policies on the labels of
+// created resources
+type LabelPolicyCreateInterceptorProvider struct {
+}
+
+func (c *LabelPolicyCreateInterceptorProvider) Name() string {
+	return LabelPolicyCreateInterceptorName
+}
+
+func (c *LabelPolicyCreateInterceptorProvider) Provide() storage.CreateOnTxInterceptor {
+	return &labelPolicyInterceptor{}
+}
+
+// LabelPolicyUpdateInterceptorProvider provides an interceptor that enforces the label policies on the label changes
+// of updated resources
+type LabelPolicyUpdateInterceptorProvider struct {
+}
+
+func (c *LabelPolicyUpdateInterceptorProvider) Name() string {
+	return LabelPolicyUpdateInterceptorName
+}
+
+func (c *LabelPolicyUpdateInterceptorProvider) Provide() storage.UpdateOnTxInterceptor {
+	return &labelPolicyInterceptor{}
+}
+
+// labelPolicyInterceptor checks the labels in the transaction which stores them, so that the labels of all writes,
+// including the ones of admission webhooks and internal updates, are checked against the current labels and policies
+type labelPolicyInterceptor struct {
+}
+
+func (c *labelPolicyInterceptor) OnTxCreate(h storage.InterceptCreateOnTxFunc) storage.InterceptCreateOnTxFunc {
+	return func(ctx context.Context, txStorage storage.Repository, obj types.Object) (types.Object, error) {
+		if err := storage.CheckLabelPolicy(ctx, txStorage, obj); err != nil {
+			return nil, err
+		}
+		return h(ctx, txStorage, obj)
+	}
+}
+
+func (c *labelPolicyInterceptor) OnTxUpdate(h storage.InterceptUpdateOnTxFunc) storage.InterceptUpdateOnTxFunc {
+	return func(ctx context.Context, txStorage storage.Repository, oldObj, newObj types.Object, labelChanges ...*types.LabelChange) (types.Object, error) {
+		if err := storage.CheckLabelPolicyChanges(ctx, txStorage, newObj.GetType(), newObj.GetID(), labelChanges); err != nil {
+			return nil, err
+		}
+		return h(ctx, txStorage, oldObj, newObj, labelChanges...)
+	}
+}
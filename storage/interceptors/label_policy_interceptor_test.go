@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package interceptors_test
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/interceptors"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Label policy interceptor", func() {
+	var (
+		ctx        context.Context
+		repository *storagefakes.FakeStorage
+		broker     *types.ServiceBroker
+		stored     bool
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		stored = false
+		broker = &types.ServiceBroker{Base: types.Base{ID: "broker-id", Labels: types.Labels{"env": {"dev"}}}}
+		repository = &storagefakes.FakeStorage{}
+		repository.ListReturns(&types.LabelPolicies{LabelPolicies: []*types.LabelPolicy{{
+			ResourceType: "service_brokers",
+			Keys:         []*types.LabelKeyPolicy{{Key: "env", Required: true}},
+		}}}, nil)
+		repository.GetForUpdateReturns(broker, nil)
+	})
+
+	Describe("OnTxCreate", func() {
+		create := func(obj types.Object) error {
+			interceptor := (&interceptors.LabelPolicyCreateInterceptorProvider{}).Provide()
+			_, err := interceptor.OnTxCreate(func(ctx context.Context, txStorage storage.Repository, obj types.Object) (types.Object, error) {
+				stored = true
+				return obj, nil
+			})(ctx, repository, obj)
+			return err
+		}
+
+		It("stores objects whose labels comply with the policy", func() {
+			Expect(create(broker)).To(Succeed())
+			Expect(stored).To(BeTrue())
+		})
+
+		It("does not store objects whose labels violate the policy", func() {
+			Expect(create(&types.ServiceBroker{})).To(HaveOccurred())
+			Expect(stored).To(BeFalse())
+		})
+	})
+
+	Describe("OnTxUpdate", func() {
+		update := func(labelChanges ...*types.LabelChange) error {
+			interceptor := (&interceptors.LabelPolicyUpdateInterceptorProvider{}).Provide()
+			_, err := interceptor.OnTxUpdate(func(ctx context.Context, txStorage storage.Repository, oldObj, newObj types.Object, labelChanges ...*types.LabelChange) (types.Object, error) {
+				stored = true
+				return newObj, nil
+			})(ctx, repository, broker, broker, labelChanges...)
+			return err
+		}
+
+		It("stores label changes complying with the policy", func() {
+			Expect(update(&types.LabelChange{Operation: types.AddLabelOperation, Key: "team", Values: []string{"a"}})).To(Succeed())
+			Expect(stored).To(BeTrue())
+			_, _, criteria := repository.GetForUpdateArgsForCall(0)
+			Expect(criteria).To(ConsistOf(query.ByField(query.EqualsOperator, "id", broker.ID)))
+		})
+
+		It("does not store label changes violating the policy", func() {
+			Expect(update(&types.LabelChange{Operation: types.RemoveLabelOperation, Key: "env"})).To(HaveOccurred())
+			Expect(stored).To(BeFalse())
+		})
+	})
+})
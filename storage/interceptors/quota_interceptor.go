@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/operations/opcontext"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/tidwall/gjson"
+)
+
+const QuotaCreateInterceptorName = "QuotaCreateInterceptor"
+
+// QuotaCreateInterceptorProvider provides an interceptor that forbids creation of instances and bindings which exceed the quotas of their tenant
+type QuotaCreateInterceptorProvider struct {
+	TenantIdentifier string
+	Repository       storage.Repository
+}
+
+func (c *QuotaCreateInterceptorProvider) Name() string {
+	return QuotaCreateInterceptorName
+}
+
+func (c *QuotaCreateInterceptorProvider) Provide() storage.CreateInterceptor {
+	return &quotaInterceptor{
+		tenantIdentifier: c.TenantIdentifier,
+		repository:       c.Repository,
+		checker:          &storage.QuotaChecker{TenantKey: c.TenantIdentifier},
+	}
+}
+
+type quotaInterceptor struct {
+	tenantIdentifier string
+	repository       storage.Repository
+	checker          *storage.QuotaChecker
+}
+
+// AroundTxCreate rejects creations exceeding the quotas before the broker is called. It does not prevent concurrent
+// creations from exceeding the quotas, this is done in the transaction. Approved and retried resources were counted when they were stored.
+func (c *quotaInterceptor) AroundTxCreate(h storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
+	return func(ctx context.Context, obj types.Object) (types.Object, error) {
+		if operation, found := opcontext.Get(ctx); !found || !operation.Reschedule && !operation.IsContinuation() {
+			if err := c.check(ctx, c.repository, obj, false); err != nil {
+				return nil, err
+			}
+		}
+		return h(ctx, obj)
+	}
+}
+
+// OnTxCreate locks the quotas of the tenant, so that concurrent creations are checked one after the other
+func (c *quotaInterceptor) OnTxCreate(h storage.InterceptCreateOnTxFunc) storage.InterceptCreateOnTxFunc {
+	return func(ctx context.Context, repository storage.Repository, obj types.Object) (types.Object, error) {
+		if err := c.check(ctx, repository, obj, true); err != nil {
+			return nil, err
+		}
+		return h(ctx, repository, obj)
+	}
+}
+
+func (c *quotaInterceptor) check(ctx context.Context, repository storage.Repository, obj types.Object, lock bool) error {
+	switch object := obj.(type) {
+	case *types.ServiceInstance:
+		tenant := c.tenantOf(object, object.Context)
+		if tenant == "" {
+			return nil
+		}
+		if lock {
+			if err := c.checker.LockQuotas(ctx, repository, tenant); err != nil {
+				return err
+			}
+		}
+		return c.checker.CheckInstance(ctx, repository, tenant, object.ServicePlanID)
+	case *types.ServiceBinding:
+		instance, err := repository.Get(ctx, types.ServiceInstanceType, query.ByField(query.EqualsOperator, "id", object.ServiceInstanceID))
+		if err != nil {
+			return util.HandleStorageError(err, types.ServiceInstanceType.String())
+		}
+		serviceInstance := instance.(*types.ServiceInstance)
+		tenant := c.tenantOf(serviceInstance, serviceInstance.Context)
+		if tenant == "" {
+			return nil
+		}
+		if lock {
+			if err := c.checker.LockQuotas(ctx, repository, tenant); err != nil {
+				return err
+			}
+		}
+		return c.checker.CheckBinding(ctx, repository, tenant, serviceInstance)
+	default:
+		return nil
+	}
+}
+
+// tenantOf returns the tenant label of the object or, for objects created through the OSB API which are not yet labeled, the tenant in their OSB context
+func (c *quotaInterceptor) tenantOf(obj types.Object, objectContext json.RawMessage) string {
+	if values := obj.GetLabels()[c.tenantIdentifier]; len(values) > 0 {
+		return values[0]
+	}
+	return gjson.GetBytes(objectContext, c.tenantIdentifier).String()
+}
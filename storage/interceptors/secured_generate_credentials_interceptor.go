@@ -67,13 +67,19 @@ func (c *generatePlatformCredentialsInterceptor) AroundTxCreate(h storage.Interc
 			platform.Credentials = &types.Credentials{
 				Basic: &types.Basic{Username: platform.ID},
 			}
+			platform.PasswordHash = ""
 			return h(ctx, obj)
 		}
 
-		if err := generateCredentials(ctx, platform); err != nil {
+		password, err := generateCredentials(ctx, platform)
+		if err != nil {
 			return nil, err
 		}
-		return h(ctx, obj)
+		createdObj, err := h(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		return withPassword(createdObj, password), nil
 	}
 }
 
@@ -93,22 +99,44 @@ func (c *generatePlatformCredentialsInterceptor) AroundTxUpdate(h storage.Interc
 			if platform.CredentialsActive {
 				log.C(ctx).Infof("Storing current credentials for platform %s as old", platform.ID)
 				platform.OldCredentials = platform.Credentials
+				platform.OldPasswordHash = platform.PasswordHash
 				platform.CredentialsActive = false
 			}
-			if err := generateCredentials(ctx, platform); err != nil {
+			password, err := generateCredentials(ctx, platform)
+			if err != nil {
+				return nil, err
+			}
+			updatedObj, err := h(ctx, platform, labelChanges...)
+			if err != nil {
 				return nil, err
 			}
+			return withPassword(updatedObj, password), nil
 		}
 		return h(ctx, platform, labelChanges...)
 	}
 }
 
-func generateCredentials(ctx context.Context, platform *types.Platform) error {
+// generateCredentials sets new credentials of which only the password hash is stored and returns the generated password
+func generateCredentials(ctx context.Context, platform *types.Platform) (string, error) {
 	credentials, err := types.GenerateCredentials()
 	if err != nil {
 		log.C(ctx).Error("could not generate credentials for platform")
-		return err
+		return "", err
 	}
+	password := credentials.Basic.Password
 	platform.Credentials = credentials
-	return nil
+	if err := platform.HashCredentials(); err != nil {
+		log.C(ctx).Error("could not hash credentials for platform")
+		return "", err
+	}
+	return password, nil
+}
+
+// withPassword sets the generated password in the stored platform so that it is returned to the client once
+func withPassword(obj types.Object, password string) types.Object {
+	platform, ok := obj.(*types.Platform)
+	if ok && platform.Credentials != nil && platform.Credentials.Basic != nil {
+		platform.Credentials.Basic.Password = password
+	}
+	return obj
 }
@@ -177,6 +177,14 @@ func (i *ServiceBindingInterceptor) AroundTxCreate(f storage.InterceptCreateArou
 			return binding, nil
 		}
 
+		// a retried binding is already stored
+		store := f
+		if operation.IsContinuation() {
+			store = func(ctx context.Context, obj types.Object) (types.Object, error) {
+				return i.repository.RawRepository.Update(ctx, obj, types.LabelChanges{})
+			}
+		}
+
 		var bindResponse *osbc.BindResponse
 		if !operation.Reschedule {
 			operation.Context.ServiceInstanceID = binding.ServiceInstanceID
@@ -213,7 +221,7 @@ func (i *ServiceBindingInterceptor) AroundTxCreate(f storage.InterceptCreateArou
 					binding.Context = instanceContext
 				}
 				if operation.IsAsyncResponse() {
-					_, err := f(ctx, obj)
+					_, err := store(ctx, obj)
 					if err != nil {
 						return nil, err
 					}
@@ -263,7 +271,7 @@ func (i *ServiceBindingInterceptor) AroundTxCreate(f storage.InterceptCreateArou
 				return nil, err
 			}
 
-			object, err := f(ctx, obj)
+			object, err := store(ctx, obj)
 			if err != nil {
 				return nil, err
 			}
@@ -593,6 +601,9 @@ func (i *ServiceBindingInterceptor) pollServiceBinding(ctx context.Context, osbC
 		case <-maxPollingDurationTicker.C:
 			return i.processMaxPollingDurationElapsed(ctx, binding, instance, plan, operation, enableOrphanMitigation)
 		case <-ticker.C:
+			if err := checkOperationCancelled(ctx, i.repository, operation); err != nil {
+				return err
+			}
 			log.C(ctx).Infof("Sending poll last operation request %s for binding with id %s and name %s",
 				logPollBindingRequest(pollingRequest), binding.ID, binding.Name)
 			pollingResponse, err := osbClient.PollBindingLastOperation(pollingRequest)
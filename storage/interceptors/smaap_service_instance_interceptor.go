@@ -26,6 +26,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"reflect"
 	"time"
 
 	"github.com/tidwall/sjson"
@@ -172,6 +173,19 @@ func (i *ServiceInstanceInterceptor) AroundTxCreate(f storage.InterceptCreateAro
 			return instance, nil
 		}
 
+		if plan.RequiresApproval() && !operation.Context.Approved {
+			log.C(ctx).Infof("Plan %s requires approval, instance %s will be provisioned after it is approved", plan.ID, instance.ID)
+			return i.awaitApproval(ctx, f, instance, operation)
+		}
+
+		// an approved or retried instance is already stored
+		store := f
+		if operation.IsContinuation() {
+			store = func(ctx context.Context, obj types.Object) (types.Object, error) {
+				return i.repository.RawRepository.Update(ctx, obj, types.LabelChanges{})
+			}
+		}
+
 		var provisionResponse *osbc.ProvisionResponse
 		if !operation.Reschedule {
 			operation.Context.ServicePlanID = instance.ServicePlanID
@@ -200,7 +214,7 @@ func (i *ServiceInstanceInterceptor) AroundTxCreate(f storage.InterceptCreateAro
 
 				//save the instance in case of an async client call
 				if operation.IsAsyncResponse() {
-					_, err := f(ctx, obj)
+					_, err := store(ctx, obj)
 					if err != nil {
 						return nil, err
 					}
@@ -236,7 +250,7 @@ func (i *ServiceInstanceInterceptor) AroundTxCreate(f storage.InterceptCreateAro
 
 			}
 
-			object, err := f(ctx, obj)
+			object, err := store(ctx, obj)
 			if err != nil {
 				return nil, err
 			}
@@ -253,6 +267,22 @@ func (i *ServiceInstanceInterceptor) AroundTxCreate(f storage.InterceptCreateAro
 	}
 }
 
+// awaitApproval stores the instance without provisioning it and keeps its operation awaiting approval
+func (i *ServiceInstanceInterceptor) awaitApproval(ctx context.Context, f storage.InterceptCreateAroundTxFunc, instance *types.ServiceInstance, operation *types.Operation) (types.Object, error) {
+	object, err := f(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	operation.Context.ServicePlanID = instance.ServicePlanID
+	operation.State = types.AWAITING_APPROVAL
+	if _, err := i.repository.Update(ctx, operation, types.LabelChanges{}); err != nil {
+		return nil, fmt.Errorf("failed to update operation with id %s to await approval: %s", operation.ID, err)
+	}
+
+	return object, nil
+}
+
 func isOperatedBySmaaP(instance *types.ServiceInstance) bool {
 	return instance.Labels != nil && len(instance.Labels[OperatedByLabelKey]) > 0
 }
@@ -280,6 +310,17 @@ func (i *ServiceInstanceInterceptor) AroundTxUpdate(f storage.InterceptUpdateAro
 			return UpdatedObject, err
 		}
 
+		if !operation.Reschedule {
+			leaseRenewal, err := i.isLeaseRenewal(ctx, updatedInstance)
+			if err != nil {
+				return nil, err
+			}
+			if leaseRenewal {
+				log.C(ctx).Infof("Renewing the lease of instance %s, skipping the broker.", updatedInstance.ID)
+				return f(ctx, updatedObj, labelChanges...)
+			}
+		}
+
 		osbClient, broker, service, plan, err := preparePrerequisites(ctx, i.repository, i.osbClientCreateFunc, updatedInstance)
 		if err != nil {
 			return nil, err
@@ -402,6 +443,22 @@ func (i *ServiceInstanceInterceptor) AroundTxUpdate(f storage.InterceptUpdateAro
 	}
 }
 
+// isLeaseRenewal determines whether the expiry of the instance is the only property changed by the update,
+// which does not concern the broker
+func (i *ServiceInstanceInterceptor) isLeaseRenewal(ctx context.Context, updatedInstance *types.ServiceInstance) (bool, error) {
+	instanceObj, err := i.repository.Get(ctx, types.ServiceInstanceType, query.ByField(query.EqualsOperator, "id", updatedInstance.ID))
+	if err != nil {
+		return false, util.HandleStorageError(err, types.ServiceInstanceType.String())
+	}
+	instance := instanceObj.(*types.ServiceInstance)
+	return !sameExpiry(instance.ExpiresAt, updatedInstance.ExpiresAt) &&
+		len(updatedInstance.Parameters) == 0 &&
+		instance.Name == updatedInstance.Name &&
+		instance.ServicePlanID == updatedInstance.ServicePlanID &&
+		reflect.DeepEqual(instance.MaintenanceInfo, updatedInstance.MaintenanceInfo) &&
+		reflect.DeepEqual(instance.Context, updatedInstance.Context), nil
+}
+
 func (i *ServiceInstanceInterceptor) AroundTxDelete(f storage.InterceptDeleteAroundTxFunc) storage.InterceptDeleteAroundTxFunc {
 	return func(ctx context.Context, deletionCriteria ...query.Criterion) error {
 		instances, err := i.repository.List(ctx, types.ServiceInstanceType, deletionCriteria...)
@@ -599,6 +656,9 @@ func (i *ServiceInstanceInterceptor) pollServiceInstance(ctx context.Context, os
 		case <-maxPollingDurationTicker.C:
 			return i.processMaxPollingDurationElapsed(ctx, instance, plan, operation, enableOrphanMitigation)
 		case <-ticker.C:
+			if err := checkOperationCancelled(ctx, i.repository, operation); err != nil {
+				return err
+			}
 			log.C(ctx).Infof("Sending poll last operation request %s for instance with id %s and name %s", logPollInstanceRequest(pollingRequest), instance.ID, instance.Name)
 			pollingResponse, err := osbClient.PollLastOperation(pollingRequest)
 			if err != nil {
@@ -679,6 +739,30 @@ func isUnreachableBroker(err error) bool {
 	return (httpError.StatusCode == http.StatusServiceUnavailable || httpError.StatusCode == http.StatusNotFound)
 }
 
+// checkOperationCancelled stops the polling of an operation which was cancelled since it was scheduled.
+// Orphan mitigation of a cancelled operation is not stopped.
+func checkOperationCancelled(ctx context.Context, repository storage.Repository, operation *types.Operation) error {
+	if operation.InOrphanMitigationState() {
+		return nil
+	}
+	object, err := repository.Get(ctx, types.OperationType, query.ByField(query.EqualsOperator, "id", operation.ID))
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return nil
+		}
+		return fmt.Errorf("could not check whether operation with id %s was cancelled: %s", operation.ID, err)
+	}
+	if object.(*types.Operation).IsCancelled() {
+		log.C(ctx).Infof("Terminating poll last operation for %s with id %s as operation with id %s was cancelled", operation.ResourceType, operation.ResourceID, operation.ID)
+		return &util.HTTPError{
+			ErrorType:   "OperationCancelled",
+			Description: fmt.Sprintf("operation with id %s was cancelled", operation.ID),
+			StatusCode:  http.StatusConflict,
+		}
+	}
+	return nil
+}
+
 func shouldStartPolling(operation *types.Operation) bool {
 
 	// In case the operation not rescheduled, don't start polling
@@ -74,6 +74,10 @@ func (c *uniqueBindingNameInterceptor) checkUniqueName(ctx context.Context, bind
 		log.C(ctx).Info("skipping unique check of binding name as this is a rescheduled operation")
 		return nil
 	}
+	if operationFound && operation.IsContinuation() {
+		log.C(ctx).Info("skipping unique check of binding name as it was checked when the operation was first executed")
+		return nil
+	}
 
 	countCriteria := []query.Criterion{
 		query.ByField(query.EqualsOperator, "service_instance_id", binding.ServiceInstanceID),
@@ -116,6 +116,10 @@ func (c *uniqueInstanceNameInterceptor) checkUniqueName(ctx context.Context, ins
 		}
 		return nil
 	}
+	if operationFound && operation.IsContinuation() {
+		log.C(ctx).Info("skipping unique check of instance name as it was checked when the operation was first executed")
+		return nil
+	}
 	countCriteria := []query.Criterion{
 		query.ByField(query.EqualsOperator, "platform_id", types.SMPlatform),
 		query.ByField(query.EqualsOperator, nameProperty, instance.Name),
@@ -3,6 +3,9 @@ package interceptors
 import (
 	"context"
 	"fmt"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
 
 	"github.com/Peripli/service-manager/pkg/query"
 
@@ -12,8 +15,15 @@ import (
 
 func NewVisibilityNotificationsInterceptor() *NotificationsInterceptor {
 	return &NotificationsInterceptor{
-		PlatformIDsProviderFunc: func(ctx context.Context, obj types.Object, _ storage.Repository) ([]string, error) {
-			platformID := obj.(*types.Visibility).PlatformID
+		PlatformIDsProviderFunc: func(ctx context.Context, obj types.Object, repository storage.Repository) ([]string, error) {
+			visibility := obj.(*types.Visibility)
+			if visibility.Inactive {
+				return []string{}, nil
+			}
+			if visibility.PlatformSelector != "" {
+				return selectedPlatformIDs(ctx, visibility, repository)
+			}
+			platformID := visibility.PlatformID
 			platformIDS := make([]string, 0)
 			if platformID != types.SMPlatform {
 				platformIDS = append(platformIDS, platformID)
@@ -68,6 +78,24 @@ func NewVisibilityNotificationsInterceptor() *NotificationsInterceptor {
 	}
 }
 
+// selectedPlatformIDs returns the IDs of the platforms whose labels match the platform selector of the visibility
+func selectedPlatformIDs(ctx context.Context, visibility *types.Visibility, repository storage.Repository) ([]string, error) {
+	platforms, err := repository.List(ctx, types.PlatformType,
+		query.ByField(query.NotEqualsOperator, "id", types.SMPlatform),
+		query.ByField(query.NotEqualsOperator, "technical", "true"))
+	if err != nil {
+		return nil, err
+	}
+	platformIDs := make([]string, 0)
+	for i := 0; i < platforms.Len(); i++ {
+		platform := platforms.ItemAt(i)
+		if storage.VisibilityMatchesPlatform(ctx, visibility, platform.GetLabels()) {
+			platformIDs = append(platformIDs, platform.GetID())
+		}
+	}
+	return platformIDs, nil
+}
+
 func fetchVisibilityPlans(ctx context.Context, repository storage.Repository, visibilities []*types.Visibility) (map[string]*types.ServicePlan, error) {
 	planSet := make(map[string]bool, len(visibilities))
 	for _, vis := range visibilities {
@@ -160,6 +188,30 @@ func (va VisibilityAdditional) Validate() error {
 	return va.ServicePlan.Validate()
 }
 
+// visibilityWindowInterceptor marks the visibilities outside of their validity window as inactive before the
+// notifications for them are created, so that the platforms are notified only about the visibilities in effect
+type visibilityWindowInterceptor struct {
+	*NotificationsInterceptor
+}
+
+func (vi *visibilityWindowInterceptor) OnTxCreate(h storage.InterceptCreateOnTxFunc) storage.InterceptCreateOnTxFunc {
+	create := vi.NotificationsInterceptor.OnTxCreate(h)
+	return func(ctx context.Context, repository storage.Repository, obj types.Object) (types.Object, error) {
+		visibility := obj.(*types.Visibility)
+		visibility.Inactive = !visibility.IsActive(time.Now())
+		return create(ctx, repository, obj)
+	}
+}
+
+func (vi *visibilityWindowInterceptor) OnTxUpdate(h storage.InterceptUpdateOnTxFunc) storage.InterceptUpdateOnTxFunc {
+	update := vi.NotificationsInterceptor.OnTxUpdate(h)
+	return func(ctx context.Context, repository storage.Repository, oldObject, newObject types.Object, labelChanges ...*types.LabelChange) (types.Object, error) {
+		visibility := newObject.(*types.Visibility)
+		visibility.Inactive = !visibility.IsActive(time.Now())
+		return update(ctx, repository, oldObject, newObject, labelChanges...)
+	}
+}
+
 type VisibilityCreateNotificationsInterceptorProvider struct {
 }
 
@@ -168,7 +220,7 @@ func (*VisibilityCreateNotificationsInterceptorProvider) Name() string {
 }
 
 func (*VisibilityCreateNotificationsInterceptorProvider) Provide() storage.CreateOnTxInterceptor {
-	return NewVisibilityNotificationsInterceptor()
+	return &visibilityWindowInterceptor{NotificationsInterceptor: NewVisibilityNotificationsInterceptor()}
 }
 
 type VisibilityUpdateNotificationsInterceptorProvider struct {
@@ -179,7 +231,7 @@ func (*VisibilityUpdateNotificationsInterceptorProvider) Name() string {
 }
 
 func (*VisibilityUpdateNotificationsInterceptorProvider) Provide() storage.UpdateOnTxInterceptor {
-	return NewVisibilityNotificationsInterceptor()
+	return &visibilityWindowInterceptor{NotificationsInterceptor: NewVisibilityNotificationsInterceptor()}
 }
 
 type VisibilityDeleteNotificationsInterceptorProvider struct {
@@ -192,3 +244,101 @@ func (*VisibilityDeleteNotificationsInterceptorProvider) Name() string {
 func (*VisibilityDeleteNotificationsInterceptorProvider) Provide() storage.DeleteOnTxInterceptor {
 	return NewVisibilityNotificationsInterceptor()
 }
+
+// PlatformVisibilityNotificationsInterceptor creates visibility notifications for a platform whose labels start or stop
+// matching the platform selector of visibilities
+type PlatformVisibilityNotificationsInterceptor struct {
+}
+
+func (*PlatformVisibilityNotificationsInterceptor) OnTxUpdate(h storage.InterceptUpdateOnTxFunc) storage.InterceptUpdateOnTxFunc {
+	return func(ctx context.Context, repository storage.Repository, oldObject, newObject types.Object, labelChanges ...*types.LabelChange) (types.Object, error) {
+		updatedObject, err := h(ctx, repository, oldObject, newObject, labelChanges...)
+		if err != nil {
+			return nil, err
+		}
+
+		platform := updatedObject.(*types.Platform)
+		if len(labelChanges) == 0 || platform.ID == types.SMPlatform || platform.Technical {
+			return updatedObject, nil
+		}
+
+		visibilities, err := repository.List(ctx, types.VisibilityType, query.ByField(query.NotEqualsOperator, "platform_selector", ""))
+		if err != nil {
+			return nil, err
+		}
+		if visibilities.Len() == 0 {
+			return updatedObject, nil
+		}
+
+		oldLabels := copyLabels(oldObject.GetLabels())
+		newLabels, _, _ := query.ApplyLabelChangesToLabels(labelChanges, copyLabels(oldLabels))
+
+		started := make([]types.Object, 0)
+		stopped := make([]types.Object, 0)
+		for i := 0; i < visibilities.Len(); i++ {
+			visibility := visibilities.ItemAt(i).(*types.Visibility)
+			if !visibility.GetReady() || visibility.Inactive {
+				continue
+			}
+			wasMatching := storage.VisibilityMatchesPlatform(ctx, visibility, oldLabels)
+			isMatching := storage.VisibilityMatchesPlatform(ctx, visibility, newLabels)
+			if !wasMatching && isMatching {
+				started = append(started, visibility)
+			} else if wasMatching && !isMatching {
+				stopped = append(stopped, visibility)
+			}
+		}
+		if len(started) == 0 && len(stopped) == 0 {
+			return updatedObject, nil
+		}
+
+		details, err := NewVisibilityNotificationsInterceptor().AdditionalDetailsFunc(ctx, types.NewObjectArray(append(started, stopped...)...), repository)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, visibility := range started {
+			log.C(ctx).Infof("Platform with id %s started matching visibility with id %s. New notification of type %s will be created", platform.ID, visibility.GetID(), types.CREATED)
+			if err := CreateNotification(ctx, repository, types.CREATED, types.VisibilityType, platform.ID, &Payload{
+				New: &ObjectPayload{
+					Resource:   visibility,
+					Additional: details[visibility.GetID()],
+				},
+			}); err != nil {
+				return nil, err
+			}
+		}
+		for _, visibility := range stopped {
+			log.C(ctx).Infof("Platform with id %s stopped matching visibility with id %s. New notification of type %s will be created", platform.ID, visibility.GetID(), types.DELETED)
+			if err := CreateNotification(ctx, repository, types.DELETED, types.VisibilityType, platform.ID, &Payload{
+				Old: &ObjectPayload{
+					Resource:   visibility,
+					Additional: details[visibility.GetID()],
+				},
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		return updatedObject, nil
+	}
+}
+
+func copyLabels(labels types.Labels) types.Labels {
+	result := make(types.Labels, len(labels))
+	for key, values := range labels {
+		result[key] = append([]string{}, values...)
+	}
+	return result
+}
+
+type PlatformVisibilityNotificationsInterceptorProvider struct {
+}
+
+func (*PlatformVisibilityNotificationsInterceptorProvider) Name() string {
+	return "PlatformVisibilityNotificationsInterceptorProvider"
+}
+
+func (*PlatformVisibilityNotificationsInterceptorProvider) Provide() storage.UpdateOnTxInterceptor {
+	return &PlatformVisibilityNotificationsInterceptor{}
+}
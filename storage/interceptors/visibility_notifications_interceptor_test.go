@@ -0,0 +1,125 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package interceptors_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/interceptors"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Visibility notifications interceptor", func() {
+	var (
+		ctx        context.Context
+		repository *storagefakes.FakeStorage
+	)
+
+	newVisibility := func(validFrom, validUntil time.Duration, inactive bool) *types.Visibility {
+		from := time.Now().Add(validFrom)
+		until := time.Now().Add(validUntil)
+		return &types.Visibility{
+			Base:          types.Base{ID: "visibility-id", Ready: true},
+			PlatformID:    "platform-id",
+			ServicePlanID: "plan-id",
+			ValidFrom:     &from,
+			ValidUntil:    &until,
+			Inactive:      inactive,
+		}
+	}
+
+	notificationTypes := func() []types.NotificationOperation {
+		var result []types.NotificationOperation
+		for i := 0; i < repository.CreateCallCount(); i++ {
+			_, object := repository.CreateArgsForCall(i)
+			notification := object.(*types.Notification)
+			Expect(notification.Resource).To(Equal(types.VisibilityType))
+			Expect(notification.PlatformID).To(Equal("platform-id"))
+			result = append(result, notification.Type)
+		}
+		return result
+	}
+
+	update := func(visibility *types.Visibility) *types.Visibility {
+		stored := *visibility
+		interceptor := (&interceptors.VisibilityUpdateNotificationsInterceptorProvider{}).Provide()
+		updated, err := interceptor.OnTxUpdate(func(ctx context.Context, txStorage storage.Repository, oldObj, newObj types.Object, labelChanges ...*types.LabelChange) (types.Object, error) {
+			return newObj, nil
+		})(ctx, repository, &stored, visibility)
+		Expect(err).ToNot(HaveOccurred())
+		return updated.(*types.Visibility)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		repository = &storagefakes.FakeStorage{}
+		repository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			switch objectType {
+			case types.ServicePlanType:
+				return &types.ServicePlans{ServicePlans: []*types.ServicePlan{
+					{Base: types.Base{ID: "plan-id"}, ServiceOfferingID: "offering-id"},
+				}}, nil
+			case types.ServiceOfferingType:
+				return &types.ServiceOfferings{ServiceOfferings: []*types.ServiceOffering{
+					{Base: types.Base{ID: "offering-id"}, BrokerID: "broker-id"},
+				}}, nil
+			case types.ServiceBrokerType:
+				return &types.ServiceBrokers{ServiceBrokers: []*types.ServiceBroker{
+					{Base: types.Base{ID: "broker-id"}, Name: "broker"},
+				}}, nil
+			}
+			return types.NewObjectArray(), nil
+		})
+		repository.CreateCalls(func(ctx context.Context, object types.Object) (types.Object, error) {
+			return object, nil
+		})
+	})
+
+	Context("when the validity window of an inactive visibility opened", func() {
+		It("activates the visibility and notifies the platform as if it was created", func() {
+			updated := update(newVisibility(-time.Minute, time.Hour, true))
+
+			Expect(updated.Inactive).To(BeFalse())
+			Expect(notificationTypes()).To(Equal([]types.NotificationOperation{types.CREATED, types.MODIFIED}))
+		})
+	})
+
+	Context("when the validity window of an active visibility closed", func() {
+		It("deactivates the visibility and notifies the platform as if it was deleted", func() {
+			updated := update(newVisibility(-time.Hour, -time.Minute, false))
+
+			Expect(updated.Inactive).To(BeTrue())
+			Expect(notificationTypes()).To(Equal([]types.NotificationOperation{types.DELETED}))
+		})
+	})
+
+	Context("when the validity window of an inactive visibility has not opened yet", func() {
+		It("keeps the visibility inactive without notifying the platform", func() {
+			updated := update(newVisibility(time.Minute, time.Hour, true))
+
+			Expect(updated.Inactive).To(BeTrue())
+			Expect(notificationTypes()).To(BeEmpty())
+		})
+	})
+})
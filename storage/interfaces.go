@@ -58,9 +58,10 @@ var (
 
 // Settings type to be loaded from the environment
 type Settings struct {
-	URI                string                `mapstructure:"uri" description:"URI of the storage"`
+	URI                string                `mapstructure:"uri" description:"URI of the storage" sensitive:"true"`
 	MigrationsURL      string                `mapstructure:"migrations_url" description:"location of a directory containing sql migrations scripts"`
-	EncryptionKey      string                `mapstructure:"encryption_key" description:"key to use for encrypting database entries"`
+	AutoMigrate        bool                  `mapstructure:"auto_migrate" description:"whether to apply pending schema migrations on startup. If disabled, startup fails when the schema is not at the latest migration version"`
+	EncryptionKey      string                `mapstructure:"encryption_key" description:"key to use for encrypting database entries" sensitive:"true"`
 	SkipSSLValidation  bool                  `mapstructure:"skip_ssl_validation" description:"whether to skip ssl verification when connecting to the storage"`
 	SSLMode            string                `mapstructure:"sslmode" description:"defines ssl mode type"`
 	SSLRootCert        string                `mapstructure:"sslrootcert" description:"The location of the root certificate file."`
@@ -69,6 +70,10 @@ type Settings struct {
 	ReadTimeout        int                   `mapstructure:"read_timeout" description:"sets the limit for reading in milliseconds"`
 	WriteTimeout       int                   `mapstructure:"write_timeout" description:"sets the limit for writing in milliseconds"`
 	Notification       *NotificationSettings `mapstructure:"notification"`
+	ReadReplicas       *ReadReplicaSettings  `mapstructure:"read_replica"`
+	ReadReplicaURIs    []string              `mapstructure:"read_replica_uris" description:"URIs of read-only replicas used for queries of API requests executed outside of transactions and before any write of the request" sensitive:"true"`
+	IntegrityKey       string                `mapstructure:"integrity_key" description:"key used to sign the integrity of credentials with HMAC-SHA256. It should not be stored in the database. If empty, a plain SHA-256 hash is used" sensitive:"true"`
+	IntegrityLegacy    bool                  `mapstructure:"integrity_legacy" description:"whether plain SHA-256 hashes are still accepted when an integrity key is set. Enable it until all existing rows are re-signed with the integrity upgrade command"`
 	IntegrityProcessor security.IntegrityProcessor
 }
 
@@ -77,6 +82,7 @@ func DefaultSettings() *Settings {
 	return &Settings{
 		URI:                "",
 		MigrationsURL:      fmt.Sprintf("file://%s/postgres/migrations", basepath),
+		AutoMigrate:        true,
 		EncryptionKey:      "",
 		SkipSSLValidation:  false,
 		MaxIdleConnections: 5,
@@ -84,11 +90,33 @@ func DefaultSettings() *Settings {
 		ReadTimeout:        900000, //15 minutes
 		WriteTimeout:       900000, //15 minutes
 		Notification:       DefaultNotificationSettings(),
-		IntegrityProcessor: &security.HashingIntegrityProcessor{
-			HashingFunc: func(data []byte) []byte {
-				hash := sha256.Sum256(data)
-				return hash[:]
-			},
+		ReadReplicas:       DefaultReadReplicaSettings(),
+		ReadReplicaURIs:    []string{},
+		IntegrityKey:       "",
+		IntegrityLegacy:    false,
+		IntegrityProcessor: hashingIntegrityProcessor(),
+	}
+}
+
+// ConfigureIntegrity replaces the hashing integrity processor with one signing the integrity when an integrity key is set
+func (s *Settings) ConfigureIntegrity() {
+	if len(s.IntegrityKey) == 0 {
+		return
+	}
+	processor := &security.HMACIntegrityProcessor{
+		Key: []byte(s.IntegrityKey),
+	}
+	if s.IntegrityLegacy {
+		processor.Legacy = hashingIntegrityProcessor()
+	}
+	s.IntegrityProcessor = processor
+}
+
+func hashingIntegrityProcessor() security.IntegrityProcessor {
+	return &security.HashingIntegrityProcessor{
+		HashingFunc: func(data []byte) []byte {
+			hash := sha256.Sum256(data)
+			return hash[:]
 		},
 	}
 }
@@ -104,12 +132,50 @@ func (s *Settings) Validate() error {
 	if len(s.EncryptionKey) != 32 {
 		return fmt.Errorf("validate Settings: StorageEncryptionKey must be exactly 32 symbols long but was %d symbols long", len(s.EncryptionKey))
 	}
+	if len(s.IntegrityKey) > 0 && len(s.IntegrityKey) < 32 {
+		return fmt.Errorf("validate Settings: StorageIntegrityKey must be at least 32 symbols long but was %d symbols long", len(s.IntegrityKey))
+	}
 	if s.IntegrityProcessor == nil {
 		return fmt.Errorf("validate Settings: StorageIntegrityProcessor must not be nil")
 	}
+	for _, uri := range s.ReadReplicaURIs {
+		if len(uri) == 0 {
+			return fmt.Errorf("validate Settings: StorageReadReplicaURIs must not contain empty values")
+		}
+	}
+	if len(s.ReadReplicaURIs) > 0 {
+		if err := s.ReadReplicas.Validate(); err != nil {
+			return err
+		}
+	}
 	return s.Notification.Validate()
 }
 
+// ReadReplicaSettings type to be loaded from the environment
+type ReadReplicaSettings struct {
+	MaxStaleness  time.Duration `mapstructure:"max_staleness" description:"maximum replication lag of a replica for it to serve reads"`
+	CheckInterval time.Duration `mapstructure:"check_interval" description:"time between replication lag checks of a replica"`
+}
+
+// DefaultReadReplicaSettings returns default values for read replica settings
+func DefaultReadReplicaSettings() *ReadReplicaSettings {
+	return &ReadReplicaSettings{
+		MaxStaleness:  time.Second * 5,
+		CheckInterval: time.Second * 5,
+	}
+}
+
+// Validate validates the read replica settings
+func (s *ReadReplicaSettings) Validate() error {
+	if s.MaxStaleness <= 0 {
+		return fmt.Errorf("read replica max staleness (%s) should be greater than 0", s.MaxStaleness)
+	}
+	if s.CheckInterval <= 0 {
+		return fmt.Errorf("read replica check interval (%s) should be greater than 0", s.CheckInterval)
+	}
+	return nil
+}
+
 // NotificationSettings type to be loaded from the environment
 type NotificationSettings struct {
 	QueuesSize           int           `mapstructure:"queues_size" description:"maximum number of notifications queued for sending to a client"`
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// CheckLabelPolicy returns an error if the labels of an object which is about to be created violate the label policy
+// of its type. The repository has to be the repository of the transaction in which the object is created.
+func CheckLabelPolicy(ctx context.Context, repository Repository, obj types.Object) error {
+	policy, err := labelPolicy(ctx, repository, obj.GetType())
+	if err != nil || policy == nil {
+		return err
+	}
+	if err := policy.CheckLabels(obj.GetLabels()); err != nil {
+		return labelPolicyViolation(err)
+	}
+	return nil
+}
+
+// CheckLabelPolicyChanges returns an error if the label changes of an object which is about to be updated violate the
+// label policy of its type. The object is locked until the end of the transaction of the repository, so that
+// concurrent label changes are checked against the labels resulting from each other.
+func CheckLabelPolicyChanges(ctx context.Context, repository Repository, objectType types.ObjectType, id string, labelChanges types.LabelChanges) error {
+	if len(labelChanges) == 0 {
+		return nil
+	}
+	policy, err := labelPolicy(ctx, repository, objectType)
+	if err != nil || policy == nil {
+		return err
+	}
+	obj, err := repository.GetForUpdate(ctx, objectType, query.ByField(query.EqualsOperator, "id", id))
+	if err != nil {
+		return err
+	}
+	oldLabels := obj.GetLabels()
+	labels, _, _ := query.ApplyLabelChangesToLabels(labelChanges, oldLabels)
+	if err := policy.CheckLabelChanges(oldLabels, labels, labelChanges); err != nil {
+		return labelPolicyViolation(err)
+	}
+	return nil
+}
+
+// labelPolicy returns the label policy for the object type, or nil if there is none
+func labelPolicy(ctx context.Context, repository Repository, objectType types.ObjectType) (*types.LabelPolicy, error) {
+	policies, err := repository.List(ctx, types.LabelPolicyType,
+		query.ByField(query.EqualsOperator, "resource_type", path.Base(string(objectType))))
+	if err != nil {
+		return nil, err
+	}
+	if policies.Len() == 0 {
+		return nil, nil
+	}
+	return policies.ItemAt(0).(*types.LabelPolicy), nil
+}
+
+func labelPolicyViolation(err error) error {
+	return &util.HTTPError{
+		ErrorType:   "BadRequest",
+		Description: fmt.Sprintf("labels violate the label policy: %s", err),
+		StatusCode:  http.StatusBadRequest,
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage_test
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Label policies", func() {
+	var (
+		ctx        context.Context
+		repository *storagefakes.FakeStorage
+		policies   *types.LabelPolicies
+		instance   *types.ServiceInstance
+	)
+
+	expectViolation := func(err error) {
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		policies = &types.LabelPolicies{LabelPolicies: []*types.LabelPolicy{{
+			ResourceType: "service_instances",
+			MaxLabels:    3,
+			Keys: []*types.LabelKeyPolicy{
+				{Key: "env", Required: true, AllowedValues: []string{"dev", "prod"}, SingleValued: true},
+				{Key: "cost_center", Pattern: "[0-9]{4}", Immutable: true},
+			},
+		}}}
+		instance = &types.ServiceInstance{Base: types.Base{
+			ID:     "instance-id",
+			Labels: types.Labels{"env": {"dev"}, "cost_center": {"1234"}},
+		}}
+
+		repository = &storagefakes.FakeStorage{}
+		repository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			Expect(criteria).To(ConsistOf(query.ByField(query.EqualsOperator, "resource_type", "service_instances")))
+			return policies, nil
+		})
+		repository.GetForUpdateCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.Object, error) {
+			return instance, nil
+		})
+	})
+
+	Describe("CheckLabelPolicy", func() {
+		check := func(labels types.Labels) error {
+			return storage.CheckLabelPolicy(ctx, repository, &types.ServiceInstance{Base: types.Base{Labels: labels}})
+		}
+
+		It("allows labels complying with the policy", func() {
+			Expect(check(types.Labels{"env": {"prod"}, "cost_center": {"0042"}})).To(Succeed())
+		})
+
+		It("rejects missing required labels", func() {
+			expectViolation(check(types.Labels{"cost_center": {"0042"}}))
+		})
+
+		It("rejects values which are not allowed", func() {
+			expectViolation(check(types.Labels{"env": {"test"}}))
+		})
+
+		It("rejects values which do not match the pattern", func() {
+			expectViolation(check(types.Labels{"env": {"dev"}, "cost_center": {"12345"}}))
+		})
+
+		It("rejects multiple values of single-valued labels", func() {
+			expectViolation(check(types.Labels{"env": {"dev", "prod"}}))
+		})
+
+		It("rejects more labels than allowed", func() {
+			expectViolation(check(types.Labels{"env": {"dev"}, "a": {"a"}, "b": {"b"}, "c": {"c"}}))
+		})
+
+		It("allows any labels if there is no policy for the type", func() {
+			policies.LabelPolicies = nil
+			Expect(check(types.Labels{})).To(Succeed())
+		})
+	})
+
+	Describe("CheckLabelPolicyChanges", func() {
+		check := func(labelChanges ...*types.LabelChange) error {
+			return storage.CheckLabelPolicyChanges(ctx, repository, types.ServiceInstanceType, instance.ID, labelChanges)
+		}
+
+		It("allows label changes complying with the policy", func() {
+			Expect(check(&types.LabelChange{Operation: types.AddLabelOperation, Key: "team", Values: []string{"a"}})).To(Succeed())
+		})
+
+		It("checks the changes against the locked current labels", func() {
+			Expect(check(&types.LabelChange{Operation: types.AddLabelOperation, Key: "team", Values: []string{"a"}})).To(Succeed())
+			Expect(repository.GetForUpdateCallCount()).To(Equal(1))
+		})
+
+		It("rejects changes of immutable labels", func() {
+			expectViolation(check(&types.LabelChange{Operation: types.RemoveLabelOperation, Key: "cost_center"}))
+		})
+
+		It("rejects removing required labels", func() {
+			expectViolation(check(&types.LabelChange{Operation: types.RemoveLabelOperation, Key: "env"}))
+		})
+
+		It("rejects adding a second value to single-valued labels", func() {
+			expectViolation(check(&types.LabelChange{Operation: types.AddLabelValuesOperation, Key: "env", Values: []string{"prod"}}))
+		})
+
+		It("rejects exceeding the max labels with the labels added concurrently", func() {
+			instance.Labels["team"] = []string{"a"}
+			expectViolation(check(&types.LabelChange{Operation: types.AddLabelOperation, Key: "owner", Values: []string{"b"}}))
+		})
+
+		It("does not check unchanged labels", func() {
+			instance.Labels = types.Labels{"env": {"legacy"}}
+			Expect(check(&types.LabelChange{Operation: types.AddLabelOperation, Key: "team", Values: []string{"a"}})).To(Succeed())
+		})
+
+		It("does not read the policies without label changes", func() {
+			Expect(check()).To(Succeed())
+			Expect(repository.ListCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -11,6 +11,9 @@ const (
 	QueryForVisibilityWithPlatformAndPlan
 	QueryForPlanByNameAndOfferingsWithVisibility
 	QueryForSharedInstances
+	QueryForTenantQuotasForUpdate
+	QueryForOperationStateTransition
+	QueryForPlatformFailedLoginAttempt
 )
 
 var namedQueries = map[NamedQuery]string{
@@ -58,19 +61,20 @@ var namedQueries = map[NamedQuery]string{
 		 ON {{.ENTITY_TABLE}}.paging_sequence = LAST_OPERATIONS.paging_sequence`,
 	QueryForLabelLessVisibilities: `
 	SELECT v.* FROM visibilities v
-	WHERE (v.platform_id in (:platform_ids) OR v.platform_id IS NULL) AND
+	WHERE (v.platform_id in (:platform_ids) OR v.platform_id IS NULL) AND v.platform_selector IS NULL AND
 	NOT EXISTS(SELECT vl.id FROM visibility_labels vl WHERE vl.visibility_id = v.id)`,
 	QueryForLabelLessPlanVisibilities: `
 	SELECT v.* FROM visibilities v
-	WHERE (v.service_plan_id in (:service_plan_ids)) AND
+	WHERE (v.service_plan_id in (:service_plan_ids)) AND v.platform_selector IS NULL AND
 	NOT EXISTS(SELECT vl.id FROM visibility_labels vl WHERE vl.visibility_id = v.id)`,
 	QueryForVisibilityWithPlatformAndPlan: `
 	SELECT v.*
 	FROM visibilities v
 	WHERE v.service_plan_id = :service_plan_id
-	AND (v.platform_id IS NULL
+	AND (v.valid_from IS NULL OR v.valid_from <= now()) AND (v.valid_until IS NULL OR v.valid_until > now())
+	AND ((v.platform_id IS NULL AND v.platform_selector IS NULL)
 		OR (v.platform_id = :platform_id AND (:key = '' IS TRUE OR NOT EXISTS(SELECT vl.id FROM visibility_labels vl WHERE vl.visibility_id = v.id)))
-		OR EXISTS(SELECT vl.id FROM visibility_labels vl WHERE vl.visibility_id = v.id AND vl.key = :key AND vl.val = :val))`,
+		OR (v.platform_selector IS NULL AND EXISTS(SELECT vl.id FROM visibility_labels vl WHERE vl.visibility_id = v.id AND vl.key = :key AND vl.val = :val)))`,
 	QueryForPlanByNameAndOfferingsWithVisibility: `
 	SELECT sp.* 
 	FROM service_plans sp
@@ -80,10 +84,11 @@ var namedQueries = map[NamedQuery]string{
 			ON sp.id = v.service_plan_id
 		 LEFT OUTER JOIN visibility_labels vl 
 			ON v.id = vl.visibility_id
-	WHERE (vl.key = :key AND vl.val = :val AND v.platform_id = :platform_id AND sp.catalog_name = :service_plan_name AND so.catalog_name = :service_offering_name)
-	OR ((v.platform_id = :platform_id OR v.platform_id IS NULL) 
+	WHERE (v.valid_from IS NULL OR v.valid_from <= now()) AND (v.valid_until IS NULL OR v.valid_until > now())
+	AND ((vl.key = :key AND vl.val = :val AND v.platform_id = :platform_id AND sp.catalog_name = :service_plan_name AND so.catalog_name = :service_offering_name)
+	OR ((v.platform_id = :platform_id OR (v.platform_id IS NULL AND v.platform_selector IS NULL))
 		AND NOT EXISTS(SELECT vl.visibility_id FROM visibility_labels vl WHERE vl.visibility_id = v.id) 
-		AND sp.catalog_name = :service_plan_name AND so.catalog_name = :service_offering_name)`,
+		AND sp.catalog_name = :service_plan_name AND so.catalog_name = :service_offering_name))`,
 	QueryForSharedInstances: `
 	SELECT service_instances.*,
 	service_instance_labels.id         "service_instance_labels.id",
@@ -101,6 +106,23 @@ var namedQueries = map[NamedQuery]string{
 			WHERE service_instance_labels.key = :tenant_identifier AND service_instance_labels.val = :tenant_id AND
 				  service_instance_labels.service_instance_id = service_instances.id
 		  )`,
+	QueryForTenantQuotasForUpdate: `
+	SELECT q.*
+	FROM quotas q
+	WHERE q.tenant = :tenant
+	ORDER BY q.id
+	FOR UPDATE`,
+	QueryForOperationStateTransition: `
+	UPDATE operations
+	SET state = :new_state, updated_at = CURRENT_TIMESTAMP
+	WHERE id = :id AND state = :state
+	RETURNING *`,
+	QueryForPlatformFailedLoginAttempt: `
+	UPDATE platforms
+	SET failed_login_attempts = CASE WHEN failed_login_attempts + 1 >= :max_failed_attempts THEN 0 ELSE failed_login_attempts + 1 END,
+		locked_until = CASE WHEN failed_login_attempts + 1 >= :max_failed_attempts THEN :locked_until ELSE locked_until END
+	WHERE id = :id
+	RETURNING *`,
 }
 
 func GetNamedQuery(query NamedQuery) string {
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	sqlxtypes "github.com/jmoiron/sqlx/types"
@@ -114,6 +115,30 @@ func update(ctx context.Context, db namedExecerContext, table string, dto interf
 	return checkRowsAffected(ctx, result)
 }
 
+// updateVersion updates the row only if it was not updated since version
+func updateVersion(ctx context.Context, db pgDB, table string, dto interface{}, version time.Time) error {
+	updateQueryString := updateQuery(table, dto)
+	if updateQueryString == "" {
+		log.C(ctx).Debugf("%s update: Nothing to update", table)
+		return nil
+	}
+	sqlQuery, args, err := sqlx.Named(updateQueryString, dto)
+	if err != nil {
+		return err
+	}
+	sqlQuery = db.Rebind(sqlQuery + " AND updated_at = ?")
+	args = append(args, version)
+	log.C(ctx).Debugf("Executing query %s", sqlQuery)
+	result, err := db.ExecContext(ctx, sqlQuery, args...)
+	if err = checkIntegrityViolation(ctx, checkUniqueViolation(ctx, err)); err != nil {
+		return err
+	}
+	if err = checkRowsAffected(ctx, result); err == util.ErrNotFoundInStorage {
+		return util.ErrConcurrentResourceModification
+	}
+	return err
+}
+
 func isAutoIncrementable(tagValue string) bool {
 	// auto_increment states that the value will be calculated in the DB
 	return strings.Contains(tagValue, "auto_increment")
@@ -251,6 +276,43 @@ func toBoolPointer(nullBool sql.NullBool) *bool {
 	return &nullBool.Bool
 }
 
+func toNullInt64(i *int) sql.NullInt64 {
+	if i == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{
+		Int64: int64(*i),
+		Valid: true,
+	}
+}
+
+func toIntPointer(nullInt sql.NullInt64) *int {
+	if !nullInt.Valid {
+		return nil
+	}
+
+	i := int(nullInt.Int64)
+	return &i
+}
+
+func toNullTime(t *time.Time) pq.NullTime {
+	if t == nil {
+		return pq.NullTime{}
+	}
+	return pq.NullTime{
+		Time:  *t,
+		Valid: true,
+	}
+}
+
+func toTimePointer(nullTime pq.NullTime) *time.Time {
+	if !nullTime.Valid {
+		return nil
+	}
+
+	return &nullTime.Time
+}
+
 func getJSONText(item json.RawMessage) sqlxtypes.JSONText {
 	if len(item) == len("null") && string(item) == "null" {
 		return sqlxtypes.JSONText("{}")
@@ -17,6 +17,12 @@
 package postgres
 
 import (
+	"context"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/jmoiron/sqlx"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -72,4 +78,40 @@ var _ = Describe("Postgres Storage Abstract", func() {
 			})
 		})
 	})
+
+	Describe("updateVersion", func() {
+		type ts struct {
+			ID    string `db:"id"`
+			Field string `db:"field"`
+		}
+
+		var db *sqlx.DB
+		var mock sqlmock.Sqlmock
+		version := time.Date(2026, 10, 18, 12, 0, 0, 123456000, time.UTC)
+
+		BeforeEach(func() {
+			mockdb, m, err := sqlmock.New()
+			Expect(err).ToNot(HaveOccurred())
+			mock = m
+			db = sqlx.NewDb(mockdb, "postgres")
+		})
+
+		AfterEach(func() {
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("updates the row only if it has the expected version", func() {
+			mock.ExpectExec(`UPDATE n/a SET id = \$1, field = \$2 WHERE id = \$3 AND updated_at = \$4`).
+				WithArgs("id", "value", "id", version).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			Expect(updateVersion(context.TODO(), db, "n/a", &ts{ID: "id", Field: "value"}, version)).To(Succeed())
+		})
+
+		It("returns a concurrent modification error if the row has another version", func() {
+			mock.ExpectExec(`UPDATE n/a SET .* AND updated_at = \$4`).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			err := updateVersion(context.TODO(), db, "n/a", &ts{ID: "id", Field: "value"}, version)
+			Expect(err).To(Equal(util.ErrConcurrentResourceModification))
+		})
+	})
 })
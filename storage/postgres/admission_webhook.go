@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+
+	sqlxtypes "github.com/jmoiron/sqlx/types"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// AdmissionWebhook entity
+//go:generate smgen storage AdmissionWebhook github.com/Peripli/service-manager/pkg/types
+type AdmissionWebhook struct {
+	BaseEntity
+
+	Name           string             `db:"name"`
+	Kind           string             `db:"kind"`
+	URL            string             `db:"url"`
+	ResourceTypes  sqlxtypes.JSONText `db:"resource_types"`
+	Operations     sqlxtypes.JSONText `db:"operations"`
+	TimeoutSeconds int                `db:"timeout_seconds"`
+	FailurePolicy  string             `db:"failure_policy"`
+}
+
+func (w *AdmissionWebhook) ToObject() (types.Object, error) {
+	resourceTypes := make([]string, 0)
+	if err := toJsonAsObject(w.ResourceTypes, &resourceTypes); err != nil {
+		return nil, fmt.Errorf("could not unmarshal resource types of admission webhook %s: %s", w.ID, err)
+	}
+	operations := make([]string, 0)
+	if err := toJsonAsObject(w.Operations, &operations); err != nil {
+		return nil, fmt.Errorf("could not unmarshal operations of admission webhook %s: %s", w.ID, err)
+	}
+	return &types.AdmissionWebhook{
+		Base: types.Base{
+			ID:             w.ID,
+			CreatedAt:      w.CreatedAt,
+			UpdatedAt:      w.UpdatedAt,
+			Labels:         make(map[string][]string),
+			PagingSequence: w.PagingSequence,
+			Ready:          w.Ready,
+		},
+		Name:           w.Name,
+		Kind:           w.Kind,
+		URL:            w.URL,
+		ResourceTypes:  resourceTypes,
+		Operations:     operations,
+		TimeoutSeconds: w.TimeoutSeconds,
+		FailurePolicy:  w.FailurePolicy,
+	}, nil
+}
+
+func (*AdmissionWebhook) FromObject(object types.Object) (storage.Entity, error) {
+	webhook, ok := object.(*types.AdmissionWebhook)
+	if !ok {
+		return nil, fmt.Errorf("object is not of type AdmissionWebhook")
+	}
+
+	resourceTypes := webhook.ResourceTypes
+	if resourceTypes == nil {
+		resourceTypes = []string{}
+	}
+	resourceTypesJSON, err := json.Marshal(resourceTypes)
+	if err != nil {
+		return nil, err
+	}
+	operations := webhook.Operations
+	if operations == nil {
+		operations = []string{}
+	}
+	operationsJSON, err := json.Marshal(operations)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AdmissionWebhook{
+		BaseEntity: BaseEntity{
+			ID:             webhook.ID,
+			CreatedAt:      webhook.CreatedAt,
+			UpdatedAt:      webhook.UpdatedAt,
+			PagingSequence: webhook.PagingSequence,
+			Ready:          webhook.Ready,
+		},
+		Name:           webhook.Name,
+		Kind:           webhook.Kind,
+		URL:            webhook.URL,
+		ResourceTypes:  resourceTypesJSON,
+		Operations:     operationsJSON,
+		TimeoutSeconds: webhook.TimeoutSeconds,
+		FailurePolicy:  webhook.FailurePolicy,
+	}, nil
+}
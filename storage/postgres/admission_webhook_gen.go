@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &AdmissionWebhook{}
+
+const AdmissionWebhookTable = "admission_webhooks"
+
+func (*AdmissionWebhook) LabelEntity() PostgresLabel {
+	return &AdmissionWebhookLabel{}
+}
+
+func (*AdmissionWebhook) TableName() string {
+	return AdmissionWebhookTable
+}
+
+func (e *AdmissionWebhook) NewLabel(id, entityID, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &AdmissionWebhookLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		AdmissionWebhookID: sql.NullString{String: entityID, Valid: entityID != ""},
+	}
+}
+
+func (e *AdmissionWebhook) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*AdmissionWebhook
+			AdmissionWebhookLabel `db:"admission_webhook_labels"`
+		}{}
+	}
+	result := &types.AdmissionWebhooks{
+		AdmissionWebhooks: make([]*types.AdmissionWebhook, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type AdmissionWebhookLabel struct {
+	BaseLabelEntity
+	AdmissionWebhookID sql.NullString `db:"admission_webhook_id"`
+}
+
+func (el AdmissionWebhookLabel) LabelsTableName() string {
+	return "admission_webhook_labels"
+}
+
+func (el AdmissionWebhookLabel) ReferenceColumn() string {
+	return "admission_webhook_id"
+}
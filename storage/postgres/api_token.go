@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	sqlxtypes "github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// APIToken entity
+//go:generate smgen storage APIToken github.com/Peripli/service-manager/pkg/types
+type APIToken struct {
+	BaseEntity
+
+	Name       string             `db:"name"`
+	Scopes     sqlxtypes.JSONText `db:"scopes"`
+	Tenant     sql.NullString     `db:"tenant"`
+	ExpiresAt  pq.NullTime        `db:"expires_at"`
+	LastUsedAt pq.NullTime        `db:"last_used_at"`
+	CreatedBy  sql.NullString     `db:"created_by"`
+	TokenHash  string             `db:"token_hash"`
+}
+
+func (t *APIToken) ToObject() (types.Object, error) {
+	scopes := make([]string, 0)
+	if err := toJsonAsObject(t.Scopes, &scopes); err != nil {
+		return nil, fmt.Errorf("could not unmarshal scopes of API token %s: %s", t.ID, err)
+	}
+	return &types.APIToken{
+		Base: types.Base{
+			ID:             t.ID,
+			CreatedAt:      t.CreatedAt,
+			UpdatedAt:      t.UpdatedAt,
+			Labels:         make(map[string][]string),
+			PagingSequence: t.PagingSequence,
+			Ready:          t.Ready,
+		},
+		Name:       t.Name,
+		Scopes:     scopes,
+		Tenant:     t.Tenant.String,
+		ExpiresAt:  toTimePointer(t.ExpiresAt),
+		LastUsedAt: toTimePointer(t.LastUsedAt),
+		CreatedBy:  t.CreatedBy.String,
+		TokenHash:  t.TokenHash,
+	}, nil
+}
+
+func (*APIToken) FromObject(object types.Object) (storage.Entity, error) {
+	token, ok := object.(*types.APIToken)
+	if !ok {
+		return nil, fmt.Errorf("object is not of type APIToken")
+	}
+
+	scopes := token.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	scopesJSON, err := json.Marshal(scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &APIToken{
+		BaseEntity: BaseEntity{
+			ID:             token.ID,
+			CreatedAt:      token.CreatedAt,
+			UpdatedAt:      token.UpdatedAt,
+			PagingSequence: token.PagingSequence,
+			Ready:          token.Ready,
+		},
+		Name:       token.Name,
+		Scopes:     scopesJSON,
+		Tenant:     toNullString(token.Tenant),
+		ExpiresAt:  toNullTime(token.ExpiresAt),
+		LastUsedAt: toNullTime(token.LastUsedAt),
+		CreatedBy:  toNullString(token.CreatedBy),
+		TokenHash:  token.TokenHash,
+	}, nil
+}
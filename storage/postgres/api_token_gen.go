@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &APIToken{}
+
+const APITokenTable = "api_tokens"
+
+func (*APIToken) LabelEntity() PostgresLabel {
+	return &APITokenLabel{}
+}
+
+func (*APIToken) TableName() string {
+	return APITokenTable
+}
+
+func (e *APIToken) NewLabel(id, entityID, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &APITokenLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		APITokenID: sql.NullString{String: entityID, Valid: entityID != ""},
+	}
+}
+
+func (e *APIToken) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*APIToken
+			APITokenLabel `db:"api_token_labels"`
+		}{}
+	}
+	result := &types.APITokens{
+		APITokens: make([]*types.APIToken, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type APITokenLabel struct {
+	BaseLabelEntity
+	APITokenID sql.NullString `db:"api_token_id"`
+}
+
+func (el APITokenLabel) LabelsTableName() string {
+	return "api_token_labels"
+}
+
+func (el APITokenLabel) ReferenceColumn() string {
+	return "api_token_id"
+}
@@ -2,4 +2,4 @@ package postgres
 
 // todo: get automatically from folder
 // change this line with the latest version of the migrations:
-const latestMigrationVersion = "20211125100000"
+const latestMigrationVersion = "20261018210000"
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// IdempotencyKey entity
+//go:generate smgen storage IdempotencyKey github.com/Peripli/service-manager/pkg/types
+type IdempotencyKey struct {
+	BaseEntity
+
+	Key         string `db:"key"`
+	UserName    string `db:"user_name"`
+	RequestHash string `db:"request_hash"`
+
+	StatusCode int    `db:"status_code"`
+	Location   string `db:"location"`
+	Response   []byte `db:"response"`
+}
+
+func (k *IdempotencyKey) ToObject() (types.Object, error) {
+	return &types.IdempotencyKey{
+		Base: types.Base{
+			ID:             k.ID,
+			CreatedAt:      k.CreatedAt,
+			UpdatedAt:      k.UpdatedAt,
+			Labels:         make(map[string][]string),
+			PagingSequence: k.PagingSequence,
+			Ready:          k.Ready,
+		},
+		Key:         k.Key,
+		UserName:    k.UserName,
+		RequestHash: k.RequestHash,
+		StatusCode:  k.StatusCode,
+		Location:    k.Location,
+		Response:    k.Response,
+	}, nil
+}
+
+func (*IdempotencyKey) FromObject(object types.Object) (storage.Entity, error) {
+	key, ok := object.(*types.IdempotencyKey)
+	if !ok {
+		return nil, fmt.Errorf("object is not of type IdempotencyKey")
+	}
+
+	return &IdempotencyKey{
+		BaseEntity: BaseEntity{
+			ID:             key.ID,
+			CreatedAt:      key.CreatedAt,
+			UpdatedAt:      key.UpdatedAt,
+			PagingSequence: key.PagingSequence,
+			Ready:          key.Ready,
+		},
+		Key:         key.Key,
+		UserName:    key.UserName,
+		RequestHash: key.RequestHash,
+		StatusCode:  key.StatusCode,
+		Location:    key.Location,
+		Response:    key.Response,
+	}, nil
+}
@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &IdempotencyKey{}
+
+const IdempotencyKeyTable = "idempotency_keys"
+
+func (*IdempotencyKey) LabelEntity() PostgresLabel {
+	return &IdempotencyKeyLabel{}
+}
+
+func (*IdempotencyKey) TableName() string {
+	return IdempotencyKeyTable
+}
+
+func (e *IdempotencyKey) NewLabel(id, entityID, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &IdempotencyKeyLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		IdempotencyKeyID: sql.NullString{String: entityID, Valid: entityID != ""},
+	}
+}
+
+func (e *IdempotencyKey) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*IdempotencyKey
+			IdempotencyKeyLabel `db:"idempotency_key_labels"`
+		}{}
+	}
+	result := &types.IdempotencyKeys{
+		IdempotencyKeys: make([]*types.IdempotencyKey, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type IdempotencyKeyLabel struct {
+	BaseLabelEntity
+	IdempotencyKeyID sql.NullString `db:"idempotency_key_id"`
+}
+
+func (el IdempotencyKeyLabel) LabelsTableName() string {
+	return "idempotency_key_labels"
+}
+
+func (el IdempotencyKeyLabel) ReferenceColumn() string {
+	return "idempotency_key_id"
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+
+	sqlxtypes "github.com/jmoiron/sqlx/types"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// LabelPolicy entity
+//go:generate smgen storage LabelPolicy github.com/Peripli/service-manager/pkg/types
+type LabelPolicy struct {
+	BaseEntity
+
+	ResourceType string             `db:"resource_type"`
+	MaxLabels    int                `db:"max_labels"`
+	Keys         sqlxtypes.JSONText `db:"keys"`
+}
+
+func (p *LabelPolicy) ToObject() (types.Object, error) {
+	keys := make([]*types.LabelKeyPolicy, 0)
+	if err := toJsonAsObject(p.Keys, &keys); err != nil {
+		return nil, fmt.Errorf("could not unmarshal keys of label policy %s: %s", p.ID, err)
+	}
+	return &types.LabelPolicy{
+		Base: types.Base{
+			ID:             p.ID,
+			CreatedAt:      p.CreatedAt,
+			UpdatedAt:      p.UpdatedAt,
+			Labels:         make(map[string][]string),
+			PagingSequence: p.PagingSequence,
+			Ready:          p.Ready,
+		},
+		ResourceType: p.ResourceType,
+		MaxLabels:    p.MaxLabels,
+		Keys:         keys,
+	}, nil
+}
+
+func (*LabelPolicy) FromObject(object types.Object) (storage.Entity, error) {
+	policy, ok := object.(*types.LabelPolicy)
+	if !ok {
+		return nil, fmt.Errorf("object is not of type LabelPolicy")
+	}
+
+	keys := policy.Keys
+	if keys == nil {
+		keys = []*types.LabelKeyPolicy{}
+	}
+	keysJSON, err := json.Marshal(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LabelPolicy{
+		BaseEntity: BaseEntity{
+			ID:             policy.ID,
+			CreatedAt:      policy.CreatedAt,
+			UpdatedAt:      policy.UpdatedAt,
+			PagingSequence: policy.PagingSequence,
+			Ready:          policy.Ready,
+		},
+		ResourceType: policy.ResourceType,
+		MaxLabels:    policy.MaxLabels,
+		Keys:         keysJSON,
+	}, nil
+}
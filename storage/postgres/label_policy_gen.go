@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &LabelPolicy{}
+
+const LabelPolicyTable = "label_policies"
+
+func (*LabelPolicy) LabelEntity() PostgresLabel {
+	return &LabelPolicyLabel{}
+}
+
+func (*LabelPolicy) TableName() string {
+	return LabelPolicyTable
+}
+
+func (e *LabelPolicy) NewLabel(id, entityID, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &LabelPolicyLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		LabelPolicyID: sql.NullString{String: entityID, Valid: entityID != ""},
+	}
+}
+
+func (e *LabelPolicy) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*LabelPolicy
+			LabelPolicyLabel `db:"label_policy_labels"`
+		}{}
+	}
+	result := &types.LabelPolicies{
+		LabelPolicies: make([]*types.LabelPolicy, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type LabelPolicyLabel struct {
+	BaseLabelEntity
+	LabelPolicyID sql.NullString `db:"label_policy_id"`
+}
+
+func (el LabelPolicyLabel) LabelsTableName() string {
+	return "label_policy_labels"
+}
+
+func (el LabelPolicyLabel) ReferenceColumn() string {
+	return "label_policy_id"
+}
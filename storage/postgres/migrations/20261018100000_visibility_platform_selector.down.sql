@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE visibilities DROP CONSTRAINT unique_public_plan_visibility;
+DELETE FROM visibilities WHERE platform_selector IS NOT NULL;
+DROP FUNCTION IF EXISTS check_unique_public_plan(varchar, varchar, varchar, varchar);
+ALTER TABLE visibilities DROP COLUMN IF EXISTS platform_selector;
+ALTER TABLE visibilities ADD CONSTRAINT unique_public_plan_visibility CHECK (check_unique_public_plan(id, service_plan_id, platform_id));
+
+COMMIT;
@@ -0,0 +1,36 @@
+BEGIN;
+
+ALTER TABLE visibilities ADD COLUMN IF NOT EXISTS platform_selector VARCHAR(2048);
+
+ALTER TABLE visibilities DROP CONSTRAINT unique_public_plan_visibility;
+
+-- visibilities with a platform selector have no platform id but are not public
+CREATE OR REPLACE FUNCTION check_unique_public_plan(visid varchar, spid varchar, pid varchar, selector varchar)
+    RETURNS boolean AS
+$$
+DECLARE
+    i int;
+BEGIN
+    IF (selector IS NOT NULL) THEN
+        RETURN true;
+    END IF;
+
+    SELECT COUNT(*) INTO i FROM visibilities WHERE service_plan_id = spid AND platform_id IS NULL AND platform_selector IS NULL AND id <> visid;
+    IF (i > 0) THEN
+        RETURN false;
+    END IF;
+
+    IF (pid IS NULL) THEN
+        SELECT COUNT(*) INTO i FROM visibilities WHERE service_plan_id = spid AND platform_id IS NOT NULL;
+        IF (i > 0) THEN
+            RETURN false;
+        END IF;
+    END IF;
+
+    RETURN true;
+END
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE visibilities ADD CONSTRAINT unique_public_plan_visibility CHECK (check_unique_public_plan(id, service_plan_id, platform_id, platform_selector));
+
+COMMIT;
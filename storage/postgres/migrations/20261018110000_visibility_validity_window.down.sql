@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE visibilities DROP COLUMN IF EXISTS inactive;
+ALTER TABLE visibilities DROP COLUMN IF EXISTS valid_until;
+ALTER TABLE visibilities DROP COLUMN IF EXISTS valid_from;
+
+COMMIT;
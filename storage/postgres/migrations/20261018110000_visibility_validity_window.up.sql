@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE visibilities ADD COLUMN IF NOT EXISTS valid_from TIMESTAMPTZ;
+ALTER TABLE visibilities ADD COLUMN IF NOT EXISTS valid_until TIMESTAMPTZ;
+ALTER TABLE visibilities ADD COLUMN IF NOT EXISTS inactive BOOLEAN NOT NULL DEFAULT '0';
+
+COMMIT;
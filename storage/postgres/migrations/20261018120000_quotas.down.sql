@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS quota_labels;
+DROP TABLE IF EXISTS quotas;
+
+COMMIT;
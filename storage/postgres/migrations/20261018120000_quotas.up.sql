@@ -0,0 +1,39 @@
+BEGIN;
+
+CREATE TABLE quotas
+(
+  id                        varchar(100) PRIMARY KEY,
+
+  tenant                    varchar(255) NOT NULL CHECK (tenant <> ''),
+  service_offering_id       varchar(100) REFERENCES service_offerings (id) ON DELETE CASCADE,
+  service_plan_id           varchar(100) REFERENCES service_plans (id) ON DELETE CASCADE,
+
+  max_instances             integer CHECK (max_instances >= 0),
+  max_bindings_per_instance integer CHECK (max_bindings_per_instance >= 0),
+
+  created_at                timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at                timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  paging_sequence           BIGSERIAL,
+
+  ready                     boolean NOT NULL
+);
+
+CREATE TABLE quota_labels
+(
+  id         varchar(100) PRIMARY KEY,
+  key        varchar(255) NOT NULL CHECK (key <> ''),
+  val        varchar(255) NOT NULL CHECK (val <> ''),
+  quota_id   varchar(100) NOT NULL REFERENCES quotas (id) ON DELETE CASCADE,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, quota_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS quotas_paging_sequence_uindex
+  on quotas (paging_sequence);
+
+-- a tenant has at most one quota in total, one per offering and one per plan
+CREATE UNIQUE INDEX IF NOT EXISTS quotas_tenant_scope_uindex
+  on quotas (tenant, COALESCE(service_offering_id, ''), COALESCE(service_plan_id, ''));
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS idempotency_key_labels;
+DROP TABLE IF EXISTS idempotency_keys;
+
+COMMIT;
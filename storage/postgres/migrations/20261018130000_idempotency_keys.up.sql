@@ -0,0 +1,43 @@
+BEGIN;
+
+CREATE TABLE idempotency_keys
+(
+  id              varchar(100) PRIMARY KEY,
+
+  key             varchar(255) NOT NULL CHECK (key <> ''),
+  user_name       varchar(255) NOT NULL,
+  request_hash    varchar(100) NOT NULL,
+
+  status_code     integer      NOT NULL DEFAULT 0,
+  location        text         NOT NULL DEFAULT '',
+  response        bytea,
+
+  created_at      timestamptz  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at      timestamptz  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  paging_sequence BIGSERIAL,
+
+  ready           boolean      NOT NULL
+);
+
+CREATE TABLE idempotency_key_labels
+(
+  id                 varchar(100) PRIMARY KEY,
+  key                varchar(255) NOT NULL CHECK (key <> ''),
+  val                varchar(255) NOT NULL CHECK (val <> ''),
+  idempotency_key_id varchar(100) NOT NULL REFERENCES idempotency_keys (id) ON DELETE CASCADE,
+  created_at         timestamptz  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         timestamptz  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, idempotency_key_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idempotency_keys_paging_sequence_uindex
+  on idempotency_keys (paging_sequence);
+
+-- keys are scoped to the user sending them, concurrent requests with the same key cannot both be stored
+CREATE UNIQUE INDEX IF NOT EXISTS idempotency_keys_user_key_uindex
+  on idempotency_keys (user_name, key);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_index
+  on idempotency_keys (created_at);
+
+COMMIT;
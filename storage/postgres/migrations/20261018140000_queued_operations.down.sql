@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS queued_operation_labels;
+DROP TABLE IF EXISTS queued_operations;
+
+COMMIT;
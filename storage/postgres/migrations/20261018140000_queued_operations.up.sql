@@ -0,0 +1,40 @@
+BEGIN;
+
+CREATE TABLE queued_operations
+(
+  id              varchar(100) PRIMARY KEY,
+
+  operation_id    varchar(100) NOT NULL REFERENCES operations (id) ON DELETE CASCADE,
+  resource_type   varchar(255) NOT NULL,
+  tenant          varchar(255) NOT NULL DEFAULT '',
+  payload         bytea,
+
+  created_at      timestamptz  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at      timestamptz  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  paging_sequence BIGSERIAL,
+
+  ready           boolean      NOT NULL
+);
+
+CREATE TABLE queued_operation_labels
+(
+  id                  varchar(100) PRIMARY KEY,
+  key                 varchar(255) NOT NULL CHECK (key <> ''),
+  val                 varchar(255) NOT NULL CHECK (val <> ''),
+  queued_operation_id varchar(100) NOT NULL REFERENCES queued_operations (id) ON DELETE CASCADE,
+  created_at          timestamptz  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at          timestamptz  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, queued_operation_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS queued_operations_paging_sequence_uindex
+  on queued_operations (paging_sequence);
+
+-- an operation is queued at most once
+CREATE UNIQUE INDEX IF NOT EXISTS queued_operations_operation_id_uindex
+  on queued_operations (operation_id);
+
+CREATE INDEX IF NOT EXISTS queued_operations_tenant_index
+  on queued_operations (resource_type, tenant);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX IF EXISTS service_instances_expires_at_index;
+ALTER TABLE service_instances DROP COLUMN IF EXISTS expires_at;
+
+COMMIT;
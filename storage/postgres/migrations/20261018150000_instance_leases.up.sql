@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE service_instances ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS service_instances_expires_at_index
+  on service_instances (expires_at);
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE platforms DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE platforms DROP COLUMN IF EXISTS failed_login_attempts;
+ALTER TABLE platforms DROP COLUMN IF EXISTS old_password_hash;
+ALTER TABLE platforms DROP COLUMN IF EXISTS password_hash;
+
+COMMIT;
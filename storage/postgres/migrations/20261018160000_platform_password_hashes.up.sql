@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE platforms ADD COLUMN IF NOT EXISTS password_hash varchar(500) NOT NULL DEFAULT '';
+ALTER TABLE platforms ADD COLUMN IF NOT EXISTS old_password_hash varchar(500) NOT NULL DEFAULT '';
+ALTER TABLE platforms ADD COLUMN IF NOT EXISTS failed_login_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE platforms ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+COMMIT;
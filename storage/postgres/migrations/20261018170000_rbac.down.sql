@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP TABLE IF EXISTS role_binding_labels;
+DROP TABLE IF EXISTS role_bindings;
+DROP TABLE IF EXISTS role_labels;
+DROP TABLE IF EXISTS roles;
+
+COMMIT;
@@ -0,0 +1,72 @@
+BEGIN;
+
+CREATE TABLE roles
+(
+  id              varchar(100) PRIMARY KEY,
+
+  name            varchar(255) NOT NULL UNIQUE CHECK (name <> ''),
+  description     text,
+  permissions     json NOT NULL DEFAULT '[]',
+
+  created_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  paging_sequence BIGSERIAL,
+
+  ready           boolean NOT NULL
+);
+
+CREATE TABLE role_labels
+(
+  id         varchar(100) PRIMARY KEY,
+  key        varchar(255) NOT NULL CHECK (key <> ''),
+  val        varchar(255) NOT NULL CHECK (val <> ''),
+  role_id    varchar(100) NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, role_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS roles_paging_sequence_uindex
+  on roles (paging_sequence);
+
+CREATE TABLE role_bindings
+(
+  id              varchar(100) PRIMARY KEY,
+
+  role_id         varchar(100) NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+  tenant          varchar(255) NOT NULL DEFAULT '',
+  subjects        json NOT NULL DEFAULT '[]',
+
+  created_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  paging_sequence BIGSERIAL,
+
+  ready           boolean NOT NULL
+);
+
+CREATE TABLE role_binding_labels
+(
+  id              varchar(100) PRIMARY KEY,
+  key             varchar(255) NOT NULL CHECK (key <> ''),
+  val             varchar(255) NOT NULL CHECK (val <> ''),
+  role_binding_id varchar(100) NOT NULL REFERENCES role_bindings (id) ON DELETE CASCADE,
+  created_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, role_binding_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS role_bindings_paging_sequence_uindex
+  on role_bindings (paging_sequence);
+
+CREATE INDEX IF NOT EXISTS role_bindings_tenant_index
+  on role_bindings (tenant);
+
+INSERT INTO roles (id, name, description, permissions, ready)
+VALUES ('viewer', 'viewer', 'reads all resources',
+        '[{"resource_type": "*", "verbs": ["get", "list"]}]', true),
+       ('operator', 'operator', 'reads all resources and manages service instances and bindings',
+        '[{"resource_type": "*", "verbs": ["get", "list"]}, {"resource_type": "service_instances", "verbs": ["*"]}, {"resource_type": "service_bindings", "verbs": ["*"]}]', true),
+       ('admin', 'admin', 'manages all resources',
+        '[{"resource_type": "*", "verbs": ["*"]}]', true);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS api_token_labels;
+DROP TABLE IF EXISTS api_tokens;
+
+COMMIT;
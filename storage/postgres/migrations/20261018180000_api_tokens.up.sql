@@ -0,0 +1,36 @@
+BEGIN;
+
+CREATE TABLE api_tokens
+(
+  id              varchar(100) PRIMARY KEY,
+
+  name            varchar(255) NOT NULL CHECK (name <> ''),
+  scopes          json NOT NULL DEFAULT '[]',
+  tenant          varchar(255),
+  expires_at      timestamptz NOT NULL,
+  last_used_at    timestamptz,
+  created_by      varchar(255),
+  token_hash      varchar(64) NOT NULL UNIQUE,
+
+  created_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  paging_sequence BIGSERIAL,
+
+  ready           boolean NOT NULL
+);
+
+CREATE TABLE api_token_labels
+(
+  id           varchar(100) PRIMARY KEY,
+  key          varchar(255) NOT NULL CHECK (key <> ''),
+  val          varchar(255) NOT NULL CHECK (val <> ''),
+  api_token_id varchar(100) NOT NULL REFERENCES api_tokens (id) ON DELETE CASCADE,
+  created_at   timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at   timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, api_token_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS api_tokens_paging_sequence_uindex
+  on api_tokens (paging_sequence);
+
+COMMIT;
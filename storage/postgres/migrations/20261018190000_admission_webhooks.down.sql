@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS admission_webhook_labels;
+DROP TABLE IF EXISTS admission_webhooks;
+
+COMMIT;
@@ -0,0 +1,36 @@
+BEGIN;
+
+CREATE TABLE admission_webhooks
+(
+  id              varchar(100) PRIMARY KEY,
+
+  name            varchar(255) NOT NULL UNIQUE CHECK (name <> ''),
+  kind            varchar(20) NOT NULL,
+  url             text NOT NULL,
+  resource_types  json NOT NULL DEFAULT '[]',
+  operations      json NOT NULL DEFAULT '[]',
+  timeout_seconds integer NOT NULL DEFAULT 0,
+  failure_policy  varchar(20) NOT NULL DEFAULT '',
+
+  created_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  paging_sequence BIGSERIAL,
+
+  ready           boolean NOT NULL
+);
+
+CREATE TABLE admission_webhook_labels
+(
+  id                   varchar(100) PRIMARY KEY,
+  key                  varchar(255) NOT NULL CHECK (key <> ''),
+  val                  varchar(255) NOT NULL CHECK (val <> ''),
+  admission_webhook_id varchar(100) NOT NULL REFERENCES admission_webhooks (id) ON DELETE CASCADE,
+  created_at           timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at           timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, admission_webhook_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS admission_webhooks_paging_sequence_uindex
+  on admission_webhooks (paging_sequence);
+
+COMMIT;
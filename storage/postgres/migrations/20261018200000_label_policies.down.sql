@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS label_policy_labels;
+DROP TABLE IF EXISTS label_policies;
+
+COMMIT;
@@ -0,0 +1,32 @@
+BEGIN;
+
+CREATE TABLE label_policies
+(
+  id              varchar(100) PRIMARY KEY,
+
+  resource_type   varchar(255) NOT NULL UNIQUE CHECK (resource_type <> ''),
+  max_labels      integer NOT NULL DEFAULT 0,
+  keys            json NOT NULL DEFAULT '[]',
+
+  created_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  paging_sequence BIGSERIAL,
+
+  ready           boolean NOT NULL
+);
+
+CREATE TABLE label_policy_labels
+(
+  id              varchar(100) PRIMARY KEY,
+  key             varchar(255) NOT NULL CHECK (key <> ''),
+  val             varchar(255) NOT NULL CHECK (val <> ''),
+  label_policy_id varchar(100) NOT NULL REFERENCES label_policies (id) ON DELETE CASCADE,
+  created_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, label_policy_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS label_policies_paging_sequence_uindex
+  on label_policies (paging_sequence);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+-- the encrypted resource and label changes of an update, replayed when the update is retried
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS payload bytea;
+
+COMMIT;
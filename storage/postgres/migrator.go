@@ -0,0 +1,182 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/golang-migrate/migrate"
+	migratepg "github.com/golang-migrate/migrate/database/postgres"
+	"github.com/golang-migrate/migrate/source"
+)
+
+// Migration describes a schema migration available in the migrations source
+type Migration struct {
+	Version    uint   `json:"version"`
+	Identifier string `json:"identifier"`
+	Applied    bool   `json:"applied"`
+}
+
+// MigrationStatus describes the state of the database schema compared to the available migrations
+type MigrationStatus struct {
+	// CurrentVersion is the version of the last applied migration or 0 if no migration has been applied
+	CurrentVersion uint `json:"current_version"`
+	// Dirty shows whether the last migration failed and the schema has to be fixed manually
+	Dirty bool `json:"dirty"`
+	// LatestVersion is the version of the last available migration
+	LatestVersion uint `json:"latest_version"`
+	// Migrations contains all available migrations
+	Migrations []Migration `json:"migrations"`
+}
+
+// UpToDate returns whether all available migrations have been successfully applied
+func (ms *MigrationStatus) UpToDate() bool {
+	return !ms.Dirty && ms.CurrentVersion == ms.LatestVersion
+}
+
+// Pending returns the migrations which are not yet applied
+func (ms *MigrationStatus) Pending() []Migration {
+	pending := make([]Migration, 0)
+	for _, migration := range ms.Migrations {
+		if !migration.Applied {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+// Migrator applies and inspects the database schema migrations
+type Migrator struct {
+	migrate *migrate.Migrate
+	source  source.Driver
+}
+
+// NewMigrator creates a Migrator for the provided database using the migrations at the provided URL
+func NewMigrator(db *sql.DB, migrationsURL string) (*Migrator, error) {
+	driver, err := migratepg.WithInstance(db, &migratepg.Config{})
+	if err != nil {
+		return nil, err
+	}
+	m, err := migrate.NewWithDatabaseInstance(migrationsURL, postgresDriverName, driver)
+	if err != nil {
+		return nil, err
+	}
+	m.Log = migrateLogger{}
+
+	src, err := source.Open(migrationsURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not open migrations source %s: %s", migrationsURL, err)
+	}
+
+	return &Migrator{
+		migrate: m,
+		source:  src,
+	}, nil
+}
+
+// Up applies all pending migrations
+func (mg *Migrator) Up() error {
+	return ignoreNoChange(mg.migrate.Up())
+}
+
+// Down rolls back the specified number of applied migrations
+func (mg *Migrator) Down(steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("number of migrations to roll back should be at least 1 but was %d", steps)
+	}
+	return ignoreNoChange(mg.migrate.Steps(-steps))
+}
+
+// Force sets the schema version without running any migration and clears the dirty state.
+// It is meant to be used after manually fixing a failed migration.
+func (mg *Migrator) Force(version int) error {
+	return mg.migrate.Force(version)
+}
+
+// Status returns the state of the database schema compared to the available migrations
+func (mg *Migrator) Status() (*MigrationStatus, error) {
+	status := &MigrationStatus{}
+
+	currentVersion, dirty, err := mg.migrate.Version()
+	if err != nil && err != migrate.ErrNilVersion {
+		return nil, err
+	}
+	status.CurrentVersion = currentVersion
+	status.Dirty = dirty
+
+	version, err := mg.source.First()
+	for err == nil {
+		identifier, readErr := mg.identifier(version)
+		if readErr != nil {
+			return nil, readErr
+		}
+		status.Migrations = append(status.Migrations, Migration{
+			Version:    version,
+			Identifier: identifier,
+			Applied:    version < currentVersion || (version == currentVersion && !dirty),
+		})
+		status.LatestVersion = version
+		version, err = mg.source.Next(version)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// Plan returns the migrations that Up would apply
+func (mg *Migrator) Plan() ([]Migration, error) {
+	status, err := mg.Status()
+	if err != nil {
+		return nil, err
+	}
+	if status.Dirty {
+		return nil, fmt.Errorf("database schema is dirty at version %d and has to be fixed and forced to a version first", status.CurrentVersion)
+	}
+	return status.Pending(), nil
+}
+
+// Close releases the resources held by the migrator. The database is not closed.
+func (mg *Migrator) Close() error {
+	return mg.source.Close()
+}
+
+func (mg *Migrator) identifier(version uint) (string, error) {
+	reader, identifier, err := mg.source.ReadUp(version)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if err := reader.Close(); err != nil {
+		log.D().WithError(err).Error("Could not close migration reader")
+	}
+	return identifier, nil
+}
+
+func ignoreNoChange(err error) error {
+	if err == migrate.ErrNoChange {
+		log.D().Debug("Database schema already up to date")
+		return nil
+	}
+	return err
+}
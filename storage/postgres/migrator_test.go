@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Migration status", func() {
+	var status *MigrationStatus
+
+	BeforeEach(func() {
+		status = &MigrationStatus{
+			CurrentVersion: 2,
+			LatestVersion:  3,
+			Migrations: []Migration{
+				{Version: 1, Identifier: "initial", Applied: true},
+				{Version: 2, Identifier: "labels", Applied: true},
+				{Version: 3, Identifier: "operations", Applied: false},
+			},
+		}
+	})
+
+	Describe("UpToDate", func() {
+		Context("when there are pending migrations", func() {
+			It("returns false", func() {
+				Expect(status.UpToDate()).To(BeFalse())
+			})
+		})
+
+		Context("when the latest migration is applied", func() {
+			It("returns true", func() {
+				status.CurrentVersion = 3
+				Expect(status.UpToDate()).To(BeTrue())
+			})
+		})
+
+		Context("when the schema is dirty", func() {
+			It("returns false", func() {
+				status.CurrentVersion = 3
+				status.Dirty = true
+				Expect(status.UpToDate()).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("Pending", func() {
+		It("returns the migrations that are not applied", func() {
+			Expect(status.Pending()).To(Equal([]Migration{{Version: 3, Identifier: "operations"}}))
+		})
+	})
+})
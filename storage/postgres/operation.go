@@ -49,6 +49,7 @@ type Operation struct {
 	RescheduleTimestamp time.Time          `db:"reschedule_timestamp"`
 	DeletionScheduled   time.Time          `db:"deletion_scheduled"`
 	Context             sqlxtypes.JSONText `db:"context"`
+	Payload             []byte             `db:"payload"`
 }
 
 func (o *Operation) ToObject() (types.Object, error) {
@@ -90,6 +91,7 @@ func (o *Operation) ToObject() (types.Object, error) {
 		ParentID:            o.ParentID.String,
 		RescheduleTimestamp: o.RescheduleTimestamp,
 		DeletionScheduled:   o.DeletionScheduled,
+		EncryptedPayload:    o.Payload,
 	}, nil
 }
 
@@ -137,6 +139,7 @@ func (*Operation) FromObject(object types.Object) (storage.Entity, error) {
 		ParentID:            toNullString(operation.ParentID),
 		RescheduleTimestamp: operation.RescheduleTimestamp,
 		DeletionScheduled:   operation.DeletionScheduled,
+		Payload:             operation.EncryptedPayload,
 	}
 	return o, nil
 }
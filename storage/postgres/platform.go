@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/Peripli/service-manager/storage"
+	"github.com/lib/pq"
 
 	"github.com/Peripli/service-manager/pkg/types"
 )
@@ -30,20 +31,24 @@ import (
 // Platform entity
 type Platform struct {
 	BaseEntity
-	Type              string         `db:"type"`
-	Name              string         `db:"name"`
-	Description       sql.NullString `db:"description"`
-	Username          string         `db:"username"`
-	OldUsername       string         `db:"old_username"`
-	Password          string         `db:"password"`
-	OldPassword       string         `db:"old_password"`
-	Integrity         []byte         `db:"integrity"`
-	Active            bool           `db:"active"`
-	Suspended         bool           `db:"suspended"`
-	CredentialsActive bool           `db:"credentials_active"`
-	LastActive        time.Time      `db:"last_active"`
-	Technical         bool           `db:"technical"`
-	Version           sql.NullString `db:"version"`
+	Type                string         `db:"type"`
+	Name                string         `db:"name"`
+	Description         sql.NullString `db:"description"`
+	Username            string         `db:"username"`
+	OldUsername         string         `db:"old_username"`
+	Password            string         `db:"password"`
+	OldPassword         string         `db:"old_password"`
+	Integrity           []byte         `db:"integrity"`
+	Active              bool           `db:"active"`
+	Suspended           bool           `db:"suspended"`
+	CredentialsActive   bool           `db:"credentials_active"`
+	LastActive          time.Time      `db:"last_active"`
+	Technical           bool           `db:"technical"`
+	Version             sql.NullString `db:"version"`
+	PasswordHash        string         `db:"password_hash"`
+	OldPasswordHash     string         `db:"old_password_hash"`
+	FailedLoginAttempts int            `db:"failed_login_attempts"`
+	LockedUntil         pq.NullTime    `db:"locked_until"`
 }
 
 func (p *Platform) FromObject(object types.Object) (storage.Entity, error) {
@@ -59,15 +64,19 @@ func (p *Platform) FromObject(object types.Object) (storage.Entity, error) {
 			PagingSequence: platform.PagingSequence,
 			Ready:          platform.Ready,
 		},
-		Type:              platform.Type,
-		Name:              platform.Name,
-		Description:       toNullString(platform.Description),
-		Active:            platform.Active,
-		Suspended:         platform.Suspended,
-		CredentialsActive: platform.CredentialsActive,
-		Technical:         platform.Technical,
-		Version:           toNullString(platform.Version),
-		LastActive:        platform.LastActive,
+		Type:                platform.Type,
+		Name:                platform.Name,
+		Description:         toNullString(platform.Description),
+		Active:              platform.Active,
+		Suspended:           platform.Suspended,
+		CredentialsActive:   platform.CredentialsActive,
+		Technical:           platform.Technical,
+		Version:             toNullString(platform.Version),
+		LastActive:          platform.LastActive,
+		PasswordHash:        platform.PasswordHash,
+		OldPasswordHash:     platform.OldPasswordHash,
+		FailedLoginAttempts: platform.FailedLoginAttempts,
+		LockedUntil:         toNullTime(platform.LockedUntil),
 	}
 
 	if platform.Description != "" {
@@ -99,16 +108,20 @@ func (p *Platform) ToObject() (types.Object, error) {
 			PagingSequence: p.PagingSequence,
 			Ready:          p.Ready,
 		},
-		Type:              p.Type,
-		Name:              p.Name,
-		Description:       p.Description.String,
-		Active:            p.Active,
-		Suspended:         p.Suspended,
-		CredentialsActive: p.CredentialsActive,
-		LastActive:        p.LastActive,
-		Technical:         p.Technical,
-		Integrity:         p.Integrity,
-		Version:           p.Version.String,
+		Type:                p.Type,
+		Name:                p.Name,
+		Description:         p.Description.String,
+		Active:              p.Active,
+		Suspended:           p.Suspended,
+		CredentialsActive:   p.CredentialsActive,
+		LastActive:          p.LastActive,
+		Technical:           p.Technical,
+		Integrity:           p.Integrity,
+		Version:             p.Version.String,
+		PasswordHash:        p.PasswordHash,
+		OldPasswordHash:     p.OldPasswordHash,
+		FailedLoginAttempts: p.FailedLoginAttempts,
+		LockedUntil:         toTimePointer(p.LockedUntil),
 	}
 	if len(p.Username) > 0 || len(p.Password) > 0 {
 		platform.Credentials = &types.Credentials{
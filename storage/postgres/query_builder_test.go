@@ -177,6 +177,64 @@ ORDER BY visibilities.paging_sequence ASC ;`)))
 				Expect(queryArgs[0]).Should(Equal("1"))
 			})
 
+			Context("when validity window criteria are used", func() {
+				inWindow := storage.GetSubQuery(storage.QueryForVisibilitiesInValidityWindow)
+
+				It("builds query for inactive visibilities whose window opened", func() {
+					_, err := qb.NewQuery(entity).
+						WithCriteria(query.ByField(query.EqualsOperator, "inactive", "true"), query.ByExists(inWindow)).
+						List(ctx)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(executedQuery).Should(Equal(trim(`
+WITH matching_resources as (SELECT DISTINCT visibilities.paging_sequence
+                            FROM visibilities
+                            WHERE (visibilities.inactive::text = ? AND EXISTS (
+                                SELECT 1
+                                WHERE (visibilities.valid_from IS NULL OR visibilities.valid_from <= now())
+                                AND (visibilities.valid_until IS NULL OR visibilities.valid_until > now()))) )
+SELECT visibilities.*,
+       visibility_labels.id            "visibility_labels.id",
+       visibility_labels.key           "visibility_labels.key",
+       visibility_labels.val           "visibility_labels.val",
+       visibility_labels.created_at    "visibility_labels.created_at",
+       visibility_labels.updated_at    "visibility_labels.updated_at",
+       visibility_labels.visibility_id "visibility_labels.visibility_id"
+FROM visibilities
+         LEFT JOIN visibility_labels ON visibilities.id = visibility_labels.visibility_id
+WHERE visibilities.paging_sequence IN (SELECT matching_resources.paging_sequence FROM matching_resources)
+ORDER BY visibilities.paging_sequence ASC ;`)))
+					Expect(queryArgs).To(HaveLen(1))
+					Expect(queryArgs[0]).Should(Equal("true"))
+				})
+
+				It("builds query for active visibilities whose window closed", func() {
+					_, err := qb.NewQuery(entity).
+						WithCriteria(query.ByField(query.EqualsOperator, "inactive", "false"), query.ByNotExists(inWindow)).
+						List(ctx)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(executedQuery).Should(Equal(trim(`
+WITH matching_resources as (SELECT DISTINCT visibilities.paging_sequence
+                            FROM visibilities
+                            WHERE (visibilities.inactive::text = ? AND NOT EXISTS (
+                                SELECT 1
+                                WHERE (visibilities.valid_from IS NULL OR visibilities.valid_from <= now())
+                                AND (visibilities.valid_until IS NULL OR visibilities.valid_until > now()))) )
+SELECT visibilities.*,
+       visibility_labels.id            "visibility_labels.id",
+       visibility_labels.key           "visibility_labels.key",
+       visibility_labels.val           "visibility_labels.val",
+       visibility_labels.created_at    "visibility_labels.created_at",
+       visibility_labels.updated_at    "visibility_labels.updated_at",
+       visibility_labels.visibility_id "visibility_labels.visibility_id"
+FROM visibilities
+         LEFT JOIN visibility_labels ON visibilities.id = visibility_labels.visibility_id
+WHERE visibilities.paging_sequence IN (SELECT matching_resources.paging_sequence FROM matching_resources)
+ORDER BY visibilities.paging_sequence ASC ;`)))
+					Expect(queryArgs).To(HaveLen(1))
+					Expect(queryArgs[0]).Should(Equal("false"))
+				})
+			})
+
 			Context("when field is missing", func() {
 				It("returns error", func() {
 					criteria := query.ByField(query.EqualsOperator, "non-existing-field", "value")
@@ -1257,7 +1315,7 @@ WHERE visibilities.id = t.id RETURNING *;`)))
 				qb.NewQuery(entity).Query(ctx, storage.QueryForLabelLessVisibilities, params)
 				Expect(executedQuery).Should(Equal(`
 	SELECT v.* FROM visibilities v
-	WHERE (v.platform_id in (?, ?) OR v.platform_id IS NULL) AND
+	WHERE (v.platform_id in (?, ?) OR v.platform_id IS NULL) AND v.platform_selector IS NULL AND
 	NOT EXISTS(SELECT vl.id FROM visibility_labels vl WHERE vl.visibility_id = v.id)`))
 			})
 		})
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// QueuedOperation entity
+//go:generate smgen storage QueuedOperation github.com/Peripli/service-manager/pkg/types
+type QueuedOperation struct {
+	BaseEntity
+
+	OperationID  string `db:"operation_id"`
+	ResourceType string `db:"resource_type"`
+	Tenant       string `db:"tenant"`
+	Payload      []byte `db:"payload"`
+}
+
+func (q *QueuedOperation) ToObject() (types.Object, error) {
+	return &types.QueuedOperation{
+		Base: types.Base{
+			ID:             q.ID,
+			CreatedAt:      q.CreatedAt,
+			UpdatedAt:      q.UpdatedAt,
+			Labels:         make(map[string][]string),
+			PagingSequence: q.PagingSequence,
+			Ready:          q.Ready,
+		},
+		OperationID:  q.OperationID,
+		ResourceType: types.ObjectType(q.ResourceType),
+		Tenant:       q.Tenant,
+		Payload:      q.Payload,
+	}, nil
+}
+
+func (*QueuedOperation) FromObject(object types.Object) (storage.Entity, error) {
+	queued, ok := object.(*types.QueuedOperation)
+	if !ok {
+		return nil, fmt.Errorf("object is not of type QueuedOperation")
+	}
+
+	return &QueuedOperation{
+		BaseEntity: BaseEntity{
+			ID:             queued.ID,
+			CreatedAt:      queued.CreatedAt,
+			UpdatedAt:      queued.UpdatedAt,
+			PagingSequence: queued.PagingSequence,
+			Ready:          queued.Ready,
+		},
+		OperationID:  queued.OperationID,
+		ResourceType: string(queued.ResourceType),
+		Tenant:       queued.Tenant,
+		Payload:      queued.Payload,
+	}, nil
+}
@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &QueuedOperation{}
+
+const QueuedOperationTable = "queued_operations"
+
+func (*QueuedOperation) LabelEntity() PostgresLabel {
+	return &QueuedOperationLabel{}
+}
+
+func (*QueuedOperation) TableName() string {
+	return QueuedOperationTable
+}
+
+func (e *QueuedOperation) NewLabel(id, entityID, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &QueuedOperationLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		QueuedOperationID: sql.NullString{String: entityID, Valid: entityID != ""},
+	}
+}
+
+func (e *QueuedOperation) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*QueuedOperation
+			QueuedOperationLabel `db:"queued_operation_labels"`
+		}{}
+	}
+	result := &types.QueuedOperations{
+		QueuedOperations: make([]*types.QueuedOperation, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type QueuedOperationLabel struct {
+	BaseLabelEntity
+	QueuedOperationID sql.NullString `db:"queued_operation_id"`
+}
+
+func (el QueuedOperationLabel) LabelsTableName() string {
+	return "queued_operation_labels"
+}
+
+func (el QueuedOperationLabel) ReferenceColumn() string {
+	return "queued_operation_id"
+}
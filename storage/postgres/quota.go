@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// Quota entity
+//go:generate smgen storage Quota github.com/Peripli/service-manager/pkg/types
+type Quota struct {
+	BaseEntity
+
+	Tenant            string         `db:"tenant"`
+	ServiceOfferingID sql.NullString `db:"service_offering_id"`
+	ServicePlanID     sql.NullString `db:"service_plan_id"`
+
+	MaxInstances           sql.NullInt64 `db:"max_instances"`
+	MaxBindingsPerInstance sql.NullInt64 `db:"max_bindings_per_instance"`
+}
+
+func (q *Quota) ToObject() (types.Object, error) {
+	return &types.Quota{
+		Base: types.Base{
+			ID:             q.ID,
+			CreatedAt:      q.CreatedAt,
+			UpdatedAt:      q.UpdatedAt,
+			Labels:         make(map[string][]string),
+			PagingSequence: q.PagingSequence,
+			Ready:          q.Ready,
+		},
+		Tenant:                 q.Tenant,
+		ServiceOfferingID:      q.ServiceOfferingID.String,
+		ServicePlanID:          q.ServicePlanID.String,
+		MaxInstances:           toIntPointer(q.MaxInstances),
+		MaxBindingsPerInstance: toIntPointer(q.MaxBindingsPerInstance),
+	}, nil
+}
+
+func (*Quota) FromObject(object types.Object) (storage.Entity, error) {
+	quota, ok := object.(*types.Quota)
+	if !ok {
+		return nil, fmt.Errorf("object is not of type Quota")
+	}
+
+	return &Quota{
+		BaseEntity: BaseEntity{
+			ID:             quota.ID,
+			CreatedAt:      quota.CreatedAt,
+			UpdatedAt:      quota.UpdatedAt,
+			PagingSequence: quota.PagingSequence,
+			Ready:          quota.Ready,
+		},
+		Tenant:                 quota.Tenant,
+		ServiceOfferingID:      toNullString(quota.ServiceOfferingID),
+		ServicePlanID:          toNullString(quota.ServicePlanID),
+		MaxInstances:           toNullInt64(quota.MaxInstances),
+		MaxBindingsPerInstance: toNullInt64(quota.MaxBindingsPerInstance),
+	}, nil
+}
@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &Quota{}
+
+const QuotaTable = "quotas"
+
+func (*Quota) LabelEntity() PostgresLabel {
+	return &QuotaLabel{}
+}
+
+func (*Quota) TableName() string {
+	return QuotaTable
+}
+
+func (e *Quota) NewLabel(id, entityID, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &QuotaLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		QuotaID: sql.NullString{String: entityID, Valid: entityID != ""},
+	}
+}
+
+func (e *Quota) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*Quota
+			QuotaLabel `db:"quota_labels"`
+		}{}
+	}
+	result := &types.Quotas{
+		Quotas: make([]*types.Quota, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type QuotaLabel struct {
+	BaseLabelEntity
+	QuotaID sql.NullString `db:"quota_id"`
+}
+
+func (el QuotaLabel) LabelsTableName() string {
+	return "quota_labels"
+}
+
+func (el QuotaLabel) ReferenceColumn() string {
+	return "quota_id"
+}
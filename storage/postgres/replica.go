@@ -0,0 +1,151 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// replicationLagQuery returns the replication lag of a standby in seconds. A standby that has replayed everything it
+// has received is considered up to date even if the primary has been idle for a while.
+const replicationLagQuery = `
+SELECT CASE
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END;`
+
+// replica is a read-only database which can serve queries as long as its replication lag is within bounds
+type replica struct {
+	name          string
+	db            pgDB
+	queryBuilder  *QueryBuilder
+	maxStaleness  time.Duration
+	checkInterval time.Duration
+
+	mutex         sync.Mutex
+	lastCheckTime time.Time
+	available     bool
+}
+
+func newReplica(name string, db pgDB, maxStaleness, checkInterval time.Duration) *replica {
+	return &replica{
+		name:          name,
+		db:            db,
+		queryBuilder:  NewQueryBuilder(db),
+		maxStaleness:  maxStaleness,
+		checkInterval: checkInterval,
+	}
+}
+
+// isAvailable returns whether the replica can serve reads. The replication lag is checked at most once per check interval.
+func (r *replica) isAvailable(ctx context.Context) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if time.Since(r.lastCheckTime) < r.checkInterval {
+		return r.available
+	}
+	r.lastCheckTime = time.Now()
+
+	var lagSeconds float64
+	if err := r.db.GetContext(ctx, &lagSeconds, replicationLagQuery); err != nil {
+		log.C(ctx).WithError(err).Warnf("Could not determine replication lag of read replica %s", r.name)
+		r.available = false
+		return false
+	}
+
+	lag := time.Duration(lagSeconds * float64(time.Second))
+	r.available = lag <= r.maxStaleness
+	if !r.available {
+		log.C(ctx).Warnf("Read replica %s is %s behind which exceeds the allowed staleness of %s", r.name, lag, r.maxStaleness)
+	}
+	return r.available
+}
+
+// markUnavailable excludes the replica from serving reads until its next check
+func (r *replica) markUnavailable() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.available = false
+	r.lastCheckTime = time.Now()
+}
+
+// replicaSet distributes reads between the available replicas in a round-robin manner
+type replicaSet struct {
+	replicas []*replica
+	next     uint32
+}
+
+// pick returns the next available replica or nil if there is none
+func (rs *replicaSet) pick(ctx context.Context) *replica {
+	if rs == nil || len(rs.replicas) == 0 {
+		return nil
+	}
+	start := atomic.AddUint32(&rs.next, 1)
+	for i := 0; i < len(rs.replicas); i++ {
+		r := rs.replicas[(int(start)+i)%len(rs.replicas)]
+		if r.isAvailable(ctx) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (rs *replicaSet) close() error {
+	if rs == nil {
+		return nil
+	}
+	var closeErr error
+	for _, r := range rs.replicas {
+		if db, ok := r.db.(*sqlx.DB); ok {
+			if err := db.Close(); err != nil {
+				closeErr = err
+			}
+		}
+	}
+	return closeErr
+}
+
+// isReplicaFailure returns whether the error is caused by the replica itself rather than by the query, in which case
+// the query can be retried on the primary database
+func isReplicaFailure(err error) bool {
+	if err == driver.ErrBadConn || err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code.Class() {
+		// connection exception, transaction rollback (e.g. conflict with recovery), operator intervention
+		case "08", "40", "57":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/Peripli/service-manager/storage/postgres/postgresfakes"
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Read replicas", func() {
+	var (
+		ctx  context.Context
+		fake *postgresfakes.FakePgDB
+		r    *replica
+	)
+
+	lagReturns := func(lag float64) func(context.Context, interface{}, string, ...interface{}) error {
+		return func(_ context.Context, dest interface{}, _ string, _ ...interface{}) error {
+			*dest.(*float64) = lag
+			return nil
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fake = &postgresfakes.FakePgDB{}
+		r = newReplica("replica", fake, 5*time.Second, time.Minute)
+	})
+
+	Describe("isAvailable", func() {
+		Context("when the replication lag is within the staleness bound", func() {
+			It("returns true", func() {
+				fake.GetContextStub = lagReturns(1)
+				Expect(r.isAvailable(ctx)).To(BeTrue())
+			})
+		})
+
+		Context("when the replication lag exceeds the staleness bound", func() {
+			It("returns false", func() {
+				fake.GetContextStub = lagReturns(10)
+				Expect(r.isAvailable(ctx)).To(BeFalse())
+			})
+		})
+
+		Context("when the replication lag cannot be determined", func() {
+			It("returns false", func() {
+				fake.GetContextReturns(fmt.Errorf("connection refused"))
+				Expect(r.isAvailable(ctx)).To(BeFalse())
+			})
+		})
+
+		Context("when checked again within the check interval", func() {
+			It("does not query the replica again", func() {
+				fake.GetContextStub = lagReturns(0)
+				Expect(r.isAvailable(ctx)).To(BeTrue())
+				Expect(r.isAvailable(ctx)).To(BeTrue())
+				Expect(fake.GetContextCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when marked as unavailable", func() {
+			It("returns false until the next check", func() {
+				fake.GetContextStub = lagReturns(0)
+				Expect(r.isAvailable(ctx)).To(BeTrue())
+				r.markUnavailable()
+				Expect(r.isAvailable(ctx)).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("pick", func() {
+		Context("when there are no replicas", func() {
+			It("returns nil", func() {
+				var rs *replicaSet
+				Expect(rs.pick(ctx)).To(BeNil())
+				Expect((&replicaSet{}).pick(ctx)).To(BeNil())
+			})
+		})
+
+		Context("when some of the replicas are unavailable", func() {
+			It("returns only available replicas", func() {
+				staleDB := &postgresfakes.FakePgDB{}
+				staleDB.GetContextStub = lagReturns(60)
+				fake.GetContextStub = lagReturns(0)
+				stale := newReplica("stale", staleDB, 5*time.Second, time.Minute)
+				rs := &replicaSet{replicas: []*replica{stale, r}}
+
+				for i := 0; i < 4; i++ {
+					Expect(rs.pick(ctx)).To(Equal(r))
+				}
+			})
+		})
+
+		Context("when all replicas are available", func() {
+			It("distributes reads between them", func() {
+				otherDB := &postgresfakes.FakePgDB{}
+				otherDB.GetContextStub = lagReturns(0)
+				fake.GetContextStub = lagReturns(0)
+				other := newReplica("other", otherDB, 5*time.Second, time.Minute)
+				rs := &replicaSet{replicas: []*replica{other, r}}
+
+				Expect(rs.pick(ctx)).ToNot(Equal(rs.pick(ctx)))
+			})
+		})
+
+		Context("when all replicas are unavailable", func() {
+			It("returns nil", func() {
+				fake.GetContextReturns(fmt.Errorf("connection refused"))
+				rs := &replicaSet{replicas: []*replica{r}}
+				Expect(rs.pick(ctx)).To(BeNil())
+			})
+		})
+	})
+
+	Describe("isReplicaFailure", func() {
+		It("returns true for connection errors", func() {
+			Expect(isReplicaFailure(driver.ErrBadConn)).To(BeTrue())
+			Expect(isReplicaFailure(&pq.Error{Code: "08006"})).To(BeTrue())
+		})
+
+		It("returns true for conflicts with recovery", func() {
+			Expect(isReplicaFailure(&pq.Error{Code: "40001"})).To(BeTrue())
+		})
+
+		It("returns false for query errors", func() {
+			Expect(isReplicaFailure(&pq.Error{Code: "42703"})).To(BeFalse())
+			Expect(isReplicaFailure(fmt.Errorf("unsupported query"))).To(BeFalse())
+		})
+	})
+})
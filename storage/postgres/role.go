@@ -0,0 +1,87 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	sqlxtypes "github.com/jmoiron/sqlx/types"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// Role entity
+//go:generate smgen storage Role github.com/Peripli/service-manager/pkg/types
+type Role struct {
+	BaseEntity
+
+	Name        string             `db:"name"`
+	Description sql.NullString     `db:"description"`
+	Permissions sqlxtypes.JSONText `db:"permissions"`
+}
+
+func (r *Role) ToObject() (types.Object, error) {
+	permissions := make([]types.Permission, 0)
+	if err := toJsonAsObject(r.Permissions, &permissions); err != nil {
+		return nil, fmt.Errorf("could not unmarshal permissions of role %s: %s", r.ID, err)
+	}
+	return &types.Role{
+		Base: types.Base{
+			ID:             r.ID,
+			CreatedAt:      r.CreatedAt,
+			UpdatedAt:      r.UpdatedAt,
+			Labels:         make(map[string][]string),
+			PagingSequence: r.PagingSequence,
+			Ready:          r.Ready,
+		},
+		Name:        r.Name,
+		Description: r.Description.String,
+		Permissions: permissions,
+	}, nil
+}
+
+func (*Role) FromObject(object types.Object) (storage.Entity, error) {
+	role, ok := object.(*types.Role)
+	if !ok {
+		return nil, fmt.Errorf("object is not of type Role")
+	}
+
+	permissions := role.Permissions
+	if permissions == nil {
+		permissions = []types.Permission{}
+	}
+	permissionsJSON, err := json.Marshal(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Role{
+		BaseEntity: BaseEntity{
+			ID:             role.ID,
+			CreatedAt:      role.CreatedAt,
+			UpdatedAt:      role.UpdatedAt,
+			PagingSequence: role.PagingSequence,
+			Ready:          role.Ready,
+		},
+		Name:        role.Name,
+		Description: toNullString(role.Description),
+		Permissions: permissionsJSON,
+	}, nil
+}
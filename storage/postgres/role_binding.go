@@ -0,0 +1,86 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+
+	sqlxtypes "github.com/jmoiron/sqlx/types"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// RoleBinding entity
+//go:generate smgen storage RoleBinding github.com/Peripli/service-manager/pkg/types
+type RoleBinding struct {
+	BaseEntity
+
+	RoleID   string             `db:"role_id"`
+	Tenant   string             `db:"tenant"`
+	Subjects sqlxtypes.JSONText `db:"subjects"`
+}
+
+func (rb *RoleBinding) ToObject() (types.Object, error) {
+	subjects := make([]types.Subject, 0)
+	if err := toJsonAsObject(rb.Subjects, &subjects); err != nil {
+		return nil, fmt.Errorf("could not unmarshal subjects of role binding %s: %s", rb.ID, err)
+	}
+	return &types.RoleBinding{
+		Base: types.Base{
+			ID:             rb.ID,
+			CreatedAt:      rb.CreatedAt,
+			UpdatedAt:      rb.UpdatedAt,
+			Labels:         make(map[string][]string),
+			PagingSequence: rb.PagingSequence,
+			Ready:          rb.Ready,
+		},
+		RoleID:   rb.RoleID,
+		Tenant:   rb.Tenant,
+		Subjects: subjects,
+	}, nil
+}
+
+func (*RoleBinding) FromObject(object types.Object) (storage.Entity, error) {
+	binding, ok := object.(*types.RoleBinding)
+	if !ok {
+		return nil, fmt.Errorf("object is not of type RoleBinding")
+	}
+
+	subjects := binding.Subjects
+	if subjects == nil {
+		subjects = []types.Subject{}
+	}
+	subjectsJSON, err := json.Marshal(subjects)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RoleBinding{
+		BaseEntity: BaseEntity{
+			ID:             binding.ID,
+			CreatedAt:      binding.CreatedAt,
+			UpdatedAt:      binding.UpdatedAt,
+			PagingSequence: binding.PagingSequence,
+			Ready:          binding.Ready,
+		},
+		RoleID:   binding.RoleID,
+		Tenant:   binding.Tenant,
+		Subjects: subjectsJSON,
+	}, nil
+}
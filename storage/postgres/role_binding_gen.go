@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &RoleBinding{}
+
+const RoleBindingTable = "role_bindings"
+
+func (*RoleBinding) LabelEntity() PostgresLabel {
+	return &RoleBindingLabel{}
+}
+
+func (*RoleBinding) TableName() string {
+	return RoleBindingTable
+}
+
+func (e *RoleBinding) NewLabel(id, entityID, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &RoleBindingLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		RoleBindingID: sql.NullString{String: entityID, Valid: entityID != ""},
+	}
+}
+
+func (e *RoleBinding) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*RoleBinding
+			RoleBindingLabel `db:"role_binding_labels"`
+		}{}
+	}
+	result := &types.RoleBindings{
+		RoleBindings: make([]*types.RoleBinding, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type RoleBindingLabel struct {
+	BaseLabelEntity
+	RoleBindingID sql.NullString `db:"role_binding_id"`
+}
+
+func (el RoleBindingLabel) LabelsTableName() string {
+	return "role_binding_labels"
+}
+
+func (el RoleBindingLabel) ReferenceColumn() string {
+	return "role_binding_id"
+}
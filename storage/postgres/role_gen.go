@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &Role{}
+
+const RoleTable = "roles"
+
+func (*Role) LabelEntity() PostgresLabel {
+	return &RoleLabel{}
+}
+
+func (*Role) TableName() string {
+	return RoleTable
+}
+
+func (e *Role) NewLabel(id, entityID, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &RoleLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		RoleID: sql.NullString{String: entityID, Valid: entityID != ""},
+	}
+}
+
+func (e *Role) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*Role
+			RoleLabel `db:"role_labels"`
+		}{}
+	}
+	result := &types.Roles{
+		Roles: make([]*types.Role, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type RoleLabel struct {
+	BaseLabelEntity
+	RoleID sql.NullString `db:"role_id"`
+}
+
+func (el RoleLabel) LabelsTableName() string {
+	return "role_labels"
+}
+
+func (el RoleLabel) ReferenceColumn() string {
+	return "role_id"
+}
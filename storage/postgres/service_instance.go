@@ -23,6 +23,7 @@ import (
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/storage"
 	sqlxtypes "github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
 )
 
 // ServiceInstance entity
@@ -40,6 +41,7 @@ type ServiceInstance struct {
 	UpdateValues         sqlxtypes.JSONText `db:"update_values"`
 	Usable               bool               `db:"usable"`
 	Shared               sql.NullBool       `db:"shared"`
+	ExpiresAt            pq.NullTime        `db:"expires_at"`
 }
 
 func (si *ServiceInstance) ToObject() (types.Object, error) {
@@ -69,6 +71,7 @@ func (si *ServiceInstance) ToObject() (types.Object, error) {
 		UpdateValues:         updateValues,
 		Usable:               si.Usable,
 		Shared:               toBoolPointer(si.Shared),
+		ExpiresAt:            toTimePointer(si.ExpiresAt),
 	}, nil
 }
 
@@ -102,6 +105,7 @@ func (*ServiceInstance) FromObject(object types.Object) (storage.Entity, error)
 		UpdateValues:         newStateBytes,
 		Usable:               serviceInstance.Usable,
 		Shared:               toNullBool(serviceInstance.Shared),
+		ExpiresAt:            toNullTime(serviceInstance.ExpiresAt),
 	}
 
 	return si, nil
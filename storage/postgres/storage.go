@@ -33,8 +33,6 @@ import (
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/Peripli/service-manager/storage"
-	"github.com/golang-migrate/migrate"
-	migratepg "github.com/golang-migrate/migrate/database/postgres"
 	_ "github.com/golang-migrate/migrate/source/file"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -54,6 +52,7 @@ type Storage struct {
 	state                 *storageState
 	layerOneEncryptionKey []byte
 	scheme                *scheme
+	replicas              *replicaSet
 	mutex                 sync.Mutex
 }
 
@@ -74,35 +73,15 @@ func (ps *Storage) Open(settings *storage.Settings) error {
 	ps.mutex.Lock()
 	defer ps.mutex.Unlock()
 	if ps.db == nil {
-		parsedUrl, err := url.Parse(settings.URI)
+		db, err := Connect(settings, ps.ConnectFunc)
 		if err != nil {
-			return fmt.Errorf("could not parse PostgreSQL URI: %s", err)
-		}
-
-		parsedQuery, err := url.ParseQuery(parsedUrl.RawQuery)
-		if err != nil {
-			return fmt.Errorf("could not parse PostgreSQL URL query: %s", err)
-		}
-
-		parsedQuery.Set("read_timeout", strconv.Itoa(settings.ReadTimeout))
-		parsedQuery.Set("write_timeout", strconv.Itoa(settings.WriteTimeout))
-		if settings.SkipSSLValidation {
-			log.D().Infof("skipping ssl validation SkipSSLValidation set to true")
-			parsedQuery.Set("sslmode", "disable")
-		} else {
-			if len(settings.SSLMode) > 0 && len(settings.SSLRootCert) > 0 {
-				log.D().Infof("ssl mode set to %s sslrootcert detected", settings.SSLMode)
-				parsedQuery.Set("sslmode", settings.SSLMode)
-				parsedQuery.Set("sslrootcert", settings.SSLRootCert)
-			}
+			return err
 		}
-		parsedUrl.RawQuery = parsedQuery.Encode()
+		ps.db = sqlx.NewDb(db, postgresDriverName)
 
-		db, err := ps.ConnectFunc(postgresDriverName, parsedUrl.String())
-		if err != nil {
-			return fmt.Errorf("could not connect to PostgreSQL: %s", err)
+		if ps.replicas, err = ps.openReplicas(settings); err != nil {
+			return err
 		}
-		ps.db = sqlx.NewDb(db, postgresDriverName)
 
 		ps.state = &storageState{
 			lastCheckTime:        time.Now(),
@@ -116,8 +95,7 @@ func (ps *Storage) Open(settings *storage.Settings) error {
 		ps.pgDB = ps.db
 		ps.queryBuilder = NewQueryBuilder(ps.pgDB)
 
-		log.D().Debugf("Updating database schema using migrations from %s", settings.MigrationsURL)
-		if err := ps.updateSchema(settings.MigrationsURL, postgresDriverName); err != nil {
+		if err := ps.updateSchema(settings); err != nil {
 			return fmt.Errorf("could not update database schema: %s", err)
 		}
 		ps.scheme = newScheme()
@@ -131,14 +109,92 @@ func (ps *Storage) Open(settings *storage.Settings) error {
 		ps.scheme.introduce(&ServiceInstance{})
 		ps.scheme.introduce(&ServiceBinding{})
 		ps.scheme.introduce(&BrokerPlatformCredential{})
+		ps.scheme.introduce(&Quota{})
+		ps.scheme.introduce(&Role{})
+		ps.scheme.introduce(&RoleBinding{})
+		ps.scheme.introduce(&APIToken{})
+		ps.scheme.introduce(&AdmissionWebhook{})
+		ps.scheme.introduce(&LabelPolicy{})
+		ps.scheme.introduce(&IdempotencyKey{})
+		ps.scheme.introduce(&QueuedOperation{})
 	}
 
 	return nil
 }
 
+func connectionURL(uri string, settings *storage.Settings) (string, error) {
+	parsedUrl, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("could not parse PostgreSQL URI: %s", err)
+	}
+
+	parsedQuery, err := url.ParseQuery(parsedUrl.RawQuery)
+	if err != nil {
+		return "", fmt.Errorf("could not parse PostgreSQL URL query: %s", err)
+	}
+
+	parsedQuery.Set("read_timeout", strconv.Itoa(settings.ReadTimeout))
+	parsedQuery.Set("write_timeout", strconv.Itoa(settings.WriteTimeout))
+	if settings.SkipSSLValidation {
+		log.D().Infof("skipping ssl validation SkipSSLValidation set to true")
+		parsedQuery.Set("sslmode", "disable")
+	} else {
+		if len(settings.SSLMode) > 0 && len(settings.SSLRootCert) > 0 {
+			log.D().Infof("ssl mode set to %s sslrootcert detected", settings.SSLMode)
+			parsedQuery.Set("sslmode", settings.SSLMode)
+			parsedQuery.Set("sslrootcert", settings.SSLRootCert)
+		}
+	}
+	parsedUrl.RawQuery = parsedQuery.Encode()
+	return parsedUrl.String(), nil
+}
+
+// Connect opens a connection to the database described by the settings without updating the database schema
+func Connect(settings *storage.Settings, connectFunc func(driver string, url string) (*sql.DB, error)) (*sql.DB, error) {
+	dbURL, err := connectionURL(settings.URI, settings)
+	if err != nil {
+		return nil, err
+	}
+	db, err := connectFunc(postgresDriverName, dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to PostgreSQL: %s", err)
+	}
+	return db, nil
+}
+
+func (ps *Storage) openReplicas(settings *storage.Settings) (*replicaSet, error) {
+	if len(settings.ReadReplicaURIs) == 0 {
+		return nil, nil
+	}
+
+	replicas := &replicaSet{}
+	for _, uri := range settings.ReadReplicaURIs {
+		replicaURL, err := connectionURL(uri, settings)
+		if err != nil {
+			return nil, err
+		}
+		db, err := ps.ConnectFunc(postgresDriverName, replicaURL)
+		if err != nil {
+			return nil, fmt.Errorf("could not connect to PostgreSQL read replica: %s", err)
+		}
+		replicaDB := sqlx.NewDb(db, postgresDriverName)
+		replicaDB.SetMaxIdleConns(settings.MaxIdleConnections)
+		replicaDB.SetMaxOpenConns(settings.MaxOpenConnections)
+
+		// the host is used for logging only so that credentials do not leak
+		parsedURL, _ := url.Parse(uri)
+		replicas.replicas = append(replicas.replicas, newReplica(parsedURL.Host, replicaDB, settings.ReadReplicas.MaxStaleness, settings.ReadReplicas.CheckInterval))
+	}
+	log.D().Infof("Routing reads outside of transactions to %d read replicas", len(replicas.replicas))
+	return replicas, nil
+}
+
 func (ps *Storage) Close() error {
 	ps.mutex.Lock()
 	defer ps.mutex.Unlock()
+	if err := ps.replicas.close(); err != nil {
+		log.D().WithError(err).Error("Could not close read replica connection")
+	}
 	if ps.db != nil {
 		return ps.db.Close()
 	}
@@ -146,28 +202,56 @@ func (ps *Storage) Close() error {
 	return nil
 }
 
+// read executes a read-only query on an available read replica and falls back to the primary database if there is
+// none or if the replica fails. Reads following a write in the same context, as well as reads in contexts which do not
+// track writes (e.g. the ones of the background jobs), are always executed on the primary database so that the written
+// data is visible.
+func (ps *Storage) read(ctx context.Context, readFunc func(queryBuilder *QueryBuilder) error) error {
+	if storage.ReadsFromReplica(ctx) {
+		if r := ps.replicas.pick(ctx); r != nil {
+			err := readFunc(r.queryBuilder)
+			if err == nil || ctx.Err() != nil || !isReplicaFailure(err) {
+				return err
+			}
+			log.C(ctx).WithError(err).Warnf("Read from replica %s failed. Falling back to primary database...", r.name)
+			r.markUnavailable()
+		}
+	}
+	return readFunc(ps.queryBuilder)
+}
+
 func (ps *Storage) checkOpen() {
 	if ps.pgDB == nil {
 		log.D().Panicln("Storage is not yet open")
 	}
 }
 
-func (ps *Storage) updateSchema(migrationsURL, pgDriverName string) error {
-	driver, err := migratepg.WithInstance(ps.db.DB, &migratepg.Config{})
+func (ps *Storage) updateSchema(settings *storage.Settings) error {
+	migrator, err := NewMigrator(ps.db.DB, settings.MigrationsURL)
 	if err != nil {
 		return err
 	}
-	m, err := migrate.NewWithDatabaseInstance(migrationsURL, pgDriverName, driver)
+	defer func() {
+		if err := migrator.Close(); err != nil {
+			log.D().WithError(err).Error("Could not close migrations source")
+		}
+	}()
+
+	if settings.AutoMigrate {
+		log.D().Debugf("Updating database schema using migrations from %s", settings.MigrationsURL)
+		return migrator.Up()
+	}
+
+	status, err := migrator.Status()
 	if err != nil {
 		return err
 	}
-	m.Log = migrateLogger{}
-	err = m.Up()
-	if err == migrate.ErrNoChange {
-		log.D().Debug("Database schema already up to date")
-		err = nil
+	if !status.UpToDate() {
+		return fmt.Errorf("database schema version %d (dirty: %t) does not match latest migration version %d and automatic migration is disabled; run the migrate command to update the schema",
+			status.CurrentVersion, status.Dirty, status.LatestVersion)
 	}
-	return err
+	log.D().Debugf("Database schema is at latest version %d", status.CurrentVersion)
+	return nil
 }
 
 func (ps *Storage) PingContext(_ context.Context) error {
@@ -185,6 +269,7 @@ func (ps *Storage) Create(ctx context.Context, obj types.Object) (types.Object,
 		return nil, err
 	}
 
+	storage.RecordWrite(ctx)
 	if err := create(ctx, ps.pgDB, pgEntity.TableName(), result, pgEntity); err != nil {
 		return nil, err
 	}
@@ -329,20 +414,21 @@ func (ps *Storage) QueryForList(ctx context.Context, objectType types.ObjectType
 		return nil, err
 	}
 
-	rows, err := ps.queryBuilder.NewQuery(entity).Query(ctx, queryName, queryParams)
-	if err != nil {
-		return nil, err
-	}
-
-	defer func() {
-		if rows == nil {
-			return
-		}
-		if err := rows.Close(); err != nil {
-			log.C(ctx).WithError(err).Error("Could not release connection when checking database")
+	var result types.ObjectList
+	err = ps.read(ctx, func(queryBuilder *QueryBuilder) error {
+		rows, err := queryBuilder.NewQuery(entity).Query(ctx, queryName, queryParams)
+		if err != nil {
+			return err
 		}
-	}()
-	return entity.RowsToList(rows)
+		defer func() {
+			if err := rows.Close(); err != nil {
+				log.C(ctx).WithError(err).Error("Could not release connection when checking database")
+			}
+		}()
+		result, err = entity.RowsToList(rows)
+		return err
+	})
+	return result, err
 }
 
 func (ps *Storage) GetForUpdate(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.Object, error) {
@@ -370,30 +456,47 @@ func (ps *Storage) list(ctx context.Context, objType types.ObjectType, forUpdate
 		return nil, err
 	}
 
-	queryBuilder := ps.queryBuilder.NewQuery(entity).WithCriteria(criteria...)
-	if forUpdate {
-		queryBuilder = queryBuilder.WithLock()
-	}
+	listFunc := func(queryBuilder *QueryBuilder) (types.ObjectList, error) {
+		q := queryBuilder.NewQuery(entity).WithCriteria(criteria...)
+		if forUpdate {
+			q = q.WithLock()
+		}
 
-	var rows *sqlx.Rows
-	if withLabels {
-		rows, err = queryBuilder.List(ctx)
-	} else {
-		rows, err = queryBuilder.ListNoLabels(ctx)
+		var (
+			rows *sqlx.Rows
+			err  error
+		)
+		if withLabels {
+			rows, err = q.List(ctx)
+		} else {
+			rows, err = q.ListNoLabels(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		defer func() {
+			if rows == nil {
+				return
+			}
+			if err := rows.Close(); err != nil {
+				log.C(ctx).WithError(err).Error("Could not release connection when checking database")
+			}
+		}()
+		return entity.RowsToList(rows)
 	}
-	if err != nil {
-		return nil, err
+
+	if forUpdate {
+		return listFunc(ps.queryBuilder)
 	}
 
-	defer func() {
-		if rows == nil {
-			return
-		}
-		if err := rows.Close(); err != nil {
-			log.C(ctx).WithError(err).Error("Could not release connection when checking database")
-		}
-	}()
-	return entity.RowsToList(rows)
+	var result types.ObjectList
+	err = ps.read(ctx, func(queryBuilder *QueryBuilder) error {
+		var err error
+		result, err = listFunc(queryBuilder)
+		return err
+	})
+	return result, err
 }
 
 func (ps *Storage) Count(ctx context.Context, objType types.ObjectType, criteria ...query.Criterion) (int, error) {
@@ -401,7 +504,13 @@ func (ps *Storage) Count(ctx context.Context, objType types.ObjectType, criteria
 	if err != nil {
 		return 0, err
 	}
-	return ps.queryBuilder.NewQuery(entity).WithCriteria(criteria...).Count(ctx)
+	var count int
+	err = ps.read(ctx, func(queryBuilder *QueryBuilder) error {
+		var err error
+		count, err = queryBuilder.NewQuery(entity).WithCriteria(criteria...).Count(ctx)
+		return err
+	})
+	return count, err
 }
 
 func (ps *Storage) CountLabelValues(ctx context.Context, objType types.ObjectType, criteria ...query.Criterion) (int, error) {
@@ -409,7 +518,13 @@ func (ps *Storage) CountLabelValues(ctx context.Context, objType types.ObjectTyp
 	if err != nil {
 		return 0, err
 	}
-	return ps.queryBuilder.NewQuery(entity).WithCriteria(criteria...).CountLabelValues(ctx)
+	var count int
+	err = ps.read(ctx, func(queryBuilder *QueryBuilder) error {
+		var err error
+		count, err = queryBuilder.NewQuery(entity).WithCriteria(criteria...).CountLabelValues(ctx)
+		return err
+	})
+	return count, err
 }
 
 func (ps *Storage) DeleteReturning(ctx context.Context, objType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
@@ -418,6 +533,7 @@ func (ps *Storage) DeleteReturning(ctx context.Context, objType types.ObjectType
 		return nil, err
 	}
 
+	storage.RecordWrite(ctx)
 	rows, err := ps.queryBuilder.NewQuery(entity).WithCriteria(criteria...).DeleteReturning(ctx, "*")
 	defer closeRows(ctx, rows)
 	if err != nil {
@@ -450,6 +566,7 @@ func (ps *Storage) Delete(ctx context.Context, objType types.ObjectType, criteri
 		return err
 	}
 
+	storage.RecordWrite(ctx)
 	result, err := ps.queryBuilder.NewQuery(entity).WithCriteria(criteria...).Delete(ctx)
 	if err != nil {
 		pqError, ok := err.(*pq.Error)
@@ -468,14 +585,22 @@ func (ps *Storage) Delete(ctx context.Context, objType types.ObjectType, criteri
 	return checkRowsAffected(ctx, result)
 }
 
-func (ps *Storage) Update(ctx context.Context, obj types.Object, labelChanges types.LabelChanges, _ ...query.Criterion) (types.Object, error) {
+// Update updates the object. Criteria other than storage.ByVersion are ignored, a version criterion makes the update
+// fail if the object was updated in the meantime.
+func (ps *Storage) Update(ctx context.Context, obj types.Object, labelChanges types.LabelChanges, criteria ...query.Criterion) (types.Object, error) {
 	obj.SetUpdatedAt(time.Now().UTC())
 
 	entity, err := ps.scheme.convert(obj)
 	if err != nil {
 		return nil, err
 	}
-	if err = update(ctx, ps.pgDB, entity.TableName(), entity); err != nil {
+	storage.RecordWrite(ctx)
+	if version, found := storage.VersionFromCriteria(criteria); found {
+		err = updateVersion(ctx, ps.pgDB, entity.TableName(), entity, version)
+	} else {
+		err = update(ctx, ps.pgDB, entity.TableName(), entity)
+	}
+	if err != nil {
 		return nil, err
 	}
 	if err = ps.updateLabels(ctx, obj.GetType(), entity.GetID(), labelChanges); err != nil {
@@ -490,6 +615,7 @@ func (ps *Storage) Update(ctx context.Context, obj types.Object, labelChanges ty
 }
 
 func (ps *Storage) UpdateLabels(ctx context.Context, objectType types.ObjectType, objectID string, labelChanges types.LabelChanges, _ ...query.Criterion) error {
+	storage.RecordWrite(ctx)
 	return ps.updateLabels(ctx, objectType, objectID, labelChanges)
 }
 
@@ -555,6 +681,8 @@ func (ps *Storage) InTransaction(ctx context.Context, f func(ctx context.Context
 		return err
 	}
 
+	// the transaction might have committed even if an error is returned, so reads that follow must go to the primary
+	storage.RecordWrite(ctx)
 	if err = tx.Commit(); err != nil {
 		return err
 	}
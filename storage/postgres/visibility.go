@@ -23,13 +23,18 @@ import (
 	"github.com/Peripli/service-manager/storage"
 
 	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/lib/pq"
 )
 
 //go:generate smgen storage Visibility github.com/Peripli/service-manager/pkg/types
 type Visibility struct {
 	BaseEntity
-	PlatformID    sql.NullString `db:"platform_id"`
-	ServicePlanID string         `db:"service_plan_id"`
+	PlatformID       sql.NullString `db:"platform_id"`
+	ServicePlanID    string         `db:"service_plan_id"`
+	PlatformSelector sql.NullString `db:"platform_selector"`
+	ValidFrom        pq.NullTime    `db:"valid_from"`
+	ValidUntil       pq.NullTime    `db:"valid_until"`
+	Inactive         bool           `db:"inactive"`
 }
 
 func (v *Visibility) ToObject() (types.Object, error) {
@@ -42,8 +47,12 @@ func (v *Visibility) ToObject() (types.Object, error) {
 			PagingSequence: v.PagingSequence,
 			Ready:          v.Ready,
 		},
-		PlatformID:    v.PlatformID.String,
-		ServicePlanID: v.ServicePlanID,
+		PlatformID:       v.PlatformID.String,
+		ServicePlanID:    v.ServicePlanID,
+		PlatformSelector: v.PlatformSelector.String,
+		ValidFrom:        toTimePointer(v.ValidFrom),
+		ValidUntil:       toTimePointer(v.ValidUntil),
+		Inactive:         v.Inactive,
 	}, nil
 }
 
@@ -60,7 +69,11 @@ func (v *Visibility) FromObject(visibility types.Object) (storage.Entity, error)
 			PagingSequence: vis.PagingSequence,
 			Ready:          vis.Ready,
 		},
-		PlatformID:    toNullString(vis.PlatformID),
-		ServicePlanID: vis.ServicePlanID,
+		PlatformID:       toNullString(vis.PlatformID),
+		ServicePlanID:    vis.ServicePlanID,
+		PlatformSelector: toNullString(vis.PlatformSelector),
+		ValidFrom:        toNullTime(vis.ValidFrom),
+		ValidUntil:       toNullTime(vis.ValidUntil),
+		Inactive:         vis.Inactive,
 	}, nil
 }
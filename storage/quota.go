@@ -0,0 +1,207 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// QuotaUsage reports the current usage of a quota
+type QuotaUsage struct {
+	Quota *types.Quota `json:"quota"`
+	// Instances is the number of instances in the scope of the quota
+	Instances int `json:"instances"`
+	// MaxInstanceBindings is the highest number of bindings of a single instance in the scope of the quota
+	MaxInstanceBindings int `json:"max_instance_bindings"`
+}
+
+// QuotaChecker enforces the quotas of tenants on their service instances and bindings.
+// The tenant of instances and bindings is the value of their TenantKey label.
+type QuotaChecker struct {
+	TenantKey string
+}
+
+// LockQuotas locks the quota rows of the tenant until the end of the transaction of the repository, so that concurrent
+// checks for the same tenant are serialized. Tenants without quotas are not locked, as there is nothing to enforce.
+// The repository has to be the repository of the transaction.
+func (qc *QuotaChecker) LockQuotas(ctx context.Context, repository Repository, tenant string) error {
+	_, err := repository.QueryForList(ctx, types.QuotaType, QueryForTenantQuotasForUpdate, map[string]interface{}{
+		"tenant": tenant,
+	})
+	return err
+}
+
+// CheckInstance returns an error if creating an instance of the plan exceeds an instance quota of the tenant
+func (qc *QuotaChecker) CheckInstance(ctx context.Context, repository Repository, tenant, planID string) error {
+	quotas, err := qc.applicableQuotas(ctx, repository, tenant, planID)
+	if err != nil {
+		return err
+	}
+	for _, quota := range quotas {
+		if quota.MaxInstances == nil {
+			continue
+		}
+		criteria, err := qc.instanceCriteria(ctx, repository, quota)
+		if err != nil {
+			return err
+		}
+		instances, err := repository.Count(ctx, types.ServiceInstanceType, criteria...)
+		if err != nil {
+			return err
+		}
+		if instances >= *quota.MaxInstances {
+			return quotaExceeded(fmt.Sprintf("tenant %s reached its quota of %d instances for %s", tenant, *quota.MaxInstances, quota.Scope()))
+		}
+	}
+	return nil
+}
+
+// CheckBinding returns an error if creating a binding for the instance exceeds a binding quota of the tenant
+func (qc *QuotaChecker) CheckBinding(ctx context.Context, repository Repository, tenant string, instance *types.ServiceInstance) error {
+	quotas, err := qc.applicableQuotas(ctx, repository, tenant, instance.ServicePlanID)
+	if err != nil {
+		return err
+	}
+	for _, quota := range quotas {
+		if quota.MaxBindingsPerInstance == nil {
+			continue
+		}
+		bindings, err := repository.Count(ctx, types.ServiceBindingType, query.ByField(query.EqualsOperator, "service_instance_id", instance.ID))
+		if err != nil {
+			return err
+		}
+		if bindings >= *quota.MaxBindingsPerInstance {
+			return quotaExceeded(fmt.Sprintf("tenant %s reached its quota of %d bindings per instance for %s", tenant, *quota.MaxBindingsPerInstance, quota.Scope()))
+		}
+	}
+	return nil
+}
+
+// Usage returns the current usage of all quotas of the tenant
+func (qc *QuotaChecker) Usage(ctx context.Context, repository Repository, tenant string) ([]*QuotaUsage, error) {
+	quotaList, err := repository.List(ctx, types.QuotaType, query.ByField(query.EqualsOperator, "tenant", tenant))
+	if err != nil {
+		return nil, err
+	}
+	usages := make([]*QuotaUsage, 0, quotaList.Len())
+	for i := 0; i < quotaList.Len(); i++ {
+		quota := quotaList.ItemAt(i).(*types.Quota)
+		criteria, err := qc.instanceCriteria(ctx, repository, quota)
+		if err != nil {
+			return nil, err
+		}
+		instances, err := repository.List(ctx, types.ServiceInstanceType, criteria...)
+		if err != nil {
+			return nil, err
+		}
+		usage := &QuotaUsage{
+			Quota:     quota,
+			Instances: instances.Len(),
+		}
+		if instances.Len() > 0 {
+			if usage.MaxInstanceBindings, err = maxInstanceBindings(ctx, repository, instances); err != nil {
+				return nil, err
+			}
+		}
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
+
+// applicableQuotas returns the quotas of the tenant which apply to instances of the plan
+func (qc *QuotaChecker) applicableQuotas(ctx context.Context, repository Repository, tenant, planID string) ([]*types.Quota, error) {
+	if tenant == "" {
+		return nil, nil
+	}
+	quotaList, err := repository.List(ctx, types.QuotaType, query.ByField(query.EqualsOperator, "tenant", tenant))
+	if err != nil || quotaList.Len() == 0 {
+		return nil, err
+	}
+	planObj, err := repository.Get(ctx, types.ServicePlanType, query.ByField(query.EqualsOperator, "id", planID))
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.ServicePlanType.String())
+	}
+	plan := planObj.(*types.ServicePlan)
+
+	quotas := make([]*types.Quota, 0, quotaList.Len())
+	for i := 0; i < quotaList.Len(); i++ {
+		quota := quotaList.ItemAt(i).(*types.Quota)
+		if (quota.ServicePlanID == "" || quota.ServicePlanID == plan.ID) &&
+			(quota.ServiceOfferingID == "" || quota.ServiceOfferingID == plan.ServiceOfferingID) {
+			quotas = append(quotas, quota)
+		}
+	}
+	return quotas, nil
+}
+
+// instanceCriteria returns the criteria selecting the instances in the scope of the quota
+func (qc *QuotaChecker) instanceCriteria(ctx context.Context, repository Repository, quota *types.Quota) ([]query.Criterion, error) {
+	criteria := []query.Criterion{query.ByLabel(query.EqualsOperator, qc.TenantKey, quota.Tenant)}
+	if quota.ServicePlanID != "" {
+		return append(criteria, query.ByField(query.EqualsOperator, "service_plan_id", quota.ServicePlanID)), nil
+	}
+	if quota.ServiceOfferingID != "" {
+		plans, err := repository.ListNoLabels(ctx, types.ServicePlanType, query.ByField(query.EqualsOperator, "service_offering_id", quota.ServiceOfferingID))
+		if err != nil {
+			return nil, err
+		}
+		planIDs := make([]string, 0, plans.Len())
+		for i := 0; i < plans.Len(); i++ {
+			planIDs = append(planIDs, plans.ItemAt(i).GetID())
+		}
+		if len(planIDs) == 0 {
+			planIDs = append(planIDs, "")
+		}
+		return append(criteria, query.ByField(query.InOperator, "service_plan_id", planIDs...)), nil
+	}
+	return criteria, nil
+}
+
+func maxInstanceBindings(ctx context.Context, repository Repository, instances types.ObjectList) (int, error) {
+	instanceIDs := make([]string, 0, instances.Len())
+	for i := 0; i < instances.Len(); i++ {
+		instanceIDs = append(instanceIDs, instances.ItemAt(i).GetID())
+	}
+	bindings, err := repository.ListNoLabels(ctx, types.ServiceBindingType, query.ByField(query.InOperator, "service_instance_id", instanceIDs...))
+	if err != nil {
+		return 0, err
+	}
+	bindingsPerInstance := make(map[string]int)
+	max := 0
+	for i := 0; i < bindings.Len(); i++ {
+		instanceID := bindings.ItemAt(i).(*types.ServiceBinding).ServiceInstanceID
+		bindingsPerInstance[instanceID]++
+		if bindingsPerInstance[instanceID] > max {
+			max = bindingsPerInstance[instanceID]
+		}
+	}
+	return max, nil
+}
+
+func quotaExceeded(description string) error {
+	return &util.HTTPError{
+		ErrorType:   "QuotaExceeded",
+		Description: description,
+		StatusCode:  http.StatusForbidden,
+	}
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage_test
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QuotaChecker", func() {
+	var (
+		ctx            context.Context
+		fakeRepository *storagefakes.FakeStorage
+		checker        *storage.QuotaChecker
+		quotas         []types.Object
+		instanceCount  int
+		bindings       []types.Object
+		countCriteria  []query.Criterion
+	)
+
+	limit := func(i int) *int {
+		return &i
+	}
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		checker = &storage.QuotaChecker{TenantKey: "tenant"}
+		quotas = []types.Object{}
+		instanceCount = 0
+		bindings = []types.Object{}
+
+		fakeRepository = &storagefakes.FakeStorage{}
+		fakeRepository.ListCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			switch objectType {
+			case types.QuotaType:
+				return types.NewObjectArray(quotas...), nil
+			case types.ServiceInstanceType:
+				return types.NewObjectArray(&types.ServiceInstance{Base: types.Base{ID: "instance-id"}}), nil
+			}
+			return types.NewObjectArray(), nil
+		})
+		fakeRepository.ListNoLabelsCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			switch objectType {
+			case types.ServicePlanType:
+				return types.NewObjectArray(&types.ServicePlan{Base: types.Base{ID: "plan-id"}}), nil
+			case types.ServiceBindingType:
+				return types.NewObjectArray(bindings...), nil
+			}
+			return types.NewObjectArray(), nil
+		})
+		fakeRepository.GetReturns(&types.ServicePlan{Base: types.Base{ID: "plan-id"}, ServiceOfferingID: "offering-id"}, nil)
+		fakeRepository.CountCalls(func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (int, error) {
+			countCriteria = criteria
+			if objectType == types.ServiceBindingType {
+				return len(bindings), nil
+			}
+			return instanceCount, nil
+		})
+	})
+
+	Describe("LockQuotas", func() {
+		It("locks the quota rows of the tenant in the transaction", func() {
+			Expect(checker.LockQuotas(ctx, fakeRepository, "t1")).To(Succeed())
+			Expect(fakeRepository.QueryForListCallCount()).To(Equal(1))
+			_, objectType, queryName, queryParams := fakeRepository.QueryForListArgsForCall(0)
+			Expect(objectType).To(Equal(types.QuotaType))
+			Expect(queryName).To(Equal(storage.QueryForTenantQuotasForUpdate))
+			Expect(queryParams).To(Equal(map[string]interface{}{"tenant": "t1"}))
+			Expect(storage.GetNamedQuery(queryName)).To(ContainSubstring("FOR UPDATE"))
+		})
+	})
+
+	Describe("CheckInstance", func() {
+		Context("when the tenant has no quotas", func() {
+			It("allows the instance", func() {
+				Expect(checker.CheckInstance(ctx, fakeRepository, "t1", "plan-id")).To(Succeed())
+				Expect(fakeRepository.CountCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the tenant reached the quota for the plan", func() {
+			It("returns forbidden", func() {
+				quotas = append(quotas, &types.Quota{Tenant: "t1", ServicePlanID: "plan-id", MaxInstances: limit(2)})
+				instanceCount = 2
+				err := checker.CheckInstance(ctx, fakeRepository, "t1", "plan-id")
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusForbidden))
+				Expect(countCriteria).To(ContainElement(query.ByField(query.EqualsOperator, "service_plan_id", "plan-id")))
+			})
+		})
+
+		Context("when the tenant reached the quota for the offering", func() {
+			It("counts the instances of all plans of the offering", func() {
+				quotas = append(quotas, &types.Quota{Tenant: "t1", ServiceOfferingID: "offering-id", MaxInstances: limit(1)})
+				instanceCount = 1
+				Expect(checker.CheckInstance(ctx, fakeRepository, "t1", "plan-id")).ToNot(Succeed())
+				Expect(countCriteria).To(ContainElement(query.ByField(query.InOperator, "service_plan_id", "plan-id")))
+			})
+		})
+
+		Context("when the quota is for another plan", func() {
+			It("allows the instance", func() {
+				quotas = append(quotas, &types.Quota{Tenant: "t1", ServicePlanID: "other-plan-id", MaxInstances: limit(0)})
+				Expect(checker.CheckInstance(ctx, fakeRepository, "t1", "plan-id")).To(Succeed())
+			})
+		})
+
+		Context("when the tenant is below the total quota", func() {
+			It("allows the instance", func() {
+				quotas = append(quotas, &types.Quota{Tenant: "t1", MaxInstances: limit(3)})
+				instanceCount = 2
+				Expect(checker.CheckInstance(ctx, fakeRepository, "t1", "plan-id")).To(Succeed())
+				Expect(countCriteria).To(ConsistOf(query.ByLabel(query.EqualsOperator, "tenant", "t1")))
+			})
+		})
+	})
+
+	Describe("CheckBinding", func() {
+		It("returns forbidden when the instance reached the binding quota", func() {
+			quotas = append(quotas, &types.Quota{Tenant: "t1", MaxBindingsPerInstance: limit(1)})
+			bindings = append(bindings, &types.ServiceBinding{ServiceInstanceID: "instance-id"})
+			instance := &types.ServiceInstance{Base: types.Base{ID: "instance-id"}, ServicePlanID: "plan-id"}
+			err := checker.CheckBinding(ctx, fakeRepository, "t1", instance)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Describe("Usage", func() {
+		It("reports the instances and the most bindings of an instance in the scope of each quota", func() {
+			quotas = append(quotas, &types.Quota{Tenant: "t1", MaxInstances: limit(3), MaxBindingsPerInstance: limit(5)})
+			bindings = append(bindings,
+				&types.ServiceBinding{ServiceInstanceID: "instance-id"},
+				&types.ServiceBinding{ServiceInstanceID: "instance-id"})
+			usages, err := checker.Usage(ctx, fakeRepository, "t1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(usages).To(HaveLen(1))
+			Expect(usages[0].Instances).To(Equal(1))
+			Expect(usages[0].MaxInstanceBindings).To(Equal(2))
+		})
+	})
+})
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type writeTrackerKey struct{}
+
+// writeTracker records whether a write was made in the scope of a context. It is shared by all contexts derived from
+// the one it was attached to, so that a write made deep in the call chain is visible to subsequent reads.
+type writeTracker struct {
+	written int32
+}
+
+// ContextWithWriteTracking returns a context that records the writes made through the storage so that subsequent
+// reads in the same context can be served by the primary database. Reads in contexts without write tracking are always
+// served by the primary database, as it is unknown whether they follow a write.
+func ContextWithWriteTracking(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(writeTrackerKey{}).(*writeTracker); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, writeTrackerKey{}, &writeTracker{})
+}
+
+// RecordWrite marks that a write was made in the scope of the provided context
+func RecordWrite(ctx context.Context) {
+	if tracker, ok := ctx.Value(writeTrackerKey{}).(*writeTracker); ok {
+		atomic.StoreInt32(&tracker.written, 1)
+	}
+}
+
+// HasWrites returns whether a write was made in the scope of the provided context
+func HasWrites(ctx context.Context) bool {
+	tracker, ok := ctx.Value(writeTrackerKey{}).(*writeTracker)
+	return ok && atomic.LoadInt32(&tracker.written) == 1
+}
+
+// ReadsFromReplica returns whether reads in the scope of the provided context can be served by a read replica. This is
+// the case only if the context tracks writes and no write was made in its scope.
+func ReadsFromReplica(ctx context.Context) bool {
+	tracker, ok := ctx.Value(writeTrackerKey{}).(*writeTracker)
+	return ok && atomic.LoadInt32(&tracker.written) == 0
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage_test
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Read consistency", func() {
+	Context("when write tracking is not enabled", func() {
+		It("does not record writes", func() {
+			ctx := context.Background()
+			storage.RecordWrite(ctx)
+			Expect(storage.HasWrites(ctx)).To(BeFalse())
+		})
+
+		It("does not read from replicas", func() {
+			Expect(storage.ReadsFromReplica(context.Background())).To(BeFalse())
+		})
+	})
+
+	Context("when write tracking is enabled", func() {
+		var ctx context.Context
+
+		BeforeEach(func() {
+			ctx = storage.ContextWithWriteTracking(context.Background())
+		})
+
+		It("has no writes initially", func() {
+			Expect(storage.HasWrites(ctx)).To(BeFalse())
+		})
+
+		It("reads from replicas until a write is made", func() {
+			Expect(storage.ReadsFromReplica(ctx)).To(BeTrue())
+			storage.RecordWrite(ctx)
+			Expect(storage.ReadsFromReplica(ctx)).To(BeFalse())
+		})
+
+		It("makes writes in derived contexts visible to the parent context", func() {
+			derived, cancel := context.WithCancel(ctx)
+			defer cancel()
+			storage.RecordWrite(derived)
+			Expect(storage.HasWrites(ctx)).To(BeTrue())
+		})
+
+		It("keeps the existing tracker when enabled again", func() {
+			storage.RecordWrite(ctx)
+			Expect(storage.HasWrites(storage.ContextWithWriteTracking(ctx))).To(BeTrue())
+		})
+	})
+})
@@ -9,6 +9,7 @@ const (
 	QueryForOperationsWithResource
 	QueryForTenantScopedServiceOfferings
 	QueryForInstanceChildrenByLabel
+	QueryForVisibilitiesInValidityWindow
 )
 
 // The sub-queries are dedicated to be used with ByExists/ByNotExists Criterion to allow additional querying/filtering
@@ -51,6 +52,10 @@ var subQueries = map[SubQuery]string{
 		SELECT 1 FROM service_instances i
         INNER JOIN service_instance_labels l ON i.id = l.service_instance_id
 		WHERE  l.key IN ({{.PARENT_KEYS}}) AND l.val = '{{.PARENT_ID}}' AND i.id = service_instances.id`,
+	QueryForVisibilitiesInValidityWindow: `
+	SELECT 1
+	WHERE (visibilities.valid_from IS NULL OR visibilities.valid_from <= now())
+	AND (visibilities.valid_until IS NULL OR visibilities.valid_until > now())`,
 }
 
 func GetSubQuery(query SubQuery) string {
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage
+
+import (
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const versionField = "updated_at"
+
+// ByVersion returns a criterion which matches an object only if it was not updated since updatedAt.
+// Passed to Update, the update fails with util.ErrConcurrentResourceModification if the object was updated in the meantime.
+func ByVersion(updatedAt time.Time) query.Criterion {
+	return query.ByField(query.EqualsOperator, versionField, util.ToRFCNanoFormat(updatedAt))
+}
+
+// VersionFromCriteria returns the version required by a ByVersion criterion, if any
+func VersionFromCriteria(criteria []query.Criterion) (time.Time, bool) {
+	for _, criterion := range criteria {
+		if criterion.Type != query.FieldQuery || criterion.LeftOp != versionField ||
+			criterion.Operator != query.EqualsOperator || len(criterion.RightOp) != 1 {
+			continue
+		}
+		updatedAt, err := time.Parse(time.RFC3339Nano, criterion.RightOp[0])
+		if err != nil {
+			continue
+		}
+		return updatedAt, true
+	}
+	return time.Time{}, false
+}
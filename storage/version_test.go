@@ -0,0 +1,43 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage_test
+
+import (
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Version criteria", func() {
+	It("returns the version of a ByVersion criterion", func() {
+		updatedAt := time.Date(2026, 10, 18, 12, 0, 0, 123456000, time.UTC)
+		version, found := storage.VersionFromCriteria([]query.Criterion{
+			query.ByField(query.EqualsOperator, "id", "id"),
+			storage.ByVersion(updatedAt),
+		})
+		Expect(found).To(BeTrue())
+		Expect(version.Equal(updatedAt)).To(BeTrue())
+	})
+
+	It("returns no version without a ByVersion criterion", func() {
+		_, found := storage.VersionFromCriteria([]query.Criterion{query.ByField(query.EqualsOperator, "id", "id")})
+		Expect(found).To(BeFalse())
+	})
+})
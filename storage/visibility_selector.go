@@ -0,0 +1,81 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// SelectorVisibilities lists the visibilities with a platform selector which satisfy the criteria. It returns the ones
+// whose selector matches the labels of the platform and the IDs of the ones whose selector does not. Visibilities with
+// a platform selector have no platform ID, so queries which treat such visibilities as public should exclude the
+// unmatched ones. The platform is fetched only if there are visibilities with a platform selector.
+func SelectorVisibilities(ctx context.Context, repository Repository, platformID string, criteria ...query.Criterion) ([]*types.Visibility, []string, error) {
+	criteria = append([]query.Criterion{query.ByField(query.NotEqualsOperator, "platform_selector", "")}, criteria...)
+	visibilities, err := repository.ListNoLabels(ctx, types.VisibilityType, criteria...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if visibilities.Len() == 0 {
+		return nil, nil, nil
+	}
+
+	platform, err := repository.Get(ctx, types.PlatformType, query.ByField(query.EqualsOperator, "id", platformID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	matching := make([]*types.Visibility, 0)
+	unmatchedIDs := make([]string, 0)
+	for i := 0; i < visibilities.Len(); i++ {
+		visibility := visibilities.ItemAt(i).(*types.Visibility)
+		if VisibilityMatchesPlatform(ctx, visibility, platform.GetLabels()) {
+			matching = append(matching, visibility)
+		} else {
+			unmatchedIDs = append(unmatchedIDs, visibility.ID)
+		}
+	}
+	return matching, unmatchedIDs, nil
+}
+
+// VisibilityMatchesPlatform returns true if the platform selector of the visibility matches the platform labels
+func VisibilityMatchesPlatform(ctx context.Context, visibility *types.Visibility, platformLabels types.Labels) bool {
+	criteria, err := query.Parse(query.LabelQuery, visibility.PlatformSelector)
+	if err != nil {
+		log.C(ctx).WithError(err).Errorf("Invalid platform selector of visibility with id %s", visibility.ID)
+		return false
+	}
+	return query.MatchesLabels(criteria, platformLabels)
+}
+
+// ExcludingVisibilities returns a criterion excluding the visibilities with the provided IDs, or no criteria if there
+// are none
+func ExcludingVisibilities(ids []string) []query.Criterion {
+	if len(ids) == 0 {
+		return []query.Criterion{}
+	}
+	return []query.Criterion{query.ByField(query.NotInOperator, "id", ids...)}
+}
+
+// ByVisibilityWindow selects the visibilities which are currently within their validity window
+func ByVisibilityWindow() query.Criterion {
+	return query.ByExists(GetSubQuery(QueryForVisibilitiesInValidityWindow))
+}
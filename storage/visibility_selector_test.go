@@ -0,0 +1,96 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage_test
+
+import (
+	"context"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Visibility platform selector", func() {
+	var (
+		ctx            context.Context
+		fakeRepository *storagefakes.FakeStorage
+		platform       *types.Platform
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		platform = &types.Platform{
+			Base: types.Base{
+				ID:     "platform-id",
+				Labels: types.Labels{"region": {"eu"}, "type": {"kubernetes"}},
+			},
+		}
+		fakeRepository = &storagefakes.FakeStorage{}
+		fakeRepository.GetReturns(platform, nil)
+	})
+
+	Describe("SelectorVisibilities", func() {
+		Context("when there are no visibilities with a platform selector", func() {
+			It("does not fetch the platform", func() {
+				fakeRepository.ListNoLabelsReturns(types.NewObjectArray(), nil)
+				matching, unmatchedIDs, err := storage.SelectorVisibilities(ctx, fakeRepository, platform.ID)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(matching).To(BeEmpty())
+				Expect(unmatchedIDs).To(BeEmpty())
+				Expect(fakeRepository.GetCallCount()).To(Equal(0))
+			})
+		})
+
+		It("splits the visibilities by whether their selector matches the platform labels", func() {
+			fakeRepository.ListNoLabelsReturns(&types.Visibilities{Visibilities: []*types.Visibility{
+				{Base: types.Base{ID: "eu"}, PlatformSelector: "region eq 'eu' and type eq 'kubernetes'"},
+				{Base: types.Base{ID: "us"}, PlatformSelector: "region eq 'us'"},
+			}}, nil)
+			byPlan := query.ByField(query.EqualsOperator, "service_plan_id", "plan-id")
+
+			matching, unmatchedIDs, err := storage.SelectorVisibilities(ctx, fakeRepository, platform.ID, byPlan)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(matching).To(HaveLen(1))
+			Expect(matching[0].ID).To(Equal("eu"))
+			Expect(unmatchedIDs).To(ConsistOf("us"))
+
+			_, _, criteria := fakeRepository.ListNoLabelsArgsForCall(0)
+			Expect(criteria).To(ContainElement(byPlan))
+			Expect(criteria).To(ContainElement(query.ByField(query.NotEqualsOperator, "platform_selector", "")))
+		})
+	})
+
+	Describe("VisibilityMatchesPlatform", func() {
+		It("does not match an invalid selector", func() {
+			visibility := &types.Visibility{PlatformSelector: "region eq"}
+			Expect(storage.VisibilityMatchesPlatform(ctx, visibility, platform.Labels)).To(BeFalse())
+		})
+	})
+
+	Describe("ExcludingVisibilities", func() {
+		It("returns no criteria when there is nothing to exclude", func() {
+			Expect(storage.ExcludingVisibilities(nil)).To(BeEmpty())
+		})
+
+		It("excludes the visibilities by ID", func() {
+			Expect(storage.ExcludingVisibilities([]string{"id"})).To(ConsistOf(query.ByField(query.NotInOperator, "id", "id")))
+		})
+	})
+})
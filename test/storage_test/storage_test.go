@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/gofrs/uuid"
 	"net/http"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -402,6 +403,49 @@ var _ = Describe("Test", func() {
 			Expect(newOp.Context.UserInfo).To(BeNil())
 		})
 	})
+
+	Context("when the quotas of a tenant are locked concurrently", func() {
+		It("serializes the transactions", func() {
+			maxInstances := 1
+			_, err := ctx.SMRepository.Create(context.Background(), &types.Quota{
+				Base:         types.Base{ID: "quota-id", Ready: true},
+				Tenant:       "t1",
+				MaxInstances: &maxInstances,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			defer func() {
+				byID := query.ByField(query.EqualsOperator, "id", "quota-id")
+				Expect(ctx.SMRepository.Delete(context.Background(), types.QuotaType, byID)).To(Succeed())
+			}()
+
+			checker := &storage.QuotaChecker{TenantKey: "tenant"}
+			locked := make(chan struct{})
+			var released int32
+			done := make(chan error, 1)
+			go func() {
+				done <- ctx.SMRepository.InTransaction(context.Background(), func(txCtx context.Context, repository storage.Repository) error {
+					if err := checker.LockQuotas(txCtx, repository, "t1"); err != nil {
+						return err
+					}
+					close(locked)
+					time.Sleep(500 * time.Millisecond)
+					atomic.StoreInt32(&released, 1)
+					return nil
+				})
+			}()
+
+			Eventually(locked, 5*time.Second).Should(BeClosed())
+			err = ctx.SMRepository.InTransaction(context.Background(), func(txCtx context.Context, repository storage.Repository) error {
+				if err := checker.LockQuotas(txCtx, repository, "t1"); err != nil {
+					return err
+				}
+				Expect(atomic.LoadInt32(&released)).To(Equal(int32(1)))
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(<-done).ToNot(HaveOccurred())
+		})
+	})
 })
 
 func compareLabels(actual, expected types.Labels) {